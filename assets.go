@@ -0,0 +1,26 @@
+// Package forum holds the files the server needs at run time, embedded in
+// the binary so it can be copied anywhere and started from any directory.
+package forum
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+//go:embed public
+var staticFiles embed.FS
+
+// Templates returns the embedded page templates
+func Templates() fs.FS {
+	sub, _ := fs.Sub(templateFiles, "templates")
+	return sub
+}
+
+// Static returns the embedded files served under /static/
+func Static() fs.FS {
+	sub, _ := fs.Sub(staticFiles, "public")
+	return sub
+}
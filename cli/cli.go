@@ -0,0 +1,389 @@
+// Package cli holds the operator subcommands of the forum binary. Each one
+// works on the Service returned by internal.InitAllDatabaseManagers.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"forum/internal"
+	"forum/models"
+	"forum/utils"
+)
+
+// ErrUsage is returned for a missing or unknown subcommand or bad arguments,
+// the usage has already been printed
+var ErrUsage = errors.New("usage error")
+
+// command is one `forum <name> [flags] [args]` subcommand
+type command struct {
+	name  string
+	args  string
+	help  string
+	flags func(fs *flag.FlagSet) // registers the flags of the command, may be nil
+	run   func(s *internal.Service, out io.Writer, fs *flag.FlagSet) error
+}
+
+var commands = []command{
+	{name: "serve", help: "run the web server (the default)"},
+	{name: "user create", args: "--name NAME --email EMAIL [--role ROLE] [--password PASSWORD]", help: "create an account, a password is generated when none is given",
+		flags: func(fs *flag.FlagSet) {
+			fs.String("name", "", "user name")
+			fs.String("email", "", "email address")
+			fs.String("role", models.RoleMember, "member, moderator or admin")
+			fs.String("password", "", "password, generated and printed when empty")
+		},
+		run: createUser},
+	{name: "user set-role", args: "USER ROLE", help: "make a user a member, moderator or admin", run: setRole},
+	{name: "user reset-password", args: "[--password PASSWORD] USER", help: "replace a password and end the user's sessions, a password is generated when none is given",
+		flags: func(fs *flag.FlagSet) {
+			fs.String("password", "", "new password, generated and printed when empty")
+		},
+		run: resetPassword},
+	{name: "user ban", args: "[--lift] USER", help: "keep a user from signing in and end their sessions",
+		flags: func(fs *flag.FlagSet) {
+			fs.Bool("lift", false, "let the user sign in again")
+		},
+		run: banUser},
+	{name: "user delete", args: "--yes [--purge] USER", help: "delete an account following the AccountDeletion policy",
+		flags: func(fs *flag.FlagSet) {
+			fs.Bool("yes", false, "confirm the deletion")
+			fs.Bool("purge", false, "remove the account row and everything the user wrote instead")
+		},
+		run: deleteUser},
+	{name: "session purge", args: "[--all]", help: "remove expired sessions",
+		flags: func(fs *flag.FlagSet) {
+			fs.Bool("all", false, "remove every session, signing everybody out")
+		},
+		run: purgeSessions},
+	{name: "stats", help: "print the number of users, threads, replies and sessions", run: stats},
+	{name: "db check", help: "check the connection, migrations, integrity and references of the database", run: checkDatabase},
+	{name: "backup create", help: "write a snapshot of the SQLite database to Backup.Dir", run: createBackup},
+	{name: "backup list", help: "list the snapshots of Backup.Dir, newest first", run: listBackups},
+	{name: "backup verify", args: "SNAPSHOT", help: "run the integrity check on a snapshot file", run: verifyBackup},
+	{name: "backup restore", args: "--yes SNAPSHOT", help: "replace the database with a verified snapshot, the current one is saved first",
+		flags: func(fs *flag.FlagSet) {
+			fs.Bool("yes", false, "confirm the restore")
+		},
+		run: restoreBackup},
+}
+
+// Usage prints the subcommands
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s\n    \t%s\n", strings.TrimSpace(c.name+" "+c.args), c.help)
+	}
+}
+
+// IsServe reports whether args start the web server: no subcommand or serve
+func IsServe(args []string) bool {
+	return len(args) == 0 || args[0] == "serve"
+}
+
+// find returns the subcommand named by the first words of args
+func find(args []string) (command, bool) {
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if c.run != nil && len(args) >= len(words) && strings.Join(args[:len(words)], " ") == c.name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// Check prints the usage and returns ErrUsage when args name no subcommand,
+// so a typo is reported before the database is opened
+func Check(args []string, out io.Writer) error {
+	if _, ok := find(args); !ok {
+		fmt.Fprintf(out, "unknown command %q\n", strings.Join(args, " "))
+		Usage(out)
+		return ErrUsage
+	}
+	return nil
+}
+
+// Run runs the subcommand named by the first words of args and writes its
+// output to out. USER is a name, or an email when it contains @.
+func Run(s *internal.Service, args []string, out io.Writer) error {
+	c, ok := find(args)
+	if !ok {
+		return Check(args, out)
+	}
+	words := strings.Fields(c.name)
+
+	fs := flag.NewFlagSet("forum "+c.name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintf(out, "usage: forum %s %s\n%s\n", c.name, c.args, c.help)
+		fs.PrintDefaults()
+	}
+	if c.flags != nil {
+		c.flags(fs)
+	}
+	if err := fs.Parse(args[len(words):]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return ErrUsage
+	}
+	if err := c.run(s, out, fs); err != nil {
+		if errors.Is(err, ErrUsage) {
+			fs.Usage()
+		}
+		return err
+	}
+	return nil
+}
+
+// argUser finds the user named by the single argument of a command
+func argUser(s *internal.Service, fs *flag.FlagSet) (models.User, error) {
+	if fs.NArg() != 1 {
+		return models.User{}, ErrUsage
+	}
+	return s.FindUser(fs.Arg(0))
+}
+
+func flagString(fs *flag.FlagSet, name string) string {
+	return fs.Lookup(name).Value.String()
+}
+
+func flagBool(fs *flag.FlagSet, name string) bool {
+	return fs.Lookup(name).Value.String() == "true"
+}
+
+func createUser(s *internal.Service, out io.Writer, fs *flag.FlagSet) error {
+	name, email := flagString(fs, "name"), flagString(fs, "email")
+	if name == "" || email == "" || fs.NArg() > 0 {
+		return ErrUsage
+	}
+	password, generated := flagString(fs, "password"), false
+	if password == "" {
+		password, generated = internal.GeneratePassword(), true
+	}
+	user, err := s.CreateAccount(name, email, password, flagString(fs, "role"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "created user %s (id %d)\n", user.Name, user.Id)
+	if generated {
+		fmt.Fprintf(out, "password: %s\n", password)
+	}
+	return nil
+}
+
+func setRole(s *internal.Service, out io.Writer, fs *flag.FlagSet) error {
+	if fs.NArg() != 2 {
+		return ErrUsage
+	}
+	user, err := s.FindUser(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := s.SetRole(user.Id, fs.Arg(1)); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s is now %s\n", user.Name, fs.Arg(1))
+	return nil
+}
+
+func resetPassword(s *internal.Service, out io.Writer, fs *flag.FlagSet) error {
+	user, err := argUser(s, fs)
+	if err != nil {
+		return err
+	}
+	password, generated := flagString(fs, "password"), false
+	if password == "" {
+		password, generated = internal.GeneratePassword(), true
+	}
+	if err := s.ResetPassword(user.Id, password); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "password of %s replaced, their sessions ended\n", user.Name)
+	if generated {
+		fmt.Fprintf(out, "password: %s\n", password)
+	}
+	return nil
+}
+
+func banUser(s *internal.Service, out io.Writer, fs *flag.FlagSet) error {
+	user, err := argUser(s, fs)
+	if err != nil {
+		return err
+	}
+	if flagBool(fs, "lift") {
+		if err := s.LiftBan(user.Id); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s can sign in again\n", user.Name)
+		return nil
+	}
+	if err := s.BanUser(user.Id); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s is banned\n", user.Name)
+	return nil
+}
+
+func deleteUser(s *internal.Service, out io.Writer, fs *flag.FlagSet) error {
+	user, err := argUser(s, fs)
+	if err != nil {
+		return err
+	}
+	if !flagBool(fs, "yes") {
+		return fmt.Errorf("pass --yes to delete %s", user.Name)
+	}
+	if flagBool(fs, "purge") {
+		if err := s.PurgeAccount(user.Id); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "purged %s\n", user.Name)
+		return nil
+	}
+	if err := s.DeleteAccount(user.Id); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "deleted %s (%s)\n", user.Name, internal.DeletionPolicy())
+	return nil
+}
+
+func purgeSessions(s *internal.Service, out io.Writer, fs *flag.FlagSet) error {
+	if fs.NArg() > 0 {
+		return ErrUsage
+	}
+	before := time.Now().Add(-utils.SessionLifetime())
+	if flagBool(fs, "all") {
+		before = time.Now().Add(time.Second)
+	}
+	n, err := s.PurgeSessions(before)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "removed %d sessions\n", n)
+	return nil
+}
+
+func stats(s *internal.Service, out io.Writer, fs *flag.FlagSet) error {
+	statuses, err := s.UserStatusCounts()
+	if err != nil {
+		return err
+	}
+	threads, err := s.TotalThreadsCount()
+	if err != nil {
+		return err
+	}
+	posts, err := s.TotalPostsCount()
+	if err != nil {
+		return err
+	}
+	sessions, err := s.SessionCount()
+	if err != nil {
+		return err
+	}
+	online, err := s.CheckOnlineUsers(10)
+	if err != nil {
+		return err
+	}
+
+	users := 0
+	var byStatus []string
+	for status, count := range statuses {
+		users += count
+		byStatus = append(byStatus, fmt.Sprintf("%d %s", count, status))
+	}
+	sort.Strings(byStatus)
+	if len(byStatus) > 0 {
+		fmt.Fprintf(out, "users     %d (%s)\n", users, strings.Join(byStatus, ", "))
+	} else {
+		fmt.Fprintln(out, "users     0")
+	}
+	fmt.Fprintf(out, "threads   %d\n", threads)
+	fmt.Fprintf(out, "replies   %d\n", posts)
+	fmt.Fprintf(out, "sessions  %d (%d online)\n", sessions, len(online))
+	return nil
+}
+
+func checkDatabase(s *internal.Service, out io.Writer, fs *flag.FlagSet) error {
+	failed := 0
+	for _, check := range s.CheckDatabase() {
+		if check.Err != nil {
+			failed++
+			fmt.Fprintf(out, "%-11s FAIL %v\n", check.Name, check.Err)
+		} else {
+			fmt.Fprintf(out, "%-11s ok\n", check.Name)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d database checks failed", failed)
+	}
+	return nil
+}
+
+func createBackup(s *internal.Service, out io.Writer, fs *flag.FlagSet) error {
+	if fs.NArg() > 0 {
+		return ErrUsage
+	}
+	backup, err := s.CreateBackup(context.Background(), time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "wrote %s (%d bytes)\n", filepath.Join(internal.BackupDir(), backup.Name), backup.Size)
+	return nil
+}
+
+func listBackups(s *internal.Service, out io.Writer, fs *flag.FlagSet) error {
+	if fs.NArg() > 0 {
+		return ErrUsage
+	}
+	backups, err := internal.ListBackups()
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups {
+		fmt.Fprintf(out, "%s  %10d  %s\n", backup.Name, backup.Size, backup.CreatedAt.Local().Format(time.DateTime))
+	}
+	if len(backups) == 0 {
+		fmt.Fprintf(out, "no snapshots in %s\n", internal.BackupDir())
+	}
+	return nil
+}
+
+func verifyBackup(s *internal.Service, out io.Writer, fs *flag.FlagSet) error {
+	if fs.NArg() != 1 {
+		return ErrUsage
+	}
+	path := fs.Arg(0)
+	if found, err := internal.BackupPath(path); err == nil {
+		path = found
+	}
+	if err := internal.VerifyBackup(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s ok\n", path)
+	return nil
+}
+
+func restoreBackup(s *internal.Service, out io.Writer, fs *flag.FlagSet) error {
+	if fs.NArg() != 1 {
+		return ErrUsage
+	}
+	if !flagBool(fs, "yes") {
+		return fmt.Errorf("pass --yes to replace the database with %s", fs.Arg(0))
+	}
+	saved, err := s.RestoreBackup(context.Background(), fs.Arg(0))
+	if saved.Name != "" {
+		fmt.Fprintf(out, "saved the current database as %s\n", saved.Name)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "restored %s\n", fs.Arg(0))
+	return nil
+}
@@ -3,105 +3,315 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"forum"
+	"forum/cli"
+	"forum/config"
 	"forum/internal"
+	"forum/internal/data"
 	"forum/routes"
 	"forum/utils"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-type Configuration struct {
-	Address      string
-	ReadTimeout  int64
-	WriteTimeout int64
-	Static       string
-}
-
-var config Configuration
+var cfg config.Configuration
 
-func init() {
-	file, err := os.Open("config/config.json")
+func main() {
+	flags := flag.NewFlagSet("forum", flag.ContinueOnError)
+	migrate := flags.Bool("migrate", false, "run the database migrations before serving")
+	printConfig := flags.Bool("print-config", false, "print the merged configuration as JSON, secrets hidden, and exit")
+	generateCert := flags.Bool("generate-cert", false, "write a self-signed certificate to TLS.Cert and TLS.Key (default pkg/cert.pem and pkg/key.pem) and exit")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: forum [flags] [command]")
+		cli.Usage(flags.Output())
+		fmt.Fprintln(flags.Output(), "Flags:")
+		flags.PrintDefaults()
+	}
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "serve" { // forum serve --migrate
+		args = args[1:]
+	}
+	var err error
+	cfg, err = config.Load(flags, args, os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if *generateCert {
+		// before the validation, which fails while the files do not exist
+		os.Exit(writeCertificate())
+	}
 	if err != nil {
-		utils.Danger("Cannot open config file", err)
+		fmt.Fprintln(os.Stderr, "invalid configuration:")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	decoder := json.NewDecoder(file)
-	config = Configuration{}
+	if *printConfig {
+		out, _ := json.MarshalIndent(cfg.Redacted(), "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	if err := utils.SetupLogging(cfg.Log.Format, cfg.Log.Level, cfg.Log.File); err != nil {
+		utils.Danger("Cannot set up logging", err)
+	}
+	slog.Info("initialized with configuration", "config", cfg)
 
-	err = decoder.Decode(&config)
-	if err != nil {
-		utils.Danger("Cannot get configuration from file", err)
+	serve := cli.IsServe(flags.Args())
+	if !serve && cli.Check(flags.Args(), os.Stdout) != nil {
+		os.Exit(2)
+	}
+	if serve {
+		templates := utils.Overlay(cfg.Templates, forum.Templates())
+		if _, err := utils.LoadTemplates(templates, cfg.DevTemplates); err != nil {
+			utils.Danger("Cannot load templates", err)
+			os.Exit(1)
+		}
 	}
-	fmt.Println("Initialized with configuration:\n", config)
-}
 
-func main() {
-	dbManager, err := internal.ConnectDB("pkg", "mydb.db")
+	dbManager, err := internal.ConnectDatabase(cfg.Database)
 	if err != nil {
 		utils.Danger("Cannot connect to database", err)
 		return
 	}
 	defer dbManager.Close()
 
-	x := os.Args
-	for _, arg := range x {
-		if arg == "--migrate" {
-			if err := internal.RunMigrations(dbManager); err != nil {
-				utils.Danger("Migration error:", err)
-				return
-			}
-			fmt.Println("Database migrations applied successfully.")
+	if *migrate {
+		if err := internal.RunMigrations(dbManager); err != nil {
+			utils.Danger("Migration error:", err)
+			return
 		}
+		slog.Info("database migrations applied")
+	}
+
+	service := internal.InitAllDatabaseManagers(dbManager)
+	internal.SetDeletionPolicy(cfg.AccountDeletion)
+	internal.SetBackupDir(cfg.Backup.Dir)
+	utils.SetSigningKey(cfg.SecretKey)
+	utils.SetBaseURL(cfg.BaseURL)
+	utils.SetCookieSettings(utils.CookieSettings{
+		Name:     cfg.Session.Cookie,
+		Lifetime: time.Duration(cfg.Session.Lifetime) * time.Hour,
+		HttpOnly: cfg.Session.HttpOnly,
+		Secure:   cfg.Session.Secure || cfg.TLS.Enabled(),
+		SameSite: utils.ParseSameSite(cfg.Session.SameSite),
+	})
+	if !serve {
+		os.Exit(runCommand(dbManager, service, flags.Args()))
 	}
 
-	internal.InitAllDatabaseManagers(dbManager)
 	mux := http.NewServeMux()
-	files := http.FileServer(http.Dir(config.Static))
-	routes.CompleteRoutes(mux, files, dbManager)
+	files := http.FileServer(http.FS(utils.Overlay(cfg.Static, forum.Static())))
+	routes.CompleteRoutes(mux, files, service)
+	metricsServer := metricsListener(mux)
+
+	health := internal.NewHealth(dbManager, writableDirs()...)
+	mux.HandleFunc("/healthz", routes.Healthz)
+	mux.HandleFunc("/readyz", routes.Readyz(health))
 
 	server := &http.Server{
-		Addr:           config.Address,
+		Addr:           cfg.Address,
 		Handler:        mux,
-		ReadTimeout:    time.Duration(config.ReadTimeout * int64(time.Second)),
-		WriteTimeout:   time.Duration(config.WriteTimeout * int64(time.Second)),
-		MaxHeaderBytes: 1 << 20,
+		ReadTimeout:    time.Duration(cfg.ReadTimeout * int64(time.Second)),
+		WriteTimeout:   time.Duration(cfg.WriteTimeout * int64(time.Second)),
+		MaxHeaderBytes: cfg.Limits.MaxHeaderBytes,
 	}
 	server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Cache-Control", "immutable, max-age=360")
 		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if cfg.TLS.Enabled() && cfg.TLS.HSTS > 0 {
+			w.Header().Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d", cfg.TLS.HSTS))
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.Limits.MaxBodyBytes)
 		mux.ServeHTTP(w, r)
 	})
 	stop := make(chan os.Signal, 1) // Setup signal channel
-	signal.Notify(stop, os.Interrupt)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go digestJob(service).Run(jobCtx)
+	go internal.ViewJob{Service: service, Interval: time.Minute}.Run(jobCtx)
+	if cfg.AutoArchive > 0 {
+		go internal.ArchiveJob{
+			Service:  service,
+			After:    time.Duration(cfg.AutoArchive) * 24 * time.Hour,
+			Interval: time.Hour,
+		}.Run(jobCtx)
+	}
+	if cfg.Backup.Interval > 0 && dbManager.Dialect() == data.SQLite {
+		go internal.BackupJob{
+			Service:  service,
+			Interval: time.Duration(cfg.Backup.Interval) * time.Hour,
+			Keep:     cfg.Backup.Keep,
+		}.Run(jobCtx)
+	}
 
 	go func() { // Start server in a goroutine
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			fmt.Printf("ListenAndServe error: %v\n", err)
+		var err error
+		if cfg.TLS.Enabled() {
+			err = server.ListenAndServeTLS(cfg.TLS.Cert, cfg.TLS.Key)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			slog.Error("ListenAndServe failed", "error", err)
 		}
 	}()
-	fmt.Println("Server started on -> localhost:8080\nPress Ctrl+C to stop")
+	redirectServer := redirectListener()
+	slog.Info("server started, press Ctrl+C to stop", "address", cfg.Address)
 
 	<-stop // Wait for interrupt signal
-	fmt.Println("Shutting down server...")
+	slog.Info("shutting down server")
+	health.Drain()
+	if cfg.ShutdownDrain > 0 {
+		time.Sleep(time.Duration(cfg.ShutdownDrain) * time.Second)
+	}
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second) // Create context with timeout for shutdown
 	defer cancel()                                                          // Ensure cancel is called to free resources
 
 	if err := server.Shutdown(ctx); err != nil {
-		fmt.Printf("Server Shutdown Failed:%+v\n", err)
+		slog.Error("server shutdown failed", "error", err)
 	} else {
-		fmt.Println("Server exited properly")
+		slog.Info("server exited properly")
+	}
+	if metricsServer != nil {
+		metricsServer.Shutdown(ctx)
+	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(ctx)
 	}
 
+	if err := service.FlushThreadViews(time.Now()); err != nil { // views counted since the last run
+		slog.Error("cannot write thread views", "error", err)
+	}
 	dbManager.Close() // Close database connection
-	err = dbManager.Ping()
-	if err != nil {
-		fmt.Println("Database is closed or unreachable:", err)
+	if err := dbManager.Ping(); err != nil {
+		slog.Info("database closed", "error", err)
 	} else {
-		fmt.Println("Database connection is open.")
+		slog.Warn("database connection is still open")
+	}
+}
+
+// runCommand runs an operator subcommand and returns the exit code:
+// 2 for a usage error, 1 when the command failed
+func runCommand(dbManager *data.DatabaseManager, service *internal.Service, args []string) int {
+	err := cli.Run(service, args, os.Stdout)
+	dbManager.Close()
+	if errors.Is(err, cli.ErrUsage) {
+		return 2
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "forum:", err)
+		return 1
+	}
+	return 0
+}
+
+// writableDirs are the directories /readyz checks: the SQLite database
+// and the log file directories
+func writableDirs() []string {
+	var dirs []string
+	if data.DialectOf(cfg.Database) == data.SQLite {
+		dirs = append(dirs, filepath.Dir(cfg.Database))
+	}
+	if cfg.Log.File != "" {
+		dirs = append(dirs, filepath.Dir(cfg.Log.File))
+	}
+	return dirs
+}
+
+// redirectListener starts the plain HTTP listener redirecting to HTTPS
+// when TLS.Redirect is set
+func redirectListener() *http.Server {
+	if !cfg.TLS.Enabled() || cfg.TLS.Redirect == "" {
+		return nil
+	}
+	server := &http.Server{
+		Addr:              cfg.TLS.Redirect,
+		Handler:           routes.RedirectHTTPS(cfg.Address),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("redirect listener failed", "error", err)
+		}
+	}()
+	slog.Info("redirecting HTTP to HTTPS", "address", cfg.TLS.Redirect)
+	return server
+}
+
+// writeCertificate generates the self-signed development certificate
+// for the host of BaseURL and localhost, it returns the exit code
+func writeCertificate() int {
+	certFile, keyFile := cfg.TLS.Cert, cfg.TLS.Key
+	if certFile == "" {
+		certFile = "pkg/cert.pem"
+	}
+	if keyFile == "" {
+		keyFile = "pkg/key.pem"
+	}
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if u, err := url.Parse(cfg.BaseURL); err == nil && u.Hostname() != "" {
+		hosts = append(hosts, u.Hostname())
+	}
+	if err := utils.GenerateCertificate(certFile, keyFile, hosts); err != nil {
+		fmt.Fprintln(os.Stderr, "cannot generate certificate:", err)
+		return 1
+	}
+	fmt.Printf("wrote %s and %s, set TLS.Cert and TLS.Key to use them\n", certFile, keyFile)
+	return 0
+}
+
+// metricsListener mounts /metrics as configured and returns the separate
+// metrics server when one is started
+func metricsListener(mux *http.ServeMux) *http.Server {
+	handler := routes.Metrics(cfg.Metrics.Token)
+	if cfg.Metrics.Address == "" {
+		if cfg.Metrics.Token != "" {
+			mux.HandleFunc("/metrics", handler)
+		}
+		return nil
+	}
+
+	metricsMux := http.NewServeMux()
+	metricsMux.HandleFunc("/metrics", handler)
+	server := &http.Server{Addr: cfg.Metrics.Address, Handler: metricsMux, ReadTimeout: 10 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("metrics listener failed", "error", err)
+		}
+	}()
+	slog.Info("metrics listener started", "address", cfg.Metrics.Address)
+	return server
+}
+
+// digestJob builds the scheduled digest mailer from configuration
+func digestJob(service *internal.Service) internal.DigestJob {
+	var sender internal.MailSender = internal.LogMailSender{}
+	if cfg.Mail.Host != "" {
+		sender = internal.SMTPMailSender{
+			Host:     cfg.Mail.Host,
+			Port:     cfg.Mail.Port,
+			Username: cfg.Mail.Username,
+			Password: cfg.Mail.Password,
+			From:     cfg.Mail.From,
+		}
+	}
+
+	interval := time.Duration(cfg.DigestCheck) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
 	}
+	return internal.DigestJob{Service: service, Sender: sender, BaseURL: utils.BaseURL(), Interval: interval}
 }
@@ -0,0 +1,421 @@
+// Package config loads the server configuration in layers: the defaults,
+// then config/config.json, then FORUM_* environment variables, then
+// command line flags, each overriding the one before.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultFile is read when neither --config nor FORUM_CONFIG names a file
+const DefaultFile = "config/config.json"
+
+// EnvPrefix starts the name of every environment variable read by Load
+const EnvPrefix = "FORUM_"
+
+// Configuration is every setting of the server
+type Configuration struct {
+	Address       string `help:"listen address, host:port"`
+	ReadTimeout   int64  `help:"seconds to read a request"`
+	WriteTimeout  int64  `help:"seconds to write a response"`
+	Static        string `help:"directory whose files replace the embedded static files"`
+	Database      string `help:"postgres:// URL or SQLite file"`
+	Templates     string `help:"directory whose templates replace the embedded ones"`
+	DevTemplates  bool   `help:"parse templates again when a file changes"`
+	BaseURL       string `help:"public URL used in emailed links"`
+	SecretKey     string `help:"signs unsubscribe links"`
+	DigestCheck   int64  `help:"minutes between digest runs"`
+	AutoArchive   int64  `help:"days without a new post before a thread is archived, 0 disables"`
+	ShutdownDrain int64  `help:"seconds /readyz fails before the server stops"`
+	// AccountDeletion is what happens to the threads and replies of a deleted account
+	AccountDeletion string `help:"threads and replies of deleted accounts: anonymize or remove"`
+	Mail            MailConfiguration
+	Log             LogConfiguration
+	Metrics         MetricsConfiguration
+	Session         SessionConfiguration
+	Limits          LimitsConfiguration
+	TLS             TLSConfiguration
+	Backup          BackupConfiguration
+}
+
+// MailConfiguration selects the digest mail sender;
+// with an empty Host emails are written to the log file
+type MailConfiguration struct {
+	Host     string `help:"SMTP host, empty logs emails instead"`
+	Port     int    `help:"SMTP port"`
+	Username string `help:"SMTP user"`
+	Password string `help:"SMTP password"`
+	From     string `help:"sender address of emails"`
+}
+
+// LogConfiguration selects the log output: Format is text or json,
+// Level is debug, info, warn or error and an empty File logs to stdout
+type LogConfiguration struct {
+	Format string `help:"text or json"`
+	Level  string `help:"debug, info, warn or error"`
+	File   string `help:"log file, empty for stdout"`
+}
+
+// MetricsConfiguration protects /metrics: with an Address the metrics are
+// served on that separate listener, otherwise on the main one when a Token is
+// set. The Token, when set, is required as "Authorization: Bearer <token>".
+type MetricsConfiguration struct {
+	Address string `help:"separate listen address for /metrics"`
+	Token   string `help:"bearer token required by /metrics"`
+}
+
+// SessionConfiguration sets the login cookie and how long a login lasts
+type SessionConfiguration struct {
+	Cookie   string `help:"name of the session cookie"`
+	Lifetime int64  `help:"hours a login lasts"`
+	HttpOnly bool   `help:"hide the session cookie from JavaScript"`
+	Secure   bool   `help:"send the session cookie over HTTPS only"`
+	SameSite string `help:"lax, strict or none"`
+}
+
+// LimitsConfiguration bounds the size of requests
+type LimitsConfiguration struct {
+	MaxHeaderBytes int   `help:"largest request header in bytes"`
+	MaxBodyBytes   int64 `help:"largest request body in bytes"`
+}
+
+// TLSConfiguration turns on HTTPS when both Cert and Key are set. Redirect
+// is an address answering plain HTTP with a redirect to HTTPS, HSTS the
+// max-age in seconds of the Strict-Transport-Security header, 0 leaves it out.
+type TLSConfiguration struct {
+	Cert     string `help:"PEM certificate file, enables HTTPS with Key"`
+	Key      string `help:"PEM private key file"`
+	Redirect string `help:"address redirecting plain HTTP to HTTPS"`
+	HSTS     int64  `help:"Strict-Transport-Security max-age in seconds, 0 disables"`
+}
+
+// BackupConfiguration schedules online snapshots of the SQLite database into
+// Dir every Interval hours, the Keep newest are kept. Interval 0 disables them.
+type BackupConfiguration struct {
+	Dir      string `help:"directory of the database snapshots"`
+	Interval int64  `help:"hours between snapshots, 0 disables"`
+	Keep     int    `help:"number of snapshots kept"`
+}
+
+// Enabled reports whether the server listens with TLS
+func (t TLSConfiguration) Enabled() bool {
+	return t.Cert != "" && t.Key != ""
+}
+
+// Defaults is the configuration before any file, variable or flag
+func Defaults() Configuration {
+	return Configuration{
+		Address:         "0.0.0.0:8080",
+		ReadTimeout:     10,
+		WriteTimeout:    600,
+		Database:        "pkg/mydb.db",
+		BaseURL:         "http://localhost:8080",
+		DigestCheck:     60,
+		AccountDeletion: "anonymize",
+		Mail:            MailConfiguration{Port: 587, From: "forum@localhost"},
+		Log:             LogConfiguration{Format: "text", Level: "info"},
+		Session:         SessionConfiguration{Cookie: "_cookie", Lifetime: 24, HttpOnly: true, SameSite: "lax"},
+		Limits:          LimitsConfiguration{MaxHeaderBytes: 1 << 20, MaxBodyBytes: 1 << 20},
+		TLS:             TLSConfiguration{HSTS: 31536000},
+		Backup:          BackupConfiguration{Dir: "pkg/backups", Interval: 24, Keep: 7},
+	}
+}
+
+// option is one setting, named after its section and field:
+// Log.Format is FORUM_LOG_FORMAT and --log-format
+type option struct {
+	words []string
+	help  string
+	field []int
+	kind  reflect.Kind
+}
+
+func (o option) env() string {
+	return EnvPrefix + strings.ToUpper(strings.Join(o.words, "_"))
+}
+
+func (o option) flag() string {
+	return strings.Join(o.words, "-")
+}
+
+// options lists every setting of Configuration
+func options() []option {
+	var opts []option
+	var walk func(t reflect.Type, prefix []string, index []int)
+	walk = func(t reflect.Type, prefix []string, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			words := append(append([]string(nil), prefix...), splitWords(f.Name)...)
+			path := append(append([]int(nil), index...), i)
+			if f.Type.Kind() == reflect.Struct {
+				walk(f.Type, words, path)
+				continue
+			}
+			opts = append(opts, option{words: words, help: f.Tag.Get("help"), field: path, kind: f.Type.Kind()})
+		}
+	}
+	walk(reflect.TypeOf(Configuration{}), nil, nil)
+	return opts
+}
+
+// splitWords lowercases a field name into its words: BaseURL is base, url
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		lowerBefore := unicode.IsLower(runes[i-1])
+		lowerAfter := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsUpper(runes[i]) && (lowerBefore || (unicode.IsUpper(runes[i-1]) && lowerAfter)) {
+			words = append(words, strings.ToLower(string(runes[start:i])))
+			start = i
+		}
+	}
+	return append(words, strings.ToLower(string(runes[start:])))
+}
+
+// set parses value into the option's field of c
+func (o option) set(c *Configuration, value string) error {
+	field := reflect.ValueOf(c).Elem().FieldByIndex(o.field)
+	switch o.kind {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("want true or false, got %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("want a number, got %q", value)
+		}
+		field.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported type %s", o.kind)
+	}
+	return nil
+}
+
+// flagValue keeps a flag's text until Load applies it after the environment
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (v *flagValue) String() string     { return v.value }
+func (v *flagValue) Set(s string) error { v.value = s; return nil }
+func (v *flagValue) IsBoolFlag() bool   { return v.isBool }
+
+// Load registers a flag per setting and --config on fs, parses args and
+// returns the merged, validated configuration. getenv is os.Getenv outside tests.
+// A missing default file leaves the defaults; a file named by --config or
+// FORUM_CONFIG has to exist.
+func Load(fs *flag.FlagSet, args []string, getenv func(string) string) (Configuration, error) {
+	opts := options()
+	values := make([]*flagValue, len(opts))
+	for i, o := range opts {
+		values[i] = &flagValue{isBool: o.kind == reflect.Bool}
+		fs.Var(values[i], o.flag(), fmt.Sprintf("%s (%s)", o.help, o.env()))
+	}
+	file := fs.String("config", "", "configuration file (FORUM_CONFIG, default "+DefaultFile+")")
+	if err := fs.Parse(args); err != nil {
+		return Configuration{}, err
+	}
+
+	c := Defaults()
+	path := *file
+	if path == "" {
+		path = getenv(EnvPrefix + "CONFIG")
+	}
+	explicit := path != ""
+	if !explicit {
+		path = DefaultFile
+	}
+	if err := readFile(&c, path); err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			slog.Warn("config file not found, using defaults", "file", path)
+		} else {
+			return Configuration{}, err
+		}
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	var errs []error
+	for i, o := range opts {
+		if value := getenv(o.env()); value != "" {
+			if err := o.set(&c, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", o.env(), err))
+			}
+		}
+		if set[o.flag()] {
+			if err := o.set(&c, values[i].value); err != nil {
+				errs = append(errs, fmt.Errorf("--%s: %w", o.flag(), err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return Configuration{}, errors.Join(errs...)
+	}
+	return c, c.Validate()
+}
+
+// readFile decodes the JSON file at path over c,
+// an unknown key is an error so a misspelt setting is not ignored
+func readFile(c *Configuration, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once
+func (c Configuration) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validAddress(c.Address), "Address: want host:port, got %q", c.Address)
+	check(c.ReadTimeout > 0, "ReadTimeout: want more than 0 seconds, got %d", c.ReadTimeout)
+	check(c.WriteTimeout > 0, "WriteTimeout: want more than 0 seconds, got %d", c.WriteTimeout)
+	check(c.Database != "", "Database: want a postgres:// URL or a SQLite file")
+	if strings.HasPrefix(c.Database, "postgres://") || strings.HasPrefix(c.Database, "postgresql://") {
+		_, err := url.Parse(c.Database)
+		check(err == nil, "Database: invalid URL")
+	}
+	base, err := url.Parse(c.BaseURL)
+	check(err == nil && (base.Scheme == "http" || base.Scheme == "https") && base.Host != "",
+		"BaseURL: want an http or https URL, got %q", c.BaseURL)
+	check(c.DigestCheck >= 0, "DigestCheck: want 0 or more minutes, got %d", c.DigestCheck)
+	check(c.AutoArchive >= 0, "AutoArchive: want 0 or more days, got %d", c.AutoArchive)
+	check(c.ShutdownDrain >= 0, "ShutdownDrain: want 0 or more seconds, got %d", c.ShutdownDrain)
+	check(c.AccountDeletion == "anonymize" || c.AccountDeletion == "remove",
+		"AccountDeletion: want anonymize or remove, got %q", c.AccountDeletion)
+
+	if c.Mail.Host != "" {
+		check(c.Mail.Port > 0 && c.Mail.Port < 65536, "Mail.Port: want 1 to 65535, got %d", c.Mail.Port)
+		check(c.Mail.From != "", "Mail.From: required with a Mail.Host")
+	}
+
+	format := strings.ToLower(c.Log.Format)
+	check(format == "" || format == "text" || format == "json", "Log.Format: want text or json, got %q", c.Log.Format)
+	var level slog.Level
+	check(c.Log.Level == "" || level.UnmarshalText([]byte(c.Log.Level)) == nil,
+		"Log.Level: want debug, info, warn or error, got %q", c.Log.Level)
+
+	check(c.Metrics.Address == "" || validAddress(c.Metrics.Address), "Metrics.Address: want host:port, got %q", c.Metrics.Address)
+
+	check(c.Session.Cookie != "" && !strings.ContainsAny(c.Session.Cookie, " ;=,\t\"\\"),
+		"Session.Cookie: want a cookie name, got %q", c.Session.Cookie)
+	check(c.Session.Lifetime > 0, "Session.Lifetime: want more than 0 hours, got %d", c.Session.Lifetime)
+	switch strings.ToLower(c.Session.SameSite) {
+	case "lax", "strict":
+	case "none":
+		check(c.Session.Secure || c.TLS.Enabled(), "Session.SameSite: none requires Session.Secure or TLS")
+	default:
+		check(false, "Session.SameSite: want lax, strict or none, got %q", c.Session.SameSite)
+	}
+
+	check(c.Limits.MaxHeaderBytes > 0, "Limits.MaxHeaderBytes: want more than 0, got %d", c.Limits.MaxHeaderBytes)
+	check(c.Limits.MaxBodyBytes > 0, "Limits.MaxBodyBytes: want more than 0, got %d", c.Limits.MaxBodyBytes)
+
+	check((c.TLS.Cert == "") == (c.TLS.Key == ""), "TLS: set both Cert and Key, or neither")
+	if c.TLS.Enabled() {
+		for _, file := range []string{c.TLS.Cert, c.TLS.Key} {
+			_, err := os.Stat(file)
+			check(err == nil, "TLS: %v", err)
+		}
+	}
+	check(c.TLS.Redirect == "" || c.TLS.Enabled(), "TLS.Redirect: requires TLS.Cert and TLS.Key")
+	check(c.TLS.Redirect == "" || validAddress(c.TLS.Redirect), "TLS.Redirect: want host:port, got %q", c.TLS.Redirect)
+	check(c.TLS.HSTS >= 0, "TLS.HSTS: want 0 or more seconds, got %d", c.TLS.HSTS)
+
+	check(c.Backup.Dir != "", "Backup.Dir: want a directory")
+	check(c.Backup.Interval >= 0, "Backup.Interval: want 0 or more hours, got %d", c.Backup.Interval)
+	check(c.Backup.Keep > 0, "Backup.Keep: want at least 1 snapshot, got %d", c.Backup.Keep)
+	return errors.Join(errs...)
+}
+
+func validAddress(address string) bool {
+	_, port, err := net.SplitHostPort(address)
+	return err == nil && port != ""
+}
+
+// Redacted returns a copy with the secrets hidden, for printing
+func (c Configuration) Redacted() Configuration {
+	hide := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return "REDACTED"
+	}
+	c.Database = redactDSN(c.Database)
+	c.SecretKey = hide(c.SecretKey)
+	c.Mail.Password = hide(c.Mail.Password)
+	c.Metrics.Token = hide(c.Metrics.Token)
+	return c
+}
+
+// LogValue lists the configuration in the log, secrets are left to the redaction
+func (c Configuration) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("address", c.Address),
+		slog.String("database", redactDSN(c.Database)),
+		slog.String("static", c.Static),
+		slog.String("templates", c.Templates),
+		slog.Bool("dev_templates", c.DevTemplates),
+		slog.String("base_url", c.BaseURL),
+		slog.String("secret_key", c.SecretKey),
+		slog.Int64("auto_archive", c.AutoArchive),
+		slog.Int64("shutdown_drain", c.ShutdownDrain),
+		slog.String("account_deletion", c.AccountDeletion),
+		slog.String("mail_host", c.Mail.Host),
+		slog.String("mail_password", c.Mail.Password),
+		slog.String("log_format", c.Log.Format),
+		slog.String("log_level", c.Log.Level),
+		slog.String("metrics_address", c.Metrics.Address),
+		slog.String("metrics_token", c.Metrics.Token),
+		slog.String("session_cookie", c.Session.Cookie),
+		slog.Int64("session_lifetime", c.Session.Lifetime),
+		slog.Int64("max_body_bytes", c.Limits.MaxBodyBytes),
+		slog.Bool("tls", c.TLS.Enabled()),
+		slog.String("tls_redirect", c.TLS.Redirect),
+		slog.String("backup_dir", c.Backup.Dir),
+		slog.Int64("backup_interval", c.Backup.Interval),
+	)
+}
+
+// redactDSN hides the password of a database URL
+func redactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil {
+		return dsn
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "REDACTED")
+	}
+	return u.String()
+}
@@ -5,3 +5,5 @@ go 1.24.6
 require github.com/mattn/go-sqlite3 v1.14.32 // direct
 
 require golang.org/x/crypto v0.42.0
+
+require github.com/lib/pq v1.10.9
@@ -0,0 +1,195 @@
+package internal
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/models"
+	"forum/utils"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownRole  = errors.New("role must be member, moderator or admin")
+	ErrWeakPassword = errors.New("password must contain uppercase, lowercase, number, and symbol")
+	ErrInvalidName  = errors.New("name must be 3 to 20 letters or digits")
+	ErrInvalidEmail = errors.New("email must look like name@example.com")
+)
+
+// FindUser looks a user up by name, or by email when the key contains @
+func (s *Service) FindUser(key string) (models.User, error) {
+	if strings.Contains(key, "@") {
+		user, err := s.Users.GetUserByEmailDetailed(key)
+		if err != nil {
+			return user, fmt.Errorf("no user with email %s", key)
+		}
+		return s.Users.GetUserByID(user.Id)
+	}
+	user, err := s.Users.GetUserByName(key)
+	if err != nil {
+		return user, fmt.Errorf("no user named %s", key)
+	}
+	return user, nil
+}
+
+// CreateAccount creates a member with the signup rules for name, email and password
+func (s *Service) CreateAccount(name, email, password, role string) (models.User, error) {
+	user := models.User{Name: name, Email: email, Password: password}
+	if len(name) < 3 || len(name) > 20 || strings.IndexFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) >= 0 {
+		return user, ErrInvalidName
+	}
+	if local, domain, ok := strings.Cut(email, "@"); !ok || local == "" || !strings.Contains(domain, ".") || strings.Contains(email, " ") {
+		return user, ErrInvalidEmail
+	}
+	if !utils.PasswordMeetsCriteria(nil, nil, password) {
+		return user, ErrWeakPassword
+	}
+	if !validRole(role) {
+		return user, ErrUnknownRole
+	}
+	if s.IfUserExist(email, name) {
+		return user, fmt.Errorf("name %s or email %s is taken", name, email)
+	}
+	if err := s.Users.CreateUser(&user); err != nil {
+		return user, err
+	}
+	if role != models.RoleMember {
+		return user, s.Accounts.SetUserRole(user.Id, role)
+	}
+	return user, nil
+}
+
+func validRole(role string) bool {
+	return role == models.RoleMember || role == models.RoleModerator || role == models.RoleAdmin
+}
+
+// SetRole changes the role of a user
+func (s *Service) SetRole(userID int, role string) error {
+	if !validRole(role) {
+		return ErrUnknownRole
+	}
+	return s.Accounts.SetUserRole(userID, role)
+}
+
+// ResetPassword replaces the password of a user and signs them out everywhere
+func (s *Service) ResetPassword(userID int, password string) error {
+	if !utils.PasswordMeetsCriteria(nil, nil, password) {
+		return ErrWeakPassword
+	}
+	if err := s.Accounts.SetUserPassword(userID, password); err != nil {
+		return err
+	}
+	_, err := s.Accounts.DeleteUserSessions(userID)
+	return err
+}
+
+// BanUser keeps a user from signing in and ends their sessions
+func (s *Service) BanUser(userID int) error {
+	if err := s.Accounts.SetUserStatus(userID, models.StatusBanned); err != nil {
+		return err
+	}
+	_, err := s.Accounts.DeleteUserSessions(userID)
+	return err
+}
+
+// LiftBan lets a banned user sign in again
+func (s *Service) LiftBan(userID int) error {
+	return s.Accounts.SetUserStatus(userID, models.StatusActive)
+}
+
+// Account deletion policies, what happens to the threads and replies of a deleted account
+const (
+	DeletionAnonymize = "anonymize" // kept under the name deleted-ID
+	DeletionRemove    = "remove"    // removed, except threads others replied to
+)
+
+var deletionPolicy = DeletionAnonymize
+
+// SetDeletionPolicy sets the policy used by DeleteAccount, anonymize unless remove
+func SetDeletionPolicy(policy string) {
+	if policy != DeletionRemove {
+		policy = DeletionAnonymize
+	}
+	deletionPolicy = policy
+}
+
+// DeletionPolicy returns the policy used by DeleteAccount
+func DeletionPolicy() string {
+	return deletionPolicy
+}
+
+// DeleteAccount closes an account following the deletion policy: the user is
+// signed out, their personal data is removed and the account keeps only
+// the status deleted and the name deleted-ID
+func (s *Service) DeleteAccount(userID int) error {
+	err := s.Accounts.AnonymizeUser(userID, deletionPolicy == DeletionRemove)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no account with id %d", userID)
+	}
+	return err
+}
+
+// PurgeAccount removes the account row with everything the user wrote and
+// every row pointing at them
+func (s *Service) PurgeAccount(userID int) error {
+	return s.Users.DeleteUserByID(userID)
+}
+
+// PurgeSessions removes the sessions created before the given time
+func (s *Service) PurgeSessions(before time.Time) (int64, error) {
+	return s.Accounts.DeleteSessionsBefore(before)
+}
+
+// UserStatusCounts returns the number of accounts per status
+func (s *Service) UserStatusCounts() (map[string]int, error) {
+	return s.Accounts.CountUsersByStatus()
+}
+
+// SessionCount returns the number of sessions not expired yet
+func (s *Service) SessionCount() (int, error) {
+	return s.Sessions.CountSessions()
+}
+
+// DatabaseCheck is the result of one check of CheckDatabase, Err is nil when it passed
+type DatabaseCheck struct {
+	Name string
+	Err  error
+}
+
+// CheckDatabase checks the connection, the migrations, the integrity of the
+// file and rows referencing missing rows
+func (s *Service) CheckDatabase() []DatabaseCheck {
+	checks := []DatabaseCheck{{Name: "connection", Err: s.Maintenance.Ping()}}
+	if checks[0].Err != nil {
+		return checks
+	}
+	checks = append(checks,
+		DatabaseCheck{Name: "migrations", Err: CheckSchema(s.Maintenance)},
+		DatabaseCheck{Name: "integrity", Err: s.Maintenance.IntegrityCheck()})
+
+	violations, err := s.OrphanedRows()
+	if err == nil && len(violations) > 0 {
+		var problems []string
+		for reference, count := range violations {
+			problems = append(problems, fmt.Sprintf("%d rows of %s", count, reference))
+		}
+		sort.Strings(problems)
+		err = fmt.Errorf("rows reference missing rows: %s", strings.Join(problems, ", "))
+	}
+	return append(checks, DatabaseCheck{Name: "references", Err: err})
+}
+
+// OrphanedRows counts the rows referencing a missing row, keyed by "table -> parent"
+func (s *Service) OrphanedRows() (map[string]int, error) {
+	return s.Maintenance.ForeignKeyViolations(schemaReferences)
+}
+
+// GeneratePassword returns a random password that meets the signup rules
+func GeneratePassword() string {
+	return rand.Text()[:16] + "a-1"
+}
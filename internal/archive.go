@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"context"
+	"time"
+
+	"forum/utils"
+)
+
+// ArchiveJob archives threads that had no new post for After
+type ArchiveJob struct {
+	Service  *Service
+	After    time.Duration
+	Interval time.Duration // how often inactive threads are looked for
+}
+
+// Run archives inactive threads every Interval until ctx is cancelled
+func (job ArchiveJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := job.RunOnce(now); err != nil {
+				utils.Warn("Auto-archive run failed:", err)
+			}
+		}
+	}
+}
+
+// RunOnce archives the threads inactive at the given time and returns how many
+func (job ArchiveJob) RunOnce(now time.Time) (int64, error) {
+	return job.Service.Threads.ArchiveInactiveThreads(now.Add(-job.After))
+}
@@ -0,0 +1,8 @@
+package internal
+
+import "forum/models"
+
+// AuditLog returns the latest entries of the moderation audit log
+func (s *Service) AuditLog(limit int) ([]models.AuditEntry, error) {
+	return s.Audit.GetAuditLog(limit)
+}
@@ -0,0 +1,233 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"forum/internal/data"
+	"forum/models"
+	"forum/utils"
+)
+
+// backupLayout names a snapshot after the time it was taken, in UTC
+const backupLayout = "forum-20060102-150405.000.db"
+
+var (
+	backupDir = "pkg/backups"
+	backupMu  sync.Mutex // one snapshot at a time, so the job and an admin do not race
+)
+
+// ErrBackupExists is returned when a snapshot was already taken that millisecond
+var ErrBackupExists = errors.New("a snapshot with this name already exists")
+
+// SetBackupDir sets the directory snapshots are written to and read from
+func SetBackupDir(dir string) {
+	if dir != "" {
+		backupDir = dir
+	}
+}
+
+// BackupDir returns the directory of the snapshots
+func BackupDir() string {
+	return backupDir
+}
+
+// CreateBackup takes a snapshot of the live database, checks it and moves it
+// into the backup directory. A failed snapshot leaves no file behind.
+func (s *Service) CreateBackup(ctx context.Context, now time.Time) (models.Backup, error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	backup := models.Backup{Name: now.UTC().Format(backupLayout), CreatedAt: now.UTC().Truncate(time.Millisecond)}
+	path := filepath.Join(backupDir, backup.Name)
+	if utils.FileExists(path) {
+		return backup, fmt.Errorf("%s: %w", backup.Name, ErrBackupExists)
+	}
+	if err := os.MkdirAll(backupDir, 0o750); err != nil {
+		return backup, err
+	}
+
+	partial := path + ".part"
+	os.Remove(partial) // left by a crash during an earlier snapshot
+	err := s.Backups.BackupTo(ctx, partial)
+	if err == nil {
+		err = VerifyBackup(partial)
+	}
+	if err == nil {
+		err = os.Rename(partial, path)
+	}
+	if err != nil {
+		os.Remove(partial)
+		return backup, err
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		backup.Size = info.Size()
+	}
+	slog.Info("database snapshot written", "name", backup.Name, "size", backup.Size)
+	return backup, nil
+}
+
+// ListBackups returns the snapshots of the backup directory, newest first
+func ListBackups() ([]models.Backup, error) {
+	entries, err := os.ReadDir(backupDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var backups []models.Backup
+	for _, entry := range entries {
+		createdAt, err := time.Parse(backupLayout, entry.Name())
+		if err != nil || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, models.Backup{Name: entry.Name(), Size: info.Size(), CreatedAt: createdAt})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// PruneBackups removes all but the keep newest snapshots and returns how many it removed
+func PruneBackups(keep int) (int, error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	backups, err := ListBackups()
+	if err != nil || len(backups) <= keep {
+		return 0, err
+	}
+	removed := 0
+	for _, backup := range backups[max(keep, 0):] {
+		if err := os.Remove(filepath.Join(backupDir, backup.Name)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// BackupPath returns the path of a snapshot named as listed by ListBackups,
+// other names are not served so a download cannot leave the directory
+func BackupPath(name string) (string, error) {
+	if _, err := time.Parse(backupLayout, name); err != nil || filepath.Base(name) != name {
+		return "", os.ErrNotExist
+	}
+	path := filepath.Join(backupDir, name)
+	if !utils.FileExists(path) {
+		return "", os.ErrNotExist
+	}
+	return path, nil
+}
+
+// VerifyBackup opens a snapshot read-only and checks that it is an intact
+// forum database
+func VerifyBackup(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	snapshot, err := data.OpenSnapshot(path)
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+
+	if err := snapshot.IntegrityCheck(); err != nil {
+		return fmt.Errorf("integrity check of %s: %w", path, err)
+	}
+	hasUsers, err := snapshot.HasTable("users")
+	if err != nil {
+		return err
+	}
+	if !hasUsers {
+		return fmt.Errorf("%s is not a forum database", path)
+	}
+	return nil
+}
+
+// RestoreBackup verifies a snapshot, given as a path or a name in the backup
+// directory, saves the live database as a new snapshot and then copies the
+// snapshot over it. It returns the saved snapshot, which undoes the restore.
+func (s *Service) RestoreBackup(ctx context.Context, snapshot string) (models.Backup, error) {
+	path := snapshot
+	if !strings.ContainsRune(snapshot, filepath.Separator) && !utils.FileExists(path) {
+		path = filepath.Join(backupDir, snapshot)
+	}
+	if err := VerifyBackup(path); err != nil {
+		return models.Backup{}, fmt.Errorf("nothing restored: %w", err)
+	}
+
+	saved, err := s.CreateBackup(ctx, time.Now())
+	if err != nil {
+		return models.Backup{}, fmt.Errorf("nothing restored, cannot save the current database: %w", err)
+	}
+	if err := s.Backups.RestoreFrom(ctx, path); err != nil {
+		return saved, err
+	}
+	if err := s.Maintenance.IntegrityCheck(); err != nil {
+		return saved, fmt.Errorf("restored database: %w", err)
+	}
+	// a snapshot from an older version lacks the tables added since
+	if err := UpgradeSchema(s.Maintenance); err != nil {
+		return saved, err
+	}
+	slog.Info("database restored", "snapshot", path, "saved", saved.Name)
+	return saved, nil
+}
+
+// AuditBackup records an admin taking or downloading a snapshot,
+// which holds every account
+func (s *Service) AuditBackup(admin models.User, action, name string) error {
+	return s.Audit.WriteAudit(admin.Id, action+"_backup", name)
+}
+
+// BackupJob takes a snapshot every Interval and keeps the Keep newest
+type BackupJob struct {
+	Service  *Service
+	Interval time.Duration
+	Keep     int
+}
+
+// Run takes snapshots until ctx is cancelled
+func (job BackupJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := job.RunOnce(ctx, now); err != nil {
+				utils.Warn("Scheduled backup failed:", err)
+			}
+		}
+	}
+}
+
+// RunOnce takes the snapshot for the given time, then removes the old ones
+func (job BackupJob) RunOnce(ctx context.Context, now time.Time) (models.Backup, error) {
+	backup, err := job.Service.CreateBackup(ctx, now)
+	if err != nil {
+		return backup, err
+	}
+	removed, err := PruneBackups(job.Keep)
+	if removed > 0 {
+		slog.Info("old database snapshots removed", "count", removed)
+	}
+	return backup, err
+}
@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"forum/models"
+)
+
+const (
+	MaxCollectionName = 64
+	MaxBookmarkNote   = 500
+)
+
+var (
+	ErrUnknownCollection = errors.New("unknown collection")
+	ErrInvalidAPIToken   = errors.New("invalid API token")
+)
+
+func (s *Service) CreateCollection(userID int, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > MaxCollectionName {
+		return errors.New("collection name must be 1 to 64 characters")
+	}
+	if _, err := s.Bookmarks.CreateCollection(userID, name); err != nil {
+		return errors.New("you already have a collection with that name")
+	}
+	return nil
+}
+
+func (s *Service) Collections(userID int) ([]models.Collection, error) {
+	return s.Bookmarks.GetCollections(userID)
+}
+
+func (s *Service) DeleteCollection(userID, collectionID int) error {
+	return s.Bookmarks.DeleteCollection(collectionID, userID)
+}
+
+// SaveBookmark bookmarks a thread, or one of its posts when postID is not 0
+func (s *Service) SaveBookmark(userID, threadID, postID, collectionID int, note string) error {
+	if _, err := s.Threads.GetThreadByID(threadID); err != nil {
+		return errors.New("thread not found")
+	}
+	if postID != 0 {
+		post, err := s.Posts.GetPostByID(postID)
+		if err != nil || post.ThreadId != threadID {
+			return errors.New("post not found")
+		}
+	}
+	note, err := s.checkBookmark(userID, collectionID, note)
+	if err != nil {
+		return err
+	}
+	return s.Bookmarks.SaveBookmark(userID, threadID, postID, collectionID, note)
+}
+
+// UpdateBookmark moves a bookmark to another collection and edits its note
+func (s *Service) UpdateBookmark(userID, bookmarkID, collectionID int, note string) error {
+	note, err := s.checkBookmark(userID, collectionID, note)
+	if err != nil {
+		return err
+	}
+	return s.Bookmarks.UpdateBookmark(bookmarkID, userID, collectionID, note)
+}
+
+func (s *Service) checkBookmark(userID, collectionID int, note string) (string, error) {
+	if collectionID != 0 && !s.Bookmarks.IsCollectionOwner(collectionID, userID) {
+		return "", ErrUnknownCollection
+	}
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > MaxBookmarkNote {
+		return "", errors.New("note can be at most 500 characters")
+	}
+	return note, nil
+}
+
+func (s *Service) DeleteBookmark(userID, bookmarkID int) error {
+	return s.Bookmarks.DeleteBookmark(bookmarkID, userID)
+}
+
+func (s *Service) IsThreadBookmarked(userID, threadID int) bool {
+	return s.Bookmarks.IsThreadBookmarked(userID, threadID)
+}
+
+func (s *Service) UserBookmarks(userID int, filter models.BookmarkFilter) ([]models.Bookmark, error) {
+	if filter.Kind != "thread" && filter.Kind != "post" {
+		filter.Kind = ""
+	}
+	bookmarks, err := s.Bookmarks.GetBookmarks(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bookmarks {
+		bookmarks[i].Excerpt = excerpt(bookmarks[i].Excerpt, excerptLength)
+	}
+	return bookmarks, nil
+}
+
+// GenerateAPIToken creates a new personal token for scripts and replaces
+// the previous one. The token is only returned here, the database keeps a hash.
+func (s *Service) GenerateAPIToken(userID int) (string, error) {
+	buf := make([]byte, 32)
+	rand.Read(buf) // never fails since go 1.24
+	token := hex.EncodeToString(buf)
+	if err := s.Bookmarks.SetAPITokenHash(userID, hashAPIToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *Service) RevokeAPIToken(userID int) error {
+	return s.Bookmarks.DeleteAPIToken(userID)
+}
+
+func (s *Service) HasAPIToken(userID int) bool {
+	return s.Bookmarks.HasAPIToken(userID)
+}
+
+// UserByAPIToken returns the active user owning token
+func (s *Service) UserByAPIToken(token string) (models.User, error) {
+	if token == "" {
+		return models.User{}, ErrInvalidAPIToken
+	}
+	userID, err := s.Bookmarks.GetUserIDByTokenHash(hashAPIToken(token))
+	if err != nil {
+		return models.User{}, ErrInvalidAPIToken
+	}
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil || user.Status != "active" {
+		return models.User{}, ErrInvalidAPIToken
+	}
+	return user, nil
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,8 +2,11 @@ package internal
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 
 	"forum/internal/data"
 	"forum/utils"
@@ -11,17 +14,53 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// InitAllDatabaseManagers initializes all DatabaseManager instances
-func InitAllDatabaseManagers(dm *data.DatabaseManager) {
-	InitPostDM(dm)
-	InitSessionDM(dm)
-	InitStatsDM(dm)
-	InitUserDM(dm)
-	InitThreadDM(dm)
+// InitAllDatabaseManagers returns the Service of the database and
+// registers its gauges for /metrics
+func InitAllDatabaseManagers(dm *data.DatabaseManager) *Service {
+	s := NewService(dm)
+	s.registerGauges()
+	return s
 }
 
 // RunMigrations ensures that all required tables are present.
 
+// ConnectDatabase opens the database named by dsn. A postgres:// URL selects
+// Postgres, where the schema is created on first use; an empty dsn keeps
+// the default SQLite file.
+func ConnectDatabase(dsn string) (*data.DatabaseManager, error) {
+	if data.DialectOf(dsn) != data.Postgres {
+		if dsn == "" {
+			return ConnectDB("pkg", "mydb.db")
+		}
+		return ConnectDB(filepath.Dir(dsn), filepath.Base(dsn))
+	}
+
+	dbManager, err := data.Open(dsn)
+	if err != nil {
+		utils.Danger("Cannot initialize database manager", err)
+		return nil, err
+	}
+	if err := dbManager.Ping(); err != nil {
+		return nil, err
+	}
+
+	hasUsers, err := dbManager.HasColumn("users", "id")
+	if err != nil {
+		return nil, err
+	}
+	if !hasUsers {
+		slog.Info("database is empty, running migrations")
+		if err := RunMigrations(dbManager); err != nil {
+			utils.Danger("Migration error:", err)
+			return nil, err
+		}
+	} else if err := UpgradeSchema(dbManager); err != nil {
+		utils.Danger("Schema upgrade error:", err)
+		return nil, err
+	}
+	return dbManager, nil
+}
+
 func ConnectDB(dir string, fileName string) (*data.DatabaseManager, error) {
 	isNewDB := !utils.FileExists(filepath.Join(dir, fileName))
 	if isNewDB {
@@ -31,7 +70,7 @@ func ConnectDB(dir string, fileName string) (*data.DatabaseManager, error) {
 		}
 	}
 
-	dbManager, err := data.NewDatabaseManager("pkg/mydb.db")
+	dbManager, err := data.NewDatabaseManager(filepath.Join(dir, fileName))
 	if err != nil {
 		utils.Danger("Cannot initialize database manager", err)
 		return nil, err
@@ -43,17 +82,357 @@ func ConnectDB(dir string, fileName string) (*data.DatabaseManager, error) {
 	}
 
 	if isNewDB {
-		fmt.Println("Database file not found, running migrations to create a new database.")
+		slog.Info("database file not found, running migrations", "path", filepath.Join(dir, fileName))
 		if err := RunMigrations(dbManager); err != nil {
 			utils.Danger("Migration error:", err)
 			return nil, err
 		}
+	} else if err := UpgradeSchema(dbManager); err != nil {
+		utils.Danger("Schema upgrade error:", err)
+		return nil, err
 	}
 	return dbManager, nil
 }
 
+// schemaUpgrades holds tables added after the initial schema.
+// Every statement must be safe to run against an existing database,
+// so existing data is kept when the server starts on an older file.
+var schemaUpgrades = []string{
+	`CREATE TABLE IF NOT EXISTS thread_watches (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  user_id    integer references users(id),
+	  thread_id  integer references threads(id),
+	  created_at timestamp not null,
+	  unique(user_id, thread_id)
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS digest_preferences (
+	  user_id      integer primary key references users(id),
+	  frequency    varchar(16) not null default 'daily',
+	  last_sent_at timestamp
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS user_blocks (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  blocker_id integer references users(id),
+	  blocked_id integer references users(id),
+	  created_at timestamp not null,
+	  unique(blocker_id, blocked_id)
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS mentions (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  user_id    integer references users(id),
+	  author_id  integer references users(id),
+	  thread_id  integer references threads(id),
+	  post_id    integer default 0,
+	  created_at timestamp not null,
+	  seen       integer default 0
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS conversations (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  uuid       varchar(64) not null unique,
+	  subject    varchar(255) default '',
+	  created_by integer references users(id),
+	  created_at timestamp not null,
+	  updated_at timestamp not null
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS conversation_members (
+	  id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	  conversation_id integer references conversations(id),
+	  user_id         integer references users(id),
+	  joined_at       timestamp not null,
+	  last_read_id    integer default 0,
+	  muted           integer default 0,
+	  left_at         timestamp,
+	  unique(conversation_id, user_id)
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS messages (
+	  id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	  conversation_id integer references conversations(id),
+	  user_id         integer references users(id),
+	  body            text,
+	  created_at      timestamp not null
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS message_reports (
+	  id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	  message_id      integer references messages(id),
+	  conversation_id integer references conversations(id),
+	  reporter_id     integer references users(id),
+	  reason          text,
+	  status          varchar(16) not null default 'open',
+	  created_at      timestamp not null,
+	  resolved_by     integer default 0,
+	  resolved_at     timestamp
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS audit_log (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  actor_id   integer references users(id),
+	  action     varchar(64) not null,
+	  detail     text,
+	  created_at timestamp not null
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS user_follows (
+	  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	  follower_id integer references users(id),
+	  followed_id integer references users(id),
+	  created_at  timestamp not null,
+	  unique(follower_id, followed_id)
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS category_follows (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  user_id    integer references users(id),
+	  category   varchar(255) not null,
+	  created_at timestamp not null,
+	  unique(user_id, category)
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS collections (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  user_id    integer references users(id),
+	  name       varchar(64) not null,
+	  created_at timestamp not null,
+	  unique(user_id, name)
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS bookmarks (
+	  id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	  user_id       integer references users(id),
+	  collection_id integer not null default 0,
+	  thread_id     integer references threads(id),
+	  post_id       integer not null default 0,
+	  note          text not null default '',
+	  created_at    timestamp not null,
+	  unique(user_id, thread_id, post_id)
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS api_tokens (
+	  user_id      integer primary key references users(id),
+	  token_hash   varchar(64) not null unique,
+	  created_at   timestamp not null,
+	  last_used_at timestamp
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS drafts (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  user_id    integer references users(id),
+	  thread_id  integer not null default 0,
+	  topic      text not null default '',
+	  body       text not null default '',
+	  category1  varchar(255) not null default '',
+	  category2  varchar(255) not null default '',
+	  updated_at timestamp not null,
+	  unique(user_id, thread_id)
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS tags (
+	  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	  name        varchar(32) not null unique,
+	  usage_count integer not null default 0,
+	  created_at  timestamp not null
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS thread_tags (
+	  thread_id integer references threads(id),
+	  tag_id    integer references tags(id),
+	  primary key(thread_id, tag_id)
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS tag_synonyms (
+	  alias      varchar(32) primary key,
+	  tag_id     integer references tags(id),
+	  created_by integer references users(id),
+	  created_at timestamp not null
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS feed_tokens (
+	  user_id    integer primary key references users(id),
+	  token_hash varchar(64) not null unique,
+	  created_at timestamp not null
+	);`,
+
+	`CREATE TABLE IF NOT EXISTS thread_reads (
+	  user_id      integer references users(id),
+	  thread_id    integer references threads(id),
+	  last_post_id integer not null default 0,
+	  read_at      timestamp not null,
+	  primary key(user_id, thread_id)
+	);`,
+}
+
+// columnUpgrades holds columns added to the initial tables,
+// they are only added when missing
+var columnUpgrades = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"users", "status", "varchar(16) not null default 'active'"},
+	{"users", "role", "varchar(16) not null default 'member'"},
+	{"users", "bio", "text not null default ''"},
+	{"users", "avatar_url", "varchar(255) not null default ''"},
+	{"threads", "pinned", "boolean not null default 0"},
+	{"threads", "locked", "boolean not null default 0"},
+	{"threads", "archived", "boolean not null default 0"},
+	{"threads", "views", "integer not null default 0"},
+}
+
+var createdTable = regexp.MustCompile(`CREATE TABLE IF NOT EXISTS (\w+)`)
+
+// CheckSchema reports the first table or column of the schema upgrades
+// that is missing, nil when every migration has been applied
+func CheckSchema(db data.SchemaStore) error {
+	for _, stmt := range schemaUpgrades {
+		table := createdTable.FindStringSubmatch(stmt)[1]
+		exists, err := db.HasTable(table)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("table %s is missing", table)
+		}
+	}
+	for _, c := range columnUpgrades {
+		exists, err := db.HasColumn(c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("column %s.%s is missing", c.table, c.column)
+		}
+	}
+	return nil
+}
+
+// UpgradeSchema creates tables and columns that are missing from an older database
+func UpgradeSchema(db data.SchemaStore) error {
+	for _, stmt := range schemaUpgrades {
+		if err := db.ExecSchema(stmt); err != nil {
+			return fmt.Errorf("schema upgrade failed: %w", err)
+		}
+	}
+	for _, c := range columnUpgrades {
+		exists, err := db.HasColumn(c.table, c.column)
+		if err != nil {
+			return fmt.Errorf("schema upgrade failed: %w", err)
+		}
+		if exists {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", c.table, c.column, c.definition)
+		if err := db.ExecSchema(stmt); err != nil {
+			return fmt.Errorf("schema upgrade failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// initialTables are the tables RunMigrations creates from scratch, the
+// schema upgrades add the others
+var initialTables = []string{
+	`CREATE TABLE users (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  uuid       varchar(64) not null unique,
+	  name       varchar(64),
+	  email      varchar(64) not null unique,
+	  password   varchar(128) not null,
+	  created_at timestamp not null,
+	  prefered_category1 varchar(255) default '',
+	  prefered_category2 varchar(255) default ''
+	);`,
+
+	`CREATE TABLE sessions (
+	  id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	  uuid          varchar(64) not null unique,
+	  email         varchar(64),
+	  user_id       integer references users(id),
+	  created_at    timestamp not null,
+	  cookie_string varchar(255),
+	  active_last   integer default 0
+	);`,
+
+	`CREATE TABLE threads (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  uuid       varchar(64) not null unique,
+	  topic      text,
+	  body       text,
+	  user_id    integer references users(id),
+	  created_at timestamp not null,
+	  category1  varchar(255) default '',
+	  category2  varchar(255) default ''
+	);`,
+
+	`CREATE TABLE posts (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  uuid       varchar(64) not null unique,
+	  body       text,
+	  user_id    integer references users(id),
+	  thread_id  integer references threads(id),
+	  created_at timestamp not null
+	);`,
+
+	`CREATE TABLE threadlikes (
+	  id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	  type      varchar(50),
+	  user_id   integer references users(id),
+	  thread_id integer references threads(id)
+	);`,
+
+	`CREATE TABLE threaddislikes (
+	  id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	  type      varchar(50),
+	  user_id   integer references users(id),
+	  thread_id integer references threads(id)
+	);`,
+
+	`CREATE TABLE likedposts (
+	  id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	  type    varchar(50),
+	  user_id integer references users(id),
+	  post_id integer references posts(id)
+	);`,
+
+	`CREATE TABLE dislikes (
+	  id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	  type    varchar(50),
+	  user_id integer references users(id),
+	  post_id integer references posts(id)
+	);`,
+}
+
+// schemaReferences are the references between the tables, checked by
+// CheckDatabase since neither database enforces them
+var schemaReferences = data.References(slices.Concat(initialTables, schemaUpgrades)...)
+
 func RunMigrations(db *data.DatabaseManager) error {
 	stmts := []string{
+		`DROP TABLE IF EXISTS thread_reads;`,
+		`DROP TABLE IF EXISTS feed_tokens;`,
+		`DROP TABLE IF EXISTS tag_synonyms;`,
+		`DROP TABLE IF EXISTS thread_tags;`,
+		`DROP TABLE IF EXISTS tags;`,
+		`DROP TABLE IF EXISTS drafts;`,
+		`DROP TABLE IF EXISTS api_tokens;`,
+		`DROP TABLE IF EXISTS bookmarks;`,
+		`DROP TABLE IF EXISTS collections;`,
+		`DROP TABLE IF EXISTS category_follows;`,
+		`DROP TABLE IF EXISTS user_follows;`,
+		`DROP TABLE IF EXISTS audit_log;`,
+		`DROP TABLE IF EXISTS message_reports;`,
+		`DROP TABLE IF EXISTS messages;`,
+		`DROP TABLE IF EXISTS conversation_members;`,
+		`DROP TABLE IF EXISTS conversations;`,
+		`DROP TABLE IF EXISTS mentions;`,
+		`DROP TABLE IF EXISTS user_blocks;`,
+		`DROP TABLE IF EXISTS digest_preferences;`,
+		`DROP TABLE IF EXISTS thread_watches;`,
 		`DROP TABLE IF EXISTS dislikes;`,
 		`DROP TABLE IF EXISTS likedposts;`,
 		`DROP TABLE IF EXISTS threaddislikes;`,
@@ -62,81 +441,13 @@ func RunMigrations(db *data.DatabaseManager) error {
 		`DROP TABLE IF EXISTS threads;`,
 		`DROP TABLE IF EXISTS sessions;`,
 		`DROP TABLE IF EXISTS users;`,
-
-		`CREATE TABLE users (
-		  id         INTEGER PRIMARY KEY AUTOINCREMENT,
-		  uuid       varchar(64) not null unique,
-		  name       varchar(64),
-		  email      varchar(64) not null unique,
-		  password   varchar(128) not null,
-		  created_at timestamp not null,
-		  prefered_category1 varchar(255) default '',
-		  prefered_category2 varchar(255) default ''
-		);`,
-
-		`CREATE TABLE sessions (
-		  id            INTEGER PRIMARY KEY AUTOINCREMENT,
-		  uuid          varchar(64) not null unique,
-		  email         varchar(64),
-		  user_id       integer references users(id),
-		  created_at    timestamp not null,
-		  cookie_string varchar(255),
-		  active_last   integer default 0
-		);`,
-
-		`CREATE TABLE threads (
-		  id         INTEGER PRIMARY KEY AUTOINCREMENT,
-		  uuid       varchar(64) not null unique,
-		  topic      text,
-		  body       text,
-		  user_id    integer references users(id),
-		  created_at timestamp not null,
-		  category1  varchar(255) default '',
-		  category2  varchar(255) default ''
-		);`,
-
-		`CREATE TABLE posts (
-		  id         INTEGER PRIMARY KEY AUTOINCREMENT,
-		  uuid       varchar(64) not null unique,
-		  body       text,
-		  user_id    integer references users(id),
-		  thread_id  integer references threads(id),
-		  created_at timestamp not null
-		);`,
-
-		`CREATE TABLE threadlikes (
-		  id        INTEGER PRIMARY KEY AUTOINCREMENT,
-		  type      varchar(50),
-		  user_id   integer references users(id),
-		  thread_id integer references threads(id)
-		);`,
-
-		`CREATE TABLE threaddislikes (
-		  id        INTEGER PRIMARY KEY AUTOINCREMENT,
-		  type      varchar(50),
-		  user_id   integer references users(id),
-		  thread_id integer references threads(id)
-		);`,
-
-		`CREATE TABLE likedposts (
-		  id      INTEGER PRIMARY KEY AUTOINCREMENT,
-		  type    varchar(50),
-		  user_id integer references users(id),
-		  post_id integer references posts(id)
-		);`,
-
-		`CREATE TABLE dislikes (
-		  id      INTEGER PRIMARY KEY AUTOINCREMENT,
-		  type    varchar(50),
-		  user_id integer references users(id),
-		  post_id integer references posts(id)
-		);`,
 	}
+	stmts = append(stmts, initialTables...)
 
 	for _, stmt := range stmts {
-		if _, err := db.DoExec(stmt); err != nil {
+		if err := db.ExecSchema(stmt); err != nil {
 			return fmt.Errorf("migration failed: %w", err)
 		}
 	}
-	return nil
+	return UpgradeSchema(db)
 }
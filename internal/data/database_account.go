@@ -0,0 +1,285 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/models"
+	"forum/utils"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Account and maintenance operations for the command line and account deletion
+
+func (dm *DatabaseManager) SetUserRole(userID int, role string) error {
+	return dm.updateUser("UPDATE users SET role = ? WHERE id = ?", role, userID)
+}
+
+func (dm *DatabaseManager) SetUserStatus(userID int, status string) error {
+	return dm.updateUser("UPDATE users SET status = ? WHERE id = ?", status, userID)
+}
+
+// SetUserPassword stores the hash of password
+func (dm *DatabaseManager) SetUserPassword(userID int, password string) error {
+	return dm.updateUser("UPDATE users SET password = ? WHERE id = ?", utils.Encrypt(password), userID)
+}
+
+// updateUser runs an update of one user, sql.ErrNoRows when there is no such user
+func (dm *DatabaseManager) updateUser(query string, value any, userID int) error {
+	result, err := dm.db.Exec(query, value, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// CountUsersByStatus returns the number of accounts per status
+func (dm *DatabaseManager) CountUsersByStatus() (map[string]int, error) {
+	rows, err := dm.db.Query("SELECT status, COUNT(*) FROM users GROUP BY status")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// DeleteUserSessions signs a user out everywhere
+func (dm *DatabaseManager) DeleteUserSessions(userID int) (int64, error) {
+	result, err := dm.db.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteSessionsBefore removes the sessions created before the given time
+func (dm *DatabaseManager) DeleteSessionsBefore(before time.Time) (int64, error) {
+	result, err := dm.db.Exec("DELETE FROM sessions WHERE created_at < ?", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// IntegrityCheck runs the SQLite integrity check, Postgres has no
+// equivalent and always passes
+func (dm *DatabaseManager) IntegrityCheck() error {
+	if dm.db.dialect == Postgres {
+		return nil
+	}
+	rows, err := dm.db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return err
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Reference is a column of Table holding the ParentColumn of a Parent row
+type Reference struct {
+	Table, Column        string
+	Parent, ParentColumn string
+}
+
+var (
+	tableName       = regexp.MustCompile(`(?i)CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(\w+)`)
+	columnReference = regexp.MustCompile(`(?im)^\s*(\w+)\s+[^,\n]*?\breferences\s+(\w+)\s*\((\w+)\)`)
+)
+
+// References lists the references declared by CREATE TABLE statements
+func References(stmts ...string) []Reference {
+	var refs []Reference
+	for _, stmt := range stmts {
+		table := tableName.FindStringSubmatch(stmt)
+		if table == nil {
+			continue
+		}
+		for _, m := range columnReference.FindAllStringSubmatch(stmt, -1) {
+			refs = append(refs, Reference{Table: table[1], Column: m[1], Parent: m[2], ParentColumn: m[3]})
+		}
+	}
+	return refs
+}
+
+// ForeignKeyViolations counts the rows referencing a missing row, keyed by
+// "table -> parent". Neither database enforces the references (Schema drops
+// them on Postgres), so each one is checked with a join on both.
+func (dm *DatabaseManager) ForeignKeyViolations(refs []Reference) (map[string]int, error) {
+	violations := map[string]int{}
+	for _, ref := range refs {
+		query := fmt.Sprintf(`SELECT COUNT(*) FROM %s c LEFT JOIN %s p ON p.%s = c.%s
+			WHERE c.%s IS NOT NULL AND p.%s IS NULL`,
+			ref.Table, ref.Parent, ref.ParentColumn, ref.Column, ref.Column, ref.ParentColumn)
+		var count int
+		if err := dm.db.QueryRow(query).Scan(&count); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", ref.Table, ref.Column, err)
+		}
+		if count > 0 {
+			violations[ref.Table+" -> "+ref.Parent] += count
+		}
+	}
+	return violations, nil
+}
+
+// personalRows removes what only concerns the user: sessions, settings,
+// subscriptions, blocks, follows, bookmarks, tokens, drafts and read markers
+var personalRows = []string{
+	"DELETE FROM sessions WHERE user_id = ?1",
+	"DELETE FROM thread_watches WHERE user_id = ?1",
+	"DELETE FROM thread_reads WHERE user_id = ?1",
+	"DELETE FROM digest_preferences WHERE user_id = ?1",
+	"DELETE FROM user_blocks WHERE blocker_id = ?1 OR blocked_id = ?1",
+	"DELETE FROM user_follows WHERE follower_id = ?1 OR followed_id = ?1",
+	"DELETE FROM category_follows WHERE user_id = ?1",
+	"DELETE FROM mentions WHERE user_id = ?1",
+	"DELETE FROM bookmarks WHERE user_id = ?1",
+	"DELETE FROM collections WHERE user_id = ?1",
+	"DELETE FROM api_tokens WHERE user_id = ?1",
+	"DELETE FROM feed_tokens WHERE user_id = ?1",
+	"DELETE FROM drafts WHERE user_id = ?1",
+	"DELETE FROM conversation_members WHERE user_id = ?1",
+	"DELETE FROM message_reports WHERE reporter_id = ?1",
+}
+
+// authoredThreads selects every thread the user started. ownThreads leaves
+// out those somebody else replied to: removing them would take the replies,
+// votes, bookmarks and mentions of other members along.
+const (
+	authoredThreads = "SELECT id FROM threads WHERE user_id = ?1"
+	ownThreads      = authoredThreads + " AND NOT EXISTS (SELECT 1 FROM posts p WHERE p.thread_id = threads.id AND p.user_id <> ?1)"
+)
+
+// contentRows removes what the user wrote and voted, the threads selected by
+// threads with every reply in them, and every row pointing at what it removes
+func contentRows(threads string) []string {
+	posts := "SELECT id FROM posts WHERE user_id = ?1 OR thread_id IN (" + threads + ")"
+	return []string{
+		"DELETE FROM threadlikes WHERE user_id = ?1 OR thread_id IN (" + threads + ")",
+		"DELETE FROM threaddislikes WHERE user_id = ?1 OR thread_id IN (" + threads + ")",
+		"DELETE FROM likedposts WHERE user_id = ?1 OR post_id IN (" + posts + ")",
+		"DELETE FROM dislikes WHERE user_id = ?1 OR post_id IN (" + posts + ")",
+		"DELETE FROM mentions WHERE author_id = ?1 OR thread_id IN (" + threads + ")",
+		"DELETE FROM bookmarks WHERE thread_id IN (" + threads + ") OR post_id IN (" + posts + ")",
+		"DELETE FROM thread_watches WHERE thread_id IN (" + threads + ")",
+		"DELETE FROM thread_reads WHERE thread_id IN (" + threads + ")",
+		"DELETE FROM drafts WHERE thread_id IN (" + threads + ")",
+		"DELETE FROM thread_tags WHERE thread_id IN (" + threads + ")",
+		"DELETE FROM posts WHERE user_id = ?1 OR thread_id IN (" + threads + ")",
+		"DELETE FROM threads WHERE id IN (" + threads + ")",
+		"DELETE FROM message_reports WHERE message_id IN (SELECT id FROM messages WHERE user_id = ?1)",
+		"DELETE FROM messages WHERE user_id = ?1",
+	}
+}
+
+// sharedRows keep records that belong to the forum, not the user, once the
+// user row is gone
+var sharedRows = []string{
+	"UPDATE audit_log SET actor_id = NULL WHERE actor_id = ?1",
+	"UPDATE conversations SET created_by = NULL WHERE created_by = ?1",
+	"UPDATE tag_synonyms SET created_by = NULL WHERE created_by = ?1",
+}
+
+// purgeUser runs the statements for userID and recounts the tags of the
+// threads it removes
+func purgeUser(tx *dbTx, userID int, statements ...[]string) error {
+	touched, err := tagIDs(tx, "SELECT DISTINCT tag_id FROM thread_tags WHERE thread_id IN ("+authoredThreads+")", userID)
+	if err != nil {
+		return err
+	}
+	for _, stmts := range statements {
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt, userID); err != nil {
+				return err
+			}
+		}
+	}
+	return recountTags(tx, touched)
+}
+
+// AnonymizeUser closes an account but keeps its row, renamed deleted-ID with
+// status deleted, so what the user wrote stays readable. With removeContent
+// their replies, votes, messages and the threads nobody else replied to are
+// removed as well, the threads with replies of others stay under deleted-ID.
+func (dm *DatabaseManager) AnonymizeUser(userID int, removeContent bool) error {
+	tx, err := dm.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := [][]string{personalRows}
+	if removeContent {
+		statements = append(statements, contentRows(ownThreads))
+	}
+	if err := purgeUser(tx, userID, statements...); err != nil {
+		return err
+	}
+
+	// an empty hash matches no password
+	name := fmt.Sprintf("deleted-%d", userID)
+	result, err := tx.Exec(`
+		UPDATE users SET uuid = ?, name = ?, email = ?, password = '', prefered_category1 = '', prefered_category2 = '',
+		  bio = '', avatar_url = '', role = 'member', status = 'deleted'
+		WHERE id = ? AND status <> 'deleted'`,
+		utils.CreateUUID(), name, name+"@invalid", userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// GetUserSessions returns the sessions of a user, newest first
+func (dm *DatabaseManager) GetUserSessions(userID int) ([]models.Session, error) {
+	rows, err := dm.db.Query("SELECT id, created_at, active_last FROM sessions WHERE user_id = ? ORDER BY created_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		session := models.Session{UserId: userID}
+		if err := rows.Scan(&session.Id, &session.CreatedAt, &session.ActiveLast); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
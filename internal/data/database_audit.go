@@ -0,0 +1,34 @@
+package data
+
+import (
+	"forum/models"
+	"time"
+)
+
+// Audit log operations
+func (dm *DatabaseManager) WriteAudit(actorID int, action, detail string) error {
+	_, err := dm.db.Exec("INSERT INTO audit_log(actor_id, action, detail, created_at) VALUES(?, ?, ?, ?)",
+		actorID, action, detail, time.Now())
+	return err
+}
+
+func (dm *DatabaseManager) GetAuditLog(limit int) ([]models.AuditEntry, error) {
+	rows, err := dm.db.Query(`
+		SELECT a.id, COALESCE(a.actor_id, 0), COALESCE(u.name, ''), a.action, a.detail, a.created_at
+		FROM audit_log a LEFT JOIN users u ON u.id = a.actor_id
+		ORDER BY a.id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.Id, &e.ActorId, &e.Actor, &e.Action, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
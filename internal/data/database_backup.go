@@ -0,0 +1,112 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Online backups with the SQLite backup API, the live database stays open
+// for readers and writers while it is copied
+
+// ErrNotSQLite is returned by the backup methods of a Postgres database,
+// which is backed up with pg_dump
+var ErrNotSQLite = errors.New("online backups need an SQLite database, use pg_dump for Postgres")
+
+const (
+	backupStepPages = 256                  // pages copied while the source is locked
+	backupStepPause = 5 * time.Millisecond // lets writers in between steps
+)
+
+// BackupTo copies the database into a new SQLite file at path. The copy
+// uses a rollback journal so it is a single file that can be moved around.
+func (dm *DatabaseManager) BackupTo(ctx context.Context, path string) error {
+	if dm.db.dialect != SQLite {
+		return ErrNotSQLite
+	}
+	dest, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	if err := copyDatabase(ctx, dest, dm.db.DB, backupStepPages); err != nil {
+		return err
+	}
+	_, err = dest.ExecContext(ctx, "PRAGMA journal_mode = DELETE")
+	return err
+}
+
+// RestoreFrom replaces the whole database with the SQLite file at path in
+// one step, connections still open see the restored data afterwards
+func (dm *DatabaseManager) RestoreFrom(ctx context.Context, path string) error {
+	if dm.db.dialect != SQLite {
+		return ErrNotSQLite
+	}
+	src, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return copyDatabase(ctx, dm.db.DB, src, -1)
+}
+
+// OpenSnapshot opens an SQLite file read-only, to check it before a restore
+func OpenSnapshot(path string) (*DatabaseManager, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return &DatabaseManager{db: &dbConn{DB: db, dialect: SQLite}}, nil
+}
+
+// copyDatabase copies the main database of src over the one of dest,
+// pages at a time (-1 copies everything at once) until ctx is done
+func copyDatabase(ctx context.Context, dest, src *sql.DB, pages int) error {
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriver any) error {
+		return srcConn.Raw(func(srcDriver any) error {
+			destSQLite, ok := destDriver.(*sqlite3.SQLiteConn)
+			srcSQLite, ok2 := srcDriver.(*sqlite3.SQLiteConn)
+			if !ok || !ok2 {
+				return ErrNotSQLite
+			}
+			backup, err := destSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+			for {
+				done, err := backup.Step(pages)
+				if err != nil {
+					backup.Finish()
+					return fmt.Errorf("backup step: %w", err)
+				}
+				if done {
+					return backup.Finish()
+				}
+				select {
+				case <-ctx.Done():
+					backup.Finish()
+					return ctx.Err()
+				case <-time.After(backupStepPause):
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,27 @@
+package data
+
+import "time"
+
+// User block operations
+func (dm *DatabaseManager) BlockUser(blockerID, blockedID int) error {
+	_, err := dm.db.Exec("INSERT OR IGNORE INTO user_blocks(blocker_id, blocked_id, created_at) VALUES(?, ?, ?)",
+		blockerID, blockedID, time.Now())
+	return err
+}
+
+func (dm *DatabaseManager) UnblockUser(blockerID, blockedID int) error {
+	_, err := dm.db.Exec("DELETE FROM user_blocks WHERE blocker_id=? AND blocked_id=?", blockerID, blockedID)
+	return err
+}
+
+// HasBlocked reports whether blockerID blocked blockedID
+func (dm *DatabaseManager) HasBlocked(blockerID, blockedID int) bool {
+	var count int
+	err := dm.db.QueryRow("SELECT COUNT(*) FROM user_blocks WHERE blocker_id=? AND blocked_id=?", blockerID, blockedID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// IsBlockedEitherWay reports whether one of the users blocked the other
+func (dm *DatabaseManager) IsBlockedEitherWay(userA, userB int) bool {
+	return dm.HasBlocked(userA, userB) || dm.HasBlocked(userB, userA)
+}
@@ -0,0 +1,163 @@
+package data
+
+import (
+	"forum/models"
+	"time"
+)
+
+// Collection operations
+func (dm *DatabaseManager) CreateCollection(userID int, name string) (int64, error) {
+	return dm.db.Insert("INSERT INTO collections(user_id, name, created_at) VALUES(?, ?, ?)",
+		userID, name, time.Now())
+}
+
+// GetCollections returns the collections of a user with their bookmark counts
+func (dm *DatabaseManager) GetCollections(userID int) ([]models.Collection, error) {
+	rows, err := dm.db.Query(`
+		SELECT c.id, c.name, c.created_at,
+		       (SELECT COUNT(*) FROM bookmarks b WHERE b.collection_id = c.id)
+		FROM collections c
+		WHERE c.user_id = ?
+		ORDER BY c.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []models.Collection{}
+	for rows.Next() {
+		var c models.Collection
+		if err := rows.Scan(&c.Id, &c.Name, &c.CreatedAt, &c.Count); err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+func (dm *DatabaseManager) IsCollectionOwner(collectionID, userID int) bool {
+	var count int
+	err := dm.db.QueryRow("SELECT COUNT(*) FROM collections WHERE id=? AND user_id=?", collectionID, userID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// DeleteCollection removes a collection, its bookmarks are kept without a collection
+func (dm *DatabaseManager) DeleteCollection(collectionID, userID int) error {
+	tx, err := dm.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE bookmarks SET collection_id=0 WHERE collection_id=? AND user_id=?", collectionID, userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM collections WHERE id=? AND user_id=?", collectionID, userID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Bookmark operations
+
+// SaveBookmark adds a bookmark, saving the same thread or post again
+// moves it to the given collection and replaces its note
+func (dm *DatabaseManager) SaveBookmark(userID, threadID, postID, collectionID int, note string) error {
+	_, err := dm.db.Exec(`
+		INSERT INTO bookmarks(user_id, collection_id, thread_id, post_id, note, created_at)
+		VALUES(?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, thread_id, post_id)
+		DO UPDATE SET collection_id = excluded.collection_id, note = excluded.note`,
+		userID, collectionID, threadID, postID, note, time.Now())
+	return err
+}
+
+func (dm *DatabaseManager) UpdateBookmark(bookmarkID, userID, collectionID int, note string) error {
+	_, err := dm.db.Exec("UPDATE bookmarks SET collection_id=?, note=? WHERE id=? AND user_id=?",
+		collectionID, note, bookmarkID, userID)
+	return err
+}
+
+func (dm *DatabaseManager) DeleteBookmark(bookmarkID, userID int) error {
+	_, err := dm.db.Exec("DELETE FROM bookmarks WHERE id=? AND user_id=?", bookmarkID, userID)
+	return err
+}
+
+func (dm *DatabaseManager) IsThreadBookmarked(userID, threadID int) bool {
+	var count int
+	err := dm.db.QueryRow("SELECT COUNT(*) FROM bookmarks WHERE user_id=? AND thread_id=? AND post_id=0",
+		userID, threadID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// GetBookmarks returns the bookmarks of a user matching filter, newest first.
+// The query matches the topic, the saved text and the note.
+func (dm *DatabaseManager) GetBookmarks(userID int, filter models.BookmarkFilter) ([]models.Bookmark, error) {
+	rows, err := dm.db.Query(`
+		SELECT b.id, b.collection_id, COALESCE(c.name, ''), b.thread_id, b.post_id,
+		       t.topic, COALESCE(p.body, t.body), b.note, b.created_at
+		FROM bookmarks b
+		JOIN threads t ON t.id = b.thread_id
+		LEFT JOIN posts p ON p.id = b.post_id
+		LEFT JOIN collections c ON c.id = b.collection_id
+		WHERE b.user_id = ?1
+		  AND (?2 = -1 OR b.collection_id = ?2)
+		  AND (?3 = '' OR (?3 = 'thread' AND b.post_id = 0) OR (?3 = 'post' AND b.post_id != 0))
+		  AND (?4 = '' OR t.topic LIKE '%' || ?4 || '%'
+		       OR COALESCE(p.body, t.body) LIKE '%' || ?4 || '%'
+		       OR b.note LIKE '%' || ?4 || '%')
+		ORDER BY b.created_at DESC, b.id DESC`,
+		userID, filter.CollectionId, filter.Kind, filter.Query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookmarks := []models.Bookmark{}
+	for rows.Next() {
+		var b models.Bookmark
+		err := rows.Scan(&b.Id, &b.CollectionId, &b.Collection, &b.ThreadId, &b.PostId,
+			&b.Topic, &b.Excerpt, &b.Note, &b.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, rows.Err()
+}
+
+// API token operations, only a hash of the token is stored
+
+// SetAPITokenHash replaces the personal token of a user
+func (dm *DatabaseManager) SetAPITokenHash(userID int, tokenHash string) error {
+	_, err := dm.db.Exec(`
+		INSERT INTO api_tokens(user_id, token_hash, created_at) VALUES(?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET token_hash = excluded.token_hash,
+		  created_at = excluded.created_at, last_used_at = NULL`,
+		userID, tokenHash, time.Now())
+	return err
+}
+
+func (dm *DatabaseManager) DeleteAPIToken(userID int) error {
+	_, err := dm.db.Exec("DELETE FROM api_tokens WHERE user_id=?", userID)
+	return err
+}
+
+func (dm *DatabaseManager) HasAPIToken(userID int) bool {
+	var count int
+	err := dm.db.QueryRow("SELECT COUNT(*) FROM api_tokens WHERE user_id=?", userID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// GetUserIDByTokenHash returns the owner of a token and records its use
+func (dm *DatabaseManager) GetUserIDByTokenHash(tokenHash string) (int, error) {
+	var userID int
+	err := dm.db.QueryRow("SELECT user_id FROM api_tokens WHERE token_hash=?", tokenHash).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = dm.db.Exec("UPDATE api_tokens SET last_used_at=? WHERE user_id=?", time.Now(), userID)
+	return userID, err
+}
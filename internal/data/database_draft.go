@@ -0,0 +1,60 @@
+package data
+
+import (
+	"forum/models"
+	"time"
+)
+
+// Draft operations, a user has at most one draft per thread
+// and one for a new thread (thread_id 0)
+
+func (dm *DatabaseManager) SaveDraft(userID int, draft *models.Draft) error {
+	draft.UpdatedAt = time.Now()
+	_, err := dm.db.Exec(`
+		INSERT INTO drafts(user_id, thread_id, topic, body, category1, category2, updated_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, thread_id) DO UPDATE SET
+		  topic = excluded.topic, body = excluded.body, category1 = excluded.category1,
+		  category2 = excluded.category2, updated_at = excluded.updated_at`,
+		userID, draft.ThreadId, draft.Topic, draft.Body, draft.Category1, draft.Category2, draft.UpdatedAt)
+	return err
+}
+
+func (dm *DatabaseManager) GetDraft(userID, threadID int) (models.Draft, error) {
+	var d models.Draft
+	err := dm.db.QueryRow(`
+		SELECT thread_id, topic, body, category1, category2, updated_at
+		FROM drafts WHERE user_id = ? AND thread_id = ?`, userID, threadID).
+		Scan(&d.ThreadId, &d.Topic, &d.Body, &d.Category1, &d.Category2, &d.UpdatedAt)
+	return d, err
+}
+
+func (dm *DatabaseManager) DeleteDraft(userID, threadID int) error {
+	_, err := dm.db.Exec("DELETE FROM drafts WHERE user_id=? AND thread_id=?", userID, threadID)
+	return err
+}
+
+// GetDrafts returns all drafts of a user, most recently edited first
+func (dm *DatabaseManager) GetDrafts(userID int) ([]models.Draft, error) {
+	rows, err := dm.db.Query(`
+		SELECT d.thread_id, COALESCE(t.topic, ''), d.topic, d.body, d.category1, d.category2, d.updated_at
+		FROM drafts d
+		LEFT JOIN threads t ON t.id = d.thread_id
+		WHERE d.user_id = ?
+		ORDER BY d.updated_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drafts []models.Draft
+	for rows.Next() {
+		var d models.Draft
+		err := rows.Scan(&d.ThreadId, &d.Target, &d.Topic, &d.Body, &d.Category1, &d.Category2, &d.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, d)
+	}
+	return drafts, rows.Err()
+}
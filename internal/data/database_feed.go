@@ -0,0 +1,126 @@
+package data
+
+import (
+	"database/sql"
+	"forum/models"
+	"time"
+)
+
+// Feed operations, entries are returned newest first
+
+func scanFeedEntries(rows *sql.Rows) ([]models.FeedEntry, error) {
+	defer rows.Close()
+
+	var entries []models.FeedEntry
+	for rows.Next() {
+		var e models.FeedEntry
+		err := rows.Scan(&e.Kind, &e.ThreadId, &e.PostId, &e.Topic, &e.Author, &e.Body, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetLatestThreadEntries returns the newest threads, of one category when category is set
+func (dm *DatabaseManager) GetLatestThreadEntries(category string, limit int) ([]models.FeedEntry, error) {
+	rows, err := dm.db.Query(`
+		SELECT 'thread', t.id, 0, t.topic, u.name, t.body, t.created_at
+		FROM threads t JOIN users u ON u.id = t.user_id
+		WHERE ?1 = '' OR t.category1 = ?1 OR t.category2 = ?1
+		ORDER BY t.created_at DESC
+		LIMIT ?2`, category, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanFeedEntries(rows)
+}
+
+// GetThreadReplyEntries returns the newest replies of a thread
+func (dm *DatabaseManager) GetThreadReplyEntries(threadID, limit int) ([]models.FeedEntry, error) {
+	rows, err := dm.db.Query(`
+		SELECT 'reply', p.thread_id, p.id, t.topic, u.name, p.body, p.created_at
+		FROM posts p
+		JOIN threads t ON t.id = p.thread_id
+		JOIN users u ON u.id = p.user_id
+		WHERE p.thread_id = ?
+		ORDER BY p.created_at DESC
+		LIMIT ?`, threadID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanFeedEntries(rows)
+}
+
+// GetUserFeedEntries returns the newest threads and replies written by a user
+func (dm *DatabaseManager) GetUserFeedEntries(userID, limit int) ([]models.FeedEntry, error) {
+	rows, err := dm.db.Query(`
+		SELECT 'thread', t.id, 0, t.topic, u.name, t.body, t.created_at
+		FROM threads t JOIN users u ON u.id = t.user_id
+		WHERE t.user_id = ?1
+		UNION ALL
+		SELECT 'reply', p.thread_id, p.id, t.topic, u.name, p.body, p.created_at
+		FROM posts p
+		JOIN threads t ON t.id = p.thread_id
+		JOIN users u ON u.id = p.user_id
+		WHERE p.user_id = ?1
+		ORDER BY 7 DESC
+		LIMIT ?2`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanFeedEntries(rows)
+}
+
+// GetNotificationEntries returns the mentions of a user and the replies
+// of other users in the threads they watch
+func (dm *DatabaseManager) GetNotificationEntries(userID, limit int) ([]models.FeedEntry, error) {
+	rows, err := dm.db.Query(`
+		SELECT 'mention', m.thread_id, m.post_id, t.topic, u.name, COALESCE(p.body, t.body), m.created_at
+		FROM mentions m
+		JOIN threads t ON t.id = m.thread_id
+		JOIN users u ON u.id = m.author_id
+		LEFT JOIN posts p ON p.id = m.post_id
+		WHERE m.user_id = ?1
+		UNION ALL
+		SELECT 'reply', p.thread_id, p.id, t.topic, u.name, p.body, p.created_at
+		FROM thread_watches w
+		JOIN posts p ON p.thread_id = w.thread_id
+		JOIN threads t ON t.id = p.thread_id
+		JOIN users u ON u.id = p.user_id
+		WHERE w.user_id = ?1 AND p.user_id != ?1 AND p.created_at > w.created_at
+		ORDER BY 7 DESC
+		LIMIT ?2`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanFeedEntries(rows)
+}
+
+// Feed token operations, only a hash of the token is stored
+
+func (dm *DatabaseManager) SetFeedTokenHash(userID int, tokenHash string) error {
+	_, err := dm.db.Exec(`
+		INSERT INTO feed_tokens(user_id, token_hash, created_at) VALUES(?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at`,
+		userID, tokenHash, time.Now())
+	return err
+}
+
+func (dm *DatabaseManager) DeleteFeedToken(userID int) error {
+	_, err := dm.db.Exec("DELETE FROM feed_tokens WHERE user_id=?", userID)
+	return err
+}
+
+func (dm *DatabaseManager) HasFeedToken(userID int) bool {
+	var count int
+	err := dm.db.QueryRow("SELECT COUNT(*) FROM feed_tokens WHERE user_id=?", userID).Scan(&count)
+	return err == nil && count > 0
+}
+
+func (dm *DatabaseManager) GetUserIDByFeedTokenHash(tokenHash string) (int, error) {
+	var userID int
+	err := dm.db.QueryRow("SELECT user_id FROM feed_tokens WHERE token_hash=?", tokenHash).Scan(&userID)
+	return userID, err
+}
@@ -0,0 +1,103 @@
+package data
+
+import (
+	"forum/models"
+	"time"
+)
+
+// User follow operations
+func (dm *DatabaseManager) FollowUser(followerID, followedID int) error {
+	_, err := dm.db.Exec("INSERT OR IGNORE INTO user_follows(follower_id, followed_id, created_at) VALUES(?, ?, ?)",
+		followerID, followedID, time.Now())
+	return err
+}
+
+func (dm *DatabaseManager) UnfollowUser(followerID, followedID int) error {
+	_, err := dm.db.Exec("DELETE FROM user_follows WHERE follower_id=? AND followed_id=?", followerID, followedID)
+	return err
+}
+
+func (dm *DatabaseManager) IsFollowingUser(followerID, followedID int) bool {
+	var count int
+	err := dm.db.QueryRow("SELECT COUNT(*) FROM user_follows WHERE follower_id=? AND followed_id=?",
+		followerID, followedID).Scan(&count)
+	return err == nil && count > 0
+}
+
+func (dm *DatabaseManager) CountFollowers(userID int) (int, error) {
+	var count int
+	err := dm.db.QueryRow("SELECT COUNT(*) FROM user_follows WHERE followed_id=?", userID).Scan(&count)
+	return count, err
+}
+
+// Category follow operations
+func (dm *DatabaseManager) FollowCategory(userID int, category string) error {
+	_, err := dm.db.Exec("INSERT OR IGNORE INTO category_follows(user_id, category, created_at) VALUES(?, ?, ?)",
+		userID, category, time.Now())
+	return err
+}
+
+func (dm *DatabaseManager) UnfollowCategory(userID int, category string) error {
+	_, err := dm.db.Exec("DELETE FROM category_follows WHERE user_id=? AND category=?", userID, category)
+	return err
+}
+
+func (dm *DatabaseManager) GetFollowedCategories(userID int) ([]string, error) {
+	rows, err := dm.db.Query("SELECT category FROM category_follows WHERE user_id=? ORDER BY category", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []string
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+// GetFeedThreads returns one page of threads started by followed users or
+// posted in followed categories, newest first. The user's own threads and
+// threads of blocked users are left out.
+func (dm *DatabaseManager) GetFeedThreads(userID, limit, offset int) ([]models.Thread, error) {
+	rows, err := dm.db.Query(`
+		SELECT t.id, t.uuid, t.topic, t.body, t.user_id, u.name, t.created_at, t.category1, t.category2,
+		       (SELECT COUNT(*) FROM posts p WHERE p.thread_id = t.id),
+		       (SELECT COUNT(*) FROM threadlikes l WHERE l.thread_id = t.id),
+		       (SELECT COUNT(*) FROM threaddislikes d WHERE d.thread_id = t.id)
+		FROM threads t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.user_id != ?1
+		  AND (t.user_id IN (SELECT followed_id FROM user_follows WHERE follower_id = ?1)
+		       OR t.category1 IN (SELECT category FROM category_follows WHERE user_id = ?1)
+		       OR t.category2 IN (SELECT category FROM category_follows WHERE user_id = ?1))
+		  AND NOT EXISTS (
+		      SELECT 1 FROM user_blocks b
+		      WHERE (b.blocker_id = ?1 AND b.blocked_id = t.user_id)
+		         OR (b.blocker_id = t.user_id AND b.blocked_id = ?1))
+		ORDER BY t.created_at DESC, t.id DESC
+		LIMIT ?2 OFFSET ?3`, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var threads []models.Thread
+	for rows.Next() {
+		var thread models.Thread
+		err := rows.Scan(&thread.Id, &thread.Uuid, &thread.Topic, &thread.Body, &thread.UserId, &thread.User,
+			&thread.CreatedAt, &thread.Category1, &thread.Category2,
+			&thread.NumReplies, &thread.LikesCount, &thread.DislikesCount)
+		if err != nil {
+			return nil, err
+		}
+		thread.CreatedAtDate = thread.CreatedAt.Format("Jan 2, 2006 at 15:04")
+		thread.Len = len(thread.Topic)
+		threads = append(threads, thread)
+	}
+	return threads, rows.Err()
+}
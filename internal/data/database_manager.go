@@ -4,17 +4,30 @@ import (
 	"database/sql"
 	"forum/models"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type DatabaseManager struct {
-	db *sql.DB
+	db *dbConn
+}
+
+// Dialect returns the SQL flavour of the database
+func (dm *DatabaseManager) Dialect() Dialect {
+	return dm.db.dialect
 }
 
 func (dm *DatabaseManager) DoExec(query string, args ...any) (sql.Result, error) {
 	return dm.db.Exec(query, args...)
 }
 
+// ExecSchema runs a schema statement written for SQLite,
+// translated for the dialect of the database
+func (dm *DatabaseManager) ExecSchema(stmt string) error {
+	_, err := dm.db.Exec(dm.db.dialect.Schema(stmt))
+	return err
+}
+
 func (dm *DatabaseManager) Ping() error {
 	return dm.db.Ping()
 }
@@ -32,7 +45,24 @@ func NewDatabaseManager(dbPath string) (*DatabaseManager, error) {
 	db.SetMaxOpenConns(10)
 	db.SetMaxIdleConns(5)
 
-	return &DatabaseManager{db: db}, nil
+	return &DatabaseManager{db: &dbConn{DB: db, dialect: SQLite}}, nil
+}
+
+// Open creates a database manager for a DSN: a postgres:// URL connects to
+// Postgres, anything else is taken as the path of an SQLite file
+func Open(dsn string) (*DatabaseManager, error) {
+	if DialectOf(dsn) != Postgres {
+		return NewDatabaseManager(dsn)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(20)
+	db.SetMaxIdleConns(10)
+
+	return &DatabaseManager{db: &dbConn{DB: db, dialect: Postgres}}, nil
 }
 
 // Close closes the database connection
@@ -40,15 +70,51 @@ func (dm *DatabaseManager) Close() error {
 	return dm.db.Close()
 }
 
+// HasColumn reports whether a table already has the column (for schema upgrades)
+func (dm *DatabaseManager) HasColumn(table, column string) (bool, error) {
+	query := "SELECT name FROM pragma_table_info(?)"
+	if dm.db.dialect == Postgres {
+		query = "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ?"
+	}
+	rows, err := dm.db.Query(query, table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// HasTable reports whether the table exists
+func (dm *DatabaseManager) HasTable(table string) (bool, error) {
+	query := "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	if dm.db.dialect == Postgres {
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+	}
+	var count int
+	err := dm.db.QueryRow(query, table).Scan(&count)
+	return count > 0, err
+}
+
 // GetDB returns the database connection (for migration purposes)
 func (dm *DatabaseManager) GetDB() *sql.DB {
-	return dm.db
+	return dm.db.DB
 }
 
 // Statistics methods needed by statistics.go
+// GetUserCount counts the accounts that are not deleted
 func (dm *DatabaseManager) GetUserCount() (int, error) {
 	var count int
-	err := dm.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	err := dm.db.QueryRow("SELECT COUNT(*) FROM users WHERE status <> 'deleted'").Scan(&count)
 	return count, err
 }
 
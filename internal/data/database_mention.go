@@ -0,0 +1,52 @@
+package data
+
+import (
+	"forum/models"
+	"time"
+)
+
+// Mention operations
+func (dm *DatabaseManager) CreateMention(userID, authorID, threadID, postID int) error {
+	_, err := dm.db.Exec("INSERT INTO mentions(user_id, author_id, thread_id, post_id, created_at) VALUES(?, ?, ?, ?, ?)",
+		userID, authorID, threadID, postID, time.Now())
+	return err
+}
+
+// GetUserMentions returns the latest mentions of a user with author and thread info
+func (dm *DatabaseManager) GetUserMentions(userID, limit int) ([]models.Mention, error) {
+	rows, err := dm.db.Query(`
+		SELECT m.id, m.user_id, m.author_id, u.name, m.thread_id, t.topic, m.post_id, m.created_at, m.seen
+		FROM mentions m
+		JOIN users u ON u.id = m.author_id
+		JOIN threads t ON t.id = m.thread_id
+		WHERE m.user_id = ?
+		ORDER BY m.created_at DESC
+		LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mentions []models.Mention
+	for rows.Next() {
+		var mention models.Mention
+		err := rows.Scan(&mention.Id, &mention.UserId, &mention.AuthorId, &mention.Author,
+			&mention.ThreadId, &mention.Topic, &mention.PostId, &mention.CreatedAt, &mention.Seen)
+		if err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions, rows.Err()
+}
+
+func (dm *DatabaseManager) CountUnseenMentions(userID int) (int, error) {
+	var count int
+	err := dm.db.QueryRow("SELECT COUNT(*) FROM mentions WHERE user_id=? AND seen=0", userID).Scan(&count)
+	return count, err
+}
+
+func (dm *DatabaseManager) MarkMentionsSeen(userID int) error {
+	_, err := dm.db.Exec("UPDATE mentions SET seen=1 WHERE user_id=? AND seen=0", userID)
+	return err
+}
@@ -0,0 +1,265 @@
+package data
+
+import (
+	"database/sql"
+	"forum/models"
+	"forum/utils"
+	"time"
+)
+
+// Conversation operations
+
+// CreateConversation creates a conversation with its members and first message
+// in one transaction and returns the conversation id
+func (dm *DatabaseManager) CreateConversation(creatorID int, memberIDs []int, subject, body string) (int64, error) {
+	tx, err := dm.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	conversationID, err := tx.Insert("INSERT INTO conversations(uuid, subject, created_by, created_at, updated_at) VALUES(?, ?, ?, ?, ?)",
+		utils.CreateUUID(), subject, creatorID, now, now)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, memberID := range append([]int{creatorID}, memberIDs...) {
+		_, err = tx.Exec("INSERT OR IGNORE INTO conversation_members(conversation_id, user_id, joined_at) VALUES(?, ?, ?)",
+			conversationID, memberID, now)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	messageID, err := tx.Insert("INSERT INTO messages(conversation_id, user_id, body, created_at) VALUES(?, ?, ?, ?)",
+		conversationID, creatorID, body, now)
+	if err != nil {
+		return 0, err
+	}
+	// the creator has read their own first message
+	_, err = tx.Exec("UPDATE conversation_members SET last_read_id=? WHERE conversation_id=? AND user_id=?",
+		messageID, conversationID, creatorID)
+	if err != nil {
+		return 0, err
+	}
+
+	return conversationID, tx.Commit()
+}
+
+// GetUserConversations returns the inbox of a user with unread counts,
+// conversations the user left are not listed
+func (dm *DatabaseManager) GetUserConversations(userID int) ([]models.Conversation, error) {
+	rows, err := dm.db.Query(`
+		SELECT c.id, c.uuid, c.subject, COALESCE(c.created_by, 0), c.created_at, c.updated_at, m.muted,
+		       (SELECT COUNT(*) FROM messages x
+		        WHERE x.conversation_id = c.id AND x.id > m.last_read_id AND x.user_id != m.user_id) AS unread,
+		       (SELECT COALESCE(group_concat(u.name, ', '), '') FROM conversation_members cm
+		        JOIN users u ON u.id = cm.user_id
+		        WHERE cm.conversation_id = c.id AND cm.left_at IS NULL) AS members
+		FROM conversations c
+		JOIN conversation_members m ON m.conversation_id = c.id
+		WHERE m.user_id = ? AND m.left_at IS NULL
+		ORDER BY c.updated_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conversations []models.Conversation
+	for rows.Next() {
+		var c models.Conversation
+		err := rows.Scan(&c.Id, &c.Uuid, &c.Subject, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt, &c.Muted, &c.Unread, &c.Members)
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, c)
+	}
+	return conversations, rows.Err()
+}
+
+// GetConversation returns a conversation with its active member names
+func (dm *DatabaseManager) GetConversation(conversationID int) (models.Conversation, error) {
+	var c models.Conversation
+	err := dm.db.QueryRow(`
+		SELECT c.id, c.uuid, c.subject, COALESCE(c.created_by, 0), c.created_at, c.updated_at,
+		       (SELECT COALESCE(group_concat(u.name, ', '), '') FROM conversation_members cm
+		        JOIN users u ON u.id = cm.user_id
+		        WHERE cm.conversation_id = c.id AND cm.left_at IS NULL)
+		FROM conversations c WHERE c.id = ?`, conversationID).
+		Scan(&c.Id, &c.Uuid, &c.Subject, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt, &c.Members)
+	return c, err
+}
+
+// IsConversationMember is true only for members who have not left
+func (dm *DatabaseManager) IsConversationMember(conversationID, userID int) bool {
+	var count int
+	err := dm.db.QueryRow("SELECT COUNT(*) FROM conversation_members WHERE conversation_id=? AND user_id=? AND left_at IS NULL",
+		conversationID, userID).Scan(&count)
+	return err == nil && count > 0
+}
+
+func (dm *DatabaseManager) IsConversationMuted(conversationID, userID int) bool {
+	var muted bool
+	err := dm.db.QueryRow("SELECT muted FROM conversation_members WHERE conversation_id=? AND user_id=?",
+		conversationID, userID).Scan(&muted)
+	return err == nil && muted
+}
+
+func (dm *DatabaseManager) GetConversationMessages(conversationID int) ([]models.Message, error) {
+	rows, err := dm.db.Query(`
+		SELECT m.id, m.conversation_id, m.user_id, u.name, m.body, m.created_at
+		FROM messages m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.conversation_id = ?
+		ORDER BY m.id`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanMessages(rows)
+}
+
+func scanMessages(rows *sql.Rows) ([]models.Message, error) {
+	var messages []models.Message
+	for rows.Next() {
+		var m models.Message
+		if err := rows.Scan(&m.Id, &m.ConversationId, &m.UserId, &m.Author, &m.Body, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+func (dm *DatabaseManager) CreateMessage(conversationID, userID int, body string) (int64, error) {
+	now := time.Now()
+	messageID, err := dm.db.Insert("INSERT INTO messages(conversation_id, user_id, body, created_at) VALUES(?, ?, ?, ?)",
+		conversationID, userID, body, now)
+	if err != nil {
+		return 0, err
+	}
+	_, err = dm.db.Exec("UPDATE conversations SET updated_at=? WHERE id=?", now, conversationID)
+	if err != nil {
+		return 0, err
+	}
+	return messageID, dm.MarkConversationRead(conversationID, userID, int(messageID))
+}
+
+func (dm *DatabaseManager) GetMessageByID(messageID int) (models.Message, error) {
+	var m models.Message
+	err := dm.db.QueryRow(`
+		SELECT m.id, m.conversation_id, m.user_id, u.name, m.body, m.created_at
+		FROM messages m JOIN users u ON u.id = m.user_id
+		WHERE m.id = ?`, messageID).
+		Scan(&m.Id, &m.ConversationId, &m.UserId, &m.Author, &m.Body, &m.CreatedAt)
+	return m, err
+}
+
+// MarkConversationRead moves the read marker forward, never backwards
+func (dm *DatabaseManager) MarkConversationRead(conversationID, userID, messageID int) error {
+	_, err := dm.db.Exec("UPDATE conversation_members SET last_read_id=? WHERE conversation_id=? AND user_id=? AND last_read_id < ?",
+		messageID, conversationID, userID, messageID)
+	return err
+}
+
+func (dm *DatabaseManager) SetConversationMuted(conversationID, userID int, muted bool) error {
+	flag := 0
+	if muted {
+		flag = 1
+	}
+	_, err := dm.db.Exec("UPDATE conversation_members SET muted=? WHERE conversation_id=? AND user_id=?",
+		flag, conversationID, userID)
+	return err
+}
+
+func (dm *DatabaseManager) LeaveConversation(conversationID, userID int) error {
+	_, err := dm.db.Exec("UPDATE conversation_members SET left_at=? WHERE conversation_id=? AND user_id=? AND left_at IS NULL",
+		time.Now(), conversationID, userID)
+	return err
+}
+
+// CountUnreadMessages counts unread messages over all conversations
+// that the user has neither left nor muted
+func (dm *DatabaseManager) CountUnreadMessages(userID int) (int, error) {
+	var count int
+	err := dm.db.QueryRow(`
+		SELECT COUNT(*) FROM messages x
+		JOIN conversation_members m ON m.conversation_id = x.conversation_id
+		WHERE m.user_id = ? AND m.left_at IS NULL AND m.muted = 0
+		  AND x.id > m.last_read_id AND x.user_id != m.user_id`, userID).Scan(&count)
+	return count, err
+}
+
+// Message report operations
+func (dm *DatabaseManager) CreateMessageReport(messageID, conversationID, reporterID int, reason string) error {
+	_, err := dm.db.Exec("INSERT INTO message_reports(message_id, conversation_id, reporter_id, reason, created_at) VALUES(?, ?, ?, ?, ?)",
+		messageID, conversationID, reporterID, reason, time.Now())
+	return err
+}
+
+func (dm *DatabaseManager) GetMessageReports(status string) ([]models.MessageReport, error) {
+	rows, err := dm.db.Query(`
+		SELECT r.id, r.message_id, r.conversation_id, r.reporter_id, u.name, r.reason, r.status, r.created_at
+		FROM message_reports r
+		JOIN users u ON u.id = r.reporter_id
+		WHERE r.status = ?
+		ORDER BY r.created_at`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []models.MessageReport
+	for rows.Next() {
+		var r models.MessageReport
+		err := rows.Scan(&r.Id, &r.MessageId, &r.ConversationId, &r.ReporterId, &r.Reporter, &r.Reason, &r.Status, &r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, rows.Err()
+}
+
+func (dm *DatabaseManager) GetMessageReport(reportID int) (models.MessageReport, error) {
+	var r models.MessageReport
+	err := dm.db.QueryRow(`
+		SELECT r.id, r.message_id, r.conversation_id, r.reporter_id, u.name, r.reason, r.status, r.created_at
+		FROM message_reports r
+		JOIN users u ON u.id = r.reporter_id
+		WHERE r.id = ?`, reportID).
+		Scan(&r.Id, &r.MessageId, &r.ConversationId, &r.ReporterId, &r.Reporter, &r.Reason, &r.Status, &r.CreatedAt)
+	return r, err
+}
+
+// GetMessagesBefore returns up to limit messages preceding the given one,
+// oldest first, used as context for reviewing a report
+func (dm *DatabaseManager) GetMessagesBefore(conversationID, messageID, limit int) ([]models.Message, error) {
+	rows, err := dm.db.Query(`
+		SELECT * FROM (
+			SELECT m.id, m.conversation_id, m.user_id, u.name, m.body, m.created_at
+			FROM messages m JOIN users u ON u.id = m.user_id
+			WHERE m.conversation_id = ? AND m.id < ?
+			ORDER BY m.id DESC LIMIT ?
+		) ORDER BY id`, conversationID, messageID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanMessages(rows)
+}
+
+// ResolveMessageReport closes a report, sql.ErrNoRows when there is none with that id
+func (dm *DatabaseManager) ResolveMessageReport(reportID, moderatorID int) error {
+	result, err := dm.db.Exec("UPDATE message_reports SET status='resolved', resolved_by=?, resolved_at=? WHERE id=?",
+		moderatorID, time.Now(), reportID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return err
+}
@@ -13,12 +13,8 @@ func (dm *DatabaseManager) GetTotalPostsCount() (int, error) {
 }
 
 func (dm *DatabaseManager) CreatePostByUser(body string, userID, threadID int) (int64, error) {
-	result, err := dm.db.Exec("INSERT INTO posts(uuid, body, user_id, thread_id, created_at) VALUES(?, ?, ?, ?, ?)",
+	return dm.db.Insert("INSERT INTO posts(uuid, body, user_id, thread_id, created_at) VALUES(?, ?, ?, ?, ?)",
 		utils.CreateUUID(), body, userID, threadID, time.Now())
-	if err != nil {
-		return 0, err
-	}
-	return result.LastInsertId()
 }
 
 // Additional methods needed by account routes
@@ -143,20 +139,9 @@ func (dm *DatabaseManager) GetThreadPosts(threadID int) ([]models.Post, error) {
 }
 
 func (dm *DatabaseManager) CreatePost(threadID int, body string, userID int) (int64, error) {
-	stmt, err := dm.db.Prepare("INSERT INTO posts(uuid, body, user_id, thread_id, created_at) VALUES(?, ?, ?, ?, ?)")
-	if err != nil {
-		return 0, err
-	}
-	defer stmt.Close()
-
 	uuid := utils.CreateUUID()
-	result, err := stmt.Exec(uuid, body, userID, threadID, time.Now())
-	if err != nil {
-		return 0, err
-	}
-
-	postID, err := result.LastInsertId()
-	return postID, err
+	return dm.db.Insert("INSERT INTO posts(uuid, body, user_id, thread_id, created_at) VALUES(?, ?, ?, ?, ?)",
+		uuid, body, userID, threadID, time.Now())
 }
 
 // Post like/dislike operations
@@ -372,13 +357,13 @@ func (dm *DatabaseManager) RemovePostDislike(userID int, postID int) error {
 
 // Add user's like to a post
 func (dm *DatabaseManager) AddPostLike(userID int, postID int) error {
-	_, err := dm.db.Exec("INSERT INTO likedposts (user_id, post_id) VALUES (?, ?)", userID, postID)
+	_, err := dm.db.Exec("INSERT INTO likedposts (type, user_id, post_id) VALUES (?, ?, ?)", "like", userID, postID)
 	return err
 }
 
 // Add user's dislike to a post
 func (dm *DatabaseManager) AddPostDislike(userID int, postID int) error {
-	_, err := dm.db.Exec("INSERT INTO dislikes (user_id, post_id) VALUES (?, ?)", userID, postID)
+	_, err := dm.db.Exec("INSERT INTO dislikes (type, user_id, post_id) VALUES (?, ?, ?)", "dislike", userID, postID)
 	return err
 }
 
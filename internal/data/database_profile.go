@@ -0,0 +1,78 @@
+package data
+
+import (
+	"forum/models"
+	"sort"
+)
+
+// Profile operations
+
+// GetProfileCounts returns the number of threads and posts a user wrote
+// and the likes received on them
+func (dm *DatabaseManager) GetProfileCounts(userID int) (threads, posts, likes int, err error) {
+	err = dm.db.QueryRow(`
+		SELECT
+		  (SELECT COUNT(*) FROM threads WHERE user_id = ?),
+		  (SELECT COUNT(*) FROM posts WHERE user_id = ?),
+		  (SELECT COUNT(*) FROM threadlikes l JOIN threads t ON t.id = l.thread_id
+		   WHERE t.user_id = ? AND l.user_id != t.user_id) +
+		  (SELECT COUNT(*) FROM likedposts l JOIN posts p ON p.id = l.post_id
+		   WHERE p.user_id = ? AND l.user_id != p.user_id)`,
+		userID, userID, userID, userID).Scan(&threads, &posts, &likes)
+	return
+}
+
+// GetRecentActivity returns the latest threads and replies of a user, newest first
+func (dm *DatabaseManager) GetRecentActivity(userID, limit int) ([]models.Activity, error) {
+	var activity []models.Activity
+
+	rows, err := dm.db.Query(`
+		SELECT id, topic, body, created_at FROM threads
+		WHERE user_id = ? ORDER BY created_at DESC LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a := models.Activity{Kind: "thread"}
+		if err := rows.Scan(&a.ThreadId, &a.Topic, &a.Excerpt, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		activity = append(activity, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	postRows, err := dm.db.Query(`
+		SELECT p.thread_id, t.topic, p.body, p.created_at
+		FROM posts p JOIN threads t ON t.id = p.thread_id
+		WHERE p.user_id = ? ORDER BY p.created_at DESC LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer postRows.Close()
+	for postRows.Next() {
+		a := models.Activity{Kind: "post"}
+		if err := postRows.Scan(&a.ThreadId, &a.Topic, &a.Excerpt, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		activity = append(activity, a)
+	}
+	if err := postRows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(activity, func(i, j int) bool {
+		return activity[i].CreatedAt.After(activity[j].CreatedAt)
+	})
+	if len(activity) > limit {
+		activity = activity[:limit]
+	}
+	return activity, nil
+}
+
+func (dm *DatabaseManager) UpdateProfile(userID int, bio, avatarURL string) error {
+	_, err := dm.db.Exec("UPDATE users SET bio=?, avatar_url=? WHERE id=?", bio, avatarURL, userID)
+	return err
+}
@@ -0,0 +1,115 @@
+package data
+
+import (
+	"forum/models"
+	"strings"
+	"time"
+)
+
+// Thread views and read markers. A marker is the last post a user has seen
+// in a thread, threads without one were never opened.
+
+// AddThreadViews adds the counted views to each thread in one transaction
+func (dm *DatabaseManager) AddThreadViews(views map[int]int) error {
+	tx, err := dm.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for threadID, count := range views {
+		if _, err := tx.Exec("UPDATE threads SET views = views + ? WHERE id = ?", count, threadID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (dm *DatabaseManager) GetThreadViews(threadID int) (int, error) {
+	var views int
+	err := dm.db.QueryRow("SELECT views FROM threads WHERE id = ?", threadID).Scan(&views)
+	return views, err
+}
+
+// MarkThreadRead moves the marker of a user forward to lastPostID, never back
+func (dm *DatabaseManager) MarkThreadRead(userID, threadID, lastPostID int) error {
+	_, err := dm.db.Exec(`
+		INSERT INTO thread_reads(user_id, thread_id, last_post_id, read_at) VALUES(?, ?, ?, ?)
+		ON CONFLICT(user_id, thread_id) DO UPDATE SET last_post_id = excluded.last_post_id, read_at = excluded.read_at
+		WHERE excluded.last_post_id > thread_reads.last_post_id`,
+		userID, threadID, lastPostID, time.Now())
+	return err
+}
+
+// MarkAllThreadsRead moves the markers of a user to the last post of every thread
+func (dm *DatabaseManager) MarkAllThreadsRead(userID int) error {
+	// WHERE true keeps SQLite from reading ON CONFLICT as a join constraint
+	_, err := dm.db.Exec(`
+		INSERT INTO thread_reads(user_id, thread_id, last_post_id, read_at)
+		SELECT ?, t.id, COALESCE((SELECT MAX(p.id) FROM posts p WHERE p.thread_id = t.id), 0), ?
+		FROM threads t WHERE true
+		ON CONFLICT(user_id, thread_id) DO UPDATE SET last_post_id = excluded.last_post_id, read_at = excluded.read_at
+		WHERE excluded.last_post_id > thread_reads.last_post_id`,
+		userID, time.Now())
+	return err
+}
+
+func (dm *DatabaseManager) GetLastReadPost(userID, threadID int) (int, error) {
+	var lastPostID int
+	err := dm.db.QueryRow("SELECT last_post_id FROM thread_reads WHERE user_id = ? AND thread_id = ?",
+		userID, threadID).Scan(&lastPostID)
+	return lastPostID, err
+}
+
+// GetThreadReadStates returns the views of the threads and, for a user other
+// than 0, the replies by others after their marker
+func (dm *DatabaseManager) GetThreadReadStates(userID int, threadIDs []int) (map[int]models.ReadState, error) {
+	states := map[int]models.ReadState{}
+	if len(threadIDs) == 0 {
+		return states, nil
+	}
+
+	args := make([]any, len(threadIDs))
+	for i, id := range threadIDs {
+		args[i] = id
+	}
+	in := "IN (?" + strings.Repeat(", ?", len(threadIDs)-1) + ")"
+
+	rows, err := dm.db.Query("SELECT id, views FROM threads WHERE id "+in, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var state models.ReadState
+		if err := rows.Scan(&id, &state.Views); err != nil {
+			return nil, err
+		}
+		states[id] = state
+	}
+	if err := rows.Err(); err != nil || userID == 0 {
+		return states, err
+	}
+
+	unread, err := dm.db.Query(`
+		SELECT r.thread_id, COUNT(p.id), MIN(p.id)
+		FROM thread_reads r
+		JOIN posts p ON p.thread_id = r.thread_id AND p.id > r.last_post_id AND p.user_id <> r.user_id
+		WHERE r.user_id = ? AND r.thread_id `+in+`
+		GROUP BY r.thread_id`, append([]any{userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer unread.Close()
+	for unread.Next() {
+		var id, count, first int
+		if err := unread.Scan(&id, &count, &first); err != nil {
+			return nil, err
+		}
+		state := states[id]
+		state.Unread, state.FirstUnread = count, first
+		states[id] = state
+	}
+	return states, unread.Err()
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"forum/models"
 	"forum/utils"
-	"log"
+	"log/slog"
 	"time"
 )
 
@@ -73,7 +73,7 @@ func (dm *DatabaseManager) ValidateSession(sessionUUID string) (models.Session,
 			_, err = dm.db.Exec("UPDATE sessions SET active_last = ? WHERE uuid = ?", currentTime, sessionUUID)
 			if err != nil {
 				// Don't fail validation if we can't update timestamp, just log it
-				log.Printf("Warning: Could not update active_last for session %s: %v\n", sessionUUID, err)
+				slog.Warn("cannot update session activity", "user_id", session.UserId, "error", err)
 			} else {
 				session.ActiveLast = currentTime
 			}
@@ -112,6 +112,14 @@ func (dm *DatabaseManager) DeleteAllSessions() error {
 	return err
 }
 
+// CountSessions returns the number of signed in sessions, expired ones are
+// left out the way the authentication middleware refuses them
+func (dm *DatabaseManager) CountSessions() (int, error) {
+	var count int
+	err := dm.db.QueryRow("SELECT COUNT(*) FROM sessions WHERE created_at >= ?", time.Now().Add(-utils.SessionLifetime())).Scan(&count)
+	return count, err
+}
+
 func (dm *DatabaseManager) GetSessionUser(userID int) (models.User, error) {
 	var user models.User
 	err := dm.db.QueryRow("SELECT id, uuid, name, email, created_at FROM users WHERE id=?", userID).
@@ -140,7 +148,7 @@ func (dm *DatabaseManager) GetUserDislikedThreads(userID int) ([]models.ThreadDi
 
 func (dm *DatabaseManager) GetUserDislikedPosts(userID int) ([]models.Dislikes, error) {
 	var dislikes []models.Dislikes
-	rows, err := dm.db.Query("SELECT type, user_id, post_id FROM dislikes WHERE user_id=?", userID)
+	rows, err := dm.db.Query("SELECT COALESCE(type, 'dislike') as type, user_id, post_id FROM dislikes WHERE user_id=?", userID)
 	if err != nil {
 		return dislikes, err
 	}
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"database/sql"
+	"forum/models"
+)
+
+// GetSitemapThreads returns every thread with the time of its latest post,
+// or of the thread itself when nobody replied yet
+func (dm *DatabaseManager) GetSitemapThreads(limit int) ([]models.SitemapEntry, error) {
+	rows, err := dm.db.Query(`
+		SELECT t.id, t.topic, t.created_at, p.created_at
+		FROM threads t
+		LEFT JOIN posts p ON p.id = (
+			SELECT id FROM posts WHERE thread_id = t.id ORDER BY created_at DESC LIMIT 1)
+		ORDER BY t.id DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.SitemapEntry
+	for rows.Next() {
+		var e models.SitemapEntry
+		var lastPost sql.NullTime
+		if err := rows.Scan(&e.ThreadId, &e.Topic, &e.LastModified, &lastPost); err != nil {
+			return nil, err
+		}
+		if lastPost.Valid && lastPost.Time.After(e.LastModified) {
+			e.LastModified = lastPost.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetActiveUserNames returns the names of the members with a public profile
+func (dm *DatabaseManager) GetActiveUserNames(limit int) ([]string, error) {
+	rows, err := dm.db.Query("SELECT name FROM users WHERE status = 'active' ORDER BY id LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,141 @@
+package data
+
+import (
+	"forum/models"
+	"time"
+)
+
+// Statistics operations for the admin dashboard. Every range is
+// created_at >= from and created_at < to.
+
+// DailyCounts returns one entry per day from the day of from up to the day
+// before to, days are in the location of from
+func (dm *DatabaseManager) DailyCounts(from, to time.Time) ([]models.DailyCount, error) {
+	var days []models.DailyCount
+	index := map[string]int{}
+	for day := dayOf(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		index[day.Format(time.DateOnly)] = len(days)
+		days = append(days, models.DailyCount{Day: day})
+	}
+
+	for _, table := range []string{"users", "threads", "posts"} {
+		rows, err := dm.db.Query("SELECT created_at FROM "+table+" WHERE created_at >= ? AND created_at < ?", from, to)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var created time.Time
+			if err := rows.Scan(&created); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			i, ok := index[created.In(from.Location()).Format(time.DateOnly)]
+			if !ok {
+				continue
+			}
+			switch table {
+			case "users":
+				days[i].Signups++
+			case "threads":
+				days[i].Threads++
+			case "posts":
+				days[i].Posts++
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return days, nil
+}
+
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// GetActiveUsersBetween ranks users by the threads and replies they wrote in the range
+func (dm *DatabaseManager) GetActiveUsersBetween(from, to time.Time, limit int) ([]models.UserActivity, error) {
+	rows, err := dm.db.Query(`
+		SELECT id, name, threads, posts FROM (
+		  SELECT u.id, u.name,
+		    (SELECT COUNT(*) FROM threads t WHERE t.user_id = u.id AND t.created_at >= ?1 AND t.created_at < ?2) AS threads,
+		    (SELECT COUNT(*) FROM posts p WHERE p.user_id = u.id AND p.created_at >= ?1 AND p.created_at < ?2) AS posts
+		  FROM users u
+		) AS activity
+		WHERE threads + posts > 0
+		ORDER BY threads + posts DESC, name
+		LIMIT ?3`, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.UserActivity
+	for rows.Next() {
+		var u models.UserActivity
+		if err := rows.Scan(&u.UserId, &u.Name, &u.Threads, &u.Posts); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// GetMostVotedThreads ranks the threads started in the range by likes and dislikes
+func (dm *DatabaseManager) GetMostVotedThreads(from, to time.Time, limit int) ([]models.ThreadVotes, error) {
+	rows, err := dm.db.Query(`
+		SELECT id, topic, likes, dislikes FROM (
+		  SELECT t.id, COALESCE(t.topic, '') AS topic,
+		    (SELECT COUNT(*) FROM threadlikes l WHERE l.thread_id = t.id) AS likes,
+		    (SELECT COUNT(*) FROM threaddislikes d WHERE d.thread_id = t.id) AS dislikes
+		  FROM threads t
+		  WHERE t.created_at >= ?1 AND t.created_at < ?2
+		) AS votes
+		WHERE likes + dislikes > 0
+		ORDER BY likes + dislikes DESC, id DESC
+		LIMIT ?3`, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var threads []models.ThreadVotes
+	for rows.Next() {
+		var t models.ThreadVotes
+		if err := rows.Scan(&t.ThreadId, &t.Topic, &t.Likes, &t.Dislikes); err != nil {
+			return nil, err
+		}
+		threads = append(threads, t)
+	}
+	return threads, rows.Err()
+}
+
+// GetCategoryDistribution counts the threads started in the range per category,
+// a thread with two categories counts in both
+func (dm *DatabaseManager) GetCategoryDistribution(from, to time.Time) ([]models.CategoryCount, error) {
+	rows, err := dm.db.Query(`
+		SELECT category, COUNT(*) FROM (
+		  SELECT category1 AS category FROM threads WHERE created_at >= ?1 AND created_at < ?2
+		  UNION ALL
+		  SELECT category2 AS category FROM threads WHERE created_at >= ?1 AND created_at < ?2
+		) AS categories
+		WHERE category <> ''
+		GROUP BY category
+		ORDER BY COUNT(*) DESC, category`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.CategoryCount
+	for rows.Next() {
+		var c models.CategoryCount
+		if err := rows.Scan(&c.Category, &c.Threads); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
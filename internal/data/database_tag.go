@@ -0,0 +1,256 @@
+package data
+
+import (
+	"database/sql"
+	"forum/models"
+	"strings"
+	"time"
+)
+
+// Tag operations, usage_count is the number of threads carrying a tag
+
+// recountTags refreshes usage_count of the given tags
+func recountTags(tx *dbTx, tagIDs []int) error {
+	for _, id := range tagIDs {
+		_, err := tx.Exec("UPDATE tags SET usage_count = (SELECT COUNT(*) FROM thread_tags WHERE tag_id = ?1) WHERE id = ?1", id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tagIDs(tx *dbTx, query string, args ...any) ([]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SetThreadTags replaces the tags of a thread, creating missing tags
+func (dm *DatabaseManager) SetThreadTags(threadID int, names []string) error {
+	tx, err := dm.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	touched, err := tagIDs(tx, "SELECT tag_id FROM thread_tags WHERE thread_id = ?", threadID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM thread_tags WHERE thread_id = ?", threadID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, name := range names {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tags(name, created_at) VALUES(?, ?)", name, now); err != nil {
+			return err
+		}
+		var id int
+		if err := tx.QueryRow("SELECT id FROM tags WHERE name = ?", name).Scan(&id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO thread_tags(thread_id, tag_id) VALUES(?, ?)", threadID, id); err != nil {
+			return err
+		}
+		touched = append(touched, id)
+	}
+	if err := recountTags(tx, touched); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (dm *DatabaseManager) GetThreadTags(threadID int) ([]string, error) {
+	tags, err := dm.GetTagsForThreads([]int{threadID})
+	return tags[threadID], err
+}
+
+// GetTagsForThreads returns the tag names of several threads keyed by thread id
+func (dm *DatabaseManager) GetTagsForThreads(threadIDs []int) (map[int][]string, error) {
+	tags := map[int][]string{}
+	if len(threadIDs) == 0 {
+		return tags, nil
+	}
+
+	args := make([]any, len(threadIDs))
+	for i, id := range threadIDs {
+		args[i] = id
+	}
+	rows, err := dm.db.Query(`
+		SELECT tt.thread_id, t.name
+		FROM thread_tags tt JOIN tags t ON t.id = tt.tag_id
+		WHERE tt.thread_id IN (?`+strings.Repeat(", ?", len(threadIDs)-1)+`)
+		ORDER BY t.name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var threadID int
+		var name string
+		if err := rows.Scan(&threadID, &name); err != nil {
+			return nil, err
+		}
+		tags[threadID] = append(tags[threadID], name)
+	}
+	return tags, rows.Err()
+}
+
+// GetTaggedThreadIDs returns the ids of the threads carrying a tag
+func (dm *DatabaseManager) GetTaggedThreadIDs(name string) (map[int]bool, error) {
+	rows, err := dm.db.Query(`
+		SELECT tt.thread_id FROM thread_tags tt JOIN tags t ON t.id = tt.tag_id
+		WHERE t.name = ?`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+func (dm *DatabaseManager) GetTag(name string) (models.Tag, error) {
+	var tag models.Tag
+	err := dm.db.QueryRow("SELECT id, name, usage_count FROM tags WHERE name = ?", name).
+		Scan(&tag.Id, &tag.Name, &tag.UsageCount)
+	return tag, err
+}
+
+// SearchTags returns used tags whose name or one of its synonyms
+// starts with prefix, most used first
+func (dm *DatabaseManager) SearchTags(prefix string, limit int) ([]models.Tag, error) {
+	rows, err := dm.db.Query(`
+		SELECT t.id, t.name, t.usage_count
+		FROM tags t
+		WHERE t.usage_count > 0
+		  AND (t.name LIKE ?1 || '%'
+		       OR EXISTS (SELECT 1 FROM tag_synonyms s WHERE s.tag_id = t.id AND s.alias LIKE ?1 || '%'))
+		ORDER BY t.usage_count DESC, t.name
+		LIMIT ?2`, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Id, &tag.Name, &tag.UsageCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// Tag synonym operations
+
+// GetTagSynonym returns the tag name an alias stands for
+func (dm *DatabaseManager) GetTagSynonym(alias string) (string, error) {
+	var name string
+	err := dm.db.QueryRow(`
+		SELECT t.name FROM tag_synonyms s JOIN tags t ON t.id = s.tag_id
+		WHERE s.alias = ?`, alias).Scan(&name)
+	return name, err
+}
+
+// AddTagSynonym makes alias stand for tag. Threads already tagged with
+// alias are moved to tag and the alias tag is removed.
+func (dm *DatabaseManager) AddTagSynonym(alias, tag string, userID int) error {
+	tx, err := dm.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.Exec("INSERT OR IGNORE INTO tags(name, created_at) VALUES(?, ?)", tag, now); err != nil {
+		return err
+	}
+	var tagID int
+	if err := tx.QueryRow("SELECT id FROM tags WHERE name = ?", tag).Scan(&tagID); err != nil {
+		return err
+	}
+
+	var aliasID int
+	err = tx.QueryRow("SELECT id FROM tags WHERE name = ?", alias).Scan(&aliasID)
+	if err == nil {
+		stmts := []string{
+			"INSERT OR IGNORE INTO thread_tags(thread_id, tag_id) SELECT thread_id, CAST(?1 AS integer) FROM thread_tags WHERE tag_id = ?2",
+			"DELETE FROM thread_tags WHERE tag_id = ?2",
+			"UPDATE tag_synonyms SET tag_id = ?1 WHERE tag_id = ?2",
+			"DELETE FROM tags WHERE id = ?2",
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt, tagID, aliasID); err != nil {
+				return err
+			}
+		}
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO tag_synonyms(alias, tag_id, created_by, created_at) VALUES(?, ?, ?, ?)
+		ON CONFLICT(alias) DO UPDATE SET tag_id = excluded.tag_id,
+		  created_by = excluded.created_by, created_at = excluded.created_at`,
+		alias, tagID, userID, now)
+	if err != nil {
+		return err
+	}
+	if err := recountTags(tx, []int{tagID}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (dm *DatabaseManager) DeleteTagSynonym(alias string) error {
+	_, err := dm.db.Exec("DELETE FROM tag_synonyms WHERE alias = ?", alias)
+	return err
+}
+
+func (dm *DatabaseManager) GetTagSynonyms() ([]models.TagSynonym, error) {
+	rows, err := dm.db.Query(`
+		SELECT s.alias, t.name, COALESCE(u.name, ''), s.created_at
+		FROM tag_synonyms s
+		JOIN tags t ON t.id = s.tag_id
+		LEFT JOIN users u ON u.id = s.created_by
+		ORDER BY t.name, s.alias`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var synonyms []models.TagSynonym
+	for rows.Next() {
+		var s models.TagSynonym
+		if err := rows.Scan(&s.Alias, &s.Tag, &s.CreatedBy, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		synonyms = append(synonyms, s)
+	}
+	return synonyms, rows.Err()
+}
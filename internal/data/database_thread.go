@@ -1,10 +1,9 @@
 package data
 
 import (
-	"fmt"
 	"forum/models"
 	"forum/utils"
-	"strings"
+	"log/slog"
 	"time"
 )
 
@@ -18,7 +17,7 @@ func (dm *DatabaseManager) GetTotalThreadsCount() (int, error) {
 func (dm *DatabaseManager) GetAllThreads() ([]models.Thread, error) {
 	var threads []models.Thread
 
-	rows, err := dm.db.Query("SELECT id, uuid, topic, body, user_id, created_at, category1, category2 FROM threads ORDER BY created_at DESC")
+	rows, err := dm.db.Query("SELECT id, uuid, topic, body, user_id, created_at, category1, category2, pinned, locked, archived FROM threads ORDER BY pinned DESC, created_at DESC")
 	if err != nil {
 		return threads, err
 	}
@@ -26,7 +25,8 @@ func (dm *DatabaseManager) GetAllThreads() ([]models.Thread, error) {
 
 	for rows.Next() {
 		var thread models.Thread
-		err = rows.Scan(&thread.Id, &thread.Uuid, &thread.Topic, &thread.Body, &thread.UserId, &thread.CreatedAt, &thread.Category1, &thread.Category2)
+		err = rows.Scan(&thread.Id, &thread.Uuid, &thread.Topic, &thread.Body, &thread.UserId, &thread.CreatedAt, &thread.Category1, &thread.Category2,
+			&thread.Pinned, &thread.Locked, &thread.Archived)
 		if err != nil {
 			continue
 		}
@@ -99,29 +99,49 @@ func (dm *DatabaseManager) GetAllThreadsByLikes() ([]models.Thread, error) {
 
 // Thread operations
 func (dm *DatabaseManager) CreateThread(topic, body string, userID, categoryID, subcategoryID int) (int64, error) {
-	stmt, err := dm.db.Prepare("INSERT INTO threads(uuid, topic, body, user_id, created_at, category1, category2) VALUES(?, ?, ?, ?, ?, ?, ?)")
-	if err != nil {
-		return 0, err
-	}
-	defer stmt.Close()
-
 	uuid := utils.CreateUUID()
-	result, err := stmt.Exec(uuid, topic, body, userID, time.Now(), categoryID, subcategoryID)
-	if err != nil {
-		return 0, err
-	}
-
-	threadID, err := result.LastInsertId()
-	return threadID, err
+	return dm.db.Insert("INSERT INTO threads(uuid, topic, body, user_id, created_at, category1, category2) VALUES(?, ?, ?, ?, ?, ?, ?)",
+		uuid, topic, body, userID, time.Now(), categoryID, subcategoryID)
 }
 
 func (dm *DatabaseManager) GetThreadByID(id int) (models.Thread, error) {
 	var thread models.Thread
-	err := dm.db.QueryRow("SELECT id, uuid, topic, body, user_id, created_at, category1, category2 FROM threads WHERE id = ?", id).Scan(
-		&thread.Id, &thread.Uuid, &thread.Topic, &thread.Body, &thread.UserId, &thread.CreatedAt, &thread.Category1, &thread.Category2)
+	err := dm.db.QueryRow("SELECT id, uuid, topic, body, user_id, created_at, category1, category2, pinned, locked, archived FROM threads WHERE id = ?", id).Scan(
+		&thread.Id, &thread.Uuid, &thread.Topic, &thread.Body, &thread.UserId, &thread.CreatedAt, &thread.Category1, &thread.Category2,
+		&thread.Pinned, &thread.Locked, &thread.Archived)
 	return thread, err
 }
 
+// Thread state operations, used by moderators
+func (dm *DatabaseManager) SetThreadPinned(threadID int, pinned bool) error {
+	_, err := dm.db.Exec("UPDATE threads SET pinned=? WHERE id=?", pinned, threadID)
+	return err
+}
+
+func (dm *DatabaseManager) SetThreadLocked(threadID int, locked bool) error {
+	_, err := dm.db.Exec("UPDATE threads SET locked=? WHERE id=?", locked, threadID)
+	return err
+}
+
+func (dm *DatabaseManager) SetThreadArchived(threadID int, archived bool) error {
+	_, err := dm.db.Exec("UPDATE threads SET archived=? WHERE id=?", archived, threadID)
+	return err
+}
+
+// ArchiveInactiveThreads archives threads without a new post since before,
+// pinned threads are never archived automatically
+func (dm *DatabaseManager) ArchiveInactiveThreads(before time.Time) (int64, error) {
+	result, err := dm.db.Exec(`
+		UPDATE threads SET archived = TRUE
+		WHERE NOT archived AND NOT pinned
+		  AND COALESCE((SELECT MAX(p.created_at) FROM posts p WHERE p.thread_id = threads.id), threads.created_at) < ?`,
+		before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func (dm *DatabaseManager) GetThreadWithPosts(id int) (models.Thread, error) {
 	// First get the thread
 	thread, err := dm.GetThreadByID(id)
@@ -147,15 +167,6 @@ func (dm *DatabaseManager) GetThreadWithPosts(id int) (models.Thread, error) {
 		thread.CreatedAtDate = thread.CreatedAt.Format("Jan 2, 2006 at 15:04")
 	}
 
-	// Post-process thread.Body and thread.Topic for HTML display
-	// Replace \n with <br> for line breaks, \t with 4 non-breaking spaces for indentation
-
-	thread.Body = strings.ReplaceAll(thread.Body, "\n", "<br>")
-	thread.Body = strings.ReplaceAll(thread.Body, "    ", "&nbsp;&nbsp;&nbsp;&nbsp;")
-	// Optional: Do the same for Topic if it contains newlines/tabs
-	// thread.Topic = strings.ReplaceAll(thread.Topic, "\n", "<br>")
-	// thread.Topic = strings.ReplaceAll(thread.Topic, "\t", "&nbsp;&nbsp;&nbsp;&nbsp;")
-
 	thread.Cards = posts
 	return thread, nil
 }
@@ -413,12 +424,8 @@ func (dm *DatabaseManager) GetThreadPostCount(threadID int) (int, error) {
 
 // User management methods needed by user.go
 func (dm *DatabaseManager) CreateThreadByUser(topic, body string, userID int, category1, category2 string) (int64, error) {
-	result, err := dm.db.Exec("INSERT INTO threads(uuid, topic, body, user_id, created_at, category1, category2) VALUES(?, ?, ?, ?, ?, ?, ?)",
+	return dm.db.Insert("INSERT INTO threads(uuid, topic, body, user_id, created_at, category1, category2) VALUES(?, ?, ?, ?, ?, ?, ?)",
 		utils.CreateUUID(), topic, body, userID, time.Now(), category1, category2)
-	if err != nil {
-		return 0, err
-	}
-	return result.LastInsertId()
 }
 
 func (dm *DatabaseManager) GetUserCreatedThreads(userID int) ([]models.Thread, error) {
@@ -433,7 +440,7 @@ func (dm *DatabaseManager) GetUserCreatedThreads(userID int) ([]models.Thread, e
 		var thread models.Thread
 		err = rows.Scan(&thread.Id, &thread.Uuid, &thread.Topic, &thread.Body, &thread.UserId, &thread.CreatedAt, &thread.Category1, &thread.Category2)
 		if err != nil {
-			fmt.Println("Error scanning thread for Account:", err)
+			slog.Error("error scanning thread for Account", "error", err)
 			continue
 		}
 
@@ -467,13 +474,13 @@ func (dm *DatabaseManager) GetThreadsByCategories(category1, category2 string) (
 	var args []interface{}
 
 	if category1 != "" && category2 != "" {
-		query = "SELECT id, uuid, topic, body, user_id, created_at, category1, category2 FROM threads WHERE category1=? OR category2=? ORDER BY created_at DESC"
+		query = "SELECT id, uuid, topic, body, user_id, created_at, category1, category2, pinned, locked, archived FROM threads WHERE category1=? OR category2=? ORDER BY pinned DESC, created_at DESC"
 		args = []interface{}{category1, category2}
 	} else if category1 != "" {
-		query = "SELECT id, uuid, topic, body, user_id, created_at, category1, category2 FROM threads WHERE category1=? ORDER BY created_at DESC"
+		query = "SELECT id, uuid, topic, body, user_id, created_at, category1, category2, pinned, locked, archived FROM threads WHERE category1=? ORDER BY pinned DESC, created_at DESC"
 		args = []interface{}{category1}
 	} else if category2 != "" {
-		query = "SELECT id, uuid, topic, body, user_id, created_at, category1, category2 FROM threads WHERE category2=? ORDER BY created_at DESC"
+		query = "SELECT id, uuid, topic, body, user_id, created_at, category1, category2, pinned, locked, archived FROM threads WHERE category2=? ORDER BY pinned DESC, created_at DESC"
 		args = []interface{}{category2}
 	} else {
 		// Return all threads if no categories specified
@@ -488,7 +495,8 @@ func (dm *DatabaseManager) GetThreadsByCategories(category1, category2 string) (
 
 	for rows.Next() {
 		var thread models.Thread
-		err = rows.Scan(&thread.Id, &thread.Uuid, &thread.Topic, &thread.Body, &thread.UserId, &thread.CreatedAt, &thread.Category1, &thread.Category2)
+		err = rows.Scan(&thread.Id, &thread.Uuid, &thread.Topic, &thread.Body, &thread.UserId, &thread.CreatedAt, &thread.Category1, &thread.Category2,
+			&thread.Pinned, &thread.Locked, &thread.Archived)
 		if err != nil {
 			continue
 		}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"forum/models"
 	"forum/utils"
+	"log/slog"
 	"time"
 )
 
@@ -12,19 +13,9 @@ func (dm *DatabaseManager) CreateUser(user *models.User) error {
 	user.Uuid = utils.CreateUUID()
 	user.CreatedAt = time.Now()
 
-	stmt, err := dm.db.Prepare(
-		"INSERT INTO users(uuid, name, email, password, created_at) VALUES(?, ?, ?, ?, ?)")
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-
-	result, err := stmt.Exec(user.Uuid, user.Name, user.Email, utils.Encrypt(user.Password), user.CreatedAt)
-	if err != nil {
-		return err
-	}
-
-	id, err := result.LastInsertId()
+	id, err := dm.db.Insert(
+		"INSERT INTO users(uuid, name, email, password, created_at) VALUES(?, ?, ?, ?, ?)",
+		user.Uuid, user.Name, user.Email, utils.Encrypt(user.Password), user.CreatedAt)
 	if err != nil {
 		return err
 	}
@@ -37,9 +28,7 @@ func (dm *DatabaseManager) DeleteUserById(user *models.User) error {
 	if user.Id == 0 {
 		return fmt.Errorf("invalid user ID")
 	}
-
-	_, err := dm.db.Exec("DELETE FROM users WHERE id=?", user.Id)
-	return err
+	return dm.DeleteUserByID(user.Id)
 }
 
 func (dm *DatabaseManager) CheckUserExists(email, name string) (bool, error) {
@@ -49,15 +38,15 @@ func (dm *DatabaseManager) CheckUserExists(email, name string) (bool, error) {
 }
 
 func (dm *DatabaseManager) GetUserByEmailDetailed(email string) (user models.User, err error) {
-	err = dm.db.QueryRow("SELECT id, uuid, name, email, password, created_at FROM users WHERE email=?", email).
-		Scan(&user.Id, &user.Uuid, &user.Name, &user.Email, &user.Password, &user.CreatedAt)
+	err = dm.db.QueryRow("SELECT id, uuid, name, email, password, created_at, status FROM users WHERE email=?", email).
+		Scan(&user.Id, &user.Uuid, &user.Name, &user.Email, &user.Password, &user.CreatedAt, &user.Status)
 	return user, err
 }
 
 func (dm *DatabaseManager) GetUserByID(id int) (user models.User, err error) {
 
-	err = dm.db.QueryRow("SELECT id, uuid, name, email, password, created_at, prefered_category1, prefered_category2 FROM users WHERE id=?", id).
-		Scan(&user.Id, &user.Uuid, &user.Name, &user.Email, &user.Password, &user.CreatedAt, &user.PreferedCategory1, &user.PreferedCategory2)
+	err = dm.db.QueryRow("SELECT id, uuid, name, email, password, created_at, prefered_category1, prefered_category2, status, role, bio, avatar_url FROM users WHERE id=?", id).
+		Scan(&user.Id, &user.Uuid, &user.Name, &user.Email, &user.Password, &user.CreatedAt, &user.PreferedCategory1, &user.PreferedCategory2, &user.Status, &user.Role, &user.Bio, &user.AvatarURL)
 	return user, err
 }
 
@@ -152,8 +141,19 @@ func (dm *DatabaseManager) GetUserByUUID(uuid string) (models.User, error) {
 	return user, err
 }
 
+// DeleteUserByID removes a user with everything they wrote and every row
+// pointing at them, in one transaction
 func (dm *DatabaseManager) DeleteUserByID(userID int) error {
-	result, err := dm.db.Exec("DELETE FROM users WHERE id=?", userID)
+	tx, err := dm.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := purgeUser(tx, userID, personalRows, contentRows(authoredThreads), sharedRows); err != nil {
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM users WHERE id=?", userID)
 	if err != nil {
 		return err
 	}
@@ -166,27 +166,20 @@ func (dm *DatabaseManager) DeleteUserByID(userID int) error {
 	if rowsAffected == 0 {
 		return fmt.Errorf("no user found with id %d", userID)
 	}
-	return nil
+	return tx.Commit()
 }
 
 func (dm *DatabaseManager) DeleteUserByName(name string) error {
-	result, err := dm.DoExec("DELETE FROM users WHERE name=?", name)
-	if err != nil {
-		return err
-	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rowsAffected == 0 {
+	var id int
+	if err := dm.db.QueryRow("SELECT id FROM users WHERE name=?", name).Scan(&id); err != nil {
 		return fmt.Errorf("no user found with name %s", name)
 	}
-	return nil
+	return dm.DeleteUserByID(id)
 }
 
 func (dm *DatabaseManager) GetUserByName(name string) (user models.User, err error) {
-	err = dm.db.QueryRow("SELECT id, uuid, name, email, password, created_at, prefered_category1, prefered_category2 FROM users WHERE name=?", name).
-		Scan(&user.Id, &user.Uuid, &user.Name, &user.Email, &user.Password, &user.CreatedAt, &user.PreferedCategory1, &user.PreferedCategory2)
+	err = dm.db.QueryRow("SELECT id, uuid, name, email, password, created_at, prefered_category1, prefered_category2, status, role, bio, avatar_url FROM users WHERE name=?", name).
+		Scan(&user.Id, &user.Uuid, &user.Name, &user.Email, &user.Password, &user.CreatedAt, &user.PreferedCategory1, &user.PreferedCategory2, &user.Status, &user.Role, &user.Bio, &user.AvatarURL)
 	return user, err
 }
 
@@ -226,7 +219,7 @@ func (dm *DatabaseManager) GetUserLikedPosts(userID int) ([]models.Likes, error)
 		var like models.Likes
 		err = rows.Scan(&like.Type, &like.UserId, &like.PostId)
 		if err != nil {
-			fmt.Println("Error scanning like:", err)
+			slog.Error("error scanning like", "error", err)
 			continue
 		}
 		likes = append(likes, like)
@@ -252,3 +245,32 @@ func (dm *DatabaseManager) GetUserLikedThreads(userID int) ([]models.ThreadLikes
 	}
 	return likes, nil
 }
+
+// LookupUsersByPrefix returns active users whose name starts with prefix,
+// leaving out anyone who blocked the viewer or was blocked by them
+func (dm *DatabaseManager) LookupUsersByPrefix(prefix string, viewerID, limit int) ([]models.UserLookup, error) {
+	rows, err := dm.db.Query(`
+		SELECT u.id, u.name
+		FROM users u
+		WHERE u.name LIKE ? || '%' AND u.status = 'active' AND u.id != ?
+		  AND NOT EXISTS (
+		      SELECT 1 FROM user_blocks b
+		      WHERE (b.blocker_id = u.id AND b.blocked_id = ?)
+		         OR (b.blocker_id = ? AND b.blocked_id = u.id))
+		ORDER BY u.name
+		LIMIT ?`, prefix, viewerID, viewerID, viewerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.UserLookup{}
+	for rows.Next() {
+		var user models.UserLookup
+		if err := rows.Scan(&user.Id, &user.Name); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
@@ -0,0 +1,129 @@
+package data
+
+import (
+	"database/sql"
+	"forum/models"
+	"time"
+)
+
+// Thread watch operations
+func (dm *DatabaseManager) WatchThread(userID, threadID int) error {
+	_, err := dm.db.Exec("INSERT OR IGNORE INTO thread_watches(user_id, thread_id, created_at) VALUES(?, ?, ?)",
+		userID, threadID, time.Now())
+	return err
+}
+
+func (dm *DatabaseManager) UnwatchThread(userID, threadID int) error {
+	_, err := dm.db.Exec("DELETE FROM thread_watches WHERE user_id=? AND thread_id=?", userID, threadID)
+	return err
+}
+
+func (dm *DatabaseManager) IsWatchingThread(userID, threadID int) bool {
+	var count int
+	err := dm.db.QueryRow("SELECT COUNT(*) FROM thread_watches WHERE user_id=? AND thread_id=?", userID, threadID).Scan(&count)
+	return err == nil && count > 0
+}
+
+// GetWatchedThreads returns the threads a user is watching, newest watch first
+func (dm *DatabaseManager) GetWatchedThreads(userID int) ([]models.ThreadWatch, error) {
+	rows, err := dm.db.Query(`
+		SELECT w.user_id, w.thread_id, t.topic, w.created_at
+		FROM thread_watches w
+		JOIN threads t ON t.id = w.thread_id
+		WHERE w.user_id = ?
+		ORDER BY w.created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var watches []models.ThreadWatch
+	for rows.Next() {
+		var watch models.ThreadWatch
+		if err := rows.Scan(&watch.UserId, &watch.ThreadId, &watch.Topic, &watch.CreatedAt); err != nil {
+			return nil, err
+		}
+		watches = append(watches, watch)
+	}
+	return watches, rows.Err()
+}
+
+// Digest preference operations
+func (dm *DatabaseManager) GetDigestFrequency(userID int) (string, error) {
+	var frequency string
+	err := dm.db.QueryRow("SELECT frequency FROM digest_preferences WHERE user_id=?", userID).Scan(&frequency)
+	if err == sql.ErrNoRows {
+		return "daily", nil
+	}
+	return frequency, err
+}
+
+func (dm *DatabaseManager) SetDigestFrequency(userID int, frequency string) error {
+	_, err := dm.db.Exec(`
+		INSERT INTO digest_preferences(user_id, frequency) VALUES(?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET frequency = excluded.frequency`, userID, frequency)
+	return err
+}
+
+func (dm *DatabaseManager) MarkDigestSent(userID int, sentAt time.Time) error {
+	_, err := dm.db.Exec(`
+		INSERT INTO digest_preferences(user_id, last_sent_at) VALUES(?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET last_sent_at = excluded.last_sent_at`, userID, sentAt)
+	return err
+}
+
+// GetDigestCandidates returns every active user watching at least one
+// thread together with their digest preference (daily when never set)
+func (dm *DatabaseManager) GetDigestCandidates() ([]models.DigestPreference, error) {
+	rows, err := dm.db.Query(`
+		SELECT u.id, u.name, u.email, COALESCE(d.frequency, 'daily'), d.last_sent_at
+		FROM users u
+		LEFT JOIN digest_preferences d ON d.user_id = u.id
+		WHERE u.status = 'active'
+		  AND EXISTS (SELECT 1 FROM thread_watches w WHERE w.user_id = u.id)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prefs []models.DigestPreference
+	for rows.Next() {
+		var pref models.DigestPreference
+		var lastSent sql.NullTime
+		if err := rows.Scan(&pref.UserId, &pref.Name, &pref.Email, &pref.Frequency, &lastSent); err != nil {
+			return nil, err
+		}
+		if lastSent.Valid {
+			pref.LastSentAt = lastSent.Time
+		}
+		prefs = append(prefs, pref)
+	}
+	return prefs, rows.Err()
+}
+
+// GetDigestEntries returns posts written by other users in watched threads
+// after the given time, grouped by thread
+func (dm *DatabaseManager) GetDigestEntries(userID int, since time.Time) ([]models.DigestEntry, error) {
+	rows, err := dm.db.Query(`
+		SELECT p.id, p.thread_id, t.topic, u.name, p.body, p.created_at
+		FROM thread_watches w
+		JOIN posts p ON p.thread_id = w.thread_id
+		JOIN threads t ON t.id = p.thread_id
+		JOIN users u ON u.id = p.user_id
+		WHERE w.user_id = ? AND p.user_id != ? AND p.created_at > ? AND p.created_at > w.created_at
+		ORDER BY p.thread_id, p.created_at`, userID, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.DigestEntry
+	for rows.Next() {
+		var entry models.DigestEntry
+		if err := rows.Scan(&entry.PostId, &entry.ThreadId, &entry.Topic, &entry.Author, &entry.Body, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
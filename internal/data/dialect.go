@@ -0,0 +1,216 @@
+package data
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+
+	"forum/internal/metrics"
+)
+
+// Dialect is the SQL flavour of the database behind a DatabaseManager.
+// Queries are written for SQLite, Rebind and Schema rewrite them for Postgres.
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite3"
+	Postgres Dialect = "postgres"
+)
+
+// DialectOf tells the dialect from a database DSN: postgres:// and
+// postgresql:// URLs are Postgres, anything else is an SQLite file
+func DialectOf(dsn string) Dialect {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return Postgres
+	}
+	return SQLite
+}
+
+// Rebind rewrites an SQLite query for the dialect: ? and ?N placeholders
+// become $N, INSERT OR IGNORE becomes ON CONFLICT DO NOTHING, LIKE becomes
+// ILIKE (SQLite's LIKE ignores case) and group_concat becomes string_agg.
+// Placeholders inside quoted strings are left alone.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	next := 1
+	inString := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			inString = !inString
+			b.WriteByte(c)
+		case c == '?' && !inString:
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			b.WriteByte('$')
+			if j > i+1 {
+				b.WriteString(query[i+1 : j])
+			} else {
+				b.WriteString(itoa(next))
+				next++
+			}
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	rebound := b.String()
+	if loc := insertOrIgnore.FindStringIndex(rebound); loc != nil {
+		rebound = rebound[:loc[0]] + "INSERT INTO" + rebound[loc[1]:]
+		rebound = strings.TrimRight(rebound, "; \t\n") + " ON CONFLICT DO NOTHING"
+	}
+	rebound = likeOperator.ReplaceAllString(rebound, "ILIKE")
+	rebound = groupConcat.ReplaceAllString(rebound, "string_agg(")
+	return rebound
+}
+
+func itoa(n int) string {
+	if n < 10 {
+		return string(rune('0' + n))
+	}
+	return itoa(n/10) + string(rune('0'+n%10))
+}
+
+var (
+	insertOrIgnore = regexp.MustCompile(`(?i)INSERT\s+OR\s+IGNORE\s+INTO`)
+	likeOperator   = regexp.MustCompile(`(?i)\bLIKE\b`)
+	groupConcat    = regexp.MustCompile(`(?i)\bgroup_concat\(`)
+	autoincrement  = regexp.MustCompile(`(?i)INTEGER\s+PRIMARY\s+KEY\s+AUTOINCREMENT`)
+	timestampType  = regexp.MustCompile(`(?i)\btimestamp\b`)
+	booleanZero    = regexp.MustCompile(`(?i)\bboolean(\s+not\s+null)?\s+default\s+0\b`)
+	foreignKey     = regexp.MustCompile(`(?i)\s+references\s+\w+\s*\(\w+\)`)
+	dropTable      = regexp.MustCompile(`(?i)^(\s*DROP\s+TABLE\s+IF\s+EXISTS\s+\w+)\s*;?\s*$`)
+)
+
+// Schema rewrites an SQLite table definition (or column definition) for the
+// dialect. Foreign keys are dropped: SQLite does not enforce them either, so
+// both databases behave the same when rows are deleted.
+func (d Dialect) Schema(stmt string) string {
+	if d != Postgres {
+		return stmt
+	}
+	stmt = autoincrement.ReplaceAllString(stmt, "SERIAL PRIMARY KEY")
+	stmt = timestampType.ReplaceAllString(stmt, "timestamptz")
+	stmt = booleanZero.ReplaceAllString(stmt, "boolean${1} default false")
+	stmt = foreignKey.ReplaceAllString(stmt, "")
+	stmt = dropTable.ReplaceAllString(stmt, "${1} CASCADE;")
+	return stmt
+}
+
+// observe counts a statement and its duration for /metrics
+func observe(op string, start time.Time) {
+	metrics.DBQueries.Inc(op)
+	metrics.DBDuration.Observe(time.Since(start).Seconds(), op)
+}
+
+// dbConn is the database handle of a DatabaseManager,
+// it rebinds every query for the dialect and measures it
+type dbConn struct {
+	*sql.DB
+	dialect Dialect
+}
+
+func (c *dbConn) Exec(query string, args ...any) (sql.Result, error) {
+	defer observe("exec", time.Now())
+	return c.DB.Exec(c.dialect.Rebind(query), args...)
+}
+
+func (c *dbConn) Query(query string, args ...any) (*sql.Rows, error) {
+	defer observe("query", time.Now())
+	return c.DB.Query(c.dialect.Rebind(query), args...)
+}
+
+func (c *dbConn) QueryRow(query string, args ...any) *sql.Row {
+	defer observe("query", time.Now())
+	return c.DB.QueryRow(c.dialect.Rebind(query), args...)
+}
+
+func (c *dbConn) Prepare(query string) (*dbStmt, error) {
+	stmt, err := c.DB.Prepare(c.dialect.Rebind(query))
+	if err != nil {
+		return nil, err
+	}
+	return &dbStmt{stmt}, nil
+}
+
+func (c *dbConn) Begin() (*dbTx, error) {
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &dbTx{Tx: tx, dialect: c.dialect}, nil
+}
+
+// Insert runs an INSERT and returns the id of the new row. Postgres has no
+// LastInsertId, the id comes back with RETURNING id instead.
+func (c *dbConn) Insert(query string, args ...any) (int64, error) {
+	return insertID(c.dialect, c.Exec, c.QueryRow, query, args...)
+}
+
+// dbStmt is a prepared statement measured like the queries of dbConn
+type dbStmt struct {
+	*sql.Stmt
+}
+
+func (s *dbStmt) Exec(args ...any) (sql.Result, error) {
+	defer observe("exec", time.Now())
+	return s.Stmt.Exec(args...)
+}
+
+func (s *dbStmt) Query(args ...any) (*sql.Rows, error) {
+	defer observe("query", time.Now())
+	return s.Stmt.Query(args...)
+}
+
+func (s *dbStmt) QueryRow(args ...any) *sql.Row {
+	defer observe("query", time.Now())
+	return s.Stmt.QueryRow(args...)
+}
+
+// dbTx is a transaction that rebinds every query for the dialect
+type dbTx struct {
+	*sql.Tx
+	dialect Dialect
+}
+
+func (t *dbTx) Exec(query string, args ...any) (sql.Result, error) {
+	defer observe("exec", time.Now())
+	return t.Tx.Exec(t.dialect.Rebind(query), args...)
+}
+
+func (t *dbTx) Query(query string, args ...any) (*sql.Rows, error) {
+	defer observe("query", time.Now())
+	return t.Tx.Query(t.dialect.Rebind(query), args...)
+}
+
+func (t *dbTx) QueryRow(query string, args ...any) *sql.Row {
+	defer observe("query", time.Now())
+	return t.Tx.QueryRow(t.dialect.Rebind(query), args...)
+}
+
+func (t *dbTx) Insert(query string, args ...any) (int64, error) {
+	return insertID(t.dialect, t.Exec, t.QueryRow, query, args...)
+}
+
+func insertID(d Dialect, exec func(string, ...any) (sql.Result, error), queryRow func(string, ...any) *sql.Row, query string, args ...any) (int64, error) {
+	if d == Postgres {
+		var id int64
+		err := queryRow(strings.TrimRight(query, "; \t\n")+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	result, err := exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
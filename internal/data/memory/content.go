@@ -0,0 +1,416 @@
+package memory
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"forum/models"
+	"forum/utils"
+)
+
+// Thread operations
+
+func (s *Store) CreateThreadByUser(topic, body string, userID int, category1, category2 string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	thread := models.Thread{
+		Id:        s.nextID(),
+		Uuid:      utils.CreateUUID(),
+		Topic:     topic,
+		Body:      body,
+		UserId:    userID,
+		CreatedAt: time.Now(),
+		Category1: category1,
+		Category2: category2,
+	}
+	s.threads = append(s.threads, thread)
+	return int64(thread.Id), nil
+}
+
+func (s *Store) findThread(id int) (int, bool) {
+	for i, t := range s.threads {
+		if t.Id == id {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (s *Store) GetThreadByID(id int) (models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.findThread(id); ok {
+		return s.threads[i], nil
+	}
+	return models.Thread{}, sql.ErrNoRows
+}
+
+func (s *Store) GetThreadWithPosts(id int) (models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.findThread(id)
+	if !ok {
+		return models.Thread{}, sql.ErrNoRows
+	}
+	thread := s.threads[i]
+	thread.User = "Unknown User"
+	if user, err := s.findUser(func(u models.User) bool { return u.Id == thread.UserId }); err == nil {
+		thread.User = user.Name
+		thread.Email = user.Email
+	}
+	thread.CreatedAtDate = thread.CreatedAt.Format("Jan 2, 2006 at 15:04")
+	thread.Cards = s.threadPosts(id)
+	return thread, nil
+}
+
+// listed fills the fields shown in thread lists
+func (s *Store) listed(thread models.Thread) models.Thread {
+	if user, err := s.findUser(func(u models.User) bool { return u.Id == thread.UserId }); err == nil {
+		thread.User = user.Name
+	}
+	thread.CreatedAtDate = thread.CreatedAt.Format("Jan 2, 2006 at 15:04")
+	thread.NumReplies = len(s.threadPosts(thread.Id))
+	thread.LikesCount = s.countVotes("threadlike", thread.Id)
+	thread.DislikesCount = s.countVotes("threaddislike", thread.Id)
+	thread.Len = len(thread.Topic)
+	return thread
+}
+
+// newestFirst lists pinned threads first, then the newest
+func newestFirst(threads []models.Thread) {
+	sort.SliceStable(threads, func(i, j int) bool {
+		if threads[i].Pinned != threads[j].Pinned {
+			return threads[i].Pinned
+		}
+		if !threads[i].CreatedAt.Equal(threads[j].CreatedAt) {
+			return threads[i].CreatedAt.After(threads[j].CreatedAt)
+		}
+		return threads[i].Id > threads[j].Id
+	})
+}
+
+func (s *Store) GetAllThreads() ([]models.Thread, error) {
+	return s.GetThreadsByCategories("", "")
+}
+
+func (s *Store) GetThreadsByCategories(category1, category2 string) ([]models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var threads []models.Thread
+	for _, t := range s.threads {
+		match := category1 == "" && category2 == ""
+		if category1 != "" && t.Category1 == category1 {
+			match = true
+		}
+		if category2 != "" && t.Category2 == category2 {
+			match = true
+		}
+		if match {
+			threads = append(threads, s.listed(t))
+		}
+	}
+	newestFirst(threads)
+	return threads, nil
+}
+
+func (s *Store) GetUserCreatedThreads(userID int) ([]models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var threads []models.Thread
+	for _, t := range s.threads {
+		if t.UserId != userID {
+			continue
+		}
+		user, _ := s.findUser(func(u models.User) bool { return u.Id == t.UserId })
+		t.User = user.Name
+		t.Email = user.Email
+		t.CreatedAtDate = t.CreatedAt.Format("Jan 2, 2006 at 15:04")
+		t.Cards = s.threadPosts(t.Id)
+		t.NumReplies = len(t.Cards)
+		t.LengthOfPosts = len(t.Cards)
+		threads = append(threads, t)
+	}
+	sort.SliceStable(threads, func(i, j int) bool { return threads[i].Id > threads[j].Id })
+	return threads, nil
+}
+
+func (s *Store) GetTotalThreadsCount() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.threads), nil
+}
+
+func (s *Store) setThread(threadID int, change func(*models.Thread)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.findThread(threadID); ok {
+		change(&s.threads[i])
+	}
+	return nil
+}
+
+func (s *Store) SetThreadPinned(threadID int, pinned bool) error {
+	return s.setThread(threadID, func(t *models.Thread) { t.Pinned = pinned })
+}
+
+func (s *Store) SetThreadLocked(threadID int, locked bool) error {
+	return s.setThread(threadID, func(t *models.Thread) { t.Locked = locked })
+}
+
+func (s *Store) SetThreadArchived(threadID int, archived bool) error {
+	return s.setThread(threadID, func(t *models.Thread) { t.Archived = archived })
+}
+
+func (s *Store) ArchiveInactiveThreads(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var archived int64
+	for i, t := range s.threads {
+		if t.Archived || t.Pinned {
+			continue
+		}
+		last := t.CreatedAt
+		for _, p := range s.posts {
+			if p.ThreadId == t.Id && p.CreatedAt.After(last) {
+				last = p.CreatedAt
+			}
+		}
+		if last.Before(before) {
+			s.threads[i].Archived = true
+			archived++
+		}
+	}
+	return archived, nil
+}
+
+// Post operations
+
+func (s *Store) CreatePostByUser(body string, userID, threadID int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	post := models.Post{
+		Id:        s.nextID(),
+		Uuid:      utils.CreateUUID(),
+		Body:      body,
+		UserId:    userID,
+		ThreadId:  threadID,
+		CreatedAt: time.Now(),
+	}
+	s.posts = append(s.posts, post)
+	return int64(post.Id), nil
+}
+
+func (s *Store) GetPostByID(id int) (models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.posts {
+		if p.Id == id {
+			return p, nil
+		}
+	}
+	return models.Post{}, sql.ErrNoRows
+}
+
+// withAuthor fills in the name of the author of a post
+func (s *Store) withAuthor(post models.Post) models.Post {
+	post.User = "Unknown User"
+	if user, err := s.findUser(func(u models.User) bool { return u.Id == post.UserId }); err == nil {
+		post.User = user.Name
+	}
+	return post
+}
+
+func (s *Store) threadPosts(threadID int) []models.Post {
+	var posts []models.Post
+	for _, p := range s.posts {
+		if p.ThreadId == threadID {
+			posts = append(posts, s.withAuthor(p))
+		}
+	}
+	return posts
+}
+
+func (s *Store) GetThreadPosts(threadID int) ([]models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.threadPosts(threadID), nil
+}
+
+func (s *Store) GetUserCreatedPosts(userID int) ([]models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var posts []models.Post
+	for i := len(s.posts) - 1; i >= 0; i-- {
+		if s.posts[i].UserId == userID {
+			posts = append(posts, s.withAuthor(s.posts[i]))
+		}
+	}
+	return posts, nil
+}
+
+func (s *Store) GetTotalPostsCount() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.posts), nil
+}
+
+// Vote operations
+
+func (s *Store) addVote(kind string, userID, targetID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.votes = append(s.votes, vote{kind, userID, targetID})
+	return nil
+}
+
+func (s *Store) removeVote(kind string, userID, targetID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.votes[:0]
+	for _, v := range s.votes {
+		if v.kind != kind || v.userID != userID || v.targetID != targetID {
+			kept = append(kept, v)
+		}
+	}
+	s.votes = kept
+	return nil
+}
+
+func (s *Store) hasVote(kind string, userID, targetID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range s.votes {
+		if v.kind == kind && v.userID == userID && v.targetID == targetID {
+			return true
+		}
+	}
+	return false
+}
+
+// countVotes must be called with the lock held
+func (s *Store) countVotes(kind string, targetID int) int {
+	count := 0
+	for _, v := range s.votes {
+		if v.kind == kind && v.targetID == targetID {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Store) votedBy(kind string, userID int) []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var targets []int
+	for _, v := range s.votes {
+		if v.kind == kind && v.userID == userID {
+			targets = append(targets, v.targetID)
+		}
+	}
+	return targets
+}
+
+func (s *Store) AddThreadLike(userID, threadID int) error {
+	return s.addVote("threadlike", userID, threadID)
+}
+
+func (s *Store) AddThreadDislike(userID, threadID int) error {
+	return s.addVote("threaddislike", userID, threadID)
+}
+
+func (s *Store) RemoveThreadLike(userID, threadID int) error {
+	return s.removeVote("threadlike", userID, threadID)
+}
+
+func (s *Store) RemoveThreadDislike(userID, threadID int) error {
+	return s.removeVote("threaddislike", userID, threadID)
+}
+
+func (s *Store) HasThreadLiked(userID, threadID int) bool {
+	return s.hasVote("threadlike", userID, threadID)
+}
+
+func (s *Store) HasThreadDisliked(userID, threadID int) bool {
+	return s.hasVote("threaddislike", userID, threadID)
+}
+
+func (s *Store) GetThreadLikesCount(threadID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.countVotes("threadlike", threadID), nil
+}
+
+func (s *Store) GetThreadDislikesCount(threadID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.countVotes("threaddislike", threadID), nil
+}
+
+func (s *Store) GetUserLikedThreads(userID int) ([]models.ThreadLikes, error) {
+	var likes []models.ThreadLikes
+	for _, id := range s.votedBy("threadlike", userID) {
+		likes = append(likes, models.ThreadLikes{Type: "like", UserId: userID, ThreadId: id})
+	}
+	return likes, nil
+}
+
+func (s *Store) GetUserDislikedThreads(userID int) ([]models.ThreadDislikes, error) {
+	var dislikes []models.ThreadDislikes
+	for _, id := range s.votedBy("threaddislike", userID) {
+		dislikes = append(dislikes, models.ThreadDislikes{Type: "dislike", UserId: userID, ThreadId: id})
+	}
+	return dislikes, nil
+}
+
+func (s *Store) AddPostLike(userID int, postID int) error {
+	return s.addVote("postlike", userID, postID)
+}
+
+func (s *Store) AddPostDislike(userID int, postID int) error {
+	return s.addVote("postdislike", userID, postID)
+}
+
+func (s *Store) RemovePostLike(userID int, postID int) error {
+	return s.removeVote("postlike", userID, postID)
+}
+
+func (s *Store) RemovePostDislike(userID int, postID int) error {
+	return s.removeVote("postdislike", userID, postID)
+}
+
+func (s *Store) HasUserLikedPost(userID int, postID int) (bool, error) {
+	return s.hasVote("postlike", userID, postID), nil
+}
+
+func (s *Store) HasUserDislikedPost(userID int, postID int) (bool, error) {
+	return s.hasVote("postdislike", userID, postID), nil
+}
+
+func (s *Store) GetPostLikesCount(postId int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.countVotes("postlike", postId), nil
+}
+
+func (s *Store) GetPostDislikesCount(postId int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.countVotes("postdislike", postId), nil
+}
+
+func (s *Store) GetUserLikedPosts(userID int) ([]models.Likes, error) {
+	var likes []models.Likes
+	for _, id := range s.votedBy("postlike", userID) {
+		likes = append(likes, models.Likes{Type: "like", UserId: userID, PostId: id})
+	}
+	return likes, nil
+}
+
+func (s *Store) GetUserDislikedPosts(userID int) ([]models.Dislikes, error) {
+	var dislikes []models.Dislikes
+	for _, id := range s.votedBy("postdislike", userID) {
+		dislikes = append(dislikes, models.Dislikes{Type: "dislike", UserId: userID, PostId: id})
+	}
+	return dislikes, nil
+}
@@ -0,0 +1,366 @@
+// Package memory keeps the forum stores in memory. It is meant for tests:
+// nothing is persisted and every Store starts empty.
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"forum/internal/data"
+	"forum/models"
+	"forum/utils"
+)
+
+// Store implements the stores of the forum content: threads, posts, users,
+// sessions, votes, audit, reads and the per-user data hanging off them
+type Store struct {
+	mu       sync.Mutex
+	lastID   int
+	users    []models.User
+	sessions []models.Session
+	threads  []models.Thread
+	posts    []models.Post
+	votes    []vote
+	audit    []models.AuditEntry
+	reads    []readMarker
+
+	watches         []models.ThreadWatch
+	digests         []digest
+	blocks          []pair
+	mentions        []models.Mention
+	follows         []pair
+	categoryFollows []categoryFollow
+	conversations   []models.Conversation
+	members         []member
+	messages        []models.Message
+	reports         []models.MessageReport
+	collections     []collection
+	bookmarks       []bookmark
+	apiTokens       []apiToken
+	drafts          []draft
+	tags            []models.Tag
+	threadTags      []threadTag
+	tagSynonyms     []tagSynonym
+}
+
+// vote is a like or dislike on a thread or a post
+type vote struct {
+	kind     string // "threadlike", "threaddislike", "postlike" or "postdislike"
+	userID   int
+	targetID int
+}
+
+var (
+	_ data.ThreadStore  = (*Store)(nil)
+	_ data.PostStore    = (*Store)(nil)
+	_ data.UserStore    = (*Store)(nil)
+	_ data.SessionStore = (*Store)(nil)
+	_ data.VoteStore    = (*Store)(nil)
+	_ data.AuditStore   = (*Store)(nil)
+	_ data.ReadStore    = (*Store)(nil)
+
+	_ data.WatchStore    = (*Store)(nil)
+	_ data.BlockStore    = (*Store)(nil)
+	_ data.MentionStore  = (*Store)(nil)
+	_ data.MessageStore  = (*Store)(nil)
+	_ data.ProfileStore  = (*Store)(nil)
+	_ data.FollowStore   = (*Store)(nil)
+	_ data.BookmarkStore = (*Store)(nil)
+	_ data.DraftStore    = (*Store)(nil)
+	_ data.TagStore      = (*Store)(nil)
+)
+
+// New returns an empty Store
+func New() *Store {
+	return &Store{}
+}
+
+// nextID hands out ids, shared by all tables like a single sequence
+func (s *Store) nextID() int {
+	s.lastID++
+	return s.lastID
+}
+
+// clock returns the time of day as hour*100 + minute, the format of sessions.active_last
+func clock(now time.Time) int {
+	return now.Hour()*100 + now.Minute()
+}
+
+// User operations
+
+func (s *Store) CreateUser(user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == user.Email {
+			return fmt.Errorf("UNIQUE constraint failed: users.email")
+		}
+	}
+	user.Id = s.nextID()
+	user.Uuid = utils.CreateUUID()
+	user.CreatedAt = time.Now()
+
+	stored := *user
+	stored.Password = utils.Encrypt(user.Password)
+	stored.Status = "active"
+	stored.Role = models.RoleMember
+	s.users = append(s.users, stored)
+	return nil
+}
+
+func (s *Store) findUser(match func(models.User) bool) (models.User, error) {
+	for _, u := range s.users {
+		if match(u) {
+			return u, nil
+		}
+	}
+	return models.User{}, sql.ErrNoRows
+}
+
+func (s *Store) GetUserByID(id int) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findUser(func(u models.User) bool { return u.Id == id })
+}
+
+func (s *Store) GetUserByEmailDetailed(email string) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findUser(func(u models.User) bool { return u.Email == email })
+}
+
+func (s *Store) GetUserByUUID(uuid string) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findUser(func(u models.User) bool { return u.Uuid == uuid })
+}
+
+func (s *Store) GetUserByName(name string) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findUser(func(u models.User) bool { return u.Name == name })
+}
+
+func (s *Store) CheckUserExists(email, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.findUser(func(u models.User) bool { return u.Email == email || u.Name == name })
+	return err == nil, nil
+}
+
+func (s *Store) GetAllUsers() ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.User(nil), s.users...), nil
+}
+
+func (s *Store) Update(userName string, userId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.users {
+		if s.users[i].Id == userId {
+			s.users[i].Name = userName
+		}
+	}
+	return nil
+}
+
+func (s *Store) UpdateUserPreferences(userID int, category1, category2 string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.users {
+		if s.users[i].Id == userID {
+			s.users[i].PreferedCategory1 = category1
+			s.users[i].PreferedCategory2 = category2
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeleteUserByID(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, u := range s.users {
+		if u.Id == userID {
+			s.users = append(s.users[:i], s.users[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no user found with id %d", userID)
+}
+
+func (s *Store) DeleteAllUsers() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = nil
+	return nil
+}
+
+func (s *Store) GetUserCount() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users), nil
+}
+
+func (s *Store) GetMostActiveUsers(limit int) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := map[int]int{}
+	for _, t := range s.threads {
+		counts[t.UserId]++
+	}
+	users := append([]models.User(nil), s.users...)
+	sort.SliceStable(users, func(i, j int) bool { return counts[users[i].Id] > counts[users[j].Id] })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// Session operations
+
+func (s *Store) CreateSession(user *models.User) (models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.sessions[:0]
+	for _, sess := range s.sessions {
+		if sess.UserId != user.Id {
+			kept = append(kept, sess)
+		}
+	}
+	s.sessions = kept
+
+	uuid := utils.CreateUUID()
+	session := models.Session{
+		Id:           s.nextID(),
+		Uuid:         uuid,
+		Email:        user.Email,
+		UserId:       user.Id,
+		CreatedAt:    time.Now(),
+		CookieString: fmt.Sprintf("%d&%s", user.Id, uuid),
+		ActiveLast:   clock(time.Now()),
+	}
+	s.sessions = append(s.sessions, session)
+	return session, nil
+}
+
+func (s *Store) ValidateSession(sessionUUID string) (models.Session, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.sessions {
+		if s.sessions[i].Uuid == sessionUUID {
+			s.sessions[i].ActiveLast = clock(time.Now())
+			return s.sessions[i], true, nil
+		}
+	}
+	return models.Session{}, false, sql.ErrNoRows
+}
+
+func (s *Store) GetSessionByCookie(cookieValue string) (models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sess := range s.sessions {
+		if sess.CookieString == cookieValue {
+			return sess, nil
+		}
+	}
+	return models.Session{}, sql.ErrNoRows
+}
+
+func (s *Store) UpdateSessionCookieString(uuid, cookieValue string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.sessions {
+		if s.sessions[i].Uuid == uuid {
+			s.sessions[i].CookieString = cookieValue
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeleteSessionByUUID(uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sess := range s.sessions {
+		if sess.Uuid == uuid {
+			s.sessions = append(s.sessions[:i], s.sessions[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeleteAllSessions() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = nil
+	return nil
+}
+
+func (s *Store) CountSessions() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, session := range s.sessions {
+		if !utils.SessionExpired(session.CreatedAt) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) CheckOnlineUsers(considerOnline int) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := clock(time.Now())
+	var users []models.User
+	seen := map[int]bool{}
+	for _, sess := range s.sessions {
+		if sess.ActiveLast <= 0 || seen[sess.UserId] {
+			continue
+		}
+		minutes := (now/100-sess.ActiveLast/100)*60 + now%100 - sess.ActiveLast%100
+		if minutes < 0 {
+			minutes += 24 * 60
+		}
+		if minutes > considerOnline {
+			continue
+		}
+		if user, err := s.findUser(func(u models.User) bool { return u.Id == sess.UserId }); err == nil {
+			seen[sess.UserId] = true
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// Audit log operations
+
+func (s *Store) WriteAudit(actorID int, action, detail string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audit = append(s.audit, models.AuditEntry{Id: s.nextID(), ActorId: actorID, Action: action, Detail: detail, CreatedAt: time.Now()})
+	return nil
+}
+
+func (s *Store) GetAuditLog(limit int) ([]models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []models.AuditEntry
+	for i := len(s.audit) - 1; i >= 0 && len(entries) < limit; i-- {
+		entry := s.audit[i]
+		if user, err := s.findUser(func(u models.User) bool { return u.Id == entry.ActorId }); err == nil {
+			entry.Actor = user.Name
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
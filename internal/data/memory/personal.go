@@ -0,0 +1,560 @@
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+// readMarker is a row of thread_reads
+type readMarker struct {
+	userID, threadID, lastPostID int
+}
+
+// digest is a row of digest_preferences
+type digest struct {
+	userID     int
+	frequency  string
+	lastSentAt time.Time
+}
+
+// bookmark is a row of bookmarks
+type bookmark struct {
+	id, userID, collectionID, threadID, postID int
+	note                                       string
+	createdAt                                  time.Time
+}
+
+// apiToken is a row of api_tokens
+type apiToken struct {
+	userID     int
+	tokenHash  string
+	lastUsedAt time.Time
+}
+
+// draft is a row of drafts
+type draft struct {
+	userID int
+	models.Draft
+}
+
+// Thread view and read marker operations
+
+func (s *Store) AddThreadViews(views map[int]int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for threadID, count := range views {
+		if i, ok := s.findThread(threadID); ok {
+			s.threads[i].Views += count
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetThreadViews(threadID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.findThread(threadID); ok {
+		return s.threads[i].Views, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+// markThreadRead moves a marker forward, never back. It must be called
+// with the lock held.
+func (s *Store) markThreadRead(userID, threadID, lastPostID int) {
+	for i, r := range s.reads {
+		if r.userID == userID && r.threadID == threadID {
+			s.reads[i].lastPostID = max(r.lastPostID, lastPostID)
+			return
+		}
+	}
+	s.reads = append(s.reads, readMarker{userID, threadID, lastPostID})
+}
+
+func (s *Store) MarkThreadRead(userID, threadID, lastPostID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.markThreadRead(userID, threadID, lastPostID)
+	return nil
+}
+
+func (s *Store) MarkAllThreadsRead(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.threads {
+		lastPostID := 0
+		for _, p := range s.posts {
+			if p.ThreadId == t.Id {
+				lastPostID = max(lastPostID, p.Id)
+			}
+		}
+		s.markThreadRead(userID, t.Id, lastPostID)
+	}
+	return nil
+}
+
+func (s *Store) GetLastReadPost(userID, threadID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.reads {
+		if r.userID == userID && r.threadID == threadID {
+			return r.lastPostID, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (s *Store) GetThreadReadStates(userID int, threadIDs []int) (map[int]models.ReadState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states := map[int]models.ReadState{}
+	for _, id := range threadIDs {
+		i, ok := s.findThread(id)
+		if !ok {
+			continue
+		}
+		state := models.ReadState{Views: s.threads[i].Views}
+		for _, r := range s.reads {
+			if userID == 0 || r.userID != userID || r.threadID != id {
+				continue
+			}
+			for _, p := range s.posts {
+				if p.ThreadId == id && p.Id > r.lastPostID && p.UserId != userID {
+					if state.Unread == 0 {
+						state.FirstUnread = p.Id
+					}
+					state.Unread++
+				}
+			}
+		}
+		states[id] = state
+	}
+	return states, nil
+}
+
+// Thread watch operations
+
+func (s *Store) WatchThread(userID, threadID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.watching(userID, threadID) {
+		s.watches = append(s.watches, models.ThreadWatch{UserId: userID, ThreadId: threadID, CreatedAt: time.Now()})
+	}
+	return nil
+}
+
+func (s *Store) UnwatchThread(userID, threadID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.watches[:0]
+	for _, w := range s.watches {
+		if w.UserId != userID || w.ThreadId != threadID {
+			kept = append(kept, w)
+		}
+	}
+	s.watches = kept
+	return nil
+}
+
+// watching must be called with the lock held
+func (s *Store) watching(userID, threadID int) bool {
+	for _, w := range s.watches {
+		if w.UserId == userID && w.ThreadId == threadID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) IsWatchingThread(userID, threadID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.watching(userID, threadID)
+}
+
+func (s *Store) GetWatchedThreads(userID int) ([]models.ThreadWatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var watches []models.ThreadWatch
+	for i := len(s.watches) - 1; i >= 0; i-- {
+		watch := s.watches[i]
+		t, ok := s.findThread(watch.ThreadId)
+		if watch.UserId != userID || !ok {
+			continue
+		}
+		watch.Topic = s.threads[t].Topic
+		watches = append(watches, watch)
+	}
+	return watches, nil
+}
+
+// findDigest must be called with the lock held
+func (s *Store) findDigest(userID int) *digest {
+	for i := range s.digests {
+		if s.digests[i].userID == userID {
+			return &s.digests[i]
+		}
+	}
+	s.digests = append(s.digests, digest{userID: userID, frequency: "daily"})
+	return &s.digests[len(s.digests)-1]
+}
+
+func (s *Store) GetDigestFrequency(userID int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findDigest(userID).frequency, nil
+}
+
+func (s *Store) SetDigestFrequency(userID int, frequency string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.findDigest(userID).frequency = frequency
+	return nil
+}
+
+func (s *Store) MarkDigestSent(userID int, sentAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.findDigest(userID).lastSentAt = sentAt
+	return nil
+}
+
+func (s *Store) GetDigestCandidates() ([]models.DigestPreference, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var prefs []models.DigestPreference
+	for _, u := range s.users {
+		if u.Status != models.StatusActive {
+			continue
+		}
+		watches := false
+		for _, w := range s.watches {
+			watches = watches || w.UserId == u.Id
+		}
+		if !watches {
+			continue
+		}
+		d := s.findDigest(u.Id)
+		prefs = append(prefs, models.DigestPreference{
+			UserId: u.Id, Name: u.Name, Email: u.Email, Frequency: d.frequency, LastSentAt: d.lastSentAt,
+		})
+	}
+	return prefs, nil
+}
+
+func (s *Store) GetDigestEntries(userID int, since time.Time) ([]models.DigestEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []models.DigestEntry
+	for _, w := range s.watches {
+		t, ok := s.findThread(w.ThreadId)
+		if w.UserId != userID || !ok {
+			continue
+		}
+		for _, p := range s.posts {
+			if p.ThreadId != w.ThreadId || p.UserId == userID || !p.CreatedAt.After(since) || !p.CreatedAt.After(w.CreatedAt) {
+				continue
+			}
+			entries = append(entries, models.DigestEntry{
+				PostId: p.Id, ThreadId: p.ThreadId, Topic: s.threads[t].Topic, Author: s.userName(p.UserId), Body: p.Body, CreatedAt: p.CreatedAt,
+			})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].ThreadId != entries[j].ThreadId {
+			return entries[i].ThreadId < entries[j].ThreadId
+		}
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// Draft operations
+
+func (s *Store) SaveDraft(userID int, d *models.Draft) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d.UpdatedAt = time.Now()
+	for i := range s.drafts {
+		if s.drafts[i].userID == userID && s.drafts[i].ThreadId == d.ThreadId {
+			s.drafts[i].Draft = *d
+			return nil
+		}
+	}
+	s.drafts = append(s.drafts, draft{userID, *d})
+	return nil
+}
+
+func (s *Store) GetDraft(userID, threadID int) (models.Draft, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.drafts {
+		if d.userID == userID && d.ThreadId == threadID {
+			return d.Draft, nil
+		}
+	}
+	return models.Draft{}, sql.ErrNoRows
+}
+
+func (s *Store) DeleteDraft(userID, threadID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.drafts[:0]
+	for _, d := range s.drafts {
+		if d.userID != userID || d.ThreadId != threadID {
+			kept = append(kept, d)
+		}
+	}
+	s.drafts = kept
+	return nil
+}
+
+func (s *Store) GetDrafts(userID int) ([]models.Draft, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var drafts []models.Draft
+	for _, d := range s.drafts {
+		if d.userID != userID {
+			continue
+		}
+		if i, ok := s.findThread(d.ThreadId); ok {
+			d.Target = s.threads[i].Topic
+		}
+		drafts = append(drafts, d.Draft)
+	}
+	sort.SliceStable(drafts, func(i, j int) bool { return drafts[i].UpdatedAt.After(drafts[j].UpdatedAt) })
+	return drafts, nil
+}
+
+// Collection operations
+
+func (s *Store) CreateCollection(userID int, name string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.collections {
+		if c.userID == userID && c.Name == name {
+			return 0, fmt.Errorf("UNIQUE constraint failed: collections.user_id, collections.name")
+		}
+	}
+	c := collection{userID, models.Collection{Id: s.nextID(), Name: name, CreatedAt: time.Now()}}
+	s.collections = append(s.collections, c)
+	return int64(c.Id), nil
+}
+
+// collection is a row of collections
+type collection struct {
+	userID int
+	models.Collection
+}
+
+func (s *Store) GetCollections(userID int) ([]models.Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	collections := []models.Collection{}
+	for _, c := range s.collections {
+		if c.userID != userID {
+			continue
+		}
+		for _, b := range s.bookmarks {
+			if b.collectionID == c.Id {
+				c.Count++
+			}
+		}
+		collections = append(collections, c.Collection)
+	}
+	sort.Slice(collections, func(i, j int) bool { return collections[i].Name < collections[j].Name })
+	return collections, nil
+}
+
+// collectionOwner must be called with the lock held
+func (s *Store) collectionOwner(collectionID, userID int) bool {
+	for _, c := range s.collections {
+		if c.Id == collectionID && c.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) IsCollectionOwner(collectionID, userID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.collectionOwner(collectionID, userID)
+}
+
+func (s *Store) DeleteCollection(collectionID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.collectionOwner(collectionID, userID) {
+		return nil
+	}
+	// the bookmarks are kept without a collection
+	for i := range s.bookmarks {
+		if s.bookmarks[i].collectionID == collectionID && s.bookmarks[i].userID == userID {
+			s.bookmarks[i].collectionID = 0
+		}
+	}
+	kept := s.collections[:0]
+	for _, c := range s.collections {
+		if c.Id != collectionID {
+			kept = append(kept, c)
+		}
+	}
+	s.collections = kept
+	return nil
+}
+
+// Bookmark operations
+
+func (s *Store) SaveBookmark(userID, threadID, postID, collectionID int, note string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, b := range s.bookmarks {
+		if b.userID == userID && b.threadID == threadID && b.postID == postID {
+			s.bookmarks[i].collectionID, s.bookmarks[i].note = collectionID, note
+			return nil
+		}
+	}
+	s.bookmarks = append(s.bookmarks, bookmark{
+		id: s.nextID(), userID: userID, collectionID: collectionID, threadID: threadID, postID: postID, note: note, createdAt: time.Now(),
+	})
+	return nil
+}
+
+func (s *Store) UpdateBookmark(bookmarkID, userID, collectionID int, note string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, b := range s.bookmarks {
+		if b.id == bookmarkID && b.userID == userID {
+			s.bookmarks[i].collectionID, s.bookmarks[i].note = collectionID, note
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeleteBookmark(bookmarkID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.bookmarks[:0]
+	for _, b := range s.bookmarks {
+		if b.id != bookmarkID || b.userID != userID {
+			kept = append(kept, b)
+		}
+	}
+	s.bookmarks = kept
+	return nil
+}
+
+func (s *Store) IsThreadBookmarked(userID, threadID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.bookmarks {
+		if b.userID == userID && b.threadID == threadID && b.postID == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) GetBookmarks(userID int, filter models.BookmarkFilter) ([]models.Bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// LIKE ignores the case of ASCII letters
+	query := strings.ToLower(filter.Query)
+	contains := func(text string) bool { return strings.Contains(strings.ToLower(text), query) }
+
+	bookmarks := []models.Bookmark{}
+	for _, b := range s.bookmarks {
+		t, ok := s.findThread(b.threadID)
+		if b.userID != userID || !ok {
+			continue
+		}
+		if filter.CollectionId != -1 && b.collectionID != filter.CollectionId {
+			continue
+		}
+		if (filter.Kind == "thread" && b.postID != 0) || (filter.Kind == "post" && b.postID == 0) {
+			continue
+		}
+		found := models.Bookmark{
+			Id: b.id, CollectionId: b.collectionID, ThreadId: b.threadID, PostId: b.postID,
+			Topic: s.threads[t].Topic, Excerpt: s.threads[t].Body, Note: b.note, CreatedAt: b.createdAt,
+		}
+		for _, p := range s.posts {
+			if b.postID != 0 && p.Id == b.postID {
+				found.Excerpt = p.Body
+			}
+		}
+		for _, c := range s.collections {
+			if c.Id == b.collectionID {
+				found.Collection = c.Name
+			}
+		}
+		if query != "" && !contains(found.Topic) && !contains(found.Excerpt) && !contains(found.Note) {
+			continue
+		}
+		bookmarks = append(bookmarks, found)
+	}
+	sort.SliceStable(bookmarks, func(i, j int) bool {
+		if !bookmarks[i].CreatedAt.Equal(bookmarks[j].CreatedAt) {
+			return bookmarks[i].CreatedAt.After(bookmarks[j].CreatedAt)
+		}
+		return bookmarks[i].Id > bookmarks[j].Id
+	})
+	return bookmarks, nil
+}
+
+// API token operations
+
+func (s *Store) SetAPITokenHash(userID int, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.apiTokens {
+		if s.apiTokens[i].userID == userID {
+			s.apiTokens[i] = apiToken{userID: userID, tokenHash: tokenHash}
+			return nil
+		}
+	}
+	s.apiTokens = append(s.apiTokens, apiToken{userID: userID, tokenHash: tokenHash})
+	return nil
+}
+
+func (s *Store) DeleteAPIToken(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.apiTokens[:0]
+	for _, t := range s.apiTokens {
+		if t.userID != userID {
+			kept = append(kept, t)
+		}
+	}
+	s.apiTokens = kept
+	return nil
+}
+
+func (s *Store) HasAPIToken(userID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.apiTokens {
+		if t.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) GetUserIDByTokenHash(tokenHash string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, t := range s.apiTokens {
+		if t.tokenHash == tokenHash {
+			s.apiTokens[i].lastUsedAt = time.Now()
+			return t.userID, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
@@ -0,0 +1,599 @@
+package memory
+
+import (
+	"database/sql"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"forum/models"
+	"forum/utils"
+)
+
+// pair is a row of user_blocks or user_follows, from blocks or follows to
+type pair struct {
+	from, to int
+}
+
+// categoryFollow is a row of category_follows
+type categoryFollow struct {
+	userID   int
+	category string
+}
+
+// member is a row of conversation_members
+type member struct {
+	conversationID int
+	userID         int
+	lastReadID     int
+	muted          bool
+	left           bool
+}
+
+// userName returns the name of a user, "" when there is none with that id.
+// It must be called with the lock held.
+func (s *Store) userName(userID int) string {
+	user, _ := s.findUser(func(u models.User) bool { return u.Id == userID })
+	return user.Name
+}
+
+// Block operations
+
+func (s *Store) BlockUser(blockerID, blockedID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.blocked(blockerID, blockedID) {
+		s.blocks = append(s.blocks, pair{blockerID, blockedID})
+	}
+	return nil
+}
+
+func (s *Store) UnblockUser(blockerID, blockedID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.blocks[:0]
+	for _, b := range s.blocks {
+		if b != (pair{blockerID, blockedID}) {
+			kept = append(kept, b)
+		}
+	}
+	s.blocks = kept
+	return nil
+}
+
+// blocked must be called with the lock held
+func (s *Store) blocked(blockerID, blockedID int) bool {
+	for _, b := range s.blocks {
+		if b == (pair{blockerID, blockedID}) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) HasBlocked(blockerID, blockedID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.blocked(blockerID, blockedID)
+}
+
+func (s *Store) IsBlockedEitherWay(userA, userB int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.blocked(userA, userB) || s.blocked(userB, userA)
+}
+
+// Mention operations
+
+func (s *Store) CreateMention(userID, authorID, threadID, postID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mentions = append(s.mentions, models.Mention{
+		Id: s.nextID(), UserId: userID, AuthorId: authorID, ThreadId: threadID, PostId: postID, CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+func (s *Store) GetUserMentions(userID, limit int) ([]models.Mention, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var mentions []models.Mention
+	for i := len(s.mentions) - 1; i >= 0 && len(mentions) < limit; i-- {
+		mention := s.mentions[i]
+		t, ok := s.findThread(mention.ThreadId)
+		if mention.UserId != userID || !ok {
+			continue
+		}
+		mention.Author = s.userName(mention.AuthorId)
+		mention.Topic = s.threads[t].Topic
+		mentions = append(mentions, mention)
+	}
+	return mentions, nil
+}
+
+func (s *Store) CountUnseenMentions(userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, m := range s.mentions {
+		if m.UserId == userID && !m.Seen {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) MarkMentionsSeen(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.mentions {
+		if s.mentions[i].UserId == userID {
+			s.mentions[i].Seen = true
+		}
+	}
+	return nil
+}
+
+func (s *Store) LookupUsersByPrefix(prefix string, viewerID, limit int) ([]models.UserLookup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := []models.UserLookup{}
+	for _, u := range s.users {
+		// LIKE ignores the case of ASCII letters
+		if u.Status != "active" || u.Id == viewerID || !strings.HasPrefix(strings.ToLower(u.Name), strings.ToLower(prefix)) {
+			continue
+		}
+		if s.blocked(u.Id, viewerID) || s.blocked(viewerID, u.Id) {
+			continue
+		}
+		users = append(users, models.UserLookup{Id: u.Id, Name: u.Name})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// Follow operations
+
+func (s *Store) FollowUser(followerID, followedID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.following(followerID, followedID) {
+		s.follows = append(s.follows, pair{followerID, followedID})
+	}
+	return nil
+}
+
+func (s *Store) UnfollowUser(followerID, followedID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.follows[:0]
+	for _, f := range s.follows {
+		if f != (pair{followerID, followedID}) {
+			kept = append(kept, f)
+		}
+	}
+	s.follows = kept
+	return nil
+}
+
+// following must be called with the lock held
+func (s *Store) following(followerID, followedID int) bool {
+	for _, f := range s.follows {
+		if f == (pair{followerID, followedID}) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) IsFollowingUser(followerID, followedID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.following(followerID, followedID)
+}
+
+func (s *Store) CountFollowers(userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, f := range s.follows {
+		if f.to == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) FollowCategory(userID int, category string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.categoryFollows {
+		if f == (categoryFollow{userID, category}) {
+			return nil
+		}
+	}
+	s.categoryFollows = append(s.categoryFollows, categoryFollow{userID, category})
+	return nil
+}
+
+func (s *Store) UnfollowCategory(userID int, category string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.categoryFollows[:0]
+	for _, f := range s.categoryFollows {
+		if f != (categoryFollow{userID, category}) {
+			kept = append(kept, f)
+		}
+	}
+	s.categoryFollows = kept
+	return nil
+}
+
+// followedCategories must be called with the lock held
+func (s *Store) followedCategories(userID int) []string {
+	var categories []string
+	for _, f := range s.categoryFollows {
+		if f.userID == userID {
+			categories = append(categories, f.category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+func (s *Store) GetFollowedCategories(userID int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.followedCategories(userID), nil
+}
+
+func (s *Store) GetFeedThreads(userID, limit, offset int) ([]models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	categories := s.followedCategories(userID)
+	followed := func(category string) bool {
+		return category != "" && slices.Contains(categories, category)
+	}
+	var threads []models.Thread
+	for _, t := range s.threads {
+		if t.UserId == userID || s.blocked(userID, t.UserId) || s.blocked(t.UserId, userID) {
+			continue
+		}
+		if s.following(userID, t.UserId) || followed(t.Category1) || followed(t.Category2) {
+			threads = append(threads, s.listed(t))
+		}
+	}
+	sort.SliceStable(threads, func(i, j int) bool {
+		if !threads[i].CreatedAt.Equal(threads[j].CreatedAt) {
+			return threads[i].CreatedAt.After(threads[j].CreatedAt)
+		}
+		return threads[i].Id > threads[j].Id
+	})
+	threads = threads[min(offset, len(threads)):]
+	return threads[:min(limit, len(threads))], nil
+}
+
+// Profile operations
+
+func (s *Store) GetProfileCounts(userID int) (threads, posts, likes int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	authors := map[string]map[int]int{"threadlike": {}, "postlike": {}}
+	for _, t := range s.threads {
+		authors["threadlike"][t.Id] = t.UserId
+		if t.UserId == userID {
+			threads++
+		}
+	}
+	for _, p := range s.posts {
+		authors["postlike"][p.Id] = p.UserId
+		if p.UserId == userID {
+			posts++
+		}
+	}
+	// likes of the user on their own content do not count
+	for _, v := range s.votes {
+		if byKind, ok := authors[v.kind]; ok && byKind[v.targetID] == userID && v.userID != userID {
+			likes++
+		}
+	}
+	return threads, posts, likes, nil
+}
+
+func (s *Store) GetRecentActivity(userID, limit int) ([]models.Activity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var activity []models.Activity
+	for _, t := range s.threads {
+		if t.UserId == userID {
+			activity = append(activity, models.Activity{Kind: "thread", ThreadId: t.Id, Topic: t.Topic, Excerpt: t.Body, CreatedAt: t.CreatedAt})
+		}
+	}
+	for _, p := range s.posts {
+		t, ok := s.findThread(p.ThreadId)
+		if p.UserId == userID && ok {
+			activity = append(activity, models.Activity{Kind: "post", ThreadId: p.ThreadId, Topic: s.threads[t].Topic, Excerpt: p.Body, CreatedAt: p.CreatedAt})
+		}
+	}
+	sort.SliceStable(activity, func(i, j int) bool {
+		return activity[i].CreatedAt.After(activity[j].CreatedAt)
+	})
+	if len(activity) > limit {
+		activity = activity[:limit]
+	}
+	return activity, nil
+}
+
+func (s *Store) UpdateProfile(userID int, bio, avatarURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.users {
+		if s.users[i].Id == userID {
+			s.users[i].Bio = bio
+			s.users[i].AvatarURL = avatarURL
+		}
+	}
+	return nil
+}
+
+// Conversation operations
+
+func (s *Store) CreateConversation(creatorID int, memberIDs []int, subject, body string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	conversation := models.Conversation{
+		Id: s.nextID(), Uuid: utils.CreateUUID(), Subject: subject, CreatedBy: creatorID, CreatedAt: now, UpdatedAt: now,
+	}
+	s.conversations = append(s.conversations, conversation)
+	for _, userID := range append([]int{creatorID}, memberIDs...) {
+		if _, ok := s.findMember(conversation.Id, userID); !ok {
+			s.members = append(s.members, member{conversationID: conversation.Id, userID: userID})
+		}
+	}
+	message := s.addMessage(conversation.Id, creatorID, body, now)
+	// the creator has read their own first message
+	s.markRead(conversation.Id, creatorID, message.Id)
+	return int64(conversation.Id), nil
+}
+
+// findMember must be called with the lock held
+func (s *Store) findMember(conversationID, userID int) (int, bool) {
+	for i, m := range s.members {
+		if m.conversationID == conversationID && m.userID == userID {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// memberNames lists the members who have not left, it must be called
+// with the lock held
+func (s *Store) memberNames(conversationID int) string {
+	var names []string
+	for _, m := range s.members {
+		if m.conversationID == conversationID && !m.left {
+			names = append(names, s.userName(m.userID))
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// unread counts the messages of others after the read marker of m, it
+// must be called with the lock held
+func (s *Store) unread(m member) int {
+	count := 0
+	for _, message := range s.messages {
+		if message.ConversationId == m.conversationID && message.Id > m.lastReadID && message.UserId != m.userID {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Store) GetUserConversations(userID int) ([]models.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var conversations []models.Conversation
+	for _, c := range s.conversations {
+		i, ok := s.findMember(c.Id, userID)
+		if !ok || s.members[i].left {
+			continue
+		}
+		c.Muted = s.members[i].muted
+		c.Unread = s.unread(s.members[i])
+		c.Members = s.memberNames(c.Id)
+		conversations = append(conversations, c)
+	}
+	sort.SliceStable(conversations, func(i, j int) bool {
+		return conversations[i].UpdatedAt.After(conversations[j].UpdatedAt)
+	})
+	return conversations, nil
+}
+
+func (s *Store) GetConversation(conversationID int) (models.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conversations {
+		if c.Id == conversationID {
+			c.Members = s.memberNames(c.Id)
+			return c, nil
+		}
+	}
+	return models.Conversation{}, sql.ErrNoRows
+}
+
+func (s *Store) IsConversationMember(conversationID, userID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.findMember(conversationID, userID)
+	return ok && !s.members[i].left
+}
+
+func (s *Store) IsConversationMuted(conversationID, userID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.findMember(conversationID, userID)
+	return ok && s.members[i].muted
+}
+
+// withSender fills in the name of the author of a message
+func (s *Store) withSender(message models.Message) models.Message {
+	message.Author = s.userName(message.UserId)
+	return message
+}
+
+func (s *Store) GetConversationMessages(conversationID int) ([]models.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var messages []models.Message
+	for _, m := range s.messages {
+		if m.ConversationId == conversationID {
+			messages = append(messages, s.withSender(m))
+		}
+	}
+	return messages, nil
+}
+
+// addMessage must be called with the lock held
+func (s *Store) addMessage(conversationID, userID int, body string, now time.Time) models.Message {
+	message := models.Message{Id: s.nextID(), ConversationId: conversationID, UserId: userID, Body: body, CreatedAt: now}
+	s.messages = append(s.messages, message)
+	for i := range s.conversations {
+		if s.conversations[i].Id == conversationID {
+			s.conversations[i].UpdatedAt = now
+		}
+	}
+	return message
+}
+
+func (s *Store) CreateMessage(conversationID, userID int, body string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	message := s.addMessage(conversationID, userID, body, time.Now())
+	s.markRead(conversationID, userID, message.Id)
+	return int64(message.Id), nil
+}
+
+func (s *Store) GetMessageByID(messageID int) (models.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.messages {
+		if m.Id == messageID {
+			return s.withSender(m), nil
+		}
+	}
+	return models.Message{}, sql.ErrNoRows
+}
+
+// markRead moves the read marker forward, never backwards. It must be
+// called with the lock held.
+func (s *Store) markRead(conversationID, userID, messageID int) {
+	if i, ok := s.findMember(conversationID, userID); ok && s.members[i].lastReadID < messageID {
+		s.members[i].lastReadID = messageID
+	}
+}
+
+func (s *Store) MarkConversationRead(conversationID, userID, messageID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.markRead(conversationID, userID, messageID)
+	return nil
+}
+
+func (s *Store) SetConversationMuted(conversationID, userID int, muted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.findMember(conversationID, userID); ok {
+		s.members[i].muted = muted
+	}
+	return nil
+}
+
+func (s *Store) LeaveConversation(conversationID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i, ok := s.findMember(conversationID, userID); ok {
+		s.members[i].left = true
+	}
+	return nil
+}
+
+func (s *Store) CountUnreadMessages(userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, m := range s.members {
+		if m.userID == userID && !m.left && !m.muted {
+			count += s.unread(m)
+		}
+	}
+	return count, nil
+}
+
+// Message report operations
+
+func (s *Store) CreateMessageReport(messageID, conversationID, reporterID int, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports = append(s.reports, models.MessageReport{
+		Id: s.nextID(), MessageId: messageID, ConversationId: conversationID, ReporterId: reporterID,
+		Reason: reason, Status: "open", CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+func (s *Store) GetMessageReports(status string) ([]models.MessageReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var reports []models.MessageReport
+	for _, r := range s.reports {
+		if r.Status == status {
+			r.Reporter = s.userName(r.ReporterId)
+			reports = append(reports, r)
+		}
+	}
+	return reports, nil
+}
+
+func (s *Store) GetMessageReport(reportID int) (models.MessageReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.reports {
+		if r.Id == reportID {
+			r.Reporter = s.userName(r.ReporterId)
+			return r, nil
+		}
+	}
+	return models.MessageReport{}, sql.ErrNoRows
+}
+
+func (s *Store) GetMessagesBefore(conversationID, messageID, limit int) ([]models.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var messages []models.Message
+	for _, m := range s.messages {
+		if m.ConversationId == conversationID && m.Id < messageID {
+			messages = append(messages, s.withSender(m))
+		}
+	}
+	return messages[max(len(messages)-limit, 0):], nil
+}
+
+func (s *Store) ResolveMessageReport(reportID, moderatorID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.reports {
+		if s.reports[i].Id == reportID {
+			s.reports[i].Status = "resolved"
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
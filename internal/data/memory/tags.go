@@ -0,0 +1,259 @@
+package memory
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"forum/models"
+)
+
+// threadTag is a row of thread_tags
+type threadTag struct {
+	threadID, tagID int
+}
+
+// tagSynonym is a row of tag_synonyms
+type tagSynonym struct {
+	alias     string
+	tagID     int
+	createdBy int
+	createdAt time.Time
+}
+
+// Tag operations, UsageCount is the number of threads carrying a tag
+
+// tagID returns the id of a tag, creating it when missing. It must be
+// called with the lock held.
+func (s *Store) tagID(name string) int {
+	for _, t := range s.tags {
+		if t.Name == name {
+			return t.Id
+		}
+	}
+	tag := models.Tag{Id: s.nextID(), Name: name}
+	s.tags = append(s.tags, tag)
+	return tag.Id
+}
+
+// tagName must be called with the lock held
+func (s *Store) tagName(id int) string {
+	for _, t := range s.tags {
+		if t.Id == id {
+			return t.Name
+		}
+	}
+	return ""
+}
+
+// recountTags refreshes the usage of every tag. It must be called with
+// the lock held.
+func (s *Store) recountTags() {
+	for i := range s.tags {
+		s.tags[i].UsageCount = 0
+		for _, tt := range s.threadTags {
+			if tt.tagID == s.tags[i].Id {
+				s.tags[i].UsageCount++
+			}
+		}
+	}
+}
+
+func (s *Store) SetThreadTags(threadID int, names []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.threadTags[:0]
+	for _, tt := range s.threadTags {
+		if tt.threadID != threadID {
+			kept = append(kept, tt)
+		}
+	}
+	s.threadTags = kept
+	for _, name := range names {
+		tagged := threadTag{threadID, s.tagID(name)}
+		if !containsThreadTag(s.threadTags, tagged) {
+			s.threadTags = append(s.threadTags, tagged)
+		}
+	}
+	s.recountTags()
+	return nil
+}
+
+func containsThreadTag(threadTags []threadTag, tagged threadTag) bool {
+	for _, tt := range threadTags {
+		if tt == tagged {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) GetThreadTags(threadID int) ([]string, error) {
+	tags, err := s.GetTagsForThreads([]int{threadID})
+	return tags[threadID], err
+}
+
+func (s *Store) GetTagsForThreads(threadIDs []int) (map[int][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wanted := map[int]bool{}
+	for _, id := range threadIDs {
+		wanted[id] = true
+	}
+	tags := map[int][]string{}
+	for _, tt := range s.threadTags {
+		if wanted[tt.threadID] {
+			tags[tt.threadID] = append(tags[tt.threadID], s.tagName(tt.tagID))
+		}
+	}
+	for _, names := range tags {
+		sort.Strings(names)
+	}
+	return tags, nil
+}
+
+func (s *Store) GetTaggedThreadIDs(name string) (map[int]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := map[int]bool{}
+	for _, tt := range s.threadTags {
+		if s.tagName(tt.tagID) == name {
+			ids[tt.threadID] = true
+		}
+	}
+	return ids, nil
+}
+
+func (s *Store) GetTag(name string) (models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tags {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return models.Tag{}, sql.ErrNoRows
+}
+
+func (s *Store) SearchTags(prefix string, limit int) ([]models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// LIKE ignores the case of ASCII letters
+	prefix = strings.ToLower(prefix)
+	matches := func(name string) bool { return strings.HasPrefix(strings.ToLower(name), prefix) }
+
+	tags := []models.Tag{}
+	for _, t := range s.tags {
+		if t.UsageCount == 0 {
+			continue
+		}
+		found := matches(t.Name)
+		for _, syn := range s.tagSynonyms {
+			found = found || (syn.tagID == t.Id && matches(syn.alias))
+		}
+		if found {
+			tags = append(tags, t)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].UsageCount != tags[j].UsageCount {
+			return tags[i].UsageCount > tags[j].UsageCount
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags, nil
+}
+
+// Tag synonym operations
+
+func (s *Store) GetTagSynonym(alias string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, syn := range s.tagSynonyms {
+		if syn.alias == alias {
+			return s.tagName(syn.tagID), nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+// AddTagSynonym makes alias stand for tag. Threads already tagged with
+// alias are moved to tag and the alias tag is removed.
+func (s *Store) AddTagSynonym(alias, tag string, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tagID := s.tagID(tag)
+
+	for i, t := range s.tags {
+		if t.Name != alias {
+			continue
+		}
+		var moved []threadTag
+		for _, tt := range s.threadTags {
+			if tt.tagID != t.Id {
+				moved = append(moved, tt)
+				continue
+			}
+			retagged := threadTag{tt.threadID, tagID}
+			if !containsThreadTag(moved, retagged) && !containsThreadTag(s.threadTags, retagged) {
+				moved = append(moved, retagged)
+			}
+		}
+		s.threadTags = moved
+		for j := range s.tagSynonyms {
+			if s.tagSynonyms[j].tagID == t.Id {
+				s.tagSynonyms[j].tagID = tagID
+			}
+		}
+		s.tags = append(s.tags[:i], s.tags[i+1:]...)
+		break
+	}
+
+	synonym := tagSynonym{alias: alias, tagID: tagID, createdBy: userID, createdAt: time.Now()}
+	replaced := false
+	for i := range s.tagSynonyms {
+		if s.tagSynonyms[i].alias == alias {
+			s.tagSynonyms[i], replaced = synonym, true
+		}
+	}
+	if !replaced {
+		s.tagSynonyms = append(s.tagSynonyms, synonym)
+	}
+	s.recountTags()
+	return nil
+}
+
+func (s *Store) DeleteTagSynonym(alias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.tagSynonyms[:0]
+	for _, syn := range s.tagSynonyms {
+		if syn.alias != alias {
+			kept = append(kept, syn)
+		}
+	}
+	s.tagSynonyms = kept
+	return nil
+}
+
+func (s *Store) GetTagSynonyms() ([]models.TagSynonym, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var synonyms []models.TagSynonym
+	for _, syn := range s.tagSynonyms {
+		synonyms = append(synonyms, models.TagSynonym{
+			Alias: syn.alias, Tag: s.tagName(syn.tagID), CreatedBy: s.userName(syn.createdBy), CreatedAt: syn.createdAt,
+		})
+	}
+	sort.Slice(synonyms, func(i, j int) bool {
+		if synonyms[i].Tag != synonyms[j].Tag {
+			return synonyms[i].Tag < synonyms[j].Tag
+		}
+		return synonyms[i].Alias < synonyms[j].Alias
+	})
+	return synonyms, nil
+}
@@ -0,0 +1,304 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"forum/models"
+)
+
+// The store interfaces are the storage the forum logic depends on.
+// DatabaseManager implements all of them on SQLite, the memory package
+// implements the stores of the forum content in memory for tests.
+
+// ThreadStore reads and writes threads
+type ThreadStore interface {
+	CreateThreadByUser(topic, body string, userID int, category1, category2 string) (int64, error)
+	GetThreadByID(id int) (models.Thread, error)
+	GetThreadWithPosts(id int) (models.Thread, error)
+	GetAllThreads() ([]models.Thread, error)
+	GetThreadsByCategories(category1, category2 string) ([]models.Thread, error)
+	GetUserCreatedThreads(userID int) ([]models.Thread, error)
+	GetTotalThreadsCount() (int, error)
+	SetThreadPinned(threadID int, pinned bool) error
+	SetThreadLocked(threadID int, locked bool) error
+	SetThreadArchived(threadID int, archived bool) error
+	ArchiveInactiveThreads(before time.Time) (int64, error)
+}
+
+// PostStore reads and writes replies
+type PostStore interface {
+	CreatePostByUser(body string, userID, threadID int) (int64, error)
+	GetPostByID(id int) (models.Post, error)
+	GetThreadPosts(threadID int) ([]models.Post, error)
+	GetUserCreatedPosts(userID int) ([]models.Post, error)
+	GetTotalPostsCount() (int, error)
+}
+
+// UserStore reads and writes accounts
+type UserStore interface {
+	CreateUser(user *models.User) error
+	GetUserByID(id int) (models.User, error)
+	GetUserByEmailDetailed(email string) (models.User, error)
+	GetUserByUUID(uuid string) (models.User, error)
+	GetUserByName(name string) (models.User, error)
+	CheckUserExists(email, name string) (bool, error)
+	GetAllUsers() ([]models.User, error)
+	Update(userName string, userId int) error
+	UpdateUserPreferences(userID int, category1, category2 string) error
+	DeleteUserByID(userID int) error
+	DeleteAllUsers() error
+	GetUserCount() (int, error)
+	GetMostActiveUsers(limit int) ([]models.User, error)
+}
+
+// SessionStore reads and writes login sessions
+type SessionStore interface {
+	CreateSession(user *models.User) (models.Session, error)
+	ValidateSession(sessionUUID string) (models.Session, bool, error)
+	GetSessionByCookie(cookieValue string) (models.Session, error)
+	UpdateSessionCookieString(uuid, cookieValue string) error
+	DeleteSessionByUUID(uuid string) error
+	DeleteAllSessions() error
+	CountSessions() (int, error)
+	CheckOnlineUsers(considerOnline int) ([]models.User, error)
+}
+
+// VoteStore reads and writes likes and dislikes on threads and posts
+type VoteStore interface {
+	AddThreadLike(userID, threadID int) error
+	AddThreadDislike(userID, threadID int) error
+	RemoveThreadLike(userID, threadID int) error
+	RemoveThreadDislike(userID, threadID int) error
+	HasThreadLiked(userID, threadID int) bool
+	HasThreadDisliked(userID, threadID int) bool
+	GetThreadLikesCount(threadID int) (int, error)
+	GetThreadDislikesCount(threadID int) (int, error)
+	GetUserLikedThreads(userID int) ([]models.ThreadLikes, error)
+	GetUserDislikedThreads(userID int) ([]models.ThreadDislikes, error)
+
+	AddPostLike(userID int, postID int) error
+	AddPostDislike(userID int, postID int) error
+	RemovePostLike(userID int, postID int) error
+	RemovePostDislike(userID int, postID int) error
+	HasUserLikedPost(userID int, postID int) (bool, error)
+	HasUserDislikedPost(userID int, postID int) (bool, error)
+	GetPostLikesCount(postId int) (int, error)
+	GetPostDislikesCount(postId int) (int, error)
+	GetUserLikedPosts(userID int) ([]models.Likes, error)
+	GetUserDislikedPosts(userID int) ([]models.Dislikes, error)
+}
+
+// AuditStore keeps the log of moderator and admin actions
+type AuditStore interface {
+	WriteAudit(actorID int, action, detail string) error
+	GetAuditLog(limit int) ([]models.AuditEntry, error)
+}
+
+// FeedStore reads the entries of the Atom and RSS feeds and the sitemap,
+// and keeps the tokens of the private feeds
+type FeedStore interface {
+	GetLatestThreadEntries(category string, limit int) ([]models.FeedEntry, error)
+	GetThreadReplyEntries(threadID, limit int) ([]models.FeedEntry, error)
+	GetUserFeedEntries(userID, limit int) ([]models.FeedEntry, error)
+	GetNotificationEntries(userID, limit int) ([]models.FeedEntry, error)
+	SetFeedTokenHash(userID int, tokenHash string) error
+	DeleteFeedToken(userID int) error
+	HasFeedToken(userID int) bool
+	GetUserIDByFeedTokenHash(tokenHash string) (int, error)
+	GetSitemapThreads(limit int) ([]models.SitemapEntry, error)
+	GetActiveUserNames(limit int) ([]string, error)
+}
+
+// StatisticsStore answers the admin dashboard queries over a range of days,
+// from included and to excluded
+type StatisticsStore interface {
+	DailyCounts(from, to time.Time) ([]models.DailyCount, error)
+	GetActiveUsersBetween(from, to time.Time, limit int) ([]models.UserActivity, error)
+	GetMostVotedThreads(from, to time.Time, limit int) ([]models.ThreadVotes, error)
+	GetCategoryDistribution(from, to time.Time) ([]models.CategoryCount, error)
+}
+
+// ReadStore keeps the view counts of threads and how far each user has
+// read them
+type ReadStore interface {
+	AddThreadViews(views map[int]int) error
+	GetThreadViews(threadID int) (int, error)
+	MarkThreadRead(userID, threadID, lastPostID int) error
+	MarkAllThreadsRead(userID int) error
+	GetLastReadPost(userID, threadID int) (int, error)
+	GetThreadReadStates(userID int, threadIDs []int) (map[int]models.ReadState, error)
+}
+
+// WatchStore keeps the threads users watch and how often they get a digest
+type WatchStore interface {
+	WatchThread(userID, threadID int) error
+	UnwatchThread(userID, threadID int) error
+	IsWatchingThread(userID, threadID int) bool
+	GetWatchedThreads(userID int) ([]models.ThreadWatch, error)
+	GetDigestFrequency(userID int) (string, error)
+	SetDigestFrequency(userID int, frequency string) error
+	MarkDigestSent(userID int, sentAt time.Time) error
+	GetDigestCandidates() ([]models.DigestPreference, error)
+	GetDigestEntries(userID int, since time.Time) ([]models.DigestEntry, error)
+}
+
+// BlockStore keeps the users who blocked each other
+type BlockStore interface {
+	BlockUser(blockerID, blockedID int) error
+	UnblockUser(blockerID, blockedID int) error
+	HasBlocked(blockerID, blockedID int) bool
+	IsBlockedEitherWay(userA, userB int) bool
+}
+
+// MentionStore keeps the @name mentions and looks users up for the composer
+type MentionStore interface {
+	CreateMention(userID, authorID, threadID, postID int) error
+	GetUserMentions(userID, limit int) ([]models.Mention, error)
+	CountUnseenMentions(userID int) (int, error)
+	MarkMentionsSeen(userID int) error
+	LookupUsersByPrefix(prefix string, viewerID, limit int) ([]models.UserLookup, error)
+}
+
+// MessageStore keeps private conversations and the reports on their messages
+type MessageStore interface {
+	CreateConversation(creatorID int, memberIDs []int, subject, body string) (int64, error)
+	GetUserConversations(userID int) ([]models.Conversation, error)
+	GetConversation(conversationID int) (models.Conversation, error)
+	IsConversationMember(conversationID, userID int) bool
+	IsConversationMuted(conversationID, userID int) bool
+	GetConversationMessages(conversationID int) ([]models.Message, error)
+	CreateMessage(conversationID, userID int, body string) (int64, error)
+	GetMessageByID(messageID int) (models.Message, error)
+	MarkConversationRead(conversationID, userID, messageID int) error
+	SetConversationMuted(conversationID, userID int, muted bool) error
+	LeaveConversation(conversationID, userID int) error
+	CountUnreadMessages(userID int) (int, error)
+	CreateMessageReport(messageID, conversationID, reporterID int, reason string) error
+	GetMessageReports(status string) ([]models.MessageReport, error)
+	GetMessageReport(reportID int) (models.MessageReport, error)
+	GetMessagesBefore(conversationID, messageID, limit int) ([]models.Message, error)
+	ResolveMessageReport(reportID, moderatorID int) error
+}
+
+// ProfileStore reads the activity shown on public profiles and saves
+// their bio and avatar
+type ProfileStore interface {
+	GetProfileCounts(userID int) (threads, posts, likes int, err error)
+	GetRecentActivity(userID, limit int) ([]models.Activity, error)
+	UpdateProfile(userID int, bio, avatarURL string) error
+}
+
+// FollowStore keeps the users and categories a user follows and reads
+// their home feed
+type FollowStore interface {
+	FollowUser(followerID, followedID int) error
+	UnfollowUser(followerID, followedID int) error
+	IsFollowingUser(followerID, followedID int) bool
+	CountFollowers(userID int) (int, error)
+	FollowCategory(userID int, category string) error
+	UnfollowCategory(userID int, category string) error
+	GetFollowedCategories(userID int) ([]string, error)
+	GetFeedThreads(userID, limit, offset int) ([]models.Thread, error)
+}
+
+// BookmarkStore keeps bookmarks, their collections and the API tokens
+// the bookmark API is used with
+type BookmarkStore interface {
+	CreateCollection(userID int, name string) (int64, error)
+	GetCollections(userID int) ([]models.Collection, error)
+	IsCollectionOwner(collectionID, userID int) bool
+	DeleteCollection(collectionID, userID int) error
+	SaveBookmark(userID, threadID, postID, collectionID int, note string) error
+	UpdateBookmark(bookmarkID, userID, collectionID int, note string) error
+	DeleteBookmark(bookmarkID, userID int) error
+	IsThreadBookmarked(userID, threadID int) bool
+	GetBookmarks(userID int, filter models.BookmarkFilter) ([]models.Bookmark, error)
+	SetAPITokenHash(userID int, tokenHash string) error
+	DeleteAPIToken(userID int) error
+	HasAPIToken(userID int) bool
+	GetUserIDByTokenHash(tokenHash string) (int, error)
+}
+
+// DraftStore keeps the composer drafts, one per user and thread
+type DraftStore interface {
+	SaveDraft(userID int, draft *models.Draft) error
+	GetDraft(userID, threadID int) (models.Draft, error)
+	DeleteDraft(userID, threadID int) error
+	GetDrafts(userID int) ([]models.Draft, error)
+}
+
+// TagStore keeps the tags of threads and their synonyms
+type TagStore interface {
+	SetThreadTags(threadID int, names []string) error
+	GetThreadTags(threadID int) ([]string, error)
+	GetTagsForThreads(threadIDs []int) (map[int][]string, error)
+	GetTaggedThreadIDs(name string) (map[int]bool, error)
+	GetTag(name string) (models.Tag, error)
+	SearchTags(prefix string, limit int) ([]models.Tag, error)
+	GetTagSynonym(alias string) (string, error)
+	AddTagSynonym(alias, tag string, userID int) error
+	DeleteTagSynonym(alias string) error
+	GetTagSynonyms() ([]models.TagSynonym, error)
+}
+
+// AccountStore changes the role, status and password of accounts, ends
+// their sessions and closes them
+type AccountStore interface {
+	SetUserRole(userID int, role string) error
+	SetUserStatus(userID int, status string) error
+	SetUserPassword(userID int, password string) error
+	CountUsersByStatus() (map[string]int, error)
+	GetUserSessions(userID int) ([]models.Session, error)
+	DeleteUserSessions(userID int) (int64, error)
+	DeleteSessionsBefore(before time.Time) (int64, error)
+	AnonymizeUser(userID int, removeContent bool) error
+}
+
+// SchemaStore looks up and creates the tables and columns of the schema
+type SchemaStore interface {
+	HasTable(table string) (bool, error)
+	HasColumn(table, column string) (bool, error)
+	ExecSchema(stmt string) error
+}
+
+// MaintenanceStore checks the database as a whole
+type MaintenanceStore interface {
+	SchemaStore
+	Ping() error
+	IntegrityCheck() error
+	ForeignKeyViolations(refs []Reference) (map[string]int, error)
+}
+
+// BackupStore copies the live database to and from a snapshot file
+type BackupStore interface {
+	BackupTo(ctx context.Context, path string) error
+	RestoreFrom(ctx context.Context, path string) error
+}
+
+var (
+	_ ThreadStore  = (*DatabaseManager)(nil)
+	_ PostStore    = (*DatabaseManager)(nil)
+	_ UserStore    = (*DatabaseManager)(nil)
+	_ SessionStore = (*DatabaseManager)(nil)
+	_ VoteStore    = (*DatabaseManager)(nil)
+	_ AuditStore   = (*DatabaseManager)(nil)
+	_ FeedStore    = (*DatabaseManager)(nil)
+
+	_ StatisticsStore = (*DatabaseManager)(nil)
+	_ ReadStore       = (*DatabaseManager)(nil)
+
+	_ WatchStore    = (*DatabaseManager)(nil)
+	_ BlockStore    = (*DatabaseManager)(nil)
+	_ MentionStore  = (*DatabaseManager)(nil)
+	_ MessageStore  = (*DatabaseManager)(nil)
+	_ ProfileStore  = (*DatabaseManager)(nil)
+	_ FollowStore   = (*DatabaseManager)(nil)
+	_ BookmarkStore = (*DatabaseManager)(nil)
+	_ DraftStore    = (*DatabaseManager)(nil)
+	_ TagStore      = (*DatabaseManager)(nil)
+
+	_ AccountStore     = (*DatabaseManager)(nil)
+	_ MaintenanceStore = (*DatabaseManager)(nil)
+	_ BackupStore      = (*DatabaseManager)(nil)
+)
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"forum/models"
+	"forum/utils"
+)
+
+// DigestJob collects new posts in watched threads and mails a summary
+// to every user whose daily or weekly digest is due
+type DigestJob struct {
+	Service  *Service
+	Sender   MailSender
+	BaseURL  string        // used to build thread and unsubscribe links
+	Interval time.Duration // how often due digests are checked
+}
+
+// Run checks for due digests every Interval until ctx is cancelled
+func (job DigestJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := job.RunOnce(now); err != nil {
+				utils.Warn("Digest run failed:", err)
+			}
+		}
+	}
+}
+
+// RunOnce sends every digest due at the given time
+func (job DigestJob) RunOnce(now time.Time) error {
+	candidates, err := job.Service.Watches.GetDigestCandidates()
+	if err != nil {
+		return err
+	}
+
+	for _, pref := range candidates {
+		period := digestPeriod(pref.Frequency)
+		if period == 0 || (!pref.LastSentAt.IsZero() && now.Sub(pref.LastSentAt) < period) {
+			continue
+		}
+
+		entries, err := job.Service.Watches.GetDigestEntries(pref.UserId, pref.LastSentAt)
+		if err != nil {
+			utils.Warn("Digest entries failed for user", pref.UserId, err)
+			continue
+		}
+		if len(entries) > 0 {
+			subject := fmt.Sprintf("Forum Talk %s digest: %d new posts", pref.Frequency, len(entries))
+			if err := job.Sender.Send(pref.Email, subject, job.composeDigest(pref, entries)); err != nil {
+				utils.Warn("Digest mail failed for user", pref.UserId, err)
+				continue // retry on the next run
+			}
+		}
+		if err := job.Service.Watches.MarkDigestSent(pref.UserId, now); err != nil {
+			utils.Warn("Cannot mark digest sent for user", pref.UserId, err)
+		}
+	}
+	return nil
+}
+
+func digestPeriod(frequency string) time.Duration {
+	switch frequency {
+	case DigestDaily:
+		return 24 * time.Hour
+	case DigestWeekly:
+		return 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+func (job DigestJob) composeDigest(pref models.DigestPreference, entries []models.DigestEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\nThere are new posts in threads you watch:\n", pref.Name)
+
+	lastThread := 0
+	for _, entry := range entries {
+		if entry.ThreadId != lastThread {
+			if lastThread != 0 {
+				fmt.Fprintf(&b, "Stop watching: %s\n", UnsubscribeURL(job.BaseURL, pref.UserId, lastThread))
+			}
+			fmt.Fprintf(&b, "\n== %s ==\n%s%s\n", entry.Topic, job.BaseURL, utils.ThreadPath(entry.ThreadId, entry.Topic))
+			lastThread = entry.ThreadId
+		}
+		body := entry.Body
+		if runes := []rune(body); len(runes) > 200 {
+			body = string(runes[:200]) + "..."
+		}
+		fmt.Fprintf(&b, "- %s (%s): %s\n", entry.Author, entry.CreatedAt.Format("Jan 2 15:04"), body)
+	}
+	fmt.Fprintf(&b, "Stop watching: %s\n", UnsubscribeURL(job.BaseURL, pref.UserId, lastThread))
+
+	fmt.Fprintf(&b, "\nStop all digests: %s\n", UnsubscribeURL(job.BaseURL, pref.UserId, 0))
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"forum/models"
+)
+
+// MaxDraftLength limits the stored topic and body, in characters
+const MaxDraftLength = 20000
+
+var ErrNoDraft = errors.New("no draft")
+
+// SaveDraft stores the composer content of a new thread (ThreadId 0)
+// or a reply, an empty draft is removed instead
+func (s *Service) SaveDraft(userID int, draft models.Draft) (models.Draft, error) {
+	if strings.TrimSpace(draft.Topic) == "" && strings.TrimSpace(draft.Body) == "" {
+		return draft, s.Drafts.DeleteDraft(userID, draft.ThreadId)
+	}
+	if utf8.RuneCountInString(draft.Topic) > MaxDraftLength || utf8.RuneCountInString(draft.Body) > MaxDraftLength {
+		return draft, fmt.Errorf("drafts can be at most %d characters", MaxDraftLength)
+	}
+	if draft.ThreadId != 0 {
+		if _, err := s.Threads.GetThreadByID(draft.ThreadId); err != nil {
+			return draft, errors.New("thread not found")
+		}
+		// replies have no topic or categories
+		draft.Topic, draft.Category1, draft.Category2 = "", "", ""
+	}
+	err := s.Drafts.SaveDraft(userID, &draft)
+	return draft, err
+}
+
+func (s *Service) Draft(userID, threadID int) (models.Draft, error) {
+	draft, err := s.Drafts.GetDraft(userID, threadID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return draft, ErrNoDraft
+	}
+	return draft, err
+}
+
+func (s *Service) UserDrafts(userID int) ([]models.Draft, error) {
+	drafts, err := s.Drafts.GetDrafts(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range drafts {
+		drafts[i].Body = excerpt(drafts[i].Body, excerptLength)
+	}
+	return drafts, nil
+}
+
+// DiscardDraft is called once the thread or reply was created
+func (s *Service) DiscardDraft(userID, threadID int) {
+	if err := s.Drafts.DeleteDraft(userID, threadID); err != nil {
+		slog.Error("DeleteDraft failed", "error", err)
+	}
+}
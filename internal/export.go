@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"forum/models"
+	"io"
+)
+
+// ExportUserData writes a zip with the profile, threads, replies, votes and
+// sessions of a user, one JSON file each
+func (s *Service) ExportUserData(userID int, w io.Writer) error {
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		name string
+		load func() (any, error)
+	}{
+		{"profile.json", func() (any, error) {
+			return models.ExportProfile{
+				Id: user.Id, Name: user.Name, Email: user.Email, Role: user.Role, Status: user.Status,
+				Bio: user.Bio, AvatarURL: user.AvatarURL, CreatedAt: user.CreatedAt,
+				PreferedCategory1: user.PreferedCategory1, PreferedCategory2: user.PreferedCategory2,
+			}, nil
+		}},
+		{"threads.json", func() (any, error) { return s.exportThreads(userID) }},
+		{"posts.json", func() (any, error) { return s.exportPosts(userID) }},
+		{"votes.json", func() (any, error) { return s.exportVotes(userID) }},
+		{"sessions.json", func() (any, error) { return s.exportSessions(userID) }},
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		content, err := file.load()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.name, err)
+		}
+		out, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func (s *Service) exportThreads(userID int) ([]models.ExportThread, error) {
+	threads, err := s.Threads.GetUserCreatedThreads(userID)
+	if err != nil {
+		return nil, err
+	}
+	s.AttachThreadTags(threads)
+	exported := []models.ExportThread{}
+	for _, t := range threads {
+		categories := []string{}
+		for _, category := range []string{t.Category1, t.Category2} {
+			if category != "" {
+				categories = append(categories, category)
+			}
+		}
+		tags := t.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		exported = append(exported, models.ExportThread{
+			Id: t.Id, Topic: t.Topic, Body: t.Body, Categories: categories, Tags: tags, CreatedAt: t.CreatedAt,
+		})
+	}
+	return exported, nil
+}
+
+func (s *Service) exportPosts(userID int) ([]models.ExportPost, error) {
+	posts, err := s.Posts.GetUserCreatedPosts(userID)
+	if err != nil {
+		return nil, err
+	}
+	exported := []models.ExportPost{}
+	for _, p := range posts {
+		exported = append(exported, models.ExportPost{Id: p.Id, ThreadId: p.ThreadId, Body: p.Body, CreatedAt: p.CreatedAt})
+	}
+	return exported, nil
+}
+
+func (s *Service) exportVotes(userID int) ([]models.ExportVote, error) {
+	votes := []models.ExportVote{}
+	threadLikes, err := s.Votes.GetUserLikedThreads(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range threadLikes {
+		votes = append(votes, models.ExportVote{Vote: "like", ThreadId: v.ThreadId})
+	}
+	threadDislikes, err := s.Votes.GetUserDislikedThreads(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range threadDislikes {
+		votes = append(votes, models.ExportVote{Vote: "dislike", ThreadId: v.ThreadId})
+	}
+	postLikes, err := s.Votes.GetUserLikedPosts(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range postLikes {
+		votes = append(votes, models.ExportVote{Vote: "like", PostId: v.PostId})
+	}
+	postDislikes, err := s.Votes.GetUserDislikedPosts(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range postDislikes {
+		votes = append(votes, models.ExportVote{Vote: "dislike", PostId: v.PostId})
+	}
+	return votes, nil
+}
+
+func (s *Service) exportSessions(userID int) ([]models.ExportSession, error) {
+	sessions, err := s.Accounts.GetUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	exported := []models.ExportSession{}
+	for _, session := range sessions {
+		exported = append(exported, models.ExportSession{
+			CreatedAt:  session.CreatedAt,
+			LastActive: fmt.Sprintf("%02d:%02d", session.ActiveLast/100, session.ActiveLast%100),
+		})
+	}
+	return exported, nil
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+
+	"forum/models"
+)
+
+// Categories offered when creating a thread (see new.thread.html)
+var Categories = []string{
+	"Sports", "Movies", "Games", "Other",
+	"AI-theme", "Tomorrow-school", "Creativity", "Miscellaneous",
+}
+
+// FeedPageSize is the number of threads on one page of "My feed"
+const FeedPageSize = 20
+
+func IsCategory(name string) bool {
+	for _, category := range Categories {
+		if category == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) FollowUser(followerID, followedID int) error {
+	if followerID == followedID {
+		return fmt.Errorf("cannot follow yourself")
+	}
+	return s.Follows.FollowUser(followerID, followedID)
+}
+
+func (s *Service) UnfollowUser(followerID, followedID int) error {
+	return s.Follows.UnfollowUser(followerID, followedID)
+}
+
+func (s *Service) IsFollowingUser(followerID, followedID int) bool {
+	return s.Follows.IsFollowingUser(followerID, followedID)
+}
+
+func (s *Service) FollowersCount(userID int) int {
+	count, err := s.Follows.CountFollowers(userID)
+	if err != nil {
+		slog.Error("CountFollowers failed", "error", err)
+		return 0
+	}
+	return count
+}
+
+func (s *Service) FollowCategory(userID int, category string) error {
+	if !IsCategory(category) {
+		return fmt.Errorf("unknown category %q", category)
+	}
+	return s.Follows.FollowCategory(userID, category)
+}
+
+func (s *Service) UnfollowCategory(userID int, category string) error {
+	return s.Follows.UnfollowCategory(userID, category)
+}
+
+func (s *Service) FollowedCategories(userID int) ([]string, error) {
+	return s.Follows.GetFollowedCategories(userID)
+}
+
+// Feed returns page (starting at 1) of the user's home feed and
+// whether a next page exists
+func (s *Service) Feed(userID, page int) ([]models.Thread, bool, error) {
+	if page < 1 {
+		page = 1
+	}
+	// one extra row tells whether there is a next page
+	threads, err := s.Follows.GetFeedThreads(userID, FeedPageSize+1, (page-1)*FeedPageSize)
+	if err != nil {
+		return nil, false, err
+	}
+	hasNext := len(threads) > FeedPageSize
+	if hasNext {
+		threads = threads[:FeedPageSize]
+	}
+	return threads, hasNext, nil
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"forum/internal/data"
+	"forum/utils"
+)
+
+// Health answers the readiness probe: the database is reachable and migrated,
+// templates are loaded and the data directories are writable.
+// It fails from the moment the server starts to shut down.
+type Health struct {
+	db       *data.DatabaseManager
+	dirs     []string
+	draining atomic.Bool
+}
+
+// NewHealth checks db and that each of dirs is writable
+func NewHealth(db *data.DatabaseManager, dirs ...string) *Health {
+	return &Health{db: db, dirs: dirs}
+}
+
+// Drain makes the readiness probe fail so traffic moves away before exit
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Ready runs every check and returns the result of each, ok when all passed
+func (h *Health) Ready() (ok bool, checks map[string]string) {
+	checks = map[string]string{}
+	ok = true
+	record := func(name string, err error) {
+		if err != nil {
+			ok = false
+			checks[name] = err.Error()
+			return
+		}
+		checks[name] = "ok"
+	}
+
+	if h.draining.Load() {
+		record("shutdown", errors.New("shutting down"))
+	}
+	dbErr := h.db.Ping()
+	record("database", dbErr)
+	if dbErr == nil {
+		record("migrations", CheckSchema(h.db))
+	}
+	record("templates", utils.TemplatesLoaded())
+	for _, dir := range h.dirs {
+		record("writable:"+dir, checkWritable(dir))
+	}
+	return ok, checks
+}
+
+// checkWritable creates and removes a file in dir
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("not writable: %w", err)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"forum/utils"
+)
+
+// MailSender delivers plain text emails; the digest job only depends on this
+type MailSender interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailSender writes emails to the log file instead of sending them,
+// useful for local development
+type LogMailSender struct{}
+
+func (LogMailSender) Send(to, subject, body string) error {
+	utils.Info("Mail to", to, "subject:", subject, "\n"+body)
+	return nil
+}
+
+// SMTPMailSender sends emails through an SMTP relay
+type SMTPMailSender struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (s SMTPMailSender) Send(to, subject, body string) error {
+	addr := fmt.Sprintf("%s:%d", s.Host, s.Port)
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return smtp.SendMail(addr, auth, s.From, []string{to}, []byte(msg.String()))
+}
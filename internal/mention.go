@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"forum/models"
+)
+
+// user names are letters and digits only, 3 to 20 characters (see SignupAccount)
+var mentionPattern = regexp.MustCompile(`@([A-Za-z0-9]{3,20})\b`)
+
+var lookupPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9]{1,20}$`)
+
+// findMentions returns [start, end, nameStart, nameEnd] index groups of every
+// @name that is not part of a word or an email address
+func findMentions(body string) [][]int {
+	var found [][]int
+	for _, loc := range mentionPattern.FindAllStringSubmatchIndex(body, -1) {
+		if loc[0] > 0 {
+			prev := body[loc[0]-1]
+			if prev == '_' || prev == '.' || prev == '-' || ('a' <= prev && prev <= 'z') ||
+				('A' <= prev && prev <= 'Z') || ('0' <= prev && prev <= '9') {
+				continue
+			}
+		}
+		found = append(found, loc)
+	}
+	return found
+}
+
+// MentionedNames returns every distinct name mentioned in body
+func MentionedNames(body string) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, loc := range findMentions(body) {
+		name := body[loc[2]:loc[3]]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// RecordMentions stores a mention for every active user named in body,
+// skipping the author and users blocking (or blocked by) the author.
+// postID is 0 when the mention is in the thread body.
+func (s *Service) RecordMentions(authorID, threadID, postID int, body string) {
+	for _, name := range MentionedNames(body) {
+		user, err := s.Users.GetUserByName(name)
+		if err != nil || user.Id == authorID || user.Status != "active" {
+			continue
+		}
+		if s.Blocks.IsBlockedEitherWay(user.Id, authorID) {
+			continue
+		}
+		if err := s.Mentions.CreateMention(user.Id, authorID, threadID, postID); err != nil {
+			slog.Error("CreateMention failed", "error", err)
+		}
+	}
+}
+
+// RenderMentions turns every @name that belongs to an existing user
+// into a link to that user's profile
+func (s *Service) RenderMentions(body string) string {
+	locs := findMentions(body)
+	if len(locs) == 0 {
+		return body
+	}
+
+	resolved := map[string]bool{}
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		name := body[loc[2]:loc[3]]
+		exists, ok := resolved[name]
+		if !ok {
+			_, err := s.Users.GetUserByName(name)
+			exists = err == nil
+			resolved[name] = exists
+		}
+		if !exists {
+			continue
+		}
+		b.WriteString(body[last:loc[0]])
+		fmt.Fprintf(&b, `<a class="mention" href="/u/%s">@%s</a>`, name, html.EscapeString(name))
+		last = loc[1]
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
+
+// LookupUsers powers composer autocomplete
+func (s *Service) LookupUsers(prefix string, viewerID int) ([]models.UserLookup, error) {
+	if !lookupPrefixPattern.MatchString(prefix) {
+		return []models.UserLookup{}, nil
+	}
+	return s.Mentions.LookupUsersByPrefix(prefix, viewerID, 10)
+}
+
+func (s *Service) UserMentions(userID int) ([]models.Mention, error) {
+	return s.Mentions.GetUserMentions(userID, 50)
+}
+
+func (s *Service) UnseenMentionsCount(userID int) int {
+	count, err := s.Mentions.CountUnseenMentions(userID)
+	if err != nil {
+		slog.Error("CountUnseenMentions failed", "error", err)
+		return 0
+	}
+	return count
+}
+
+func (s *Service) MarkMentionsSeen(userID int) error {
+	return s.Mentions.MarkMentionsSeen(userID)
+}
+
+func (s *Service) BlockUser(blockerID, blockedID int) error {
+	if blockerID == blockedID {
+		return fmt.Errorf("cannot block yourself")
+	}
+	return s.Blocks.BlockUser(blockerID, blockedID)
+}
+
+func (s *Service) UnblockUser(blockerID, blockedID int) error {
+	return s.Blocks.UnblockUser(blockerID, blockedID)
+}
+
+func (s *Service) HasBlocked(blockerID, blockedID int) bool {
+	return s.Blocks.HasBlocked(blockerID, blockedID)
+}
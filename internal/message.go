@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"forum/models"
+)
+
+// MaxConversationMembers limits group conversations, the creator included
+const MaxConversationMembers = 8
+
+// reportContextSize is how many earlier messages a moderator sees with a report
+const reportContextSize = 5
+
+var (
+	ErrNotConversationMember = errors.New("you are not a member of this conversation")
+	ErrEmptyMessage          = errors.New("message body is required")
+)
+
+// StartConversation resolves the comma or space separated recipient names
+// and creates the conversation with its first message
+func (s *Service) StartConversation(creator models.User, recipients, subject, body string) (int64, error) {
+	if strings.TrimSpace(body) == "" {
+		return 0, ErrEmptyMessage
+	}
+
+	var memberIDs []int
+	seen := map[int]bool{creator.Id: true}
+	for _, name := range strings.FieldsFunc(recipients, func(r rune) bool { return r == ',' || r == ' ' }) {
+		name = strings.TrimPrefix(name, "@")
+		user, err := s.Users.GetUserByName(name)
+		if err != nil || user.Status != "active" {
+			return 0, fmt.Errorf("unknown user %q", name)
+		}
+		if seen[user.Id] {
+			continue
+		}
+		if s.Blocks.IsBlockedEitherWay(creator.Id, user.Id) {
+			return 0, fmt.Errorf("you cannot message %s", user.Name)
+		}
+		seen[user.Id] = true
+		memberIDs = append(memberIDs, user.Id)
+	}
+
+	if len(memberIDs) == 0 {
+		return 0, errors.New("at least one recipient is required")
+	}
+	if len(memberIDs)+1 > MaxConversationMembers {
+		return 0, fmt.Errorf("a conversation can have at most %d members", MaxConversationMembers)
+	}
+	return s.Messages.CreateConversation(creator.Id, memberIDs, strings.TrimSpace(subject), body)
+}
+
+func (s *Service) Inbox(userID int) ([]models.Conversation, error) {
+	return s.Messages.GetUserConversations(userID)
+}
+
+func (s *Service) UnreadMessagesCount(userID int) int {
+	count, err := s.Messages.CountUnreadMessages(userID)
+	if err != nil {
+		slog.Error("CountUnreadMessages failed", "error", err)
+		return 0
+	}
+	return count
+}
+
+// OpenConversation returns a conversation for one of its members
+// and moves their read marker to the latest message
+func (s *Service) OpenConversation(conversationID, userID int) (models.Conversation, []models.Message, error) {
+	if !s.Messages.IsConversationMember(conversationID, userID) {
+		return models.Conversation{}, nil, ErrNotConversationMember
+	}
+	conversation, err := s.Messages.GetConversation(conversationID)
+	if err != nil {
+		return conversation, nil, err
+	}
+	conversation.Muted = s.Messages.IsConversationMuted(conversationID, userID)
+
+	messages, err := s.Messages.GetConversationMessages(conversationID)
+	if err != nil {
+		return conversation, nil, err
+	}
+	if len(messages) > 0 {
+		if err := s.Messages.MarkConversationRead(conversationID, userID, messages[len(messages)-1].Id); err != nil {
+			slog.Error("MarkConversationRead failed", "error", err)
+		}
+	}
+	return conversation, messages, nil
+}
+
+func (s *Service) ReplyToConversation(conversationID, userID int, body string) error {
+	if strings.TrimSpace(body) == "" {
+		return ErrEmptyMessage
+	}
+	if !s.Messages.IsConversationMember(conversationID, userID) {
+		return ErrNotConversationMember
+	}
+	_, err := s.Messages.CreateMessage(conversationID, userID, body)
+	return err
+}
+
+func (s *Service) LeaveConversation(conversationID, userID int) error {
+	if !s.Messages.IsConversationMember(conversationID, userID) {
+		return ErrNotConversationMember
+	}
+	return s.Messages.LeaveConversation(conversationID, userID)
+}
+
+func (s *Service) MuteConversation(conversationID, userID int, muted bool) error {
+	if !s.Messages.IsConversationMember(conversationID, userID) {
+		return ErrNotConversationMember
+	}
+	return s.Messages.SetConversationMuted(conversationID, userID, muted)
+}
+
+// ReportMessage lets a member flag a message for moderators,
+// this is the only way moderators get to read private messages
+func (s *Service) ReportMessage(messageID, reporterID int, reason string) (models.Message, error) {
+	message, err := s.Messages.GetMessageByID(messageID)
+	if err != nil || !s.Messages.IsConversationMember(message.ConversationId, reporterID) {
+		// a missing message and someone else's message look the same
+		return message, ErrNotConversationMember
+	}
+	return message, s.Messages.CreateMessageReport(messageID, message.ConversationId, reporterID, strings.TrimSpace(reason))
+}
+
+func (s *Service) OpenMessageReports() ([]models.MessageReport, error) {
+	return s.Messages.GetMessageReports("open")
+}
+
+// ReviewMessageReport loads the reported message with a few earlier
+// messages for context and records the access in the audit log
+func (s *Service) ReviewMessageReport(reportID int, moderator models.User) (models.MessageReport, error) {
+	if !moderator.IsModerator() {
+		return models.MessageReport{}, errors.New("moderator role required")
+	}
+	report, err := s.Messages.GetMessageReport(reportID)
+	if err != nil {
+		return report, err
+	}
+
+	detail := fmt.Sprintf("report=%d message=%d conversation=%d", report.Id, report.MessageId, report.ConversationId)
+	if err := s.Audit.WriteAudit(moderator.Id, "view_message_report", detail); err != nil {
+		// no audit entry, no access
+		return models.MessageReport{}, err
+	}
+
+	report.Message, err = s.Messages.GetMessageByID(report.MessageId)
+	if err != nil {
+		return report, err
+	}
+	report.Context, err = s.Messages.GetMessagesBefore(report.ConversationId, report.MessageId, reportContextSize)
+	return report, err
+}
+
+func (s *Service) ResolveMessageReport(reportID int, moderator models.User) error {
+	if !moderator.IsModerator() {
+		return errors.New("moderator role required")
+	}
+	if err := s.Messages.ResolveMessageReport(reportID, moderator.Id); err != nil {
+		return err
+	}
+	return s.Audit.WriteAudit(moderator.Id, "resolve_message_report", fmt.Sprintf("report=%d", reportID))
+}
@@ -0,0 +1,18 @@
+package internal
+
+import "forum/internal/metrics"
+
+// onlineMinutes is how recently a user must have been active to count as online
+const onlineMinutes = 10
+
+// registerGauges reads sessions and online users from the Service at scrape time
+func (s *Service) registerGauges() {
+	metrics.SetGaugeFunc("forum_sessions_active", "Signed in sessions.", func() (float64, error) {
+		count, err := s.Sessions.CountSessions()
+		return float64(count), err
+	})
+	metrics.SetGaugeFunc("forum_users_online", "Users active in the last 10 minutes.", func() (float64, error) {
+		users, err := s.Sessions.CheckOnlineUsers(onlineMinutes)
+		return float64(len(users)), err
+	})
+}
@@ -0,0 +1,22 @@
+package metrics
+
+// The metrics of the forum, gauges for sessions and online users
+// are set by the internal package once the stores are ready
+var (
+	HTTPRequests = NewCounter("forum_http_requests_total",
+		"HTTP requests by route, method and status.", "route", "method", "status")
+	HTTPDuration = NewHistogram("forum_http_request_duration_seconds",
+		"HTTP request latency by route.", nil, "route")
+
+	DBQueries = NewCounter("forum_db_queries_total",
+		"Database statements by operation.", "op")
+	DBDuration = NewHistogram("forum_db_query_duration_seconds",
+		"Database statement latency by operation.", nil, "op")
+
+	TemplateRender = NewHistogram("forum_template_render_seconds",
+		"Template render time by page.", nil, "page")
+
+	ThreadsCreated = NewCounter("forum_threads_created_total", "Threads created.")
+	PostsCreated   = NewCounter("forum_posts_created_total", "Posts created.")
+	VotesCast      = NewCounter("forum_votes_total", "Votes cast by target and kind.", "target", "kind")
+)
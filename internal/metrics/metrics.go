@@ -0,0 +1,261 @@
+// Package metrics keeps counters, histograms and gauges in memory and
+// writes them in the Prometheus text format, without a client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the latency buckets in seconds
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+type metric interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds the metrics written by Write
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// Default is the registry of the metrics declared in this package
+var Default = &Registry{}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.metrics {
+		if existing.name() == m.name() {
+			r.metrics[i] = m
+			return
+		}
+	}
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric in the Prometheus text exposition format
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// vec keeps one value per combination of label values
+type vec[T any] struct {
+	mu     sync.Mutex
+	labels []string
+	series map[string]*T
+	values map[string][]string
+}
+
+func newVec[T any](labels []string) vec[T] {
+	return vec[T]{labels: labels, series: map[string]*T{}, values: map[string][]string{}}
+}
+
+func (v *vec[T]) get(labelValues []string, init func() *T) *T {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: want %d label values, got %d", len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = init()
+		v.series[key] = s
+		v.values[key] = append([]string(nil), labelValues...)
+	}
+	return s
+}
+
+// sortedKeys returns the series keys in a stable order for output
+func (v *vec[T]) sortedKeys() []string {
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *vec[T]) labelString(key string, extra ...string) string {
+	var pairs []string
+	for i, l := range v.labels {
+		pairs = append(pairs, l+`="`+escapeLabel(v.values[key][i])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+extra[i+1]+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, +1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// Counter is a value that only goes up, per combination of labels
+type Counter struct {
+	metricName, help string
+	vec[float64]
+}
+
+// NewCounter declares a counter in the Default registry
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{metricName: name, help: help, vec: newVec[float64](labels)}
+	Default.register(c)
+	return c
+}
+
+// Inc adds one for the label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v for the label values
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.get(labelValues, func() *float64 { return new(float64) }) += v
+}
+
+// Value returns the current count for the label values
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.series[strings.Join(labelValues, "\xff")]; ok {
+		return *s
+	}
+	return 0
+}
+
+func (c *Counter) name() string { return c.metricName }
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeHeader(w, c.metricName, c.help, "counter")
+	for _, key := range c.sortedKeys() {
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, c.labelString(key), formatFloat(*c.series[key]))
+	}
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// Histogram counts observations into buckets, per combination of labels
+type Histogram struct {
+	metricName, help string
+	buckets          []float64
+	vec[histogramSeries]
+}
+
+// NewHistogram declares a histogram in the Default registry,
+// nil buckets are DefaultBuckets
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	h := &Histogram{metricName: name, help: help, buckets: buckets, vec: newVec[histogramSeries](labels)}
+	Default.register(h)
+	return h
+}
+
+// Observe records v (seconds for durations) for the label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.get(labelValues, func() *histogramSeries {
+		return &histogramSeries{counts: make([]uint64, len(h.buckets))}
+	})
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// Count returns the number of observations for the label values
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[strings.Join(labelValues, "\xff")]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *Histogram) name() string { return h.metricName }
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.metricName, h.help, "histogram")
+	for _, key := range h.sortedKeys() {
+		s := h.series[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.labelString(key, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.labelString(key, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, h.labelString(key), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, h.labelString(key), s.count)
+	}
+}
+
+// gaugeFunc is a gauge read when the metrics are written
+type gaugeFunc struct {
+	metricName, help string
+	fn               func() (float64, error)
+}
+
+// SetGaugeFunc declares a gauge whose value comes from fn at scrape time,
+// declaring the same name again replaces the function.
+// A gauge whose fn fails is left out of the output.
+func SetGaugeFunc(name, help string, fn func() (float64, error)) {
+	Default.register(&gaugeFunc{metricName: name, help: help, fn: fn})
+}
+
+func (g *gaugeFunc) name() string { return g.metricName }
+
+func (g *gaugeFunc) write(w io.Writer) {
+	v, err := g.fn()
+	if err != nil {
+		return
+	}
+	writeHeader(w, g.metricName, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.metricName, formatFloat(v))
+}
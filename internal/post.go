@@ -1,41 +1,39 @@
 package internal
 
 import (
-	"fmt"
-	"forum/internal/data"
+	"forum/internal/metrics"
 	"forum/models"
+	"log/slog"
 )
 
-var postDM *data.DatabaseManager
-
-func InitPostDM(dm *data.DatabaseManager) {
-	postDM = dm
-}
-
-func CreatePost(threadID int, body string, userID int) (int64, error) {
-	return postDM.CreatePostByUser(body, userID, threadID)
+func (s *Service) CreatePost(threadID int, body string, userID int) (int64, error) {
+	id, err := s.Posts.CreatePostByUser(body, userID, threadID)
+	if err == nil {
+		metrics.PostsCreated.Inc()
+	}
+	return id, err
 }
 
-func GetLikes(postID int) (int, error) {
-	return postDM.GetPostLikesCount(postID)
+func (s *Service) GetLikes(postID int) (int, error) {
+	return s.Votes.GetPostLikesCount(postID)
 }
 
-func GetDislikes(postID int) (int, error) {
-	return postDM.GetPostDislikesCount(postID)
+func (s *Service) GetDislikes(postID int) (int, error) {
+	return s.Votes.GetPostDislikesCount(postID)
 }
 
-func LikeOnPostCreation(userID, postID int) error {
-	return postDM.CreatePostLikeOnCreation(userID, postID)
+func (s *Service) LikeOnPostCreation(userID, postID int) error {
+	return s.Votes.AddPostLike(userID, postID)
 }
 
-func DislikeOnPostCreation(userID, postID int) error {
-	return postDM.CreatePostDislikeOnCreation(userID, postID)
+func (s *Service) DislikeOnPostCreation(userID, postID int) error {
+	return s.Votes.AddPostDislike(userID, postID)
 }
 
-func PrepareLikedPosts(userID int, postID int) bool {
-	likes, err := postDM.GetUserLikedPosts(userID)
+func (s *Service) PrepareLikedPosts(userID int, postID int) bool {
+	likes, err := s.Votes.GetUserLikedPosts(userID)
 	if err != nil {
-		fmt.Println("Error on PrepareLikedPosts")
+		slog.Error("PrepareLikedPosts failed")
 		return false
 	}
 
@@ -48,10 +46,10 @@ func PrepareLikedPosts(userID int, postID int) bool {
 }
 
 // true if user disliked this post
-func PrepareDislikedPosts(userID, postID int) bool {
-	dislikes, err := postDM.GetUserDislikedPosts(userID)
+func (s *Service) PrepareDislikedPosts(userID, postID int) bool {
+	dislikes, err := s.Votes.GetUserDislikedPosts(userID)
 	if err != nil {
-		fmt.Println("Error on PrepareDislikedPosts")
+		slog.Error("PrepareDislikedPosts failed")
 		return false
 	}
 
@@ -63,17 +61,17 @@ func PrepareDislikedPosts(userID, postID int) bool {
 	return false
 }
 
-func GetLikesPostsFromDB(likes []models.Likes) ([]models.Post, error) {
+func (s *Service) GetLikesPostsFromDB(likes []models.Likes) ([]models.Post, error) {
 	// This function should get full post objects from the likes
 	var posts []models.Post
 	for _, like := range likes {
-		post, err := postDM.GetPostByID(like.PostId)
+		post, err := s.Posts.GetPostByID(like.PostId)
 		if err != nil {
 			continue // Skip posts that can't be found
 		}
 
 		// Populate User information for the post
-		user, err := userDM.GetPostUser(post.UserId)
+		user, err := s.Users.GetUserByID(post.UserId)
 		if err != nil {
 			// If user loading fails, create a placeholder
 			post.User = "Unknown User"
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"forum/models"
+)
+
+const (
+	MaxBioLength       = 500
+	recentActivitySize = 10
+	excerptLength      = 140
+)
+
+var ErrProfileNotFound = errors.New("profile not found")
+
+// UserProfile builds the public profile of name as seen by viewer,
+// viewer is nil for guests. The email is only visible to the owner and admins.
+func (s *Service) UserProfile(name string, viewer *models.User) (models.Profile, error) {
+	user, err := s.Users.GetUserByName(name)
+	if err != nil || user.Status == "deleted" {
+		return models.Profile{}, ErrProfileNotFound
+	}
+
+	profile := models.Profile{
+		Id:        user.Id,
+		Name:      user.Name,
+		Role:      user.Role,
+		Bio:       user.Bio,
+		AvatarURL: user.AvatarURL,
+		JoinedAt:  user.CreatedAt,
+	}
+	if viewer != nil {
+		profile.IsOwner = viewer.Id == user.Id
+		if profile.IsOwner || viewer.IsAdmin() {
+			profile.Email = user.Email
+		}
+		if !profile.IsOwner {
+			profile.CanBlock = true
+			profile.Following = s.Follows.IsFollowingUser(viewer.Id, user.Id)
+			profile.Blocked = s.Blocks.HasBlocked(viewer.Id, user.Id)
+			profile.CanMessage = user.Status == "active" && !s.Blocks.IsBlockedEitherWay(viewer.Id, user.Id)
+		}
+	}
+
+	profile.ThreadCount, profile.PostCount, profile.LikesReceived, err = s.Profiles.GetProfileCounts(user.Id)
+	if err != nil {
+		return profile, err
+	}
+	profile.Followers, err = s.Follows.CountFollowers(user.Id)
+	if err != nil {
+		return profile, err
+	}
+	profile.Recent, err = s.Profiles.GetRecentActivity(user.Id, recentActivitySize)
+	if err != nil {
+		return profile, err
+	}
+	for i := range profile.Recent {
+		profile.Recent[i].Excerpt = excerpt(profile.Recent[i].Excerpt, excerptLength)
+	}
+	return profile, nil
+}
+
+// UpdateProfile saves the bio and avatar of a user, the avatar must be
+// an absolute http(s) image address or empty
+func (s *Service) UpdateProfile(userID int, bio, avatarURL string) error {
+	bio = strings.TrimSpace(bio)
+	if utf8.RuneCountInString(bio) > MaxBioLength {
+		return fmt.Errorf("bio can be at most %d characters", MaxBioLength)
+	}
+
+	avatarURL = strings.TrimSpace(avatarURL)
+	if avatarURL != "" {
+		u, err := url.Parse(avatarURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(avatarURL) > 255 {
+			return errors.New("avatar must be an http or https address")
+		}
+	}
+	return s.Profiles.UpdateProfile(userID, bio, avatarURL)
+}
+
+// excerpt shortens text to at most n runes
+func excerpt(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	return string([]rune(text)[:n]) + "..."
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"context"
+	"forum/models"
+	"forum/utils"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ViewWindow is how long repeated visits of the same viewer count as one view
+const ViewWindow = 30 * time.Minute
+
+// viewKey is one viewer of one thread
+type viewKey struct {
+	threadID int
+	viewer   string
+}
+
+// viewCounter keeps views in memory until they are flushed, so reading a
+// thread never waits for the SQLite write lock. Its maps are made on the
+// first view.
+type viewCounter struct {
+	mu      sync.Mutex
+	seen    map[viewKey]time.Time
+	pending map[int]int
+}
+
+// RecordThreadView counts a visit of viewer, a user or a guest address,
+// unless they already viewed the thread within ViewWindow
+func (s *Service) RecordThreadView(threadID int, viewer string, now time.Time) {
+	s.views.mu.Lock()
+	defer s.views.mu.Unlock()
+	if s.views.seen == nil {
+		s.views.seen, s.views.pending = map[viewKey]time.Time{}, map[int]int{}
+	}
+	key := viewKey{threadID, viewer}
+	if last, ok := s.views.seen[key]; ok && now.Sub(last) < ViewWindow {
+		return
+	}
+	s.views.seen[key] = now
+	s.views.pending[threadID]++
+}
+
+// PendingThreadViews returns the views of a thread not flushed yet
+func (s *Service) PendingThreadViews(threadID int) int {
+	s.views.mu.Lock()
+	defer s.views.mu.Unlock()
+	return s.views.pending[threadID]
+}
+
+// FlushThreadViews writes the pending views and forgets viewers whose window
+// has passed. Views that fail to be written are kept for the next flush.
+func (s *Service) FlushThreadViews(now time.Time) error {
+	s.views.mu.Lock()
+	pending := s.views.pending
+	s.views.pending = map[int]int{}
+	for key, last := range s.views.seen {
+		if now.Sub(last) >= ViewWindow {
+			delete(s.views.seen, key)
+		}
+	}
+	s.views.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	err := s.Reads.AddThreadViews(pending)
+	if err != nil {
+		s.views.mu.Lock()
+		for threadID, count := range pending {
+			s.views.pending[threadID] += count
+		}
+		s.views.mu.Unlock()
+	}
+	return err
+}
+
+// ViewJob writes the views counted by Service every Interval
+type ViewJob struct {
+	Service  *Service
+	Interval time.Duration
+}
+
+// Run flushes the views every Interval until ctx is cancelled, the last
+// views are flushed by whoever closes the database
+func (job ViewJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := job.Service.FlushThreadViews(now); err != nil {
+				utils.Warn("Cannot write thread views:", err)
+			}
+		}
+	}
+}
+
+// ThreadViews returns the written and pending views of a thread
+func (s *Service) ThreadViews(threadID int) int {
+	count, err := s.Reads.GetThreadViews(threadID)
+	if err != nil {
+		slog.Error("GetThreadViews failed", "error", err)
+	}
+	return count + s.PendingThreadViews(threadID)
+}
+
+// MarkThreadRead records that a user has seen a thread up to lastPostID
+func (s *Service) MarkThreadRead(userID, threadID, lastPostID int) error {
+	return s.Reads.MarkThreadRead(userID, threadID, lastPostID)
+}
+
+// MarkAllThreadsRead records that a user has seen every thread
+func (s *Service) MarkAllThreadsRead(userID int) error {
+	return s.Reads.MarkAllThreadsRead(userID)
+}
+
+// AttachReadState fills the views of every thread and, for a user other
+// than 0, the replies they have not read yet
+func (s *Service) AttachReadState(threads []models.Thread, userID int) {
+	ids := make([]int, len(threads))
+	for i := range threads {
+		ids[i] = threads[i].Id
+	}
+	states, err := s.Reads.GetThreadReadStates(userID, ids)
+	if err != nil {
+		slog.Error("GetThreadReadStates failed", "error", err)
+		return
+	}
+	for i := range threads {
+		state := states[threads[i].Id]
+		threads[i].Views = state.Views + s.PendingThreadViews(threads[i].Id)
+		threads[i].Unread = state.Unread
+		threads[i].FirstUnread = state.FirstUnread
+	}
+}
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"forum/internal/data"
+	"forum/internal/metrics"
+	"forum/models"
+)
+
+// Service holds the stores the forum logic reads and writes through.
+// Handlers get it from the request context, jobs and the command line
+// are given the one InitAllDatabaseManagers returns.
+type Service struct {
+	Threads  data.ThreadStore
+	Posts    data.PostStore
+	Users    data.UserStore
+	Sessions data.SessionStore
+	Votes    data.VoteStore
+	Audit    data.AuditStore
+	Feeds    data.FeedStore
+	Stats    data.StatisticsStore
+	Reads    data.ReadStore
+
+	Watches   data.WatchStore
+	Blocks    data.BlockStore
+	Mentions  data.MentionStore
+	Messages  data.MessageStore
+	Profiles  data.ProfileStore
+	Follows   data.FollowStore
+	Bookmarks data.BookmarkStore
+	Drafts    data.DraftStore
+	Tags      data.TagStore
+
+	Accounts    data.AccountStore
+	Maintenance data.MaintenanceStore
+	Backups     data.BackupStore
+
+	views viewCounter // thread views not written to Reads yet
+}
+
+// NewService returns a Service keeping everything in the SQLite database
+func NewService(dm *data.DatabaseManager) *Service {
+	return &Service{
+		Threads: dm, Posts: dm, Users: dm, Sessions: dm, Votes: dm,
+		Audit: dm, Feeds: dm, Stats: dm, Reads: dm,
+		Watches: dm, Blocks: dm, Mentions: dm, Messages: dm, Profiles: dm,
+		Follows: dm, Bookmarks: dm, Drafts: dm, Tags: dm,
+		Accounts: dm, Maintenance: dm, Backups: dm,
+	}
+}
+
+// ToggleThreadVote likes (or dislikes) a thread. Voting the same way again
+// takes the vote back, voting the other way replaces it.
+func (s *Service) ToggleThreadVote(userID, threadID int, like bool) error {
+	liked := s.Votes.HasThreadLiked(userID, threadID)
+	disliked := s.Votes.HasThreadDisliked(userID, threadID)
+
+	if like && liked {
+		return s.Votes.RemoveThreadLike(userID, threadID)
+	}
+	if !like && disliked {
+		return s.Votes.RemoveThreadDislike(userID, threadID)
+	}
+	if like {
+		if disliked {
+			if err := s.Votes.RemoveThreadDislike(userID, threadID); err != nil {
+				return err
+			}
+		}
+		return countVote("thread", "like", s.Votes.AddThreadLike(userID, threadID))
+	}
+	if liked {
+		if err := s.Votes.RemoveThreadLike(userID, threadID); err != nil {
+			return err
+		}
+	}
+	return countVote("thread", "dislike", s.Votes.AddThreadDislike(userID, threadID))
+}
+
+// TogglePostVote is ToggleThreadVote for replies
+func (s *Service) TogglePostVote(userID, postID int, like bool) error {
+	liked, err := s.Votes.HasUserLikedPost(userID, postID)
+	if err != nil {
+		return err
+	}
+	disliked, err := s.Votes.HasUserDislikedPost(userID, postID)
+	if err != nil {
+		return err
+	}
+
+	if like && liked {
+		return s.Votes.RemovePostLike(userID, postID)
+	}
+	if !like && disliked {
+		return s.Votes.RemovePostDislike(userID, postID)
+	}
+	if like {
+		if disliked {
+			if err := s.Votes.RemovePostDislike(userID, postID); err != nil {
+				return err
+			}
+		}
+		return countVote("post", "like", s.Votes.AddPostLike(userID, postID))
+	}
+	if liked {
+		if err := s.Votes.RemovePostLike(userID, postID); err != nil {
+			return err
+		}
+	}
+	return countVote("post", "dislike", s.Votes.AddPostDislike(userID, postID))
+}
+
+// countVote counts a vote that was stored for /metrics
+func countVote(target, kind string, err error) error {
+	if err == nil {
+		metrics.VotesCast.Inc(target, kind)
+	}
+	return err
+}
+
+// ThreadVotes returns the vote counts of a thread and how userID voted,
+// userID is 0 for guests
+func (s *Service) ThreadVotes(userID, threadID int) models.ThreadVoteStatus {
+	status := models.ThreadVoteStatus{}
+	status.Likes, _ = s.Votes.GetThreadLikesCount(threadID)
+	status.Dislikes, _ = s.Votes.GetThreadDislikesCount(threadID)
+	if userID > 0 {
+		status.UserLiked = s.Votes.HasThreadLiked(userID, threadID)
+		status.UserDisliked = s.Votes.HasThreadDisliked(userID, threadID)
+	}
+	return status
+}
+
+// PostVotes is ThreadVotes for replies
+func (s *Service) PostVotes(userID, postID int) models.ThreadVoteStatus {
+	status := models.ThreadVoteStatus{}
+	status.Likes, _ = s.Votes.GetPostLikesCount(postID)
+	status.Dislikes, _ = s.Votes.GetPostDislikesCount(postID)
+	if userID > 0 {
+		status.UserLiked, _ = s.Votes.HasUserLikedPost(userID, postID)
+		status.UserDisliked, _ = s.Votes.HasUserDislikedPost(userID, postID)
+	}
+	return status
+}
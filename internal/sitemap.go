@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/url"
+	"time"
+
+	"forum/utils"
+)
+
+// sitemapLimit is the number of URLs a single sitemap file may hold
+const sitemapLimit = 50000
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap lists the public pages: the index, threads, tags and profiles
+func (s *Service) Sitemap() ([]byte, error) {
+	base := utils.BaseURL()
+	set := sitemapURLSet{URLs: []sitemapURL{{Loc: base + "/"}}}
+
+	threads, err := s.Feeds.GetSitemapThreads(sitemapLimit)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range threads {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     base + utils.ThreadPath(t.ThreadId, t.Topic),
+			LastMod: t.LastModified.UTC().Format(time.RFC3339),
+		})
+	}
+
+	tags, err := s.Tags.SearchTags("", sitemapLimit)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		set.URLs = append(set.URLs, sitemapURL{Loc: base + "/tag/" + url.PathEscape(tag.Name)})
+	}
+
+	names, err := s.Feeds.GetActiveUserNames(sitemapLimit)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		set.URLs = append(set.URLs, sitemapURL{Loc: base + "/u/" + url.PathEscape(name)})
+	}
+
+	if len(set.URLs) > sitemapLimit {
+		set.URLs = set.URLs[:sitemapLimit]
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(set); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
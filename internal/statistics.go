@@ -1,30 +1,73 @@
 package internal
 
 import (
-	"forum/internal/data"
+	"errors"
 	"forum/models"
+	"time"
 )
 
-// statistics DatabaseManager instance for statistics operations
-var statsDM *data.DatabaseManager
+// DashboardListSize is how many users and threads the dashboard ranks
+const DashboardListSize = 10
 
-// InitStatsDM initializes the global DatabaseManager for statistics operations
-func InitStatsDM(dm *data.DatabaseManager) {
-	statsDM = dm
+// MaxDashboardDays bounds the dashboard range
+const MaxDashboardDays = 366
+
+// ErrInvalidRange is returned for a range ending before it starts or longer than MaxDashboardDays
+var ErrInvalidRange = errors.New("invalid date range")
+
+func (s *Service) UserCount() (int, error) {
+	return s.Users.GetUserCount()
 }
 
-func UserCount() (int, error) {
-	return statsDM.GetUserCount()
+func (s *Service) MostActiveUsers(limit int) (users []models.User, err error) {
+	return s.Users.GetMostActiveUsers(limit)
 }
 
-func MostActiveUsers(limit int) (users []models.User, err error) {
-	return statsDM.GetMostActiveUsers(limit)
+func (s *Service) TotalPostsCount() (int, error) {
+	return s.Posts.GetTotalPostsCount()
 }
 
-func TotalPostsCount() (int, error) {
-	return statsDM.GetTotalPostsCount()
+func (s *Service) TotalThreadsCount() (int, error) {
+	return s.Threads.GetTotalThreadsCount()
 }
 
-func TotalThreadsCount() (int, error) {
-	return statsDM.GetTotalThreadsCount()
+// Dashboard gathers the admin statistics for the days from through to, both included
+func (s *Service) Dashboard(from, to time.Time) (models.Dashboard, error) {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, from.Location()).AddDate(0, 0, 1)
+	if !end.After(from) || end.Sub(from) > MaxDashboardDays*24*time.Hour+time.Hour {
+		return models.Dashboard{}, ErrInvalidRange
+	}
+	d := models.Dashboard{From: from, To: end.AddDate(0, 0, -1)}
+
+	var err error
+	if d.TotalUsers, err = s.UserCount(); err != nil {
+		return d, err
+	}
+	if d.TotalThreads, err = s.TotalThreadsCount(); err != nil {
+		return d, err
+	}
+	if d.TotalPosts, err = s.TotalPostsCount(); err != nil {
+		return d, err
+	}
+
+	if d.Daily, err = s.Stats.DailyCounts(from, end); err != nil {
+		return d, err
+	}
+	for _, day := range d.Daily {
+		d.Signups += day.Signups
+		d.Threads += day.Threads
+		d.Posts += day.Posts
+	}
+	if d.ActiveUsers, err = s.Stats.GetActiveUsersBetween(from, end, DashboardListSize); err != nil {
+		return d, err
+	}
+	if d.VotedThreads, err = s.Stats.GetMostVotedThreads(from, end, DashboardListSize); err != nil {
+		return d, err
+	}
+	if d.Categories, err = s.Stats.GetCategoryDistribution(from, end); err != nil {
+		return d, err
+	}
+	d.Online, err = s.CheckOnlineUsers(onlineMinutes)
+	return d, err
 }
@@ -0,0 +1,290 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"forum/models"
+	"forum/utils"
+)
+
+// FeedSize is the number of entries in an Atom or RSS feed
+const FeedSize = 30
+
+var (
+	ErrFeedNotFound     = errors.New("feed not found")
+	ErrInvalidFeedToken = errors.New("invalid feed token")
+)
+
+// LatestThreadsFeed lists the newest threads, of one category when category is set
+func (s *Service) LatestThreadsFeed(category string) (models.Feed, error) {
+	feed := models.Feed{Title: "Forum Talk: latest threads", Link: "/", Path: "/feeds/latest"}
+	if category != "" {
+		if !IsCategory(category) {
+			return feed, ErrFeedNotFound
+		}
+		feed.Title = "Forum Talk: " + category
+		feed.Path = "/feeds/category/" + url.PathEscape(category)
+	}
+	entries, err := s.Feeds.GetLatestThreadEntries(category, FeedSize)
+	return withEntries(feed, entries, time.Time{}), err
+}
+
+// ThreadRepliesFeed lists the newest replies of a thread
+func (s *Service) ThreadRepliesFeed(threadID int) (models.Feed, error) {
+	thread, err := s.Threads.GetThreadByID(threadID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Feed{}, ErrFeedNotFound
+	} else if err != nil {
+		return models.Feed{}, err
+	}
+	feed := models.Feed{
+		Title: "Forum Talk: " + thread.Topic,
+		Link:  utils.ThreadPath(thread.Id, thread.Topic),
+		Path:  fmt.Sprintf("/feeds/thread/%d", thread.Id),
+	}
+	entries, err := s.Feeds.GetThreadReplyEntries(thread.Id, FeedSize)
+	return withEntries(feed, entries, thread.CreatedAt), err
+}
+
+// UserPostsFeed lists the newest threads and replies of a member
+func (s *Service) UserPostsFeed(name string) (models.Feed, error) {
+	user, err := s.Users.GetUserByName(name)
+	if err != nil || user.Status != "active" {
+		return models.Feed{}, ErrFeedNotFound
+	}
+	feed := models.Feed{
+		Title: "Forum Talk: posts by " + user.Name,
+		Link:  "/u/" + url.PathEscape(user.Name),
+		Path:  "/feeds/user/" + url.PathEscape(user.Name),
+	}
+	entries, err := s.Feeds.GetUserFeedEntries(user.Id, FeedSize)
+	return withEntries(feed, entries, user.CreatedAt), err
+}
+
+// NotificationsFeed lists the mentions of a user and the replies in the
+// threads they watch. It is reached with a secret token instead of a session.
+func (s *Service) NotificationsFeed(token string) (models.Feed, error) {
+	user, err := s.UserByFeedToken(token)
+	if err != nil {
+		return models.Feed{}, err
+	}
+	feed := models.Feed{
+		Title: "Forum Talk: notifications for " + user.Name,
+		Link:  "/mentions",
+		Path:  "/feeds/notifications/" + token,
+	}
+	entries, err := s.Feeds.GetNotificationEntries(user.Id, FeedSize)
+	if err != nil {
+		return feed, err
+	}
+
+	// a reply mentioning the user in a watched thread is listed once
+	seen := map[[2]int]bool{}
+	unique := entries[:0]
+	for _, e := range entries {
+		key := [2]int{e.ThreadId, e.PostId}
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, e)
+		}
+	}
+	return withEntries(feed, unique, user.CreatedAt), nil
+}
+
+// withEntries sets the entries of a feed and its updated time,
+// the newest entry or fallback for an empty feed
+func withEntries(feed models.Feed, entries []models.FeedEntry, fallback time.Time) models.Feed {
+	feed.Entries = entries
+	feed.Updated = fallback
+	if len(entries) > 0 {
+		feed.Updated = entries[0].CreatedAt
+	}
+	if feed.Updated.IsZero() {
+		feed.Updated = time.Unix(0, 0)
+	}
+	feed.Updated = feed.Updated.UTC().Truncate(time.Second)
+	return feed
+}
+
+// entryID never changes, even when thread links do
+func entryID(e models.FeedEntry) string {
+	if e.PostId != 0 {
+		return fmt.Sprintf("%s/thread/read?id=%d#post-%d", utils.BaseURL(), e.ThreadId, e.PostId)
+	}
+	return fmt.Sprintf("%s/thread/read?id=%d", utils.BaseURL(), e.ThreadId)
+}
+
+func entryTitle(e models.FeedEntry) string {
+	switch {
+	case e.Kind == "mention":
+		return e.Author + " mentioned you in " + e.Topic
+	case e.PostId != 0:
+		return "Re: " + e.Topic
+	}
+	return e.Topic
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Link      atomLink   `xml:"link"`
+	Author    atomPerson `xml:"author"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+// RenderFeed encodes a feed as Atom ("atom") or RSS 2.0 ("rss")
+func RenderFeed(feed models.Feed, format string) ([]byte, error) {
+	base := utils.BaseURL()
+	var doc interface{}
+	switch format {
+	case "atom":
+		atom := atomFeed{
+			Title:   feed.Title,
+			ID:      base + feed.Path,
+			Updated: feed.Updated.Format(time.RFC3339),
+			Links: []atomLink{
+				{Rel: "self", Type: "application/atom+xml", Href: base + feed.Path + ".atom"},
+				{Rel: "alternate", Type: "text/html", Href: base + feed.Link},
+			},
+		}
+		for _, e := range feed.Entries {
+			created := e.CreatedAt.UTC().Format(time.RFC3339)
+			atom.Entries = append(atom.Entries, atomEntry{
+				Title:     entryTitle(e),
+				ID:        entryID(e),
+				Published: created,
+				Updated:   created,
+				Link:      atomLink{Rel: "alternate", Type: "text/html", Href: base + utils.PostPath(e.ThreadId, e.Topic, e.PostId)},
+				Author:    atomPerson{Name: e.Author},
+				Content:   atomText{Type: "text", Body: e.Body},
+			})
+		}
+		doc = atom
+	case "rss":
+		rss := rssFeed{Version: "2.0", Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          base + feed.Link,
+			Description:   feed.Title,
+			LastBuildDate: feed.Updated.Format(http.TimeFormat),
+		}}
+		for _, e := range feed.Entries {
+			rss.Channel.Items = append(rss.Channel.Items, rssItem{
+				Title:       entryTitle(e),
+				Link:        base + utils.PostPath(e.ThreadId, e.Topic, e.PostId),
+				GUID:        rssGUID{ID: entryID(e)},
+				PubDate:     e.CreatedAt.UTC().Format(http.TimeFormat),
+				Description: e.Body,
+			})
+		}
+		doc = rss
+	default:
+		return nil, ErrFeedNotFound
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GenerateFeedToken creates the secret part of the private feed URLs of a user
+// and replaces the previous one. Only a hash is stored.
+func (s *Service) GenerateFeedToken(userID int) (string, error) {
+	buf := make([]byte, 24)
+	rand.Read(buf) // never fails since go 1.24
+	token := hex.EncodeToString(buf)
+	if err := s.Feeds.SetFeedTokenHash(userID, hashAPIToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *Service) RevokeFeedToken(userID int) error {
+	return s.Feeds.DeleteFeedToken(userID)
+}
+
+func (s *Service) HasFeedToken(userID int) bool {
+	return s.Feeds.HasFeedToken(userID)
+}
+
+// UserByFeedToken returns the active user owning a feed token
+func (s *Service) UserByFeedToken(token string) (models.User, error) {
+	if token == "" {
+		return models.User{}, ErrInvalidFeedToken
+	}
+	userID, err := s.Feeds.GetUserIDByFeedTokenHash(hashAPIToken(token))
+	if err != nil {
+		return models.User{}, ErrInvalidFeedToken
+	}
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil || user.Status != "active" {
+		return models.User{}, ErrInvalidFeedToken
+	}
+	return user, nil
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"forum/models"
+)
+
+const (
+	MaxTagsPerThread = 5
+	MaxTagLength     = 32
+	tagSuggestions   = 10
+)
+
+var ErrTagNotFound = errors.New("tag not found")
+
+// NormalizeTag lowercases a tag and keeps letters, digits and . + # -,
+// spaces and underscores become dashes: "#Go Lang" is "go-lang"
+func NormalizeTag(raw string) string {
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), "#")
+	var b strings.Builder
+	for _, r := range strings.ToLower(raw) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '+', r == '#':
+			b.WriteRune(r)
+		case r == '-' || r == '_' || r == ' ':
+			if s := b.String(); s != "" && !strings.HasSuffix(s, "-") {
+				b.WriteRune('-')
+			}
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// CanonicalTag normalises a tag and replaces a synonym by its tag
+func (s *Service) CanonicalTag(raw string) string {
+	name := NormalizeTag(raw)
+	if name == "" {
+		return ""
+	}
+	if canonical, err := s.Tags.GetTagSynonym(name); err == nil {
+		return canonical
+	}
+	return name
+}
+
+// ParseTags reads a comma separated tag list as typed in the composer
+func (s *Service) ParseTags(input string) ([]string, error) {
+	seen := map[string]bool{}
+	var tags []string
+	for _, raw := range strings.Split(input, ",") {
+		name := s.CanonicalTag(raw)
+		if name == "" || seen[name] {
+			continue
+		}
+		if len(name) > MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", name, MaxTagLength)
+		}
+		seen[name] = true
+		tags = append(tags, name)
+	}
+	if len(tags) > MaxTagsPerThread {
+		return nil, fmt.Errorf("a thread can have at most %d tags", MaxTagsPerThread)
+	}
+	return tags, nil
+}
+
+func (s *Service) SetThreadTags(threadID int, tags []string) error {
+	return s.Tags.SetThreadTags(threadID, tags)
+}
+
+// AttachThreadTags fills the Tags of every thread
+func (s *Service) AttachThreadTags(threads []models.Thread) {
+	ids := make([]int, len(threads))
+	for i := range threads {
+		ids[i] = threads[i].Id
+	}
+	tags, err := s.Tags.GetTagsForThreads(ids)
+	if err != nil {
+		slog.Error("GetTagsForThreads failed", "error", err)
+		return
+	}
+	for i := range threads {
+		threads[i].Tags = tags[threads[i].Id]
+	}
+}
+
+// FilterThreadsByTag keeps the threads carrying tag, in their order
+func (s *Service) FilterThreadsByTag(threads []models.Thread, tag string) ([]models.Thread, error) {
+	ids, err := s.Tags.GetTaggedThreadIDs(s.CanonicalTag(tag))
+	if err != nil {
+		return nil, err
+	}
+	var filtered []models.Thread
+	for _, thread := range threads {
+		if ids[thread.Id] {
+			filtered = append(filtered, thread)
+		}
+	}
+	return filtered, nil
+}
+
+// TagInfo returns a tag by its normalised name
+func (s *Service) TagInfo(name string) (models.Tag, error) {
+	tag, err := s.Tags.GetTag(name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return tag, ErrTagNotFound
+	}
+	return tag, err
+}
+
+// SuggestTags autocompletes a tag prefix, synonyms suggest their tag
+func (s *Service) SuggestTags(prefix string) ([]models.Tag, error) {
+	prefix = NormalizeTag(prefix)
+	if prefix == "" {
+		return []models.Tag{}, nil
+	}
+	return s.Tags.SearchTags(prefix, tagSuggestions)
+}
+
+// AddTagSynonym makes alias stand for tag, threads tagged with alias are retagged
+func (s *Service) AddTagSynonym(moderator models.User, alias, tag string) error {
+	if !moderator.IsModerator() {
+		return errors.New("moderators only")
+	}
+	alias, tag = NormalizeTag(alias), s.CanonicalTag(tag)
+	if alias == "" || tag == "" {
+		return errors.New("alias and tag are required")
+	}
+	if alias == tag {
+		return errors.New("a tag cannot be its own synonym")
+	}
+	if len(alias) > MaxTagLength || len(tag) > MaxTagLength {
+		return fmt.Errorf("tags are at most %d characters", MaxTagLength)
+	}
+	if err := s.Tags.AddTagSynonym(alias, tag, moderator.Id); err != nil {
+		return err
+	}
+	return s.Audit.WriteAudit(moderator.Id, "add_tag_synonym", alias+" -> "+tag)
+}
+
+func (s *Service) RemoveTagSynonym(moderator models.User, alias string) error {
+	if !moderator.IsModerator() {
+		return errors.New("moderators only")
+	}
+	if err := s.Tags.DeleteTagSynonym(alias); err != nil {
+		return err
+	}
+	return s.Audit.WriteAudit(moderator.Id, "remove_tag_synonym", alias)
+}
+
+// PopularTags returns the most used tags
+func (s *Service) PopularTags(limit int) ([]models.Tag, error) {
+	return s.Tags.SearchTags("", limit)
+}
+
+func (s *Service) TagSynonyms() ([]models.TagSynonym, error) {
+	return s.Tags.GetTagSynonyms()
+}
@@ -3,121 +3,62 @@ package internal
 import (
 	"errors"
 	"fmt"
-	"forum/internal/data"
+	"forum/internal/metrics"
 	"forum/models"
+	"forum/utils"
+	"log/slog"
 	"net/http"
+	"strings"
 )
 
-// Global DatabaseManager instance for thread operations
-var threadDM *data.DatabaseManager
-
-// InitThreadDM initializes the global DatabaseManager for thread operations
-func InitThreadDM(dm *data.DatabaseManager) {
-	threadDM = dm
-}
-
-func GetAllThreads() (threads []models.Thread, err error) {
-	threads, err = threadDM.GetAllThreads()
+func (s *Service) GetAllThreads() (threads []models.Thread, err error) {
+	threads, err = s.Threads.GetAllThreads()
 	if err != nil {
-		fmt.Println("Error on select GetAllThreads")
+		slog.Error("GetAllThreads failed")
 		return
 	}
 	return
 }
 
-func FilterThreadsByCategories(category1, category2 string) ([]models.Thread, error) {
-	// We need to add this method to DatabaseManager
-	return threadDM.GetThreadsByCategories(category1, category2)
-}
-
-func ThreadWithPosts(threadID int) (models.Thread, error) {
-	return threadDM.GetThreadWithPosts(threadID)
-}
-func CrThreadByUser(topic, body string, userID int, category1, category2 string) (int64, error) {
-	return threadDM.CreateThreadByUser(topic, body, userID, category1, category2)
-}
-
-// Additional functions needed by API routes
-func ThreadById(threadID int) (models.Thread, error) {
-	return threadDM.GetThreadByID(threadID)
+func (s *Service) FilterThreadsByCategories(category1, category2 string) ([]models.Thread, error) {
+	return s.Threads.GetThreadsByCategories(category1, category2)
 }
 
-func GetThreadLikesCount(threadID int) (int, error) {
-	return threadDM.GetThreadLikesCount(threadID)
-}
-
-func GetThreadDislikesCount(threadID int) (int, error) {
-	return threadDM.GetThreadDislikesCount(threadID)
-}
-
-func ReadDMFromAccount(URLIDConv int) ([]models.Thread, error) {
-	// Get user info first
-	userInfo, err := threadDM.GetUserByID(URLIDConv)
-	if err != nil {
-		return []models.Thread{}, err
-	}
-
-	// Get user's threads
-	userThreads, err := threadDM.GetThreadsByUserID(URLIDConv)
-	if err != nil {
-		fmt.Printf("Error getting user threads: %v\n", err)
-		userThreads = []models.Thread{} // Empty slice if error
-	}
-
-	// Get user's posts
-	userPosts, err := threadDM.GetPostsByUserID(URLIDConv)
+func (s *Service) ThreadWithPosts(threadID int) (models.Thread, error) {
+	thread, err := s.Threads.GetThreadWithPosts(threadID)
 	if err != nil {
-		fmt.Printf("Error getting user posts: %v\n", err)
-		userPosts = []models.Post{} // Empty slice if error
+		return thread, err
 	}
 
-	// Get user's liked posts
-	likedPosts, err := threadDM.GetLikedPostsByUserID(URLIDConv)
-	if err != nil {
-		fmt.Printf("Error getting user liked posts: %v\n", err)
-		likedPosts = []models.Post{} // Empty slice if error
+	// Keep line breaks and indentation of the body in HTML
+	thread.Body = strings.ReplaceAll(thread.Body, "\n", "<br>")
+	thread.Body = strings.ReplaceAll(thread.Body, "    ", "&nbsp;&nbsp;&nbsp;&nbsp;")
+	thread.Body = s.RenderMentions(thread.Body)
+	thread.Tags, _ = s.Tags.GetThreadTags(thread.Id)
+	for i := range thread.Cards {
+		thread.Cards[i].Body = s.RenderMentions(thread.Cards[i].Body)
 	}
-
-	// Get user's liked threads
-	likedThreads, err := threadDM.GetLikedThreadsByUserID(URLIDConv)
-	if err != nil {
-		fmt.Printf("Error getting user liked threads: %v\n", err)
-		likedThreads = []models.Thread{} // Empty slice if error
-	}
-
-	// Create account data structure that matches the template expectations
-	// The template expects an array where the first element has user info and contains Cards/LikedPosts
-	var templateData []models.Thread
-
-	// Always create at least one element for the template
-	var firstElement models.Thread
-
-	if len(userThreads) > 0 {
-		// Use the first thread as a base
-		firstElement = userThreads[0]
-
-		// Add the rest of the threads if any
-		if len(userThreads) > 1 {
-			templateData = append(templateData, userThreads[1:]...)
-		}
-	} else {
-		// No threads, create a dummy thread with user info
-		firstElement = models.Thread{
-			User:      userInfo.Name,
-			Email:     userInfo.Email,
-			CreatedAt: userInfo.CreatedAt,
-		}
+	return thread, nil
+}
+func (s *Service) CrThreadByUser(topic, body string, userID int, category1, category2 string) (int64, error) {
+	id, err := s.Threads.CreateThreadByUser(topic, body, userID, category1, category2)
+	if err == nil {
+		metrics.ThreadsCreated.Inc()
 	}
+	return id, err
+}
 
-	// Set the Cards and LikedPosts on the first element
-	firstElement.Cards = userPosts
-	firstElement.LikedPosts = likedPosts
-	firstElement.UserLikedThreads = likedThreads
+// Additional functions needed by API routes
+func (s *Service) ThreadById(threadID int) (models.Thread, error) {
+	return s.Threads.GetThreadByID(threadID)
+}
 
-	// Insert the first element at the beginning
-	templateData = append([]models.Thread{firstElement}, templateData...)
+func (s *Service) GetThreadLikesCount(threadID int) (int, error) {
+	return s.Votes.GetThreadLikesCount(threadID)
+}
 
-	return templateData, nil
+func (s *Service) GetThreadDislikesCount(threadID int) (int, error) {
+	return s.Votes.GetThreadDislikesCount(threadID)
 }
 
 func SortThreadsByLikesDesc(threads []models.Thread) ([]models.Thread, error) {
@@ -125,7 +66,7 @@ func SortThreadsByLikesDesc(threads []models.Thread) ([]models.Thread, error) {
 	n := len(threads)
 	for i := 0; i < n; i++ {
 		for j := 0; j < n-i-1; j++ {
-			if threads[j].LikesCount < threads[j+1].LikesCount {
+			if pinnedFirst(threads[j+1], threads[j]) || (threads[j].Pinned == threads[j+1].Pinned && threads[j].LikesCount < threads[j+1].LikesCount) {
 				threads[j], threads[j+1] = threads[j+1], threads[j]
 			}
 		}
@@ -138,7 +79,7 @@ func SortThreadsByLatest(threads []models.Thread) ([]models.Thread, error) {
 	n := len(threads)
 	for i := 0; i < n; i++ {
 		for j := 0; j < n-i-1; j++ {
-			if threads[j].CreatedAt.Before(threads[j+1].CreatedAt) {
+			if pinnedFirst(threads[j+1], threads[j]) || (threads[j].Pinned == threads[j+1].Pinned && threads[j].CreatedAt.Before(threads[j+1].CreatedAt)) {
 				threads[j], threads[j+1] = threads[j+1], threads[j]
 			}
 		}
@@ -146,79 +87,106 @@ func SortThreadsByLatest(threads []models.Thread) ([]models.Thread, error) {
 	return threads, nil
 }
 
-func GetCookieValue(request *http.Request) int {
-	// Debug: Print all cookies
-	fmt.Printf("DEBUG: All cookies for request: ")
-	for _, cookie := range request.Cookies() {
-		fmt.Printf("[%s=%s] ", cookie.Name, cookie.Value)
+// pinnedFirst reports whether a must move before b whatever the sort order
+func pinnedFirst(a, b models.Thread) bool {
+	return a.Pinned && !b.Pinned
+}
+
+var (
+	ErrThreadLocked        = errors.New("this thread is locked, new replies are disabled")
+	ErrThreadArchived      = errors.New("this thread is archived and no longer accepts replies")
+	ErrUnknownThreadAction = errors.New("unknown thread action")
+)
+
+// CanReply returns why a thread does not accept new posts, nil when it does
+func CanReply(thread models.Thread) error {
+	if thread.Archived {
+		return ErrThreadArchived
+	}
+	if thread.Locked {
+		return ErrThreadLocked
 	}
-	fmt.Println()
+	return nil
+}
 
-	// Get the _cookie from request
-	cook, err := request.Cookie("_cookie")
-	if err != nil {
-		if errors.Is(err, http.ErrNoCookie) {
-			fmt.Println("Cookie '_cookie' not found")
-		} else {
-			fmt.Println("Error on Get Cookie:", err)
-		}
-		return -1
+// ModerateThread pins, locks or archives a thread (or reverts it)
+// and records the action in the audit log
+func (s *Service) ModerateThread(moderator models.User, threadID int, action string) error {
+	if !moderator.IsModerator() {
+		return errors.New("moderators only")
+	}
+	if _, err := s.Threads.GetThreadByID(threadID); err != nil {
+		return err
 	}
 
-	fmt.Printf("Found cookie: %s = %s\n", cook.Name, cook.Value)
+	var err error
+	switch action {
+	case "pin", "unpin":
+		err = s.Threads.SetThreadPinned(threadID, action == "pin")
+	case "lock", "unlock":
+		err = s.Threads.SetThreadLocked(threadID, action == "lock")
+	case "archive", "unarchive":
+		err = s.Threads.SetThreadArchived(threadID, action == "archive")
+	default:
+		return ErrUnknownThreadAction
+	}
+	if err != nil {
+		return err
+	}
+	return s.Audit.WriteAudit(moderator.Id, action+"_thread", fmt.Sprintf("thread %d", threadID))
+}
 
-	// Check if session exists in database with this cookie string
-	session, err := GetSessionByCookie(cook.Value)
+func (s *Service) GetCookieValue(request *http.Request) int {
+	// Get the _cookie from request
+	cook, err := request.Cookie(utils.SessionCookieName())
 	if err != nil {
-		fmt.Printf("No valid session found for cookie: %s\n", cook.Value)
+		if !errors.Is(err, http.ErrNoCookie) {
+			slog.WarnContext(request.Context(), "cannot read session cookie", "error", err)
+		}
 		return -1
 	}
 
-	if session.UserId == 0 {
-		fmt.Println("Session found but no valid user ID")
+	// Check if session exists in database with this cookie string
+	session, err := s.GetSessionByCookie(cook.Value)
+	if err != nil || session.UserId == 0 {
+		slog.DebugContext(request.Context(), "no session for cookie")
 		return -1
 	}
-
-	fmt.Printf("Authenticated user ID %d via cookie string\n", session.UserId)
 	return session.UserId
 }
 
-func ApplyThreadLike(stateLike string, userID int, threadId int) {
-	fmt.Println("Apply thread likes in database proccess")
-	threadDM.AddThreadLike(userID, threadId)
-	// can apply only if threadId is right // TODO check sequence
+func (s *Service) ApplyThreadLike(stateLike string, userID int, threadId int) {
+	s.Votes.AddThreadLike(userID, threadId)
 }
 
-func ApplyThreadDislike(stateLike string, userID int, threadId int) {
-	fmt.Println("Apply thread dislikes in database proccess")
-	threadDM.AddThreadDislike(userID, threadId)
-	// can apply only if threadId is right // TODO check sequence
+func (s *Service) ApplyThreadDislike(stateLike string, userID int, threadId int) {
+	s.Votes.AddThreadDislike(userID, threadId)
 }
 
 // Check if user has already liked a thread
-func HasUserLikedThread(userID int, threadID int) bool {
-	return threadDM.HasUserLikedThread(userID, threadID) > 0
+func (s *Service) HasUserLikedThread(userID int, threadID int) bool {
+	return s.Votes.HasThreadLiked(userID, threadID)
 }
 
 // Check if user has already disliked a thread
-func HasUserDislikedThread(userID int, threadID int) bool {
-	return threadDM.HasUserDislikedThread(userID, threadID) > 0
+func (s *Service) HasUserDislikedThread(userID int, threadID int) bool {
+	return s.Votes.HasThreadDisliked(userID, threadID)
 }
 
 // Remove user's like from a thread
-func RemoveThreadLike(userID int, threadID int) error {
-	return threadDM.RemoveThreadLike(userID, threadID)
+func (s *Service) RemoveThreadLike(userID int, threadID int) error {
+	return s.Votes.RemoveThreadLike(userID, threadID)
 }
 
 // Remove user's dislike from a thread
-func RemoveThreadDislike(userID int, threadID int) error {
-	return threadDM.RemoveThreadDislike(userID, threadID)
+func (s *Service) RemoveThreadDislike(userID int, threadID int) error {
+	return s.Votes.RemoveThreadDislike(userID, threadID)
 }
 
-func PrepareThreadLikedPosts(userID, threadid int) bool {
-	likes, err := userDM.GetUserLikedThreads(userID)
+func (s *Service) PrepareThreadLikedPosts(userID, threadid int) bool {
+	likes, err := s.Votes.GetUserLikedThreads(userID)
 	if err != nil {
-		fmt.Println("Error on PrepareThreadLikedPosts")
+		slog.Error("PrepareThreadLikedPosts failed")
 		return false
 	}
 
@@ -230,10 +198,10 @@ func PrepareThreadLikedPosts(userID, threadid int) bool {
 	return false
 }
 
-func PrepareThreadDislikedPosts(userID, threadid int) bool {
-	dislikes, err := userDM.GetUserDislikedThreads(userID)
+func (s *Service) PrepareThreadDislikedPosts(userID, threadid int) bool {
+	dislikes, err := s.Votes.GetUserDislikedThreads(userID)
 	if err != nil {
-		fmt.Println("Error on PrepareThreadDislikedPosts")
+		slog.Error("PrepareThreadDislikedPosts failed")
 		return false
 	}
 
@@ -246,60 +214,28 @@ func PrepareThreadDislikedPosts(userID, threadid int) bool {
 }
 
 // Smart like function - handles vote switching
-func SmartApplyThreadLike(userID int, threadID int) error {
-	// Check if user already liked this thread
-	if HasUserLikedThread(userID, threadID) {
-		// User already liked, so remove the like (toggle off)
-		return RemoveThreadLike(userID, threadID)
-	}
-
-	// Check if user disliked this thread, if so remove the dislike first
-	if HasUserDislikedThread(userID, threadID) {
-		err := RemoveThreadDislike(userID, threadID)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Add the like
-	ApplyThreadLike("like", userID, threadID)
-	return nil
+func (s *Service) SmartApplyThreadLike(userID int, threadID int) error {
+	return s.ToggleThreadVote(userID, threadID, true)
 }
 
 // Smart dislike function - handles vote switching
-func SmartApplyThreadDislike(userID int, threadID int) error {
-	// Check if user already disliked this thread
-	if HasUserDislikedThread(userID, threadID) {
-		// User already disliked, so remove the dislike (toggle off)
-		return RemoveThreadDislike(userID, threadID)
-	}
-
-	// Check if user liked this thread, if so remove the like first
-	if HasUserLikedThread(userID, threadID) {
-		err := RemoveThreadLike(userID, threadID)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Add the dislike
-	ApplyThreadDislike("dislike", userID, threadID)
-	return nil
+func (s *Service) SmartApplyThreadDislike(userID int, threadID int) error {
+	return s.ToggleThreadVote(userID, threadID, false)
 }
 
 // Thread methods needed by API routes
-func GetLikesCount(thread models.Thread) int {
-	likes, err := threadDM.GetThreadLikes(thread.Id)
+func (s *Service) GetLikesCount(thread models.Thread) int {
+	likes, err := s.Votes.GetThreadLikesCount(thread.Id)
 	if err != nil {
 		return 0
 	}
-	return len(likes)
+	return likes
 }
 
-func GetDislikesCount(thread models.Thread) int {
-	dislikes, err := threadDM.GetThreadDislikes(thread.Id)
+func (s *Service) GetDislikesCount(thread models.Thread) int {
+	dislikes, err := s.Votes.GetThreadDislikesCount(thread.Id)
 	if err != nil {
 		return 0
 	}
-	return len(dislikes)
+	return dislikes
 }
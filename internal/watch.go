@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"fmt"
+	"forum/models"
+	"forum/utils"
+	"log/slog"
+)
+
+// Digest frequencies a user can choose from
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+	DigestOff    = "off"
+)
+
+func (s *Service) WatchThread(userID, threadID int) error {
+	return s.Watches.WatchThread(userID, threadID)
+}
+
+func (s *Service) UnwatchThread(userID, threadID int) error {
+	return s.Watches.UnwatchThread(userID, threadID)
+}
+
+func (s *Service) IsWatchingThread(userID, threadID int) bool {
+	return s.Watches.IsWatchingThread(userID, threadID)
+}
+
+func (s *Service) WatchedThreads(userID int) ([]models.ThreadWatch, error) {
+	return s.Watches.GetWatchedThreads(userID)
+}
+
+func (s *Service) DigestFrequency(userID int) string {
+	frequency, err := s.Watches.GetDigestFrequency(userID)
+	if err != nil {
+		slog.Error("GetDigestFrequency failed", "error", err)
+		return DigestDaily
+	}
+	return frequency
+}
+
+func (s *Service) SetDigestFrequency(userID int, frequency string) error {
+	switch frequency {
+	case DigestDaily, DigestWeekly, DigestOff:
+		return s.Watches.SetDigestFrequency(userID, frequency)
+	default:
+		return fmt.Errorf("unknown digest frequency %q", frequency)
+	}
+}
+
+// unsubscribeValue is the signed payload of an unsubscribe link;
+// threadID 0 stands for "all digests"
+func unsubscribeValue(userID, threadID int) string {
+	return fmt.Sprintf("unsubscribe:%d:%d", userID, threadID)
+}
+
+// UnsubscribeURL builds a link that works without logging in
+func UnsubscribeURL(baseURL string, userID, threadID int) string {
+	return fmt.Sprintf("%s/unsubscribe?user=%d&thread=%d&sig=%s",
+		baseURL, userID, threadID, utils.Sign(unsubscribeValue(userID, threadID)))
+}
+
+// Unsubscribe verifies a signed link and removes the watch,
+// or turns digests off when threadID is 0
+func (s *Service) Unsubscribe(userID, threadID int, signature string) error {
+	if !utils.VerifySignature(unsubscribeValue(userID, threadID), signature) {
+		return fmt.Errorf("invalid unsubscribe signature")
+	}
+	if threadID == 0 {
+		return s.Watches.SetDigestFrequency(userID, DigestOff)
+	}
+	return s.Watches.UnwatchThread(userID, threadID)
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Backup is a snapshot file of the database in the backup directory
+type Backup struct {
+	Name      string
+	Size      int64
+	CreatedAt time.Time
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// The personal data export, one JSON file per type in the zip
+
+// ExportProfile is the account, without the password hash
+type ExportProfile struct {
+	Id                int       `json:"id"`
+	Name              string    `json:"name"`
+	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	Status            string    `json:"status"`
+	Bio               string    `json:"bio"`
+	AvatarURL         string    `json:"avatarUrl"`
+	PreferedCategory1 string    `json:"preferedCategory1"`
+	PreferedCategory2 string    `json:"preferedCategory2"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+type ExportThread struct {
+	Id         int       `json:"id"`
+	Topic      string    `json:"topic"`
+	Body       string    `json:"body"`
+	Categories []string  `json:"categories"`
+	Tags       []string  `json:"tags"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type ExportPost struct {
+	Id        int       `json:"id"`
+	ThreadId  int       `json:"threadId"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ExportVote is a like or dislike of a thread or of a reply
+type ExportVote struct {
+	Vote     string `json:"vote"` // like or dislike
+	ThreadId int    `json:"threadId,omitempty"`
+	PostId   int    `json:"postId,omitempty"`
+}
+
+type ExportSession struct {
+	CreatedAt  time.Time `json:"createdAt"`
+	LastActive string    `json:"lastActive"` // hh:mm of the last request
+}
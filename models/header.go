@@ -14,6 +14,9 @@ type User struct {
 	CreatedAt         time.Time
 	PreferedCategory1 string
 	PreferedCategory2 string
+	Status            string
+	Bio               string
+	AvatarURL         string
 }
 
 type Session struct {
@@ -55,6 +58,7 @@ type LoginSkin struct {
 	Name   string
 	Email  string
 	Error  string
+	Next   string // local page to return to after login
 }
 
 type Thread struct {
@@ -79,6 +83,16 @@ type Thread struct {
 	UserDisliked     bool
 	Category1        string
 	Category2        string
+	Watching         bool
+	Bookmarked       bool
+	Pinned           bool // listed before all other threads
+	Locked           bool // no new replies
+	Archived         bool // read-only, set by hand or after inactivity
+	CanModerate      bool
+	Tags             []string
+	Views            int
+	Unread           int // replies by others since the reader's last visit
+	FirstUnread      int // post id of the first unread reply
 }
 
 type LikeProperties struct {
@@ -122,3 +136,168 @@ type ThreadDislikes struct {
 	LengthOfDislikes int
 	UserDisliked     bool
 }
+
+type ThreadWatch struct {
+	UserId    int
+	ThreadId  int
+	Topic     string
+	CreatedAt time.Time
+}
+
+type DigestPreference struct {
+	UserId     int
+	Name       string
+	Email      string
+	Frequency  string
+	LastSentAt time.Time
+}
+
+type DigestEntry struct {
+	PostId    int
+	ThreadId  int
+	Topic     string
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+type UserLookup struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Conversation struct {
+	Id        int
+	Uuid      string
+	Subject   string
+	CreatedBy int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Members   string
+	Unread    int
+	Muted     bool
+}
+
+type Message struct {
+	Id             int
+	ConversationId int
+	UserId         int
+	Author         string
+	Body           string
+	CreatedAt      time.Time
+}
+
+type MessageReport struct {
+	Id             int
+	MessageId      int
+	ConversationId int
+	ReporterId     int
+	Reporter       string
+	Reason         string
+	Status         string
+	CreatedAt      time.Time
+	Message        Message
+	Context        []Message
+}
+
+type AuditEntry struct {
+	Id        int
+	ActorId   int
+	Actor     string
+	Action    string
+	Detail    string
+	CreatedAt time.Time
+}
+
+type Mention struct {
+	Id        int
+	UserId    int
+	AuthorId  int
+	Author    string
+	ThreadId  int
+	Topic     string
+	PostId    int
+	CreatedAt time.Time
+	Seen      bool
+}
+
+type Collection struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Count     int       `json:"count"`
+}
+
+// Bookmark saves a thread, or one post of it when PostId is not 0.
+// CollectionId 0 means the bookmark is not in any collection.
+type Bookmark struct {
+	Id           int       `json:"id"`
+	CollectionId int       `json:"collection_id"`
+	Collection   string    `json:"collection"`
+	ThreadId     int       `json:"thread_id"`
+	PostId       int       `json:"post_id"`
+	Topic        string    `json:"topic"`
+	Excerpt      string    `json:"excerpt"`
+	Note         string    `json:"note"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// BookmarkFilter narrows the bookmarks list, CollectionId -1 means any collection
+// and Kind is "thread", "post" or empty for both
+type BookmarkFilter struct {
+	CollectionId int
+	Kind         string
+	Query        string
+}
+
+// Draft is an unsent new thread (ThreadId 0) or reply to ThreadId
+type Draft struct {
+	ThreadId  int       `json:"thread_id"`
+	Target    string    `json:"-"` // topic of the replied thread, for the drafts list
+	Topic     string    `json:"topic"`
+	Body      string    `json:"body"`
+	Category1 string    `json:"category1"`
+	Category2 string    `json:"category2"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Tag is a normalised free-form label on threads
+type Tag struct {
+	Id         int    `json:"-"`
+	Name       string `json:"name"`
+	UsageCount int    `json:"usage_count"`
+}
+
+// TagSynonym maps an alias to the tag used in its place
+type TagSynonym struct {
+	Alias     string
+	Tag       string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// FeedEntry is a thread (PostId 0) or a post published in a feed
+type FeedEntry struct {
+	Kind      string // thread, reply or mention
+	ThreadId  int
+	PostId    int
+	Topic     string
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+type Feed struct {
+	Title   string
+	Link    string // page the feed mirrors, relative to the base URL
+	Path    string // the feed itself, relative to the base URL
+	Updated time.Time
+	Entries []FeedEntry
+}
+
+// SitemapEntry is a thread listed in sitemap.xml
+type SitemapEntry struct {
+	ThreadId     int
+	Topic        string
+	LastModified time.Time
+}
@@ -0,0 +1,8 @@
+package models
+
+// ReadState is what the index shows a reader about a thread
+type ReadState struct {
+	Views       int
+	Unread      int // replies by others since the last visit
+	FirstUnread int // id of the first of them
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// DailyCount is what was created on one day of the dashboard range
+type DailyCount struct {
+	Day     time.Time
+	Signups int
+	Threads int
+	Posts   int
+}
+
+// UserActivity ranks a user by the threads and replies written in the range
+type UserActivity struct {
+	UserId  int
+	Name    string
+	Threads int
+	Posts   int
+}
+
+// ThreadVotes ranks a thread by its likes and dislikes
+type ThreadVotes struct {
+	ThreadId int
+	Topic    string
+	Likes    int
+	Dislikes int
+}
+
+// CategoryCount is the number of threads in a category
+type CategoryCount struct {
+	Category string
+	Threads  int
+}
+
+// Dashboard is the admin view of the forum between From and To, both days
+// included. The totals cover all time, the rest only the range.
+type Dashboard struct {
+	From time.Time
+	To   time.Time
+
+	TotalUsers   int
+	TotalThreads int
+	TotalPosts   int
+
+	Signups int
+	Threads int
+	Posts   int
+
+	Daily        []DailyCount
+	ActiveUsers  []UserActivity
+	VotedThreads []ThreadVotes
+	Categories   []CategoryCount
+	Online       []User
+}
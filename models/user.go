@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Roles stored in users.role
+const (
+	RoleMember    = "member"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Statuses stored in users.status, only active accounts can sign in
+const (
+	StatusActive  = "active"
+	StatusBanned  = "banned"
+	StatusDeleted = "deleted"
+)
+
+func (user *User) IsAdmin() bool {
+	return user.Role == RoleAdmin
+}
+
+// IsModerator is true for moderators and admins
+func (user *User) IsModerator() bool {
+	return user.Role == RoleModerator || user.Role == RoleAdmin
+}
+
+// Profile is the view model of the public /u/{name} page.
+// Email is only filled in when the viewer may see it.
+type Profile struct {
+	Id            int
+	Name          string
+	Email         string
+	Role          string
+	Bio           string
+	AvatarURL     string
+	JoinedAt      time.Time
+	ThreadCount   int
+	PostCount     int
+	LikesReceived int
+	Recent        []Activity
+	IsOwner       bool
+	CanMessage    bool
+	CanBlock      bool
+	Following     bool
+	Followers     int
+	Blocked       bool
+}
+
+// Initial is shown in place of a missing avatar
+func (p Profile) Initial() string {
+	for _, r := range p.Name {
+		return strings.ToUpper(string(r))
+	}
+	return "?"
+}
+
+// Activity is a thread or reply in a profile's recent activity
+type Activity struct {
+	Kind      string // "thread" or "post"
+	ThreadId  int
+	Topic     string
+	Excerpt   string
+	CreatedAt time.Time
+}
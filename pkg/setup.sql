@@ -1,3 +1,18 @@
+DROP TABLE IF EXISTS drafts;
+DROP TABLE IF EXISTS api_tokens;
+DROP TABLE IF EXISTS bookmarks;
+DROP TABLE IF EXISTS collections;
+DROP TABLE IF EXISTS category_follows;
+DROP TABLE IF EXISTS user_follows;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS message_reports;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS conversation_members;
+DROP TABLE IF EXISTS conversations;
+DROP TABLE IF EXISTS mentions;
+DROP TABLE IF EXISTS user_blocks;
+DROP TABLE IF EXISTS digest_preferences;
+DROP TABLE IF EXISTS thread_watches;
 DROP TABLE IF EXISTS dislikes;
 DROP TABLE IF EXISTS likedposts;
 DROP TABLE IF EXISTS threaddislikes;
@@ -16,7 +31,11 @@ CREATE TABLE users (
   password   varchar(128) not null,
   created_at timestamp not null,
   prefered_category1 varchar(255) default '',
-  prefered_category2 varchar(255) default ''
+  prefered_category2 varchar(255) default '',
+  status     varchar(16) not null default 'active',
+  role       varchar(16) not null default 'member',
+  bio        text not null default '',
+  avatar_url varchar(255) not null default ''
 );
 
 CREATE TABLE sessions (
@@ -37,7 +56,10 @@ CREATE TABLE threads (
   user_id    integer references users(id),
   created_at timestamp not null,
   category1  varchar(255) default '',
-  category2  varchar(255) default ''
+  category2  varchar(255) default '',
+  pinned     boolean not null default 0,
+  locked     boolean not null default 0,
+  archived   boolean not null default 0
 );
 
 CREATE TABLE posts (
@@ -75,4 +97,164 @@ CREATE TABLE dislikes (
   type    varchar(50),
   user_id integer references users(id),
   post_id integer references posts(id)
-);
\ No newline at end of file
+);
+
+CREATE TABLE thread_watches (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id    integer references users(id),
+  thread_id  integer references threads(id),
+  created_at timestamp not null,
+  unique(user_id, thread_id)
+);
+
+CREATE TABLE digest_preferences (
+  user_id      integer primary key references users(id),
+  frequency    varchar(16) not null default 'daily',
+  last_sent_at timestamp
+);
+
+CREATE TABLE user_blocks (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  blocker_id integer references users(id),
+  blocked_id integer references users(id),
+  created_at timestamp not null,
+  unique(blocker_id, blocked_id)
+);
+
+CREATE TABLE mentions (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id    integer references users(id),
+  author_id  integer references users(id),
+  thread_id  integer references threads(id),
+  post_id    integer default 0,
+  created_at timestamp not null,
+  seen       integer default 0
+);
+
+CREATE TABLE conversations (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  uuid       varchar(64) not null unique,
+  subject    varchar(255) default '',
+  created_by integer references users(id),
+  created_at timestamp not null,
+  updated_at timestamp not null
+);
+
+CREATE TABLE conversation_members (
+  id              INTEGER PRIMARY KEY AUTOINCREMENT,
+  conversation_id integer references conversations(id),
+  user_id         integer references users(id),
+  joined_at       timestamp not null,
+  last_read_id    integer default 0,
+  muted           integer default 0,
+  left_at         timestamp,
+  unique(conversation_id, user_id)
+);
+
+CREATE TABLE messages (
+  id              INTEGER PRIMARY KEY AUTOINCREMENT,
+  conversation_id integer references conversations(id),
+  user_id         integer references users(id),
+  body            text,
+  created_at      timestamp not null
+);
+
+CREATE TABLE message_reports (
+  id              INTEGER PRIMARY KEY AUTOINCREMENT,
+  message_id      integer references messages(id),
+  conversation_id integer references conversations(id),
+  reporter_id     integer references users(id),
+  reason          text,
+  status          varchar(16) not null default 'open',
+  created_at      timestamp not null,
+  resolved_by     integer default 0,
+  resolved_at     timestamp
+);
+
+CREATE TABLE audit_log (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  actor_id   integer references users(id),
+  action     varchar(64) not null,
+  detail     text,
+  created_at timestamp not null
+);
+
+CREATE TABLE user_follows (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+  follower_id integer references users(id),
+  followed_id integer references users(id),
+  created_at  timestamp not null,
+  unique(follower_id, followed_id)
+);
+
+CREATE TABLE category_follows (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id    integer references users(id),
+  category   varchar(255) not null,
+  created_at timestamp not null,
+  unique(user_id, category)
+);
+
+CREATE TABLE collections (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id    integer references users(id),
+  name       varchar(64) not null,
+  created_at timestamp not null,
+  unique(user_id, name)
+);
+
+CREATE TABLE bookmarks (
+  id            INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id       integer references users(id),
+  collection_id integer not null default 0,
+  thread_id     integer references threads(id),
+  post_id       integer not null default 0,
+  note          text not null default '',
+  created_at    timestamp not null,
+  unique(user_id, thread_id, post_id)
+);
+
+CREATE TABLE api_tokens (
+  user_id      integer primary key references users(id),
+  token_hash   varchar(64) not null unique,
+  created_at   timestamp not null,
+  last_used_at timestamp
+);
+
+CREATE TABLE drafts (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id    integer references users(id),
+  thread_id  integer not null default 0,
+  topic      text not null default '',
+  body       text not null default '',
+  category1  varchar(255) not null default '',
+  category2  varchar(255) not null default '',
+  updated_at timestamp not null,
+  unique(user_id, thread_id)
+);
+
+CREATE TABLE tags (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+  name        varchar(32) not null unique,
+  usage_count integer not null default 0,
+  created_at  timestamp not null
+);
+
+CREATE TABLE thread_tags (
+  thread_id integer references threads(id),
+  tag_id    integer references tags(id),
+  primary key(thread_id, tag_id)
+);
+
+CREATE TABLE tag_synonyms (
+  alias      varchar(32) primary key,
+  tag_id     integer references tags(id),
+  created_by integer references users(id),
+  created_at timestamp not null
+);
+
+CREATE TABLE feed_tokens (
+  user_id    integer primary key references users(id),
+  token_hash varchar(64) not null unique,
+  created_at timestamp not null
+);
@@ -0,0 +1,141 @@
+// Draft autosave for composer forms marked with data-draft="<thread id>"
+// (0 for a new thread). The content is copied to sessionStorage on every
+// keystroke and saved to /api/drafts every few seconds, so it survives an
+// expired session: the form is only submitted once the draft could be saved.
+function setupDraftAutosave(form) {
+  const threadId = form.dataset.draft;
+  const key = "draft:" + threadId;
+  const status = document.createElement("div");
+  status.className = "small text-muted draft-status";
+  form.appendChild(status);
+
+  const fields = {
+    topic: form.querySelector('[name="topic"]'),
+    body: form.querySelector('[name="body"]'),
+    category1: form.querySelector('[name="selection1"]'),
+    category2: form.querySelector('[name="selection2"]'),
+  };
+
+  let dirty = false;
+
+  function current() {
+    const draft = { thread_id: threadId };
+    for (const name in fields) {
+      draft[name] = fields[name] ? fields[name].value : "";
+    }
+    return draft;
+  }
+
+  function isEmpty(draft) {
+    return !(draft.topic || "").trim() && !(draft.body || "").trim();
+  }
+
+  function fill(draft) {
+    for (const name in fields) {
+      if (fields[name] && draft[name]) {
+        fields[name].value = draft[name];
+        fields[name].dispatchEvent(new Event("change"));
+      }
+    }
+  }
+
+  function keepLocal(submitted) {
+    const draft = current();
+    draft.saved = Date.now();
+    draft.submitted = submitted;
+    sessionStorage.setItem(key, JSON.stringify(draft));
+  }
+
+  function loginLink() {
+    return "/login/?next=" + encodeURIComponent(location.pathname + location.search);
+  }
+
+  function sessionExpired() {
+    status.innerHTML =
+      'Your session has expired. Your text is kept in this tab, <a href="' +
+      loginLink() +
+      '">log in again</a> to continue.';
+  }
+
+  // save sends the draft to the server and resolves to true when it was stored
+  function save() {
+    const body = new URLSearchParams(current());
+    return fetch("/api/drafts", { method: "POST", body: body }).then(function (res) {
+      if (res.status === 401) {
+        sessionExpired();
+        return false;
+      }
+      if (!res.ok) {
+        return false;
+      }
+      dirty = false;
+      status.textContent = "Draft saved at " + new Date().toLocaleTimeString();
+      return true;
+    });
+  }
+
+  // restore prefers the most recent of the server draft and the local copy
+  function restore() {
+    const local = JSON.parse(sessionStorage.getItem(key) || "null");
+    fetch("/api/drafts?thread_id=" + encodeURIComponent(threadId))
+      .then(function (res) {
+        if (res.status === 200) {
+          return res.json();
+        }
+        return null;
+      })
+      .then(function (server) {
+        if (!isEmpty(current())) {
+          return;
+        }
+        if (local && !local.submitted && (!server || local.saved > Date.parse(server.updated_at))) {
+          fill(local);
+          dirty = true;
+          status.textContent = "Restored your unsaved text.";
+          save();
+        } else if (server) {
+          fill(server);
+          status.textContent = "Restored your draft from " + new Date(server.updated_at).toLocaleString();
+        } else if (local && local.submitted) {
+          // the last submit went through, the server removed the draft
+          sessionStorage.removeItem(key);
+        }
+      })
+      .catch(function () {});
+  }
+
+  form.addEventListener("input", function () {
+    dirty = true;
+    keepLocal(false);
+  });
+
+  setInterval(function () {
+    if (dirty) {
+      save().catch(function () {});
+    }
+  }, 5000);
+
+  form.addEventListener("submit", function (e) {
+    if (e.defaultPrevented) {
+      return;
+    }
+    e.preventDefault();
+    save()
+      .then(function (saved) {
+        if (saved) {
+          keepLocal(true);
+          form.submit();
+        }
+      })
+      .catch(function () {
+        // offline or server down, let the browser try the normal submit
+        form.submit();
+      });
+  });
+
+  restore();
+}
+
+window.addEventListener("DOMContentLoaded", function () {
+  document.querySelectorAll("form[data-draft]").forEach(setupDraftAutosave);
+});
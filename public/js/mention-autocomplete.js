@@ -0,0 +1,71 @@
+// @mention autocomplete for composer textareas marked with data-mentions
+function setupMentionAutocomplete(textarea) {
+  const list = document.createElement("div");
+  list.className = "mention-list";
+  list.style.display = "none";
+  textarea.parentNode.insertBefore(list, textarea.nextSibling);
+
+  let timer = null;
+
+  function currentPrefix() {
+    const before = textarea.value.slice(0, textarea.selectionStart);
+    const match = before.match(/(^|[^A-Za-z0-9_.-])@([A-Za-z0-9]{1,20})$/);
+    return match ? match[2] : null;
+  }
+
+  function insertName(name) {
+    const caret = textarea.selectionStart;
+    const before = textarea.value.slice(0, caret);
+    const after = textarea.value.slice(caret);
+    const start = before.lastIndexOf("@");
+    textarea.value = before.slice(0, start) + "@" + name + " " + after;
+    const pos = start + name.length + 2;
+    textarea.setSelectionRange(pos, pos);
+    list.style.display = "none";
+    textarea.focus();
+  }
+
+  function showUsers(users) {
+    list.innerHTML = "";
+    if (!users || users.length === 0) {
+      list.style.display = "none";
+      return;
+    }
+    users.forEach(function (user) {
+      const item = document.createElement("a");
+      item.className = "mention-item";
+      item.textContent = "@" + user.name;
+      item.addEventListener("mousedown", function (e) {
+        e.preventDefault(); // keep focus in the textarea
+        insertName(user.name);
+      });
+      list.appendChild(item);
+    });
+    list.style.display = "";
+  }
+
+  textarea.addEventListener("input", function () {
+    clearTimeout(timer);
+    const prefix = currentPrefix();
+    if (!prefix) {
+      list.style.display = "none";
+      return;
+    }
+    timer = setTimeout(function () {
+      fetch("/api/users/lookup?prefix=" + encodeURIComponent(prefix), {
+        credentials: "same-origin",
+      })
+        .then((response) => (response.ok ? response.json() : []))
+        .then(showUsers)
+        .catch(() => showUsers([]));
+    }, 200);
+  });
+
+  textarea.addEventListener("blur", function () {
+    list.style.display = "none";
+  });
+}
+
+window.addEventListener("DOMContentLoaded", function () {
+  document.querySelectorAll("textarea[data-mentions]").forEach(setupMentionAutocomplete);
+});
@@ -0,0 +1,39 @@
+// Tag autocomplete for inputs marked with data-tags. The input holds a comma
+// separated list, suggestions for the last tag are offered through a datalist
+// whose options repeat the tags typed before it.
+function setupTagAutocomplete(input, index) {
+  const list = document.createElement("datalist");
+  list.id = "tag-suggestions-" + index;
+  input.setAttribute("list", list.id);
+  input.after(list);
+
+  let timer = null;
+  input.addEventListener("input", function () {
+    clearTimeout(timer);
+    timer = setTimeout(function () {
+      const parts = input.value.split(",");
+      const prefix = parts.pop().trim();
+      const before = parts.map(function (p) { return p.trim(); }).filter(Boolean);
+      if (prefix === "") {
+        list.innerHTML = "";
+        return;
+      }
+      fetch("/api/tags?q=" + encodeURIComponent(prefix))
+        .then(function (res) { return res.ok ? res.json() : []; })
+        .then(function (tags) {
+          list.innerHTML = "";
+          tags.forEach(function (tag) {
+            const option = document.createElement("option");
+            option.value = before.concat(tag.name).join(", ");
+            option.label = tag.name + " (" + tag.usage_count + ")";
+            list.appendChild(option);
+          });
+        })
+        .catch(function () {});
+    }, 200);
+  });
+}
+
+window.addEventListener("DOMContentLoaded", function () {
+  document.querySelectorAll("input[data-tags]").forEach(setupTagAutocomplete);
+});
@@ -1,56 +1,105 @@
 package routes
 
 import (
+	"bytes"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"forum/internal"
 	"forum/utils"
 )
 
+// GET /account?user_id=
+// old profile address, kept so existing links keep working
 func ReadThreadsFromAccount(writer http.ResponseWriter, request *http.Request) {
-	if request.Method == "POST" {
-		utils.MethodNotAllowed(writer, request, "POST method not allowed")
-		return
-	} else if request.Method == "GET" {
-		// Check if user is authenticated
-		if !IsAuthenticated(request) {
-			http.Redirect(writer, request, "/login", 302)
-			return
-		}
-
-		// Get user ID from URL parameters
-		URLID := request.URL.Query().Get("user_id")
-		if URLID == "" {
-			utils.BadRequest(writer, request, "User ID is required")
-			return
-		}
-
-		URLIDConv, err := strconv.Atoi(URLID)
-		if err != nil {
-			utils.BadRequest(writer, request, "Invalid user ID format")
-			return
-		}
-
-		templateData, err := internal.ReadDMFromAccount(URLIDConv)
-		if err != nil {
-			utils.BadRequest(writer, request, "Failed to from Account")
-			return
-		}
-		// Use the utility function to generate HTML
-		utils.GenerateHTML(writer, templateData, "layout", "private.navbar", "account", "accountbypost", "cookie-consent")
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	userID, err := strconv.Atoi(request.URL.Query().Get("user_id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid user ID format")
+		return
 	}
+
+	user := GetService(request).GetUserById(userID)
+	if user.Id == 0 {
+		utils.NotFound(writer, request)
+		return
+	}
+	http.Redirect(writer, request, "/u/"+url.PathEscape(user.Name), http.StatusMovedPermanently)
 }
 
 func AccountCheck(writer http.ResponseWriter, request *http.Request) {
-	sess, err := SessionCheck(writer, request)
-	if err != nil || sess.Uuid == "" {
-		fmt.Println("Session check error:", err)
+	user := GetCurrentUser(request)
+	if user == nil {
+		slog.DebugContext(request.Context(), "account check: no authenticated user")
 		http.Redirect(writer, request, "/login", 302)
 		return
 	}
 
-	// Redirect to account page with user ID
-	http.Redirect(writer, request, fmt.Sprintf("/account?user_id=%v", sess.UserId), 302)
+	// Redirect to the profile of the current user
+	http.Redirect(writer, request, "/u/"+url.PathEscape(user.Name), 302)
+}
+
+// GET /account/export
+// zip of the profile, threads, replies, votes and sessions of the current user
+func ExportAccount(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	var archive bytes.Buffer
+	if err := GetService(request).ExportUserData(user.Id, &archive); err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	slog.InfoContext(request.Context(), "data export", "user_id", user.Id)
+
+	filename := fmt.Sprintf("forum-%s-%s.zip", user.Name, time.Now().Format(time.DateOnly))
+	writer.Header().Set("Content-Type", "application/zip")
+	writer.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.Header().Set("Content-Length", strconv.Itoa(archive.Len()))
+	archive.WriteTo(writer)
+}
+
+// POST /account/delete
+// close the account of the current user once their password is confirmed
+func DeleteAccount(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	if request.PostFormValue("confirm") != "yes" || !utils.CheckPassword(user.Password, request.PostFormValue("password")) {
+		renderProfile(writer, request, user.Name, "Confirm with your password to delete your account")
+		return
+	}
+	if err := GetService(request).DeleteAccount(user.Id); err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	slog.InfoContext(request.Context(), "account deleted", "user_id", user.Id, "policy", internal.DeletionPolicy())
+
+	http.SetCookie(writer, &http.Cookie{Name: utils.SessionCookieName(), Value: "", Path: "/", MaxAge: -1, Expires: time.Unix(1, 0)})
+	http.Redirect(writer, request, "/", http.StatusFound)
 }
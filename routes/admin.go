@@ -0,0 +1,180 @@
+package routes
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"forum/internal"
+	"forum/models"
+	"forum/utils"
+)
+
+// dashboardDays is the range shown when none is selected
+const dashboardDays = 30
+
+// dashboardPreset is a link to the last days up to today
+type dashboardPreset struct {
+	Label string
+	From  string
+}
+
+// dashboardRange reads the from and to days (YYYY-MM-DD) of the query,
+// by default the last dashboardDays days up to today
+func dashboardRange(request *http.Request) (from, to time.Time, err error) {
+	to = time.Now()
+	if value := request.URL.Query().Get("to"); value != "" {
+		if to, err = time.ParseInLocation(time.DateOnly, value, time.Local); err != nil {
+			return from, to, err
+		}
+	}
+	from = to.AddDate(0, 0, -(dashboardDays - 1))
+	if value := request.URL.Query().Get("from"); value != "" {
+		if from, err = time.ParseInLocation(time.DateOnly, value, time.Local); err != nil {
+			return from, to, err
+		}
+	}
+	return from, to, nil
+}
+
+// loadDashboard answers 400 for a bad range and 500 for a failed query,
+// ok is false when it did
+func loadDashboard(writer http.ResponseWriter, request *http.Request) (dashboard models.Dashboard, ok bool) {
+	from, to, err := dashboardRange(request)
+	if err != nil {
+		utils.BadRequest(writer, request, "Dates must be YYYY-MM-DD")
+		return dashboard, false
+	}
+	dashboard, err = GetService(request).Dashboard(from, to)
+	if errors.Is(err, internal.ErrInvalidRange) {
+		utils.BadRequest(writer, request, fmt.Sprintf("The range must start before it ends and span at most %d days", internal.MaxDashboardDays))
+		return dashboard, false
+	} else if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return dashboard, false
+	}
+	return dashboard, true
+}
+
+// GET /admin?from=&to=
+// totals, activity over time, rankings and online users for admins
+func AdminDashboard(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	dashboard, ok := loadDashboard(writer, request)
+	if !ok {
+		return
+	}
+
+	// the busiest day and category set the full width of the bars
+	maxDay, maxCategory := 0, 0
+	for _, day := range dashboard.Daily {
+		maxDay = max(maxDay, day.Signups+day.Threads+day.Posts)
+	}
+	for _, category := range dashboard.Categories {
+		maxCategory = max(maxCategory, category.Threads)
+	}
+
+	today := time.Now()
+	pageData := struct {
+		models.Dashboard
+		MaxDay      int
+		MaxCategory int
+		FromDay     string
+		ToDay       string
+		Presets     []dashboardPreset
+		Today       string
+	}{
+		Dashboard:   dashboard,
+		MaxDay:      maxDay,
+		MaxCategory: maxCategory,
+		FromDay:     dashboard.From.Format(time.DateOnly),
+		ToDay:       dashboard.To.Format(time.DateOnly),
+		Today:       today.Format(time.DateOnly),
+	}
+	for _, days := range []int{7, 30, 90, 365} {
+		pageData.Presets = append(pageData.Presets, dashboardPreset{
+			Label: strconv.Itoa(days) + " days",
+			From:  today.AddDate(0, 0, -(days - 1)).Format(time.DateOnly),
+		})
+	}
+	utils.ServePage(writer, request, utils.PrivatePage("admin"), pageData)
+}
+
+// GET /admin/export?table=daily|users|threads|categories&from=&to=
+// one dashboard table as CSV
+func AdminExport(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	table := request.URL.Query().Get("table")
+	if table == "" {
+		table = "daily"
+	}
+	var header []string
+	switch table {
+	case "daily":
+		header = []string{"day", "signups", "threads", "posts"}
+	case "users":
+		header = []string{"user_id", "name", "threads", "posts"}
+	case "threads":
+		header = []string{"thread_id", "topic", "likes", "dislikes"}
+	case "categories":
+		header = []string{"category", "threads"}
+	default:
+		utils.BadRequest(writer, request, "Unknown table, want daily, users, threads or categories")
+		return
+	}
+
+	dashboard, ok := loadDashboard(writer, request)
+	if !ok {
+		return
+	}
+
+	records := [][]string{header}
+	switch table {
+	case "daily":
+		for _, d := range dashboard.Daily {
+			records = append(records, []string{d.Day.Format(time.DateOnly), strconv.Itoa(d.Signups), strconv.Itoa(d.Threads), strconv.Itoa(d.Posts)})
+		}
+	case "users":
+		for _, u := range dashboard.ActiveUsers {
+			records = append(records, []string{strconv.Itoa(u.UserId), u.Name, strconv.Itoa(u.Threads), strconv.Itoa(u.Posts)})
+		}
+	case "threads":
+		for _, t := range dashboard.VotedThreads {
+			records = append(records, []string{strconv.Itoa(t.ThreadId), t.Topic, strconv.Itoa(t.Likes), strconv.Itoa(t.Dislikes)})
+		}
+	case "categories":
+		for _, c := range dashboard.Categories {
+			records = append(records, []string{c.Category, strconv.Itoa(c.Threads)})
+		}
+	}
+
+	filename := fmt.Sprintf("forum-%s-%s-%s.csv", table, dashboard.From.Format(time.DateOnly), dashboard.To.Format(time.DateOnly))
+	writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	writer.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	out := csv.NewWriter(writer)
+	for _, record := range records {
+		out.Write(csvSafe(record))
+	}
+	out.Flush()
+}
+
+// csvSafe keeps spreadsheets from running user text such as topics as formulas
+func csvSafe(record []string) []string {
+	for i, field := range record {
+		if field != "" && (field[0] == '=' || field[0] == '+' || field[0] == '-' || field[0] == '@') {
+			record[i] = "'" + field
+		}
+	}
+	return record
+}
@@ -7,7 +7,6 @@ import (
 	"strconv"
 	"strings"
 
-	"forum/internal"
 	"forum/models"
 	"forum/utils"
 )
@@ -19,6 +18,8 @@ func GetThreadCounts(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	service := GetService(request)
+
 	path := request.URL.Path
 	parts := strings.Split(path, "/")
 	if len(parts) < 4 {
@@ -34,13 +35,13 @@ func GetThreadCounts(writer http.ResponseWriter, request *http.Request) {
 	}
 
 	// Get likes and dislikes count using DatabaseManager
-	likesCount, err := internal.GetThreadLikesCount(threadId)
+	likesCount, err := service.GetThreadLikesCount(threadId)
 	if err != nil {
 		utils.InternalServerError(writer, request, err)
 		return
 	}
 
-	dislikesCount, err := internal.GetThreadDislikesCount(threadId)
+	dislikesCount, err := service.GetThreadDislikesCount(threadId)
 	if err != nil {
 		utils.InternalServerError(writer, request, err)
 		return
@@ -62,8 +63,8 @@ func LikeThread(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	dbManager := GetDatabaseManager(request)
-	if dbManager == nil {
+	service := GetService(request)
+	if service == nil {
 		utils.InternalServerError(writer, request, fmt.Errorf("database connection unavailable"))
 		return
 	}
@@ -91,30 +92,21 @@ func LikeThread(writer http.ResponseWriter, request *http.Request) {
 	}
 
 	// Check if thread exists
-	_, err = dbManager.GetThreadByID(threadId)
+	_, err = service.Threads.GetThreadByID(threadId)
 	if err != nil {
 		utils.NotFound(writer, request)
 		return
 	}
 
 	// Apply the like using smart function
-	err = dbManager.SmartApplyThreadLike(user.Id, threadId)
+	err = service.ToggleThreadVote(user.Id, threadId, true)
 	if err != nil {
 		utils.InternalServerError(writer, request, err)
 		return
 	}
 
-	// Get updated counts
-	likesCount, _ := dbManager.GetThreadLikesCount(threadId)
-	dislikesCount, _ := dbManager.GetThreadDislikesCount(threadId)
-
 	// Return updated counts with vote status
-	status := models.ThreadVoteStatus{
-		Likes:        likesCount,
-		Dislikes:     dislikesCount,
-		UserLiked:    dbManager.HasThreadLiked(user.Id, threadId),
-		UserDisliked: dbManager.HasThreadDisliked(user.Id, threadId),
-	}
+	status := service.ThreadVotes(user.Id, threadId)
 
 	writer.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(writer).Encode(status)
@@ -127,8 +119,8 @@ func DislikeThread(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	dbManager := GetDatabaseManager(request)
-	if dbManager == nil {
+	service := GetService(request)
+	if service == nil {
 		utils.InternalServerError(writer, request, fmt.Errorf("database connection unavailable"))
 		return
 	}
@@ -156,30 +148,21 @@ func DislikeThread(writer http.ResponseWriter, request *http.Request) {
 	}
 
 	// Check if thread exists
-	_, err = dbManager.GetThreadByID(threadId)
+	_, err = service.Threads.GetThreadByID(threadId)
 	if err != nil {
 		utils.NotFound(writer, request)
 		return
 	}
 
 	// Apply the dislike using smart function
-	err = dbManager.SmartApplyThreadDislike(user.Id, threadId)
+	err = service.ToggleThreadVote(user.Id, threadId, false)
 	if err != nil {
 		utils.InternalServerError(writer, request, err)
 		return
 	}
 
-	// Get updated counts
-	likesCount, _ := dbManager.GetThreadLikesCount(threadId)
-	dislikesCount, _ := dbManager.GetThreadDislikesCount(threadId)
-
 	// Return updated counts with vote status
-	status := models.ThreadVoteStatus{
-		Likes:        likesCount,
-		Dislikes:     dislikesCount,
-		UserLiked:    dbManager.HasThreadLiked(user.Id, threadId),
-		UserDisliked: dbManager.HasThreadDisliked(user.Id, threadId),
-	}
+	status := service.ThreadVotes(user.Id, threadId)
 
 	writer.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(writer).Encode(status)
@@ -187,8 +170,8 @@ func DislikeThread(writer http.ResponseWriter, request *http.Request) {
 
 // GET /api/thread/{id}/status
 func GetThreadVoteStatus(writer http.ResponseWriter, request *http.Request) {
-	dbManager := GetDatabaseManager(request)
-	if dbManager == nil {
+	service := GetService(request)
+	if service == nil {
 		utils.InternalServerError(writer, request, fmt.Errorf("database connection unavailable"))
 		return
 	}
@@ -209,7 +192,7 @@ func GetThreadVoteStatus(writer http.ResponseWriter, request *http.Request) {
 	}
 
 	// Check if thread exists
-	_, err = dbManager.GetThreadByID(threadId)
+	_, err = service.Threads.GetThreadByID(threadId)
 	if err != nil {
 		utils.NotFound(writer, request)
 		return
@@ -218,17 +201,12 @@ func GetThreadVoteStatus(writer http.ResponseWriter, request *http.Request) {
 	// Get current user (may be nil for unauthenticated users)
 	user := GetCurrentUser(request)
 
-	// Get counts
-	likesCount, _ := dbManager.GetThreadLikesCount(threadId)
-	dislikesCount, _ := dbManager.GetThreadDislikesCount(threadId)
-
 	// Return vote status (even for unauthenticated users, just without personal vote info)
-	status := models.ThreadVoteStatus{
-		Likes:        likesCount,
-		Dislikes:     dislikesCount,
-		UserLiked:    user != nil && dbManager.HasThreadLiked(user.Id, threadId),
-		UserDisliked: user != nil && dbManager.HasThreadDisliked(user.Id, threadId),
+	userID := 0
+	if user != nil {
+		userID = user.Id
 	}
+	status := service.ThreadVotes(userID, threadId)
 
 	writer.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(writer).Encode(status)
@@ -241,8 +219,8 @@ func VoteThread(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	dbManager := GetDatabaseManager(request)
-	if dbManager == nil {
+	service := GetService(request)
+	if service == nil {
 		utils.InternalServerError(writer, request, fmt.Errorf("database connection unavailable"))
 		return
 	}
@@ -266,7 +244,7 @@ func VoteThread(writer http.ResponseWriter, request *http.Request) {
 	}
 
 	// Check if thread exists
-	_, err = dbManager.GetThreadByID(threadId)
+	_, err = service.Threads.GetThreadByID(threadId)
 	if err != nil {
 		utils.NotFound(writer, request)
 		return
@@ -284,9 +262,9 @@ func VoteThread(writer http.ResponseWriter, request *http.Request) {
 	// Apply the appropriate vote
 	switch voteType {
 	case "like":
-		err = dbManager.SmartApplyThreadLike(user.Id, threadId)
+		err = service.ToggleThreadVote(user.Id, threadId, true)
 	case "dislike":
-		err = dbManager.SmartApplyThreadDislike(user.Id, threadId)
+		err = service.ToggleThreadVote(user.Id, threadId, false)
 	default:
 		http.Redirect(writer, request, request.Header.Get("Referer"), http.StatusSeeOther)
 		return
@@ -304,17 +282,7 @@ func VoteThread(writer http.ResponseWriter, request *http.Request) {
 
 	if isAjax {
 		// Return JSON response for AJAX
-		likesCount, _ := dbManager.GetThreadLikesCount(threadId)
-		dislikesCount, _ := dbManager.GetThreadDislikesCount(threadId)
-		userLiked := dbManager.HasThreadLiked(user.Id, threadId)
-		userDisliked := dbManager.HasThreadDisliked(user.Id, threadId)
-
-		response := models.ThreadVoteStatus{
-			Likes:        likesCount,
-			Dislikes:     dislikesCount,
-			UserLiked:    userLiked,
-			UserDisliked: userDisliked,
-		}
+		response := service.ThreadVotes(user.Id, threadId)
 
 		writer.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(writer).Encode(response)
@@ -361,39 +329,28 @@ func LikePost(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	// Get database manager
-	dbManager := GetDatabaseManager(request)
-	if dbManager == nil {
+	service := GetService(request)
+	if service == nil {
 		utils.InternalServerError(writer, request, fmt.Errorf("database connection unavailable"))
 		return
 	}
 
 	// Verify the post exists
-	_, err = dbManager.GetPostByID(postId)
+	_, err = service.Posts.GetPostByID(postId)
 	if err != nil {
 		utils.NotFound(writer, request)
 		return
 	}
 
 	// Apply the like using smart function
-	err = dbManager.SmartApplyPostLike(user.Id, postId)
+	err = service.TogglePostVote(user.Id, postId, true)
 	if err != nil {
 		utils.InternalServerError(writer, request, err)
 		return
 	}
 
 	// Return updated counts with vote status
-	likes, _ := dbManager.GetPostLikesCount(postId)
-	dislikes, _ := dbManager.GetPostDislikesCount(postId)
-	userLiked, _ := dbManager.HasUserLikedPost(user.Id, postId)
-	userDisliked, _ := dbManager.HasUserDislikedPost(user.Id, postId)
-
-	status := models.ThreadVoteStatus{
-		Likes:        likes,
-		Dislikes:     dislikes,
-		UserLiked:    userLiked,
-		UserDisliked: userDisliked,
-	}
+	status := service.PostVotes(user.Id, postId)
 
 	writer.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(writer).Encode(status)
@@ -428,39 +385,28 @@ func DislikePost(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	// Get database manager
-	dbManager := GetDatabaseManager(request)
-	if dbManager == nil {
+	service := GetService(request)
+	if service == nil {
 		utils.InternalServerError(writer, request, fmt.Errorf("database connection unavailable"))
 		return
 	}
 
 	// Verify the post exists
-	_, err = dbManager.GetPostByID(postId)
+	_, err = service.Posts.GetPostByID(postId)
 	if err != nil {
 		utils.NotFound(writer, request)
 		return
 	}
 
 	// Apply the dislike using smart function
-	err = dbManager.SmartApplyPostDislike(user.Id, postId)
+	err = service.TogglePostVote(user.Id, postId, false)
 	if err != nil {
 		utils.InternalServerError(writer, request, err)
 		return
 	}
 
 	// Return updated counts with vote status
-	likes, _ := dbManager.GetPostLikesCount(postId)
-	dislikes, _ := dbManager.GetPostDislikesCount(postId)
-	userLiked, _ := dbManager.HasUserLikedPost(user.Id, postId)
-	userDisliked, _ := dbManager.HasUserDislikedPost(user.Id, postId)
-
-	status := models.ThreadVoteStatus{
-		Likes:        likes,
-		Dislikes:     dislikes,
-		UserLiked:    userLiked,
-		UserDisliked: userDisliked,
-	}
+	status := service.PostVotes(user.Id, postId)
 
 	writer.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(writer).Encode(status)
@@ -486,37 +432,25 @@ func GetPostVoteStatus(writer http.ResponseWriter, request *http.Request) {
 	// Get current user (may be nil for unauthenticated users)
 	user := GetCurrentUser(request)
 
-	// Get database manager
-	dbManager := GetDatabaseManager(request)
-	if dbManager == nil {
+	service := GetService(request)
+	if service == nil {
 		utils.InternalServerError(writer, request, fmt.Errorf("database connection unavailable"))
 		return
 	}
 
 	// Verify the post exists
-	_, err = dbManager.GetPostByID(postId)
+	_, err = service.Posts.GetPostByID(postId)
 	if err != nil {
 		utils.NotFound(writer, request)
 		return
 	}
 
-	// Get counts and user vote status
-	likes, _ := dbManager.GetPostLikesCount(postId)
-	dislikes, _ := dbManager.GetPostDislikesCount(postId)
-
-	var userLiked, userDisliked bool
-	if user != nil {
-		userLiked, _ = dbManager.HasUserLikedPost(user.Id, postId)
-		userDisliked, _ = dbManager.HasUserDislikedPost(user.Id, postId)
-	}
-
 	// Return vote status (even for unauthenticated users, just without personal vote info)
-	status := models.ThreadVoteStatus{
-		Likes:        likes,
-		Dislikes:     dislikes,
-		UserLiked:    userLiked,
-		UserDisliked: userDisliked,
+	userID := 0
+	if user != nil {
+		userID = user.Id
 	}
+	status := service.PostVotes(userID, postId)
 
 	writer.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(writer).Encode(status)
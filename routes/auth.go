@@ -2,11 +2,11 @@ package routes
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
-	"forum/internal"
 	"forum/models"
 	"forum/utils"
 )
@@ -24,7 +24,8 @@ func Login(writer http.ResponseWriter, request *http.Request, LS models.LoginSki
 			Email:  LS.Email,
 			Error:  "Signup successful! Please log in.",
 		}
-		utils.GenerateHTML(writer, &LS, "login.layout", "public.navbar", "login")
+		LS.Next = safeNext(request.FormValue("next"))
+		utils.ServePage(writer, request, utils.LoginPage("login"), &LS)
 		Error = ""
 		return
 	} else {
@@ -46,7 +47,8 @@ func Login(writer http.ResponseWriter, request *http.Request, LS models.LoginSki
 			}
 		}
 
-		utils.GenerateHTML(writer, &LS, "login.layout", "public.navbar", "login")
+		LS.Next = safeNext(request.FormValue("next"))
+		utils.ServePage(writer, request, utils.LoginPage("login"), &LS)
 		Error = ""
 	}
 }
@@ -74,7 +76,7 @@ func Signup(writer http.ResponseWriter, request *http.Request, LS models.LoginSk
 			}
 		}
 		Error = ""
-		utils.GenerateHTML(writer, &LS, "public.navbar", "login.layout", "signup")
+		utils.ServePage(writer, request, utils.LoginPage("signup"), &LS)
 	case "POST":
 		// Generate a UUID for the signup process (optional, for tracking or CSRF protection)
 		// uuid := utils.GenerateUUID() // Implement this function if needed
@@ -95,6 +97,8 @@ func SignupAccount(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	service := GetService(request)
+
 	err := request.ParseForm()
 	if err != nil {
 		utils.BadRequest(writer, request, "Cannot parse form data")
@@ -119,7 +123,7 @@ func SignupAccount(writer http.ResponseWriter, request *http.Request) {
 			Email:  email,
 			Error:  Error,
 		}
-		utils.GenerateHTML(writer, &user, "login.layout", "public.navbar", "signup")
+		utils.ServePage(writer, request, utils.LoginPage("signup"), &user)
 		return
 	}
 
@@ -134,7 +138,7 @@ func SignupAccount(writer http.ResponseWriter, request *http.Request) {
 				Email:  email,
 				Error:  Error,
 			}
-			utils.GenerateHTML(writer, &user, "login.layout", "public.navbar", "signup")
+			utils.ServePage(writer, request, utils.LoginPage("signup"), &user)
 			return
 		}
 	}
@@ -147,7 +151,7 @@ func SignupAccount(writer http.ResponseWriter, request *http.Request) {
 			Email:  email,
 			Error:  "Wrong UserName format",
 		}
-		utils.GenerateHTML(writer, &user, "login.layout", "public.navbar", "signup")
+		utils.ServePage(writer, request, utils.LoginPage("signup"), &user)
 		return
 	}
 	for _, ch := range name {
@@ -159,12 +163,12 @@ func SignupAccount(writer http.ResponseWriter, request *http.Request) {
 				Email:  email,
 				Error:  "Wrong UserName format",
 			}
-			utils.GenerateHTML(writer, &user, "login.layout", "public.navbar", "signup")
+			utils.ServePage(writer, request, utils.LoginPage("signup"), &user)
 			return
 		}
 	}
 	// Check if user already exists
-	checkExists := internal.IfUserExist(request.PostFormValue("email"), request.PostFormValue("name"))
+	checkExists := service.IfUserExist(request.PostFormValue("email"), request.PostFormValue("name"))
 	if checkExists {
 		Error = "This name/email already exists\nTry to signup again using different username/email"
 		user := models.LoginSkin{
@@ -174,7 +178,7 @@ func SignupAccount(writer http.ResponseWriter, request *http.Request) {
 			Email:  request.PostFormValue("email"),
 			Error:  Error,
 		}
-		utils.GenerateHTML(writer, &user, "login.layout", "public.navbar", "signup")
+		utils.ServePage(writer, request, utils.LoginPage("signup"), &user)
 		return
 	}
 
@@ -187,7 +191,7 @@ func SignupAccount(writer http.ResponseWriter, request *http.Request) {
 			Email:  request.PostFormValue("email"),
 			Error:  Error,
 		}
-		utils.GenerateHTML(writer, &user, "login.layout", "public.navbar", "signup")
+		utils.ServePage(writer, request, utils.LoginPage("signup"), &user)
 		return
 	}
 
@@ -198,7 +202,7 @@ func SignupAccount(writer http.ResponseWriter, request *http.Request) {
 	}
 
 	// Use database manager to create user
-	if err := internal.CreateUser(usertoSign); err != nil {
+	if err := service.CreateUser(usertoSign); err != nil {
 		utils.InternalServerError(writer, request, err)
 		return
 	}
@@ -213,8 +217,8 @@ func Authenticate(writer http.ResponseWriter, request *http.Request) {
 		utils.MethodNotAllowed(writer, request, "POST method only")
 		return
 	}
-	dbManager := GetDatabaseManager(request)
-	if dbManager == nil {
+	service := GetService(request)
+	if service == nil {
 		utils.InternalServerError(writer, request, fmt.Errorf("database connection unavailable"))
 		return
 	}
@@ -232,54 +236,46 @@ func Authenticate(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	fmt.Printf("Login attempt for email: %s\n", email)
-
-	// Use database manager to get user
-	user, err := dbManager.GetUserByEmail(email)
+	user, err := service.Users.GetUserByEmailDetailed(email)
 	if err != nil {
 		Error = "You might entered wrong email/password \n Try again"
 		Login(writer, request, models.LoginSkin{})
 		return
 	}
 
-	fmt.Printf("Found user: %s (ID: %d)\n", user.Name, user.Id)
-
-	if utils.CheckPassword(user.Password, password) {
-		fmt.Println("Password correct, checking existing session...")
-
+	passwordOK := utils.CheckPassword(user.Password, password)
+	if passwordOK && user.Status != "" && user.Status != models.StatusActive {
+		// banned users are told so, deleted accounts look like a wrong password
+		slog.InfoContext(request.Context(), "login refused", "user_id", user.Id, "status", user.Status)
+		Error = "You might entered wrong email/password \n Try again"
+		if user.Status == models.StatusBanned {
+			Error = "This account has been banned"
+		}
+		Login(writer, request, models.LoginSkin{})
+	} else if passwordOK {
 		// Check if user already has a session from middleware
 		if IsAuthenticated(request) {
-			fmt.Println("Existing valid session found, redirecting to home")
 			http.Redirect(writer, request, "/", 302)
 			return
 		}
 
-		fmt.Println("Creating new session...")
-		// Create session using database manager
-		session, err := dbManager.CreateSession(&user)
+		session, err := service.Sessions.CreateSession(&user)
 		if err != nil {
-			fmt.Printf("Failed to create session: %v\n", err)
 			utils.InternalServerError(writer, request, err)
 			return
 		}
 
-		fmt.Printf("Session created successfully. CookieString: %s\n", session.CookieString)
+		slog.InfoContext(request.Context(), "login", "user_id", user.Id)
 
 		// Use the cookie string from the session
-		cookie := http.Cookie{
-			Name:     "_cookie",
-			Value:    session.CookieString,
-			HttpOnly: false, // Allow JavaScript access for debugging
-			Expires:  time.Now().Add(24 * time.Hour),
-			Path:     "/",
-			SameSite: http.SameSiteLaxMode, // Allow same-site requests
-			Secure:   false,                // Allow over HTTP for local development
+		http.SetCookie(writer, utils.SessionCookie(session.CookieString))
+		if next := safeNext(request.PostFormValue("next")); next != "" {
+			http.Redirect(writer, request, next, 302)
+			return
 		}
-		fmt.Printf("Setting cookie: %s = %s\n", cookie.Name, cookie.Value) // Debug
-		http.SetCookie(writer, &cookie)
 		http.Redirect(writer, request, "/", 302)
 	} else {
-		fmt.Printf("Password incorrect for user: %s\n", user.Name)
+		slog.InfoContext(request.Context(), "login failed", "user_id", user.Id)
 		Error = "You might entered wrong email/password \n Try again"
 		Login(writer, request, models.LoginSkin{})
 	}
@@ -292,14 +288,16 @@ func Logout(writer http.ResponseWriter, request *http.Request) {
 		utils.MethodNotAllowed(writer, request, "GET method only")
 		return
 	}
-	cookie, err := request.Cookie("_cookie")
+
+	service := GetService(request)
+	cookie, err := request.Cookie(utils.SessionCookieName())
 
 	if err != http.ErrNoCookie && cookie != nil {
 		// Find session by cookie value
-		session, err := internal.GetSessionByCookie(cookie.Value)
+		session, err := service.GetSessionByCookie(cookie.Value)
 		if err == nil {
 			// Delete the session
-			internal.DeleteByUUID(session.Uuid)
+			service.DeleteByUUID(session.Uuid)
 		}
 
 		// Invalidate the cookie
@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"forum/internal"
+	"forum/internal/data"
+	"forum/models"
+	"forum/utils"
+)
+
+// GET /admin/backups lists the database snapshots,
+// POST /admin/backups takes one now
+func AdminBackups(writer http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case "GET":
+	case "POST":
+		admin := GetCurrentUser(request)
+		backup, err := GetService(request).CreateBackup(request.Context(), time.Now())
+		if errors.Is(err, data.ErrNotSQLite) || errors.Is(err, internal.ErrBackupExists) {
+			utils.BadRequest(writer, request, err.Error())
+			return
+		} else if err != nil {
+			utils.InternalServerError(writer, request, err)
+			return
+		}
+		if err := GetService(request).AuditBackup(*admin, "create", backup.Name); err != nil {
+			slog.ErrorContext(request.Context(), "cannot audit backup", "error", err)
+		}
+		http.Redirect(writer, request, "/admin/backups", http.StatusFound)
+		return
+	default:
+		utils.MethodNotAllowed(writer, request, "GET or POST method only")
+		return
+	}
+
+	backups, err := internal.ListBackups()
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	pageData := struct {
+		Backups []models.Backup
+		Dir     string
+	}{
+		Backups: backups,
+		Dir:     internal.BackupDir(),
+	}
+	utils.ServePage(writer, request, utils.PrivatePage("admin.backups"), pageData)
+}
+
+// GET /admin/backups/download?name=
+// one snapshot as an SQLite file, every download is audited
+func AdminBackupDownload(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	name := request.URL.Query().Get("name")
+	path, err := internal.BackupPath(name)
+	if err != nil {
+		utils.NotFound(writer, request)
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	if err := GetService(request).AuditBackup(*GetCurrentUser(request), "download", name); err != nil {
+		slog.ErrorContext(request.Context(), "cannot audit backup", "error", err)
+	}
+	writer.Header().Set("Content-Type", "application/vnd.sqlite3")
+	writer.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	writer.Header().Set("Cache-Control", "no-store")
+	http.ServeContent(writer, request, name, info.ModTime(), file)
+}
@@ -0,0 +1,306 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"forum/models"
+	"forum/utils"
+)
+
+type bookmarksPage struct {
+	Bookmarks   []models.Bookmark
+	Collections []models.Collection
+	Filter      models.BookmarkFilter
+	HasToken    bool
+	NewToken    string
+	Error       string
+}
+
+// bookmarkFilter reads ?collection=&kind=&q=, a missing collection means all
+func bookmarkFilter(request *http.Request) models.BookmarkFilter {
+	vals := request.URL.Query()
+	filter := models.BookmarkFilter{
+		CollectionId: -1,
+		Kind:         vals.Get("kind"),
+		Query:        strings.TrimSpace(vals.Get("q")),
+	}
+	if id, err := strconv.Atoi(vals.Get("collection")); err == nil {
+		filter.CollectionId = id
+	}
+	return filter
+}
+
+// GET /bookmarks?collection=&kind=&q=
+// saved threads and posts of the current user
+func Bookmarks(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+	renderBookmarks(writer, request, bookmarksPage{Filter: bookmarkFilter(request)})
+}
+
+func renderBookmarks(writer http.ResponseWriter, request *http.Request, page bookmarksPage) {
+	service := GetService(request)
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	var err error
+	page.Bookmarks, err = service.UserBookmarks(user.Id, page.Filter)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	page.Collections, err = service.Collections(user.Id)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	page.HasToken = service.HasAPIToken(user.Id)
+
+	if page.Error != "" {
+		writer.WriteHeader(http.StatusBadRequest)
+	}
+	utils.ServePage(writer, request, utils.PrivatePage("bookmarks"), page)
+}
+
+// bookmarkFormError shows a validation error on the bookmarks page
+func bookmarkFormError(writer http.ResponseWriter, request *http.Request, err error) {
+	renderBookmarks(writer, request, bookmarksPage{
+		Filter: models.BookmarkFilter{CollectionId: -1},
+		Error:  err.Error(),
+	})
+}
+
+// POST /bookmarks/add
+// bookmark a thread, or one of its posts when post_id is set
+func AddBookmark(writer http.ResponseWriter, request *http.Request) {
+	service := GetService(request)
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	threadID, err := strconv.Atoi(request.PostFormValue("thread_id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid thread ID format")
+		return
+	}
+	// post_id and collection_id are optional
+	postID, _ := strconv.Atoi(request.PostFormValue("post_id"))
+	collectionID, _ := strconv.Atoi(request.PostFormValue("collection_id"))
+
+	if err := service.SaveBookmark(user.Id, threadID, postID, collectionID, request.PostFormValue("note")); err != nil {
+		utils.BadRequest(writer, request, err.Error())
+		return
+	}
+
+	target := threadPath(service, threadID)
+	if postID != 0 {
+		target += "#post-" + strconv.Itoa(postID)
+	}
+	http.Redirect(writer, request, target, http.StatusFound)
+}
+
+// POST /bookmarks/update
+// move a bookmark to another collection and edit its note
+func UpdateBookmark(writer http.ResponseWriter, request *http.Request) {
+	user, bookmarkID, ok := bookmarkAction(writer, request)
+	if !ok {
+		return
+	}
+
+	collectionID, _ := strconv.Atoi(request.PostFormValue("collection_id"))
+	if err := GetService(request).UpdateBookmark(user.Id, bookmarkID, collectionID, request.PostFormValue("note")); err != nil {
+		bookmarkFormError(writer, request, err)
+		return
+	}
+	http.Redirect(writer, request, "/bookmarks", http.StatusFound)
+}
+
+// POST /bookmarks/remove
+func RemoveBookmark(writer http.ResponseWriter, request *http.Request) {
+	user, bookmarkID, ok := bookmarkAction(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := GetService(request).DeleteBookmark(user.Id, bookmarkID); err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	http.Redirect(writer, request, "/bookmarks", http.StatusFound)
+}
+
+// POST /bookmarks/collections
+// create a named collection
+func CreateCollection(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	if err := GetService(request).CreateCollection(user.Id, request.PostFormValue("name")); err != nil {
+		bookmarkFormError(writer, request, err)
+		return
+	}
+	http.Redirect(writer, request, "/bookmarks", http.StatusFound)
+}
+
+// POST /bookmarks/collections/delete
+// delete a collection, its bookmarks are kept
+func DeleteCollection(writer http.ResponseWriter, request *http.Request) {
+	user, collectionID, ok := bookmarkAction(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := GetService(request).DeleteCollection(user.Id, collectionID); err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	http.Redirect(writer, request, "/bookmarks", http.StatusFound)
+}
+
+// POST /bookmarks/token
+// generate (action=generate) or revoke (action=revoke) the personal API token
+func BookmarkToken(writer http.ResponseWriter, request *http.Request) {
+	service := GetService(request)
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	switch request.PostFormValue("action") {
+	case "generate":
+		token, err := service.GenerateAPIToken(user.Id)
+		if err != nil {
+			utils.InternalServerError(writer, request, err)
+			return
+		}
+		// the token is shown once, it cannot be read back later
+		renderBookmarks(writer, request, bookmarksPage{
+			Filter:   models.BookmarkFilter{CollectionId: -1},
+			NewToken: token,
+		})
+	case "revoke":
+		if err := service.RevokeAPIToken(user.Id); err != nil {
+			utils.InternalServerError(writer, request, err)
+			return
+		}
+		http.Redirect(writer, request, "/bookmarks", http.StatusFound)
+	default:
+		utils.BadRequest(writer, request, "Unknown token action")
+	}
+}
+
+// bookmarkAction checks the method, user and id shared by the POST handlers above
+func bookmarkAction(writer http.ResponseWriter, request *http.Request) (*models.User, int, bool) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return nil, 0, false
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return nil, 0, false
+	}
+
+	id, err := strconv.Atoi(request.PostFormValue("id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid ID format")
+		return nil, 0, false
+	}
+	return user, id, true
+}
+
+// apiUser returns the owner of the "Authorization: Bearer <token>" header,
+// or the session user when no token is sent
+func apiUser(request *http.Request) *models.User {
+	header := request.Header.Get("Authorization")
+	if header == "" {
+		return GetCurrentUser(request)
+	}
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found {
+		return nil
+	}
+	user, err := GetService(request).UserByAPIToken(strings.TrimSpace(token))
+	if err != nil {
+		return nil
+	}
+	return &user
+}
+
+// GET /api/bookmarks?collection=&kind=&q=
+// saved items as JSON, authenticated with the personal API token
+func APIBookmarks(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	user := apiUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Valid API token required")
+		return
+	}
+
+	bookmarks, err := GetService(request).UserBookmarks(user.Id, bookmarkFilter(request))
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(bookmarks)
+}
+
+// GET /api/bookmarks/collections
+// collections with their bookmark counts as JSON
+func APICollections(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	user := apiUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Valid API token required")
+		return
+	}
+
+	collections, err := GetService(request).Collections(user.Id)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(collections)
+}
@@ -4,21 +4,21 @@ import (
 	"fmt"
 	"net/http"
 
-	"forum/internal"
 	"forum/utils"
 )
 
 // Debug route to test cookie functionality
 func DebugPage(writer http.ResponseWriter, request *http.Request) {
-	utils.GenerateHTML(writer, nil, "layout", "private.navbar", "debug")
+	utils.ServePage(writer, request, utils.PrivatePage("debug"), nil)
 }
 
 // Debug route to test cookie values
 func DebugCookieTest(writer http.ResponseWriter, request *http.Request) {
+	service := GetService(request)
 	writer.Header().Set("Content-Type", "text/html")
 
 	// Test GetCookieValue function
-	userID := internal.GetCookieValue(request)
+	userID := service.GetCookieValue(request)
 
 	fmt.Fprintf(writer, "<h3>Cookie Debug Information</h3>")
 	fmt.Fprintf(writer, "<p><strong>GetCookieValue() result:</strong> %d</p>", userID)
@@ -30,14 +30,14 @@ func DebugCookieTest(writer http.ResponseWriter, request *http.Request) {
 	fmt.Fprintf(writer, "</ul>")
 
 	// Try to get _cookie directly
-	cookie, err := request.Cookie("_cookie")
+	cookie, err := request.Cookie(utils.SessionCookieName())
 	if err != nil {
 		fmt.Fprintf(writer, "<p><strong>_cookie error:</strong> %v</p>", err)
 	} else {
 		fmt.Fprintf(writer, "<p><strong>_cookie value:</strong> %s</p>", cookie.Value)
 
 		// Test session lookup
-		session, err := internal.GetSessionByCookie(cookie.Value)
+		session, err := service.GetSessionByCookie(cookie.Value)
 		if err != nil {
 			fmt.Fprintf(writer, "<p><strong>Session lookup error:</strong> %v</p>", err)
 		} else {
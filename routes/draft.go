@@ -0,0 +1,107 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"forum/internal"
+	"forum/models"
+	"forum/utils"
+)
+
+// GET /api/drafts?thread_id=
+// the saved draft of a new thread (thread_id 0) or reply, 204 when there is none
+// POST /api/drafts
+// save the composer content, called periodically by draft-autosave.js
+func APIDraft(writer http.ResponseWriter, request *http.Request) {
+	service := GetService(request)
+	user := GetCurrentUser(request)
+	if user == nil {
+		// the composer keeps a local copy and asks the user to log in again
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	threadID, err := strconv.Atoi(request.FormValue("thread_id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid thread ID format")
+		return
+	}
+
+	switch request.Method {
+	case "GET":
+		draft, err := service.Draft(user.Id, threadID)
+		if errors.Is(err, internal.ErrNoDraft) {
+			writer.WriteHeader(http.StatusNoContent)
+			return
+		} else if err != nil {
+			utils.InternalServerError(writer, request, err)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(draft)
+	case "POST":
+		draft, err := service.SaveDraft(user.Id, models.Draft{
+			ThreadId:  threadID,
+			Topic:     request.PostFormValue("topic"),
+			Body:      request.PostFormValue("body"),
+			Category1: request.PostFormValue("category1"),
+			Category2: request.PostFormValue("category2"),
+		})
+		if err != nil {
+			utils.BadRequest(writer, request, err.Error())
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(map[string]interface{}{"saved_at": draft.UpdatedAt})
+	default:
+		utils.MethodNotAllowed(writer, request, "GET or POST method only")
+	}
+}
+
+// GET /drafts
+// list the unsent threads and replies of the current user
+func Drafts(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	drafts, err := GetService(request).UserDrafts(user.Id)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	utils.ServePage(writer, request, utils.PrivatePage("drafts"), struct{ Drafts []models.Draft }{drafts})
+}
+
+// POST /drafts/delete
+// discard a draft
+func DeleteDraft(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	threadID, err := strconv.Atoi(request.PostFormValue("thread_id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid thread ID format")
+		return
+	}
+	GetService(request).DiscardDraft(user.Id, threadID)
+	http.Redirect(writer, request, "/drafts", http.StatusFound)
+}
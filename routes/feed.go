@@ -0,0 +1,126 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"forum/internal"
+	"forum/models"
+	"forum/utils"
+)
+
+// GET /feeds/latest.atom
+// GET /feeds/category/{name}.atom
+// GET /feeds/thread/{id}.atom
+// GET /feeds/user/{name}.atom
+// GET /feeds/notifications/{token}.atom
+// Atom feeds, every feed is also served as RSS 2.0 with the .rss extension
+func Feeds(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" && request.Method != "HEAD" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	service := GetService(request)
+
+	name := strings.TrimPrefix(request.URL.Path, "/feeds/")
+	format := strings.TrimPrefix(path.Ext(name), ".")
+	if format != "atom" && format != "rss" {
+		utils.NotFound(writer, request)
+		return
+	}
+	name = strings.TrimSuffix(name, path.Ext(name))
+	kind, arg, _ := strings.Cut(name, "/")
+
+	var feed models.Feed
+	var err error
+	private := false
+	switch {
+	case kind == "latest" && arg == "":
+		feed, err = service.LatestThreadsFeed("")
+	case kind == "category" && arg != "":
+		feed, err = service.LatestThreadsFeed(arg)
+	case kind == "thread":
+		threadID, convErr := strconv.Atoi(arg)
+		if convErr != nil {
+			utils.NotFound(writer, request)
+			return
+		}
+		feed, err = service.ThreadRepliesFeed(threadID)
+	case kind == "user" && arg != "":
+		feed, err = service.UserPostsFeed(arg)
+	case kind == "notifications":
+		feed, err = service.NotificationsFeed(arg)
+		private = true
+	default:
+		utils.NotFound(writer, request)
+		return
+	}
+	if errors.Is(err, internal.ErrFeedNotFound) || errors.Is(err, internal.ErrInvalidFeedToken) {
+		utils.NotFound(writer, request)
+		return
+	} else if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	body, err := internal.RenderFeed(feed, format)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	// readers revalidate with If-None-Match or If-Modified-Since
+	// and get a 304 from ServeContent while nothing changed
+	sum := sha256.Sum256(body)
+	writer.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	writer.Header().Set("Content-Type", "application/"+format+"+xml; charset=utf-8")
+	if private {
+		writer.Header().Set("Cache-Control", "private, no-cache")
+	} else {
+		writer.Header().Set("Cache-Control", "no-cache")
+	}
+	http.ServeContent(writer, request, "", feed.Updated, bytes.NewReader(body))
+}
+
+// POST /feeds/token
+// generate (action=generate) or revoke (action=revoke) the secret of the notifications feed
+func FeedToken(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	service := GetService(request)
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	switch request.PostFormValue("action") {
+	case "generate":
+		token, err := service.GenerateFeedToken(user.Id)
+		if err != nil {
+			utils.InternalServerError(writer, request, err)
+			return
+		}
+		// the feed address is shown once, it cannot be read back later
+		renderWatching(writer, request, user, utils.BaseURL()+"/feeds/notifications/"+token+".atom")
+	case "revoke":
+		if err := service.RevokeFeedToken(user.Id); err != nil {
+			utils.InternalServerError(writer, request, err)
+			return
+		}
+		http.Redirect(writer, request, "/watching", http.StatusFound)
+	default:
+		utils.BadRequest(writer, request, "Unknown token action")
+	}
+}
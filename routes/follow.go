@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"forum/utils"
+)
+
+// POST /follow/user
+// follow a member, their new threads show up in "My feed"
+func FollowUser(writer http.ResponseWriter, request *http.Request) {
+	toggleUserFollow(writer, request, true)
+}
+
+// POST /unfollow/user
+func UnfollowUser(writer http.ResponseWriter, request *http.Request) {
+	toggleUserFollow(writer, request, false)
+}
+
+func toggleUserFollow(writer http.ResponseWriter, request *http.Request, follow bool) {
+	service := GetService(request)
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	targetID, err := strconv.Atoi(request.PostFormValue("user_id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid user ID format")
+		return
+	}
+	target := service.GetUserById(targetID)
+	if target.Id == 0 {
+		utils.NotFound(writer, request)
+		return
+	}
+
+	if follow {
+		err = service.FollowUser(user.Id, target.Id)
+	} else {
+		err = service.UnfollowUser(user.Id, target.Id)
+	}
+	if err != nil {
+		utils.BadRequest(writer, request, err.Error())
+		return
+	}
+	http.Redirect(writer, request, "/u/"+url.PathEscape(target.Name), http.StatusFound)
+}
+
+// POST /follow/category
+// follow a category, its new threads show up in "My feed"
+func FollowCategory(writer http.ResponseWriter, request *http.Request) {
+	toggleCategoryFollow(writer, request, true)
+}
+
+// POST /unfollow/category
+func UnfollowCategory(writer http.ResponseWriter, request *http.Request) {
+	toggleCategoryFollow(writer, request, false)
+}
+
+func toggleCategoryFollow(writer http.ResponseWriter, request *http.Request, follow bool) {
+	service := GetService(request)
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	var err error
+	category := request.PostFormValue("category")
+	if follow {
+		err = service.FollowCategory(user.Id, category)
+	} else {
+		err = service.UnfollowCategory(user.Id, category)
+	}
+	if err != nil {
+		utils.BadRequest(writer, request, err.Error())
+		return
+	}
+	http.Redirect(writer, request, "/?tab=feed", http.StatusFound)
+}
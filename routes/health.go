@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"forum/internal"
+)
+
+// healthStatus is the JSON body of /healthz and /readyz
+type healthStatus struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// GET /healthz
+// the process is alive and serving
+func Healthz(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(writer).Encode(healthStatus{Status: "ok"})
+}
+
+// GET /readyz
+// 200 when the forum can serve traffic, 503 with the failed checks otherwise
+func Readyz(health *internal.Health) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		ok, checks := health.Ready()
+		status := healthStatus{Status: "ok", Checks: checks}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Cache-Control", "no-store")
+		if !ok {
+			status.Status = "unavailable"
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(writer).Encode(status)
+	}
+}
@@ -1,8 +1,9 @@
 package routes
 
 import (
-	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 
 	"forum/internal"
 	"forum/models"
@@ -16,69 +17,77 @@ func Err(writer http.ResponseWriter, request *http.Request) {
 		utils.MethodNotAllowed(writer, request, "GET method only")
 		return
 	}
-	vals := request.URL.Query()
+	data := map[string]interface{}{"Message": request.URL.Query().Get("msg")}
 
 	// Use middleware to check authentication
 	if IsAuthenticated(request) {
-		utils.GenerateHTML(writer, vals.Get("msg"), "layout", "private.navbar", "error")
+		utils.ServePage(writer, request, utils.PrivatePage("error"), data)
 	} else {
-		utils.GenerateHTML(writer, vals.Get("msg"), "layout", "public.navbar", "error")
+		utils.ServePage(writer, request, utils.PublicPage("error"), data)
 	}
 }
 
 func Index(writer http.ResponseWriter, request *http.Request) {
+	service := GetService(request)
 	if request.URL.Path != "/" {
 		utils.NotFound(writer, request)
 		return
 	}
+	if request.Method == "GET" && request.URL.Query().Get("tab") == "feed" {
+		Feed(writer, request)
+		return
+	}
+
 	var threads []models.Thread
 	var err error
 	category1, category2 := "", ""
 	sortBy := ""
+	tag := ""
 	reset := "false"
 	catbool := false
 	var user *models.User
 	// Check if this is a POST request with filter parameters
 	switch request.Method {
 	case "POST":
-		fmt.Println("POST request received for filtering/sorting")
+		slog.DebugContext(request.Context(), "filter threads")
 		err = request.ParseForm()
 		if err == nil {
 			category1 = request.PostFormValue("selection1")
 			category2 = request.PostFormValue("selection2")
+			tag = request.PostFormValue("tag")
 			sortBy = request.URL.Query().Get("sort")
 
 			// Save user preferences if user is authenticated
 			if IsAuthenticated(request) {
 				user = GetCurrentUser(request)
 				if user != nil {
-					err := internal.UpdateUserPreferences(user.Id, category1, category2)
+					err := service.UpdateUserPreferences(user.Id, category1, category2)
 					if err != nil {
-						fmt.Printf("Error updating user preferences: %v\n", err)
+						slog.Error("error updating user preferences", "error", err)
 					}
 				}
 			}
 
 			// Handle sorting and filtering
 			if category1 != "" || category2 != "" {
-				fmt.Println("Filtering by categories:", category1, category2)
-				threads, err = internal.FilterThreadsByCategories(category1, category2)
+				slog.DebugContext(request.Context(), "filter by categories", "category1", category1, "category2", category2)
+				threads, err = service.FilterThreadsByCategories(category1, category2)
 				catbool = true
 
 			} else {
-				fmt.Println("No filters applied, showing all threads")
-				threads, err = internal.GetAllThreads()
+				slog.DebugContext(request.Context(), "no filters applied")
+				threads, err = service.GetAllThreads()
 				catbool = false
 			}
 
 			if sortBy == "most_liked" {
-				fmt.Println("Sorting by most liked")
+				slog.DebugContext(request.Context(), "sort by most liked")
 				threads, err = internal.SortThreadsByLikesDesc(threads)
 			} else if sortBy == "latest" {
 				threads, err = internal.SortThreadsByLatest(threads)
 			}
 			if err != nil {
-				fmt.Println("Error retrieving threads:", err)
+				slog.Error("error retrieving threads", "error", err)
 				utils.ErrorMessage(writer, request, "Error retrieving threads")
 				return
 			}
@@ -93,7 +102,7 @@ func Index(writer http.ResponseWriter, request *http.Request) {
 			if user != nil {
 				userIdFind = user.Id
 				// Get fresh user data to ensure we have preferred categories
-				fullUser := internal.GetUserById(user.Id)
+				fullUser := service.GetUserById(user.Id)
 				category1 = fullUser.PreferedCategory1
 				category2 = fullUser.PreferedCategory2
 				if category1 != "" || category2 != "" {
@@ -106,6 +115,7 @@ func Index(writer http.ResponseWriter, request *http.Request) {
 		err = request.ParseForm()
 		if err == nil {
 			sortBy = request.URL.Query().Get("sort")
+			tag = request.URL.Query().Get("tag")
 			reset = request.URL.Query().Get("reset")
 		} else {
 			utils.BadRequest(writer, request, "Cannot parse form data")
@@ -113,25 +123,25 @@ func Index(writer http.ResponseWriter, request *http.Request) {
 		}
 
 		if reset == "true" {
-			threads, err = internal.GetAllThreads()
+			threads, err = service.GetAllThreads()
 			catbool = true
 			category1, category2 = "", ""
-			sortBy = ""
+			sortBy, tag = "", ""
 
 			if userIdFind != -1 {
-				err := internal.UpdateUserPreferences(userIdFind, category1, category2)
+				err := service.UpdateUserPreferences(userIdFind, category1, category2)
 				if err != nil {
-					fmt.Printf("Error updating user preferences: %v\n", err)
+					slog.Error("error updating user preferences", "error", err)
 				}
 			}
 		} else {
 
 			if category1 != "" || category2 != "" {
-				threads, _ = internal.FilterThreadsByCategories(category1, category2)
+				threads, _ = service.FilterThreadsByCategories(category1, category2)
 				catbool = true
 			} else {
-				fmt.Println("No filters applied, showing all threads GET")
-				threads, err = internal.GetAllThreads()
+				slog.DebugContext(request.Context(), "no filters applied")
+				threads, err = service.GetAllThreads()
 				catbool = false
 			}
 
@@ -150,51 +160,27 @@ func Index(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	// the tag filter narrows the category filter down
+	tag = service.CanonicalTag(tag)
+	if err == nil && tag != "" {
+		threads, err = service.FilterThreadsByTag(threads, tag)
+	}
+
 	if err == nil {
 		// Get current user from middleware
 		user = GetCurrentUser(request)
-		userName := ""
 		if user != nil {
-			userName = user.Name
 			// Populate user-specific vote information for each thread
 			for i := range threads {
-				threads[i].UserLiked = internal.HasThreadLiked(user.Id, threads[i].Id)
-				threads[i].UserDisliked = internal.HasThreadDisliked(user.Id, threads[i].Id)
+				threads[i].UserLiked = service.HasThreadLiked(user.Id, threads[i].Id)
+				threads[i].UserDisliked = service.HasThreadDisliked(user.Id, threads[i].Id)
 			}
 		}
 
 		// Create expanded data structure
-		pageData := struct {
-			Threads           []models.Thread
-			Title             string
-			Message           string
-			User              string
-			Count             int
-			Online            int
-			PreferedCategory1 string
-			PreferedCategory2 string
-			SortBy            string
-		}{
-			Threads: threads,
-			Title:   "Forum Home",
-			Message: "Welcome to the Forum",
-			User:    userName,
-			SortBy:  sortBy,
-			Count: func() int {
-				count, err := internal.UserCount()
-				if err != nil {
-					return 0
-				}
-				return count
-			}(),
-			Online: func() int {
-				online, err := internal.CheckOnlineUsers(10)
-				if err != nil {
-					return 0
-				}
-				return len(online)
-			}(),
-		}
+		pageData := newIndexPage(service, threads, user)
+		pageData.SortBy = sortBy
+		pageData.Tag = tag
 
 		if catbool {
 			pageData.PreferedCategory1 = category1
@@ -203,11 +189,105 @@ func Index(writer http.ResponseWriter, request *http.Request) {
 
 		// Use middleware authentication check
 		if IsAuthenticated(request) {
-			utils.GenerateHTML(writer, pageData, "layout", "private.navbar", "index")
+			utils.ServePage(writer, request, utils.PrivatePage("index"), pageData)
 		} else {
-			utils.GenerateHTML(writer, pageData, "layout", "public.navbar", "index")
+			utils.ServePage(writer, request, utils.PublicPage("index"), pageData)
 		}
 	} else {
 		utils.NotFound(writer, request)
 	}
 }
+
+// indexPage is the data of the index template, shared by all threads and "My feed"
+type indexPage struct {
+	Threads           []models.Thread
+	Title             string
+	Message           string
+	User              string
+	Count             int
+	Online            int
+	PreferedCategory1 string
+	PreferedCategory2 string
+	SortBy            string
+	Tag               string // active tag filter
+	Tab               string
+	PrevPage          int // 0 when there is no previous page
+	NextPage          int // 0 when there is no next page
+	Categories        []string
+	Followed          map[string]bool
+}
+
+func newIndexPage(service *internal.Service, threads []models.Thread, user *models.User) indexPage {
+	userID, userName := 0, ""
+	if user != nil {
+		userID, userName = user.Id, user.Name
+	}
+	service.AttachThreadTags(threads)
+	service.AttachReadState(threads, userID)
+	return indexPage{
+		Threads: threads,
+		Title:   "Forum Home",
+		Message: "Welcome to the Forum",
+		User:    userName,
+		Tab:     "all",
+		Count: func() int {
+			count, err := service.UserCount()
+			if err != nil {
+				return 0
+			}
+			return count
+		}(),
+		Online: func() int {
+			online, err := service.CheckOnlineUsers(10)
+			if err != nil {
+				return 0
+			}
+			return len(online)
+		}(),
+	}
+}
+
+// GET /?tab=feed&page=
+// threads from followed members and categories, newest first
+func Feed(writer http.ResponseWriter, request *http.Request) {
+	service := GetService(request)
+	user := GetCurrentUser(request)
+	if user == nil {
+		http.Redirect(writer, request, "/login/", http.StatusFound)
+		return
+	}
+
+	page, err := strconv.Atoi(request.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	threads, hasNext, err := service.Feed(user.Id, page)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	for i := range threads {
+		threads[i].UserLiked = service.HasThreadLiked(user.Id, threads[i].Id)
+		threads[i].UserDisliked = service.HasThreadDisliked(user.Id, threads[i].Id)
+	}
+
+	followed, err := service.FollowedCategories(user.Id)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	pageData := newIndexPage(service, threads, user)
+	pageData.Tab = "feed"
+	pageData.PrevPage = page - 1
+	if hasNext {
+		pageData.NextPage = page + 1
+	}
+	pageData.Categories = internal.Categories
+	pageData.Followed = map[string]bool{}
+	for _, category := range followed {
+		pageData.Followed[category] = true
+	}
+	utils.ServePage(writer, request, utils.PrivatePage("index"), pageData)
+}
@@ -1,32 +1,54 @@
 package routes
 
 import (
-	"forum/internal/data"
+	"forum/internal"
 	"forum/models"
 	"forum/utils"
 	"net/http"
 	"strings"
 )
 
-func CompleteRoutes(mux *http.ServeMux, files http.Handler, dbManager *data.DatabaseManager) {
+func CompleteRoutes(mux *http.ServeMux, files http.Handler, service *internal.Service) {
 
 	mux.Handle("/static/", http.StripPrefix("/static/", files))
 
 	baseChain := Chain(
-		WithErrorRecovery(),
 		WithLogging(),
-		WithDatabaseManager(dbManager), // if we turn off this option, 500 error occurs in auth and login, since no dbmanager in context
+		WithMetrics(),
+		WithErrorRecovery(),
+		WithService(service), // if we turn off this option, 500 error occurs in auth and login, since no service in context
 		WithAuthentication(),
 	)
 
 	authChain := Chain(
-		WithErrorRecovery(),
 		WithLogging(),
-		WithDatabaseManager(dbManager),
+		WithMetrics(),
+		WithErrorRecovery(),
+		WithService(service),
 		WithAuthentication(),
 		RequireAuth(),
 	) // authChain includes RequireAuth
 
+	modChain := Chain(
+		WithLogging(),
+		WithMetrics(),
+		WithErrorRecovery(),
+		WithService(service),
+		WithAuthentication(),
+		RequireAuth(),
+		RequireModerator(),
+	) // modChain is authChain limited to moderators and admins
+
+	adminChain := Chain(
+		WithLogging(),
+		WithMetrics(),
+		WithErrorRecovery(),
+		WithService(service),
+		WithAuthentication(),
+		RequireAuth(),
+		RequireAdmin(),
+	) // adminChain is authChain limited to admins
+
 	dataLS := models.LoginSkin{}
 
 	mux.HandleFunc("/", baseChain(Index))
@@ -44,9 +66,58 @@ func CompleteRoutes(mux *http.ServeMux, files http.Handler, dbManager *data.Data
 	mux.HandleFunc("/thread/create", authChain(CreateThread))
 	mux.HandleFunc("/thread/post", authChain(PostThread))
 	mux.HandleFunc("/thread/read", baseChain(ReadThread))
+	mux.HandleFunc("/t/", baseChain(ShowThread))
+	mux.HandleFunc("/thread/watch", authChain(WatchThread))
+	mux.HandleFunc("/thread/unwatch", authChain(UnwatchThread))
+	mux.HandleFunc("/threads/read-all", authChain(MarkAllRead))
+	mux.HandleFunc("/watching", authChain(Watching))
+	mux.HandleFunc("/unsubscribe", baseChain(Unsubscribe))
+	mux.HandleFunc("/mentions", authChain(Mentions))
+	mux.HandleFunc("/messages", authChain(Messages))
+	mux.HandleFunc("/messages/new", authChain(NewMessage))
+	mux.HandleFunc("/messages/view", authChain(ViewConversation))
+	mux.HandleFunc("/messages/reply", authChain(ReplyMessage))
+	mux.HandleFunc("/messages/leave", authChain(LeaveConversation))
+	mux.HandleFunc("/messages/mute", authChain(MuteConversation))
+	mux.HandleFunc("/messages/report", authChain(ReportMessage))
+
+	mux.HandleFunc("/drafts", authChain(Drafts))
+	mux.HandleFunc("/drafts/delete", authChain(DeleteDraft))
+	mux.HandleFunc("/bookmarks", authChain(Bookmarks))
+	mux.HandleFunc("/bookmarks/add", authChain(AddBookmark))
+	mux.HandleFunc("/bookmarks/update", authChain(UpdateBookmark))
+	mux.HandleFunc("/bookmarks/remove", authChain(RemoveBookmark))
+	mux.HandleFunc("/bookmarks/collections", authChain(CreateCollection))
+	mux.HandleFunc("/bookmarks/collections/delete", authChain(DeleteCollection))
+	mux.HandleFunc("/bookmarks/token", authChain(BookmarkToken))
+
+	mux.HandleFunc("/moderation/reports", modChain(ModerationReports))
+	mux.HandleFunc("/moderation/report", modChain(ModerationReport))
+	mux.HandleFunc("/moderation/resolve", modChain(ResolveReport))
+	mux.HandleFunc("/thread/moderate", modChain(ModerateThread))
+	mux.HandleFunc("/moderation/tags", modChain(ModerationTags))
+	mux.HandleFunc("/moderation/tags/synonym", modChain(AddTagSynonym))
+	mux.HandleFunc("/moderation/tags/synonym/delete", modChain(RemoveTagSynonym))
+	mux.HandleFunc("/admin", adminChain(AdminDashboard))
+	mux.HandleFunc("/admin/export", adminChain(AdminExport))
+	mux.HandleFunc("/admin/backups", adminChain(AdminBackups))
+	mux.HandleFunc("/admin/backups/download", adminChain(AdminBackupDownload))
+	mux.HandleFunc("/tag/", baseChain(TagPage))
+	mux.HandleFunc("/feeds/", baseChain(Feeds))
+	mux.HandleFunc("/sitemap.xml", baseChain(Sitemap))
+	mux.HandleFunc("/robots.txt", baseChain(Robots))
+	mux.HandleFunc("/feeds/token", authChain(FeedToken))
 
+	mux.HandleFunc("/u/", baseChain(Profile))
+	mux.HandleFunc("/profile/edit", authChain(EditProfile))
+	mux.HandleFunc("/follow/user", authChain(FollowUser))
+	mux.HandleFunc("/unfollow/user", authChain(UnfollowUser))
+	mux.HandleFunc("/follow/category", authChain(FollowCategory))
+	mux.HandleFunc("/unfollow/category", authChain(UnfollowCategory))
 	mux.HandleFunc("/account", baseChain(ReadThreadsFromAccount))
 	mux.HandleFunc("/accountcheck", baseChain(AccountCheck))
+	mux.HandleFunc("/account/export", authChain(ExportAccount))
+	mux.HandleFunc("/account/delete", authChain(DeleteAccount))
 	mux.HandleFunc("/debug", baseChain(DebugPage))
 
 	mux.HandleFunc("/back/", baseChain(func(w http.ResponseWriter, r *http.Request) {
@@ -79,6 +150,20 @@ func CompleteRoutes(mux *http.ServeMux, files http.Handler, dbManager *data.Data
 			} else {
 				utils.NotFound(w, r)
 			}
+		} else if path == "/api/users/lookup" {
+			LookupUsers(w, r)
+		} else if path == "/api/users/block" {
+			BlockUser(w, r)
+		} else if path == "/api/users/unblock" {
+			UnblockUser(w, r)
+		} else if path == "/api/tags" {
+			APITags(w, r)
+		} else if path == "/api/drafts" {
+			APIDraft(w, r)
+		} else if path == "/api/bookmarks" {
+			APIBookmarks(w, r)
+		} else if path == "/api/bookmarks/collections" {
+			APICollections(w, r)
 		} else {
 			utils.NotFound(w, r)
 		}
@@ -0,0 +1,108 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"forum/models"
+	"forum/utils"
+)
+
+// GET /api/users/lookup?prefix=
+// autocomplete for @mentions in the composer
+func LookupUsers(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	users, err := GetService(request).LookupUsers(request.URL.Query().Get("prefix"), user.Id)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(users)
+}
+
+// POST /api/users/block
+func BlockUser(writer http.ResponseWriter, request *http.Request) {
+	setBlock(writer, request, true)
+}
+
+// POST /api/users/unblock
+func UnblockUser(writer http.ResponseWriter, request *http.Request) {
+	setBlock(writer, request, false)
+}
+
+func setBlock(writer http.ResponseWriter, request *http.Request, block bool) {
+	service := GetService(request)
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	targetID, err := strconv.Atoi(request.FormValue("user_id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid user ID format")
+		return
+	}
+	if service.GetUserById(targetID).Id == 0 {
+		utils.NotFound(writer, request)
+		return
+	}
+
+	if block {
+		err = service.BlockUser(user.Id, targetID)
+	} else {
+		err = service.UnblockUser(user.Id, targetID)
+	}
+	if err != nil {
+		utils.BadRequest(writer, request, err.Error())
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(map[string]bool{"blocked": service.HasBlocked(user.Id, targetID)})
+}
+
+// GET /mentions
+// list the threads and posts where the current user was mentioned
+func Mentions(writer http.ResponseWriter, request *http.Request) {
+	service := GetService(request)
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	mentions, err := service.UserMentions(user.Id)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	if err := service.MarkMentionsSeen(user.Id); err != nil {
+		utils.Warn("Cannot mark mentions seen:", err)
+	}
+
+	utils.ServePage(writer, request, utils.PrivatePage("mentions"), struct{ Mentions []models.Mention }{mentions})
+}
@@ -0,0 +1,231 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"forum/internal"
+	"forum/models"
+	"forum/utils"
+)
+
+// GET /messages
+// inbox of private conversations
+func Messages(writer http.ResponseWriter, request *http.Request) {
+	service := GetService(request)
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	conversations, err := service.Inbox(user.Id)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	pageData := struct {
+		Conversations []models.Conversation
+		Unread        int
+	}{
+		Conversations: conversations,
+		Unread:        service.UnreadMessagesCount(user.Id),
+	}
+	utils.ServePage(writer, request, utils.PrivatePage("messages"), pageData)
+}
+
+type newMessageForm struct {
+	To      string
+	Subject string
+	Body    string
+	Error   string
+}
+
+// GET /messages/new
+// show the form for a new conversation
+// POST /messages/new
+// create the conversation with its first message
+func NewMessage(writer http.ResponseWriter, request *http.Request) {
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	switch request.Method {
+	case "GET":
+		form := newMessageForm{To: request.URL.Query().Get("to")}
+		utils.ServePage(writer, request, utils.PrivatePage("new.message"), form)
+	case "POST":
+		form := newMessageForm{
+			To:      request.PostFormValue("to"),
+			Subject: request.PostFormValue("subject"),
+			Body:    request.PostFormValue("body"),
+		}
+		conversationID, err := GetService(request).StartConversation(*user, form.To, form.Subject, form.Body)
+		if err != nil {
+			// errors from StartConversation are meant for the user
+			form.Error = err.Error()
+			writer.WriteHeader(http.StatusBadRequest)
+			utils.ServePage(writer, request, utils.PrivatePage("new.message"), form)
+			return
+		}
+		http.Redirect(writer, request, "/messages/view?id="+strconv.FormatInt(conversationID, 10), http.StatusFound)
+	default:
+		utils.MethodNotAllowed(writer, request, "GET or POST method only")
+	}
+}
+
+// GET /messages/view?id=
+// read a conversation, only its members may see it
+func ViewConversation(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	conversationID, err := strconv.Atoi(request.URL.Query().Get("id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid conversation ID format")
+		return
+	}
+
+	conversation, messages, err := GetService(request).OpenConversation(conversationID, user.Id)
+	if err != nil {
+		conversationError(writer, request, err)
+		return
+	}
+
+	pageData := struct {
+		Conversation models.Conversation
+		Messages     []models.Message
+		UserId       int
+	}{
+		Conversation: conversation,
+		Messages:     messages,
+		UserId:       user.Id,
+	}
+	utils.ServePage(writer, request, utils.PrivatePage("conversation"), pageData)
+}
+
+// POST /messages/reply
+// add a message to a conversation
+func ReplyMessage(writer http.ResponseWriter, request *http.Request) {
+	user, conversationID, ok := conversationAction(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := GetService(request).ReplyToConversation(conversationID, user.Id, request.PostFormValue("body")); err != nil {
+		conversationError(writer, request, err)
+		return
+	}
+	http.Redirect(writer, request, "/messages/view?id="+strconv.Itoa(conversationID), http.StatusFound)
+}
+
+// POST /messages/leave
+// leave a conversation, it disappears from the inbox
+func LeaveConversation(writer http.ResponseWriter, request *http.Request) {
+	user, conversationID, ok := conversationAction(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := GetService(request).LeaveConversation(conversationID, user.Id); err != nil {
+		conversationError(writer, request, err)
+		return
+	}
+	http.Redirect(writer, request, "/messages", http.StatusFound)
+}
+
+// POST /messages/mute
+// mute or unmute a conversation, muted conversations do not count as unread
+func MuteConversation(writer http.ResponseWriter, request *http.Request) {
+	user, conversationID, ok := conversationAction(writer, request)
+	if !ok {
+		return
+	}
+
+	muted := request.PostFormValue("muted") == "true"
+	if err := GetService(request).MuteConversation(conversationID, user.Id, muted); err != nil {
+		conversationError(writer, request, err)
+		return
+	}
+	http.Redirect(writer, request, "/messages/view?id="+strconv.Itoa(conversationID), http.StatusFound)
+}
+
+// POST /messages/report
+// flag a message for moderator review
+func ReportMessage(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	messageID, err := strconv.Atoi(request.PostFormValue("message_id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid message ID format")
+		return
+	}
+
+	message, err := GetService(request).ReportMessage(messageID, user.Id, request.PostFormValue("reason"))
+	if err != nil {
+		conversationError(writer, request, err)
+		return
+	}
+	http.Redirect(writer, request, "/messages/view?id="+strconv.Itoa(message.ConversationId), http.StatusFound)
+}
+
+// conversationAction checks the method, user and conversation id shared by
+// the POST handlers of a conversation
+func conversationAction(writer http.ResponseWriter, request *http.Request) (*models.User, int, bool) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return nil, 0, false
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return nil, 0, false
+	}
+
+	conversationID, err := strconv.Atoi(request.PostFormValue("id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid conversation ID format")
+		return nil, 0, false
+	}
+	return user, conversationID, true
+}
+
+// conversationError maps errors of the conversation service to responses,
+// a conversation the user is not part of is reported as missing
+func conversationError(writer http.ResponseWriter, request *http.Request, err error) {
+	switch {
+	case errors.Is(err, internal.ErrNotConversationMember):
+		utils.NotFound(writer, request)
+	case errors.Is(err, internal.ErrEmptyMessage):
+		utils.BadRequest(writer, request, err.Error())
+	default:
+		utils.InternalServerError(writer, request, err)
+	}
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"forum/internal/metrics"
+	"forum/utils"
+)
+
+// GET /metrics
+// Prometheus metrics; with a token the request needs "Authorization: Bearer <token>"
+func Metrics(token string) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != "GET" {
+			utils.MethodNotAllowed(writer, request, "GET method only")
+			return
+		}
+		if token != "" {
+			given := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				writer.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				http.Error(writer, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics.Default.Write(writer)
+	}
+}
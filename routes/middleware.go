@@ -3,19 +3,25 @@ package routes
 import (
 	"context"
 	"fmt"
-	"forum/internal/data"
+	"forum/internal"
+	"forum/internal/metrics"
 	"forum/models"
 	"forum/utils"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type ContextKey string // ContextKey types for different context values
 
 const (
-	DBManagerKey ContextKey = "dbManager"
-	UserKey      ContextKey = "user"
-	SessionKey   ContextKey = "session"
+	ServiceKey ContextKey = "service"
+	UserKey    ContextKey = "user"
+	SessionKey ContextKey = "session"
 )
 
 // Middleware represents a function that wraps an http.HandlerFunc
@@ -35,11 +41,11 @@ func Chain(middlewares ...Middleware) Middleware {
 	}
 }
 
-// WithDatabaseManager middleware adds database manager to context
-func WithDatabaseManager(dbManager *data.DatabaseManager) Middleware {
+// WithService middleware adds the forum service to context
+func WithService(service *internal.Service) Middleware {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			ctx := context.WithValue(r.Context(), DBManagerKey, dbManager)
+			ctx := context.WithValue(r.Context(), ServiceKey, service)
 			next(w, r.WithContext(ctx))
 		}
 	}
@@ -49,14 +55,14 @@ func WithDatabaseManager(dbManager *data.DatabaseManager) Middleware {
 func WithAuthentication() Middleware {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			dbManager := GetDatabaseManager(r)
-			if dbManager == nil {
+			service := GetService(r)
+			if service == nil {
 				next(w, r)
 				return
 			}
 
 			// Check for session cookie
-			cookie, err := r.Cookie("_cookie")
+			cookie, err := r.Cookie(utils.SessionCookieName())
 			if err != nil {
 				// No cookie, continue without authentication
 				next(w, r)
@@ -64,15 +70,22 @@ func WithAuthentication() Middleware {
 			}
 
 			// Validate session
-			session, err := dbManager.GetSessionByCookie(cookie.Value)
+			session, err := service.Sessions.GetSessionByCookie(cookie.Value)
 			if err != nil {
 				// Invalid session, continue without authentication
 				next(w, r)
 				return
 			}
 
+			// A session past its lifetime is removed, the user signs in again
+			if !session.CreatedAt.IsZero() && utils.SessionExpired(session.CreatedAt) {
+				service.Sessions.DeleteSessionByUUID(session.Uuid)
+				next(w, r)
+				return
+			}
+
 			// Validate and update session activity
-			_, isValid, err := dbManager.ValidateSession(session.Uuid)
+			_, isValid, err := service.Sessions.ValidateSession(session.Uuid)
 			if err != nil || !isValid {
 				// Invalid session, continue without authentication
 				next(w, r)
@@ -80,11 +93,17 @@ func WithAuthentication() Middleware {
 			}
 
 			// Get user from session
-			user, err := dbManager.GetUserByID(session.UserId)
+			user, err := service.Users.GetUserByID(session.UserId)
 			if err != nil {
 				next(w, r) // User not found, continue without authentication
 				return
 			}
+			// A banned or deleted account is signed out
+			if user.Status != "" && user.Status != models.StatusActive {
+				service.Sessions.DeleteSessionByUUID(session.Uuid)
+				next(w, r)
+				return
+			}
 			// Add session and user to context
 			ctx := context.WithValue(r.Context(), SessionKey, session)
 			ctx = context.WithValue(ctx, UserKey, user)
@@ -99,7 +118,7 @@ func RequireAuth() Middleware {
 		return func(w http.ResponseWriter, r *http.Request) {
 			user := GetCurrentUser(r)
 			if user == nil {
-				http.Redirect(w, r, "/login/", http.StatusFound)
+				http.Redirect(w, r, "/login/?next="+url.QueryEscape(returnPath(r)), http.StatusFound)
 				return
 			}
 			next(w, r)
@@ -107,23 +126,154 @@ func RequireAuth() Middleware {
 	}
 }
 
-// WithLogging middleware logs requests
-func WithLogging() Middleware {
+// RequireModerator middleware lets only moderators and admins through,
+// it must run after RequireAuth
+func RequireModerator() Middleware {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			fmt.Printf("[%s] %s %s\n", r.Method, r.URL.Path, r.RemoteAddr)
+			user := GetCurrentUser(r)
+			if user == nil || !user.IsModerator() {
+				utils.Forbidden(w, r, "Moderator role required")
+				return
+			}
 			next(w, r)
 		}
 	}
 }
 
+// RequireAdmin middleware lets only admins through,
+// it must run after RequireAuth
+func RequireAdmin() Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			user := GetCurrentUser(r)
+			if user == nil || !user.IsAdmin() {
+				utils.Forbidden(w, r, "Admin role required")
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+// returnPath is the page to come back to after logging in: the requested page
+// for GET, the page that sent the form otherwise
+func returnPath(r *http.Request) string {
+	if r.Method == "GET" {
+		return r.URL.RequestURI()
+	}
+	if referer, err := url.Parse(r.Referer()); err == nil && referer.Host == r.Host {
+		return referer.RequestURI()
+	}
+	return "/"
+}
+
+// safeNext only accepts local paths, so ?next= cannot send users to another site
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return ""
+	}
+	return next
+}
+
+// statusRecorder remembers the status code and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// WithLogging middleware gives every request an id, carried in the context
+// and the X-Request-ID header, and logs the status and latency when done
+func WithLogging() Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := utils.NewRequestID(r.Header.Get("X-Request-ID"))
+			ctx := utils.WithRequestID(r.Context(), id)
+			w.Header().Set("X-Request-ID", id)
+
+			rec := &statusRecorder{ResponseWriter: w}
+			next(rec, r.WithContext(ctx))
+
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			slog.InfoContext(ctx, "request",
+				"method", r.Method,
+				"path", loggedPath(r.URL.Path),
+				"status", rec.status,
+				"bytes", rec.bytes,
+				"duration", time.Since(start),
+				"remote", r.RemoteAddr)
+		}
+	}
+}
+
+// loggedPath hides the token of private notification feeds, which stands
+// in for a password
+func loggedPath(urlPath string) string {
+	const prefix = "/feeds/notifications/"
+	if token, ok := strings.CutPrefix(urlPath, prefix); ok && token != "" {
+		return prefix + "{token}" + path.Ext(token)
+	}
+	return urlPath
+}
+
+// WithMetrics middleware counts requests and their latency per route,
+// the route is the mux pattern so ids in paths do not add series
+func WithMetrics() Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec, ok := w.(*statusRecorder)
+			if !ok {
+				rec = &statusRecorder{ResponseWriter: w}
+			}
+			next(rec, r)
+
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := r.Pattern
+			if route == "" {
+				route = "other"
+			}
+			metrics.HTTPRequests.Inc(route, r.Method, strconv.Itoa(status))
+			metrics.HTTPDuration.Observe(time.Since(start).Seconds(), route)
+		}
+	}
+}
+
 // WithErrorRecovery middleware handles panics and converts them to 500 errors
 func WithErrorRecovery() Middleware {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
-					log.Printf("Panic recovered: %v", err)
+					slog.ErrorContext(r.Context(), "panic recovered", "panic", err)
 					utils.InternalServerError(w, r, fmt.Errorf("panic: %v", err))
 				}
 			}()
@@ -141,10 +291,10 @@ func NotFoundHandler() http.HandlerFunc {
 
 // Helper functions to retrieve values from context
 
-// GetDatabaseManager retrieves database manager from context
-func GetDatabaseManager(r *http.Request) *data.DatabaseManager {
-	if dbManager, ok := r.Context().Value(DBManagerKey).(*data.DatabaseManager); ok {
-		return dbManager
+// GetService retrieves the forum service from context
+func GetService(r *http.Request) *internal.Service {
+	if service, ok := r.Context().Value(ServiceKey).(*internal.Service); ok {
+		return service
 	}
 	return nil
 }
@@ -0,0 +1,120 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"forum/internal"
+	"forum/models"
+	"forum/utils"
+)
+
+// GET /moderation/reports
+// open message reports and the latest audit log entries
+func ModerationReports(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	reports, err := GetService(request).OpenMessageReports()
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	audit, err := GetService(request).AuditLog(50)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	pageData := struct {
+		Reports []models.MessageReport
+		Audit   []models.AuditEntry
+	}{
+		Reports: reports,
+		Audit:   audit,
+	}
+	utils.ServePage(writer, request, utils.PrivatePage("moderation.reports"), pageData)
+}
+
+// GET /moderation/report?id=
+// read a reported message with its context, every visit is audited
+func ModerationReport(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	reportID, err := strconv.Atoi(request.URL.Query().Get("id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid report ID format")
+		return
+	}
+
+	report, err := GetService(request).ReviewMessageReport(reportID, *GetCurrentUser(request))
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.NotFound(writer, request)
+		return
+	} else if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	utils.ServePage(writer, request, utils.PrivatePage("moderation.report"), report)
+}
+
+// POST /moderation/resolve
+// close a message report
+func ResolveReport(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	reportID, err := strconv.Atoi(request.PostFormValue("id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid report ID format")
+		return
+	}
+
+	err = GetService(request).ResolveMessageReport(reportID, *GetCurrentUser(request))
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.NotFound(writer, request)
+		return
+	} else if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	http.Redirect(writer, request, "/moderation/reports", http.StatusFound)
+}
+
+// POST /thread/moderate
+// pin, lock or archive a thread, or revert it with unpin, unlock and unarchive
+func ModerateThread(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	threadID, err := strconv.Atoi(request.PostFormValue("id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid thread ID format")
+		return
+	}
+
+	err = GetService(request).ModerateThread(*GetCurrentUser(request), threadID, request.PostFormValue("action"))
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.NotFound(writer, request)
+		return
+	} else if errors.Is(err, internal.ErrUnknownThreadAction) {
+		utils.BadRequest(writer, request, err.Error())
+		return
+	} else if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	http.Redirect(writer, request, threadPath(GetService(request), threadID), http.StatusFound)
+}
@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"forum/internal"
+	"forum/models"
+	"forum/utils"
+)
+
+// GET /u/{name}
+// public profile of a user
+func Profile(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	name := strings.TrimPrefix(request.URL.Path, "/u/")
+	if name == "" || strings.Contains(name, "/") {
+		utils.NotFound(writer, request)
+		return
+	}
+
+	renderProfile(writer, request, name, "")
+}
+
+// renderProfile shows the profile of name, formError comes from a failed edit
+func renderProfile(writer http.ResponseWriter, request *http.Request, name, formError string) {
+	viewer := GetCurrentUser(request)
+	profile, err := GetService(request).UserProfile(name, viewer)
+	if err == internal.ErrProfileNotFound {
+		utils.NotFound(writer, request)
+		return
+	} else if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	pageData := struct {
+		models.Profile
+		Error          string
+		DeletionPolicy string
+	}{
+		Profile:        profile,
+		Error:          formError,
+		DeletionPolicy: internal.DeletionPolicy(),
+	}
+	if formError != "" {
+		writer.WriteHeader(http.StatusBadRequest)
+	}
+	if viewer != nil {
+		utils.ServePage(writer, request, utils.PrivatePage("profile"), pageData)
+	} else {
+		utils.ServePage(writer, request, utils.PublicPage("profile"), pageData)
+	}
+}
+
+// POST /profile/edit
+// update bio and avatar of the current user
+func EditProfile(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	err := GetService(request).UpdateProfile(user.Id, request.PostFormValue("bio"), request.PostFormValue("avatar_url"))
+	if err != nil {
+		// validation errors are meant for the user
+		renderProfile(writer, request, user.Name, err.Error())
+		return
+	}
+	http.Redirect(writer, request, "/u/"+url.PathEscape(user.Name), http.StatusFound)
+}
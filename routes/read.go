@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"forum/internal"
+	"forum/models"
+	"forum/utils"
+)
+
+// recordVisit counts a view of the thread and, for a signed-in user, moves
+// their read marker to the last reply shown
+func recordVisit(writer http.ResponseWriter, request *http.Request, thread *models.Thread) {
+	user := GetCurrentUser(request)
+	var viewer string
+	if user != nil {
+		viewer = "user:" + strconv.Itoa(user.Id)
+	} else {
+		viewer = "guest:" + guestID(writer, request)
+	}
+	service := GetService(request)
+	service.RecordThreadView(thread.Id, viewer, time.Now())
+	thread.Views = service.ThreadViews(thread.Id)
+
+	if user == nil {
+		return
+	}
+	lastPostID := 0
+	for _, post := range thread.Cards {
+		lastPostID = max(lastPostID, post.Id)
+	}
+	if err := service.MarkThreadRead(user.Id, thread.Id, lastPostID); err != nil {
+		utils.Warn("Cannot mark thread as read:", err)
+	}
+}
+
+// guestID returns the id in the visitor cookie, handing out a new one on
+// the first visit. The remote address would count everybody behind the
+// same NAT or proxy as one viewer; a guest refusing cookies counts again
+// on every visit instead.
+func guestID(writer http.ResponseWriter, request *http.Request) string {
+	if cookie, err := request.Cookie(utils.VisitorCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	id := utils.CreateUUID()
+	http.SetCookie(writer, utils.VisitorCookie(id, internal.ViewWindow))
+	return id
+}
+
+// POST /threads/read-all
+// mark every thread as read up to its last reply
+func MarkAllRead(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	currentUser := GetCurrentUser(request)
+	if currentUser == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	if err := GetService(request).MarkAllThreadsRead(currentUser.Id); err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	next := safeNext(request.PostFormValue("next"))
+	if next == "" {
+		next = "/"
+	}
+	http.Redirect(writer, request, next, http.StatusFound)
+}
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+
+	"forum/utils"
+)
+
+// GET /sitemap.xml
+// public threads, tags and profiles for search engines
+func Sitemap(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" && request.Method != "HEAD" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	body, err := GetService(request).Sitemap()
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	writer.Header().Set("Cache-Control", "public, max-age=3600")
+	writer.Write(body)
+}
+
+// GET /robots.txt
+// keep crawlers out of private and state-changing pages
+func Robots(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" && request.Method != "HEAD" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(writer, "User-agent: *\n")
+	for _, path := range []string{
+		"/api/", "/back/", "/login/", "/signup", "/authenticate", "/logout",
+		"/thread/", "/messages", "/mentions", "/drafts", "/bookmarks", "/watching",
+		"/moderation/", "/profile/", "/follow/", "/unfollow/", "/feeds/notifications/",
+		"/unsubscribe", "/account", "/debug",
+	} {
+		fmt.Fprintf(writer, "Disallow: %s\n", path)
+	}
+	fmt.Fprintf(writer, "\nSitemap: %s/sitemap.xml\n", utils.BaseURL())
+}
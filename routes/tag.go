@@ -0,0 +1,153 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"forum/internal"
+	"forum/models"
+	"forum/utils"
+)
+
+// GET /tag/{name}
+// threads carrying a tag, synonyms redirect to their tag
+func TagPage(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	service := GetService(request)
+
+	raw := strings.TrimPrefix(request.URL.Path, "/tag/")
+	name := service.CanonicalTag(raw)
+	if name == "" {
+		utils.NotFound(writer, request)
+		return
+	}
+	if name != raw {
+		http.Redirect(writer, request, "/tag/"+url.PathEscape(name), http.StatusMovedPermanently)
+		return
+	}
+
+	if _, err := service.TagInfo(name); errors.Is(err, internal.ErrTagNotFound) {
+		utils.NotFound(writer, request)
+		return
+	} else if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	threads, err := service.GetAllThreads()
+	if err == nil {
+		threads, err = service.FilterThreadsByTag(threads, name)
+	}
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	user := GetCurrentUser(request)
+	if user != nil {
+		for i := range threads {
+			threads[i].UserLiked = service.HasThreadLiked(user.Id, threads[i].Id)
+			threads[i].UserDisliked = service.HasThreadDisliked(user.Id, threads[i].Id)
+		}
+	}
+
+	pageData := newIndexPage(service, threads, user)
+	pageData.Title = "#" + name
+	pageData.Tab = "tag"
+	pageData.Tag = name
+	if user != nil {
+		utils.ServePage(writer, request, utils.PrivatePage("index"), pageData)
+	} else {
+		utils.ServePage(writer, request, utils.PublicPage("index"), pageData)
+	}
+}
+
+// GET /api/tags?q=
+// autocomplete for tags, most used first
+func APITags(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	tags, err := GetService(request).SuggestTags(request.URL.Query().Get("q"))
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(tags)
+}
+
+// GET /moderation/tags
+// most used tags and their synonyms
+func ModerationTags(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+	renderModerationTags(writer, request, "")
+}
+
+func renderModerationTags(writer http.ResponseWriter, request *http.Request, message string) {
+	service := GetService(request)
+	tags, err := service.PopularTags(100)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	synonyms, err := service.TagSynonyms()
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	pageData := struct {
+		Tags     []models.Tag
+		Synonyms []models.TagSynonym
+		Error    string
+	}{tags, synonyms, message}
+	if message != "" {
+		writer.WriteHeader(http.StatusBadRequest)
+	}
+	utils.ServePage(writer, request, utils.PrivatePage("moderation.tags"), pageData)
+}
+
+// POST /moderation/tags/synonym
+// make an alias stand for a tag, threads tagged with the alias are retagged
+func AddTagSynonym(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	err := GetService(request).AddTagSynonym(*GetCurrentUser(request), request.PostFormValue("alias"), request.PostFormValue("tag"))
+	if err != nil {
+		renderModerationTags(writer, request, err.Error())
+		return
+	}
+	http.Redirect(writer, request, "/moderation/tags", http.StatusFound)
+}
+
+// POST /moderation/tags/synonym/delete
+// remove a synonym, threads keep their tag
+func RemoveTagSynonym(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	if err := GetService(request).RemoveTagSynonym(*GetCurrentUser(request), request.PostFormValue("alias")); err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	http.Redirect(writer, request, "/moderation/tags", http.StatusFound)
+}
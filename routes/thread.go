@@ -1,13 +1,11 @@
 package routes
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"forum/internal"
-	"forum/models"
 	"forum/utils"
 )
 
@@ -24,12 +22,13 @@ func NewThread(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	utils.GenerateHTML(writer, nil, "layout", "private.navbar", "new.thread")
+	utils.ServePage(writer, request, utils.PrivatePage("new.thread"), nil)
 }
 
 // POST /thread/create
 // create the thread
 func CreateThread(writer http.ResponseWriter, request *http.Request) {
+	service := GetService(request)
 	if request.Method != "POST" {
 		utils.MethodNotAllowed(writer, request, "POST method only")
 		return
@@ -75,26 +74,41 @@ func CreateThread(writer http.ResponseWriter, request *http.Request) {
 		selected2 = ""
 	}
 
+	tags, err := service.ParseTags(request.PostFormValue("tags"))
+	if err != nil {
+		utils.BadRequest(writer, request, err.Error())
+		return
+	}
+
 	// Use CreateThreadByUser which accepts string categories
-	idTo, err := internal.CrThreadByUser(topic, body, currentUser.Id, selected, selected2)
+	idTo, err := service.CrThreadByUser(topic, body, currentUser.Id, selected, selected2)
 	if err != nil {
 		utils.InternalServerError(writer, request, err)
 		return
 	}
 
-	http.Redirect(writer, request, "/thread/read?id="+strconv.Itoa(int(idTo)), http.StatusFound)
+	if err := service.SetThreadTags(int(idTo), tags); err != nil {
+		utils.Warn("Cannot tag created thread:", err)
+	}
+	service.RecordMentions(currentUser.Id, int(idTo), 0, body)
+	service.DiscardDraft(currentUser.Id, 0)
+
+	// Authors automatically watch their own threads
+	if err := service.WatchThread(currentUser.Id, int(idTo)); err != nil {
+		utils.Warn("Cannot watch created thread:", err)
+	}
+
+	http.Redirect(writer, request, utils.ThreadPath(int(idTo), topic), http.StatusFound)
 }
 
-// GET /thread/read
-// show the details of the thread, including the posts and the form to write a post
+// GET /thread/read?id=
+// old thread links, permanently redirected to the canonical /t/{id}-{slug}
 func ReadThread(writer http.ResponseWriter, request *http.Request) {
 	if request.Method != "GET" {
 		utils.MethodNotAllowed(writer, request, "GET method only")
 		return
 	}
 
-	thread := models.Thread{}
-	var err error
 	id := request.URL.Query().Get("id")
 
 	// Validate thread ID
@@ -109,22 +123,60 @@ func ReadThread(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	thread, err = internal.ThreadWithPosts(resid)
+	thread, err := GetService(request).ThreadById(resid)
 	if err != nil {
-		// Check if it's a "not found" error vs other database errors
 		if err.Error() == "sql: no rows in result set" {
-			utils.BadRequest(writer, request, "Thread not found")
+			utils.NotFound(writer, request)
 		} else {
 			utils.InternalServerError(writer, request, err)
 		}
 		return
 	}
+	http.Redirect(writer, request, utils.ThreadPath(thread.Id, thread.Topic), http.StatusMovedPermanently)
+}
+
+// GET /t/{id}-{slug}
+// show the details of the thread, including the posts and the form to write a post.
+// A missing or outdated slug redirects to the canonical path.
+func ShowThread(writer http.ResponseWriter, request *http.Request) {
+	service := GetService(request)
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	idPart, _, _ := strings.Cut(strings.TrimPrefix(request.URL.Path, "/t/"), "-")
+	threadID, err := strconv.Atoi(idPart)
+	if err != nil {
+		utils.NotFound(writer, request)
+		return
+	}
+
+	thread, err := service.ThreadWithPosts(threadID)
+	if err != nil {
+		if err.Error() == "sql: no rows in result set" {
+			utils.NotFound(writer, request)
+		} else {
+			utils.InternalServerError(writer, request, err)
+		}
+		return
+	}
+
+	if canonical := utils.ThreadPath(thread.Id, thread.Topic); request.URL.Path != canonical {
+		http.Redirect(writer, request, canonical, http.StatusMovedPermanently)
+		return
+	}
+
+	recordVisit(writer, request, &thread)
 
 	// Check authentication status to determine which template to use
 	if IsAuthenticated(request) {
-		utils.GenerateHTML(writer, &thread, "layout", "private.navbar", "private.thread")
+		thread.Watching = service.IsWatchingThread(GetCurrentUser(request).Id, thread.Id)
+		thread.Bookmarked = service.IsThreadBookmarked(GetCurrentUser(request).Id, thread.Id)
+		thread.CanModerate = GetCurrentUser(request).IsModerator()
+		utils.ServePage(writer, request, utils.PrivatePage("private.thread"), &thread)
 	} else {
-		utils.GenerateHTML(writer, &thread, "layout", "public.navbar", "public.thread")
+		utils.ServePage(writer, request, utils.PublicPage("public.thread"), &thread)
 	}
 }
 
@@ -136,6 +188,8 @@ func PostThread(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	service := GetService(request)
+
 	// Check if user is authenticated
 	if !IsAuthenticated(request) {
 		utils.Unauthorized(writer, request, "Authentication required")
@@ -171,7 +225,7 @@ func PostThread(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	thread, err := internal.ThreadById(threadID)
+	thread, err := service.ThreadById(threadID)
 	if err != nil {
 		if err.Error() == "sql: no rows in result set" {
 			utils.NotFound(writer, request)
@@ -180,13 +234,32 @@ func PostThread(writer http.ResponseWriter, request *http.Request) {
 		}
 		return
 	}
+	if err := internal.CanReply(thread); err != nil {
+		utils.Forbidden(writer, request, err.Error())
+		return
+	}
 
-	_, err = internal.CreatePost(thread.Id, body, currentUser.Id)
+	postID, err := service.CreatePost(thread.Id, body, currentUser.Id)
 	if err != nil {
 		utils.InternalServerError(writer, request, err)
 		return
 	}
+	service.RecordMentions(currentUser.Id, thread.Id, int(postID), body)
+	service.DiscardDraft(currentUser.Id, thread.Id)
+
+	// Replying to a thread starts watching it
+	if err := service.WatchThread(currentUser.Id, thread.Id); err != nil {
+		utils.Warn("Cannot watch replied thread:", err)
+	}
+
+	http.Redirect(writer, request, utils.PostPath(thread.Id, thread.Topic, int(postID)), http.StatusFound)
+}
 
-	url := fmt.Sprint("/thread/read?id=", id)
-	http.Redirect(writer, request, url, 302)
+// threadPath returns the canonical path of a thread for redirects
+func threadPath(service *internal.Service, threadID int) string {
+	thread, err := service.ThreadById(threadID)
+	if err != nil {
+		return "/t/" + strconv.Itoa(threadID)
+	}
+	return utils.ThreadPath(thread.Id, thread.Topic)
 }
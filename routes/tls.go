@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RedirectHTTPS answers plain HTTP requests with a permanent redirect to the
+// same host and path on the HTTPS listener at httpsAddress
+func RedirectHTTPS(httpsAddress string) http.HandlerFunc {
+	_, port, _ := net.SplitHostPort(httpsAddress)
+	return func(writer http.ResponseWriter, request *http.Request) {
+		host := request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		} else {
+			host = strings.Trim(host, "[]")
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		target := "https://" + host + request.URL.RequestURI()
+		http.Redirect(writer, request, target, http.StatusMovedPermanently)
+	}
+}
@@ -0,0 +1,146 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"forum/models"
+	"forum/utils"
+)
+
+// POST /thread/watch
+// start watching a thread for digest emails
+func WatchThread(writer http.ResponseWriter, request *http.Request) {
+	toggleWatch(writer, request, true)
+}
+
+// POST /thread/unwatch
+// stop watching a thread
+func UnwatchThread(writer http.ResponseWriter, request *http.Request) {
+	toggleWatch(writer, request, false)
+}
+
+func toggleWatch(writer http.ResponseWriter, request *http.Request, watch bool) {
+	service := GetService(request)
+	if request.Method != "POST" {
+		utils.MethodNotAllowed(writer, request, "POST method only")
+		return
+	}
+
+	currentUser := GetCurrentUser(request)
+	if currentUser == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	threadID, err := strconv.Atoi(request.PostFormValue("id"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid thread ID format")
+		return
+	}
+
+	if _, err := service.ThreadById(threadID); err != nil {
+		utils.NotFound(writer, request)
+		return
+	}
+
+	if watch {
+		err = service.WatchThread(currentUser.Id, threadID)
+	} else {
+		err = service.UnwatchThread(currentUser.Id, threadID)
+	}
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+
+	// The watching page unwatches in place, everything else returns to the thread
+	if request.PostFormValue("next") == "/watching" {
+		http.Redirect(writer, request, "/watching", http.StatusFound)
+		return
+	}
+	http.Redirect(writer, request, threadPath(service, threadID), http.StatusFound)
+}
+
+// GET /watching
+// list watched threads and the digest frequency
+// POST /watching
+// update the digest frequency
+func Watching(writer http.ResponseWriter, request *http.Request) {
+	currentUser := GetCurrentUser(request)
+	if currentUser == nil {
+		utils.Unauthorized(writer, request, "Authentication required")
+		return
+	}
+
+	switch request.Method {
+	case "GET":
+		renderWatching(writer, request, currentUser, "")
+	case "POST":
+		if err := GetService(request).SetDigestFrequency(currentUser.Id, request.PostFormValue("frequency")); err != nil {
+			utils.BadRequest(writer, request, "Unknown digest frequency")
+			return
+		}
+		http.Redirect(writer, request, "/watching", http.StatusFound)
+	default:
+		utils.MethodNotAllowed(writer, request, "GET or POST method only")
+	}
+}
+
+// renderWatching shows the watching page, newFeedURL is only set
+// right after the notifications feed token was generated
+func renderWatching(writer http.ResponseWriter, request *http.Request, user *models.User, newFeedURL string) {
+	service := GetService(request)
+	watches, err := service.WatchedThreads(user.Id)
+	if err != nil {
+		utils.InternalServerError(writer, request, err)
+		return
+	}
+	pageData := struct {
+		Watches      []models.ThreadWatch
+		Frequency    string
+		HasFeedToken bool
+		NewFeedURL   string
+	}{
+		Watches:      watches,
+		Frequency:    service.DigestFrequency(user.Id),
+		HasFeedToken: service.HasFeedToken(user.Id),
+		NewFeedURL:   newFeedURL,
+	}
+	utils.ServePage(writer, request, utils.PrivatePage("watching"), pageData)
+}
+
+// GET /unsubscribe?user=&thread=&sig=
+// one-click unsubscribe from digest emails, works without a session
+func Unsubscribe(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		utils.MethodNotAllowed(writer, request, "GET method only")
+		return
+	}
+
+	vals := request.URL.Query()
+	userID, err := strconv.Atoi(vals.Get("user"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid unsubscribe link")
+		return
+	}
+	threadID, err := strconv.Atoi(vals.Get("thread"))
+	if err != nil {
+		utils.BadRequest(writer, request, "Invalid unsubscribe link")
+		return
+	}
+
+	if err := GetService(request).Unsubscribe(userID, threadID, vals.Get("sig")); err != nil {
+		utils.Forbidden(writer, request, "This unsubscribe link is invalid or has been tampered with")
+		return
+	}
+
+	message := "You will no longer receive digests for this thread."
+	if threadID == 0 {
+		message = "Digest emails are turned off. You can turn them back on from the Watching page."
+	}
+	utils.ServePage(writer, request, utils.PublicPage("error"), map[string]interface{}{
+		"Title":   "Unsubscribed",
+		"Message": message,
+	})
+}
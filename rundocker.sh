@@ -19,6 +19,6 @@ docker build -t $image_name .; print_log $GREEN "image created" $ORANGE $image_n
 
 print_log $GREEN "running" $ORANGE $container_name
 
-docker run --rm -it -p 8080:8080 -v ./pkg/mydb.db:/app/pkg/mydb.db -v ./config/config.json:/app/config/config.json -v ./templates:/app/templates -v ./public:/app/public  --name $container_name $image_name
+docker run --rm -it -p 8080:8080 -v ./pkg/mydb.db:/app/pkg/mydb.db -v ./config/config.json:/app/config/config.json  --name $container_name $image_name
 docker rmi $image_name
 print_log $GREEN "complete"
\ No newline at end of file
@@ -0,0 +1,317 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"forum"
+	"forum/internal"
+	"forum/internal/data"
+	"forum/models"
+	"forum/routes"
+	"forum/utils"
+)
+
+// readExport unzips an export into its files
+func readExport(t *testing.T, archive []byte) map[string][]byte {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("Expected a zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		rc, _ := file.Open()
+		var content bytes.Buffer
+		content.ReadFrom(rc)
+		rc.Close()
+		files[file.Name] = content.Bytes()
+	}
+	return files
+}
+
+func countRows(dm *data.DatabaseManager, query string, args ...any) int {
+	var count int
+	dm.GetDB().QueryRow(query, args...).Scan(&count)
+	return count
+}
+
+func TestExportUserData(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+	alice := mustCreateUser(t, service, "alice")
+	bob := mustCreateUser(t, service, "bob")
+	aliceThread := mustCreateThread(t, service, alice.Id, "Alice's thread", "Games")
+	dm.SetThreadTags(aliceThread, []string{"go"})
+	bobThread := mustCreateThread(t, service, bob.Id, "Bob's thread", "Movies")
+	bobReply, _ := dm.CreatePostByUser("Bob replies", bob.Id, aliceThread)
+	aliceReply, _ := dm.CreatePostByUser("Alice replies", alice.Id, bobThread)
+	dm.AddThreadLike(alice.Id, bobThread)
+	dm.AddPostDislike(alice.Id, int(bobReply))
+	dm.CreateSession(&alice)
+
+	var archive bytes.Buffer
+	if err := service.ExportUserData(alice.Id, &archive); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	files := readExport(t, archive.Bytes())
+	for _, name := range []string{"profile.json", "threads.json", "posts.json", "votes.json", "sessions.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Expected %s in the export", name)
+		}
+	}
+
+	var profile models.ExportProfile
+	json.Unmarshal(files["profile.json"], &profile)
+	if profile.Name != "alice" || profile.Email != "alice@example.com" || strings.Contains(string(files["profile.json"]), "$2a$") {
+		t.Errorf("Unexpected profile %s", files["profile.json"])
+	}
+	var threads []models.ExportThread
+	json.Unmarshal(files["threads.json"], &threads)
+	if len(threads) != 1 || threads[0].Id != aliceThread || len(threads[0].Tags) != 1 || threads[0].Categories[0] != "Games" {
+		t.Errorf("Unexpected threads %s", files["threads.json"])
+	}
+	var posts []models.ExportPost
+	json.Unmarshal(files["posts.json"], &posts)
+	if len(posts) != 1 || posts[0].Id != int(aliceReply) || posts[0].ThreadId != bobThread {
+		t.Errorf("Unexpected posts %s", files["posts.json"])
+	}
+	var votes []models.ExportVote
+	json.Unmarshal(files["votes.json"], &votes)
+	if len(votes) != 2 || votes[0] != (models.ExportVote{Vote: "like", ThreadId: bobThread}) ||
+		votes[1] != (models.ExportVote{Vote: "dislike", PostId: int(bobReply)}) {
+		t.Errorf("Unexpected votes %s", files["votes.json"])
+	}
+	var sessions []models.ExportSession
+	json.Unmarshal(files["sessions.json"], &sessions)
+	if len(sessions) != 1 || strings.Contains(string(files["sessions.json"]), "cookie") {
+		t.Errorf("Unexpected sessions %s", files["sessions.json"])
+	}
+}
+
+func TestDeleteAccountAnonymize(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+	internal.SetDeletionPolicy(internal.DeletionAnonymize)
+	alice := mustCreateUser(t, service, "alice")
+	bob := mustCreateUser(t, service, "bob")
+	aliceThread := mustCreateThread(t, service, alice.Id, "Alice's thread", "Games")
+	bobThread := mustCreateThread(t, service, bob.Id, "Bob's thread", "Movies")
+	aliceReply, _ := dm.CreatePostByUser("Alice replies", alice.Id, bobThread)
+	dm.AddThreadLike(alice.Id, bobThread)
+	dm.FollowUser(alice.Id, bob.Id)
+	dm.WatchThread(alice.Id, bobThread)
+	dm.MarkThreadRead(alice.Id, bobThread, int(aliceReply))
+	dm.CreateSession(&alice)
+	if _, err := dm.CreateConversation(alice.Id, []int{bob.Id}, "Hello", "Hi bob"); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if err := service.DeleteAccount(alice.Id); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	user, err := dm.GetUserByID(alice.Id)
+	if err != nil || user.Status != models.StatusDeleted || user.Name != "deleted-1" || user.Email == alice.Email {
+		t.Errorf("Expected an anonymous deleted account, got %+v (%v)", user, err)
+	}
+	if utils.CheckPassword(user.Password, "Pass123!") {
+		t.Error("Expected the password to stop working")
+	}
+	if thread, err := dm.GetThreadWithPosts(aliceThread); err != nil || thread.User != "deleted-1" {
+		t.Errorf("Expected the thread to stay under the new name, got %q (%v)", thread.User, err)
+	}
+	if count := countRows(dm, "SELECT COUNT(*) FROM posts WHERE user_id = ?", alice.Id); count != 1 {
+		t.Errorf("Expected the reply to stay, got %d", count)
+	}
+	if count := countRows(dm, "SELECT COUNT(*) FROM threadlikes WHERE user_id = ?", alice.Id); count != 1 {
+		t.Errorf("Expected the votes to stay with the anonymous account, got %d", count)
+	}
+	for _, table := range []string{"sessions", "thread_watches", "thread_reads", "conversation_members"} {
+		if count := countRows(dm, "SELECT COUNT(*) FROM "+table+" WHERE user_id = ?", alice.Id); count != 0 {
+			t.Errorf("Expected no %s left, got %d", table, count)
+		}
+	}
+	if count := countRows(dm, "SELECT COUNT(*) FROM user_follows WHERE follower_id = ?", alice.Id); count != 0 {
+		t.Errorf("Expected no follows left, got %d", count)
+	}
+	if count, _ := dm.GetUserCount(); count != 1 {
+		t.Errorf("Expected deleted accounts not to be counted, got %d", count)
+	}
+	if err := service.DeleteAccount(alice.Id); err == nil {
+		t.Error("Expected a deleted account to be deleted only once")
+	}
+	if violations, _ := service.OrphanedRows(); len(violations) != 0 {
+		t.Errorf("Expected no orphaned rows, got %v", violations)
+	}
+}
+
+func TestDeleteAccountRemove(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+	internal.SetDeletionPolicy(internal.DeletionRemove)
+	defer internal.SetDeletionPolicy(internal.DeletionAnonymize)
+	alice := mustCreateUser(t, service, "alice")
+	bob := mustCreateUser(t, service, "bob")
+	answered := mustCreateThread(t, service, alice.Id, "Alice's thread", "Games")
+	unanswered := mustCreateThread(t, service, alice.Id, "Nobody answered", "Games")
+	bobThread := mustCreateThread(t, service, bob.Id, "Bob's thread", "Movies")
+	dm.SetThreadTags(answered, []string{"go"})
+	dm.SetThreadTags(unanswered, []string{"go"})
+	bobReply, _ := dm.CreatePostByUser("Bob replies", bob.Id, answered)
+	aliceReply, _ := dm.CreatePostByUser("Alice replies", alice.Id, bobThread)
+	dm.AddThreadLike(bob.Id, answered)
+	dm.AddThreadLike(bob.Id, unanswered)
+	dm.AddThreadLike(alice.Id, bobThread)
+	dm.AddPostDislike(alice.Id, int(bobReply))
+	dm.SaveBookmark(bob.Id, answered, 0, 0, "")
+	if _, err := dm.CreateConversation(alice.Id, []int{bob.Id}, "Hello", "Hi bob"); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if err := service.DeleteAccount(alice.Id); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if user, _ := dm.GetUserByID(alice.Id); user.Status != models.StatusDeleted {
+		t.Errorf("Expected a deleted account, got %q", user.Status)
+	}
+	if _, err := dm.GetThreadByID(unanswered); err == nil {
+		t.Error("Expected the thread nobody replied to to be removed")
+	}
+	if count := countRows(dm, "SELECT COUNT(*) FROM posts WHERE id = ?", aliceReply); count != 0 {
+		t.Errorf("Expected alice's reply to be removed, got %d", count)
+	}
+	if count := countRows(dm, "SELECT COUNT(*) FROM threadlikes WHERE user_id = ? OR thread_id = ?", alice.Id, unanswered); count != 0 {
+		t.Errorf("Expected alice's votes and those on the removed thread to go, got %d", count)
+	}
+	if count := countRows(dm, "SELECT COUNT(*) FROM dislikes WHERE user_id = ?", alice.Id); count != 0 {
+		t.Errorf("Expected alice's reply votes to be removed, got %d", count)
+	}
+	if count := countRows(dm, "SELECT usage_count FROM tags WHERE name = 'go'"); count != 1 {
+		t.Errorf("Expected the tag to be recounted, got %d", count)
+	}
+	if count := countRows(dm, "SELECT COUNT(*) FROM messages WHERE user_id = ?", alice.Id); count != 0 {
+		t.Errorf("Expected alice's messages to be removed, got %d", count)
+	}
+
+	// bob replied to alice's thread: it stays under the placeholder with
+	// bob's reply, vote and bookmark
+	if thread, err := dm.GetThreadWithPosts(answered); err != nil || thread.User != "deleted-1" {
+		t.Errorf("Expected the answered thread to stay under the new name, got %q (%v)", thread.User, err)
+	}
+	if count := countRows(dm, "SELECT COUNT(*) FROM posts WHERE id = ?", bobReply); count != 1 {
+		t.Errorf("Expected bob's reply to stay, got %d", count)
+	}
+	if count := countRows(dm, "SELECT COUNT(*) FROM threadlikes WHERE user_id = ? AND thread_id = ?", bob.Id, answered); count != 1 {
+		t.Errorf("Expected bob's vote to stay, got %d", count)
+	}
+	if count := countRows(dm, "SELECT COUNT(*) FROM bookmarks WHERE user_id = ?", bob.Id); count != 1 {
+		t.Errorf("Expected bob's bookmark to stay, got %d", count)
+	}
+	if _, err := dm.GetThreadByID(bobThread); err != nil {
+		t.Errorf("Expected bob's thread to stay: %v", err)
+	}
+	if violations, _ := service.OrphanedRows(); len(violations) != 0 {
+		t.Errorf("Expected no orphaned rows, got %v", violations)
+	}
+}
+
+func TestPurgeAccount(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+	alice := mustCreateUser(t, service, "alice")
+	bob := mustCreateUser(t, service, "bob")
+	aliceThread := mustCreateThread(t, service, alice.Id, "Alice's thread", "Games")
+	bobThread := mustCreateThread(t, service, bob.Id, "Bob's thread", "Movies")
+	dm.SetThreadTags(aliceThread, []string{"go"})
+	bobReply, _ := dm.CreatePostByUser("Bob replies", bob.Id, aliceThread)
+	aliceReply, _ := dm.CreatePostByUser("Alice replies", alice.Id, bobThread)
+	dm.AddThreadLike(bob.Id, aliceThread)
+	dm.AddPostDislike(alice.Id, int(bobReply))
+	dm.FollowUser(bob.Id, alice.Id)
+	dm.WatchThread(alice.Id, bobThread)
+	dm.SaveBookmark(bob.Id, aliceThread, 0, 0, "")
+	dm.MarkThreadRead(alice.Id, bobThread, int(aliceReply))
+	dm.WriteAudit(alice.Id, "pin", "thread")
+	dm.CreateSession(&alice)
+	if _, err := dm.CreateConversation(alice.Id, []int{bob.Id}, "Hello", "Hi bob"); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if err := dm.DeleteUserByID(alice.Id); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := dm.GetUserByID(alice.Id); err == nil {
+		t.Error("Expected the account row to be removed")
+	}
+	if violations, _ := service.OrphanedRows(); len(violations) != 0 {
+		t.Errorf("Expected no orphaned rows, got %v", violations)
+	}
+	entries, err := dm.GetAuditLog(10)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected the audit entry to stay without its actor, got %v (%v)", entries, err)
+	}
+	if conversations, err := dm.GetUserConversations(bob.Id); err != nil || len(conversations) != 1 {
+		t.Errorf("Expected bob to keep the conversation, got %v (%v)", conversations, err)
+	}
+	if err := dm.DeleteUserByID(alice.Id); err == nil {
+		t.Error("Expected deleting a missing user to fail")
+	}
+}
+
+func TestAccountRoutes(t *testing.T) {
+	dm := openTestDB(t)
+	if _, err := utils.LoadTemplates(forum.Templates(), false); err != nil {
+		t.Fatal(err)
+	}
+	s := internal.NewService(dm)
+	alice := mustCreateUser(t, s, "alice")
+	mustCreateThread(t, s, alice.Id, "Alice's thread", "Games")
+	session, _ := s.Sessions.CreateSession(&alice)
+
+	serve := func(handler http.HandlerFunc, method, target string, form url.Values) *httptest.ResponseRecorder {
+		chain := routes.Chain(routes.WithService(s), routes.WithAuthentication(), routes.RequireAuth())
+		request := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.AddCookie(&http.Cookie{Name: utils.SessionCookieName(), Value: session.CookieString})
+		recorder := httptest.NewRecorder()
+		chain(handler)(recorder, request)
+		return recorder
+	}
+
+	rec := serve(routes.ExportAccount, "GET", "/account/export", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" ||
+		!strings.Contains(rec.Header().Get("Content-Disposition"), "forum-alice-") {
+		t.Fatalf("Expected a zip download, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected the export not to be cached, got %q", rec.Header().Get("Cache-Control"))
+	}
+	if files := readExport(t, rec.Body.Bytes()); len(files) != 5 {
+		t.Errorf("Expected 5 files, got %d", len(files))
+	}
+
+	rec = serve(routes.DeleteAccount, "POST", "/account/delete", url.Values{"password": {"wrong"}, "confirm": {"yes"}})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a wrong password to be refused, got %d", rec.Code)
+	}
+	if user, _ := dm.GetUserByID(alice.Id); user.Status != models.StatusActive {
+		t.Errorf("Expected the account to stay, got %q", user.Status)
+	}
+
+	rec = serve(routes.DeleteAccount, "POST", "/account/delete", url.Values{"password": {"Pass123!"}, "confirm": {"yes"}})
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/" {
+		t.Errorf("Expected a redirect home, got %d", rec.Code)
+	}
+	if user, _ := dm.GetUserByID(alice.Id); user.Status != models.StatusDeleted {
+		t.Errorf("Expected a deleted account, got %q", user.Status)
+	}
+	if rec = serve(routes.ExportAccount, "GET", "/account/export", nil); rec.Code != http.StatusFound {
+		t.Errorf("Expected the old session to be signed out, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"forum"
+	"forum/internal"
+	"forum/models"
+	"forum/routes"
+	"forum/utils"
+)
+
+func TestDashboard(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	alice := models.User{Name: "alice", Email: "alice@example.com", Password: "Pass123!"}
+	bob := models.User{Name: "bob", Email: "bob@example.com", Password: "Pass123!"}
+	for _, user := range []*models.User{&alice, &bob} {
+		if err := dm.CreateUser(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	first, _ := dm.CreateThreadByUser("=SUM(1)", "Body", alice.Id, "Games", "Movies")
+	second, _ := dm.CreateThreadByUser("Second", "Body", alice.Id, "Games", "")
+	dm.CreatePostByUser("Reply", bob.Id, int(first))
+	dm.AddThreadLike(bob.Id, int(second))
+	dm.AddThreadLike(alice.Id, int(second))
+	dm.AddThreadDislike(bob.Id, int(first))
+
+	today := time.Now()
+	d, err := service.Dashboard(today.AddDate(0, 0, -6), today)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d.TotalUsers != 2 || d.TotalThreads != 2 || d.TotalPosts != 1 {
+		t.Errorf("Unexpected totals %d users, %d threads, %d posts", d.TotalUsers, d.TotalThreads, d.TotalPosts)
+	}
+	if len(d.Daily) != 7 {
+		t.Fatalf("Expected 7 days, got %d", len(d.Daily))
+	}
+	if last := d.Daily[6]; last.Signups != 2 || last.Threads != 2 || last.Posts != 1 || d.Posts != 1 {
+		t.Errorf("Expected today's activity on the last day, got %+v", last)
+	}
+	if len(d.ActiveUsers) != 2 || d.ActiveUsers[0].Name != "alice" || d.ActiveUsers[0].Threads != 2 || d.ActiveUsers[1].Posts != 1 {
+		t.Errorf("Unexpected active users %+v", d.ActiveUsers)
+	}
+	if len(d.VotedThreads) != 2 || d.VotedThreads[0].ThreadId != int(second) || d.VotedThreads[0].Likes != 2 {
+		t.Errorf("Unexpected voted threads %+v", d.VotedThreads)
+	}
+	if len(d.Categories) != 2 || d.Categories[0] != (models.CategoryCount{Category: "Games", Threads: 2}) {
+		t.Errorf("Unexpected categories %+v", d.Categories)
+	}
+
+	past, err := service.Dashboard(today.AddDate(0, 0, -20), today.AddDate(0, 0, -10))
+	if err != nil || past.Signups != 0 || len(past.ActiveUsers) != 0 || len(past.Categories) != 0 || past.TotalUsers != 2 {
+		t.Errorf("Expected an empty range with all time totals, got %+v (%v)", past, err)
+	}
+	if _, err := service.Dashboard(today, today.AddDate(0, 0, -1)); err != internal.ErrInvalidRange {
+		t.Errorf("Expected a reversed range to fail, got %v", err)
+	}
+}
+
+func TestDashboardRoutes(t *testing.T) {
+	dm := openTestDB(t)
+	if _, err := utils.LoadTemplates(forum.Templates(), false); err != nil {
+		t.Fatal(err)
+	}
+	s := internal.NewService(dm)
+
+	admin := models.User{Name: "admin", Email: "admin@example.com", Password: "Pass123!"}
+	member := models.User{Name: "member", Email: "member@example.com", Password: "Pass123!"}
+	for _, user := range []*models.User{&admin, &member} {
+		if err := dm.CreateUser(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	dm.GetDB().Exec("UPDATE users SET role = 'admin' WHERE id = ?", admin.Id)
+	threadID, _ := dm.CreateThreadByUser("=HYPERLINK(\"x\")", "Body", member.Id, "Games", "")
+	dm.AddThreadLike(admin.Id, int(threadID))
+
+	get := func(user models.User, handler http.HandlerFunc, target string) *httptest.ResponseRecorder {
+		session, _ := s.Sessions.CreateSession(&user)
+		chain := routes.Chain(routes.WithService(s), routes.WithAuthentication(), routes.RequireAuth(), routes.RequireAdmin())
+		request := httptest.NewRequest("GET", target, nil)
+		request.AddCookie(&http.Cookie{Name: utils.SessionCookieName(), Value: session.CookieString})
+		recorder := httptest.NewRecorder()
+		chain(handler)(recorder, request)
+		return recorder
+	}
+
+	if code := get(member, routes.AdminDashboard, "/admin").Code; code != http.StatusForbidden {
+		t.Errorf("Expected members to be refused, got %d", code)
+	}
+	if rec := get(admin, routes.AdminDashboard, "/admin"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Most voted threads") {
+		t.Errorf("Expected the dashboard, got %d", rec.Code)
+	}
+	if code := get(admin, routes.AdminDashboard, "/admin?from=2026-13-01").Code; code != http.StatusBadRequest {
+		t.Errorf("Expected a bad date to be refused, got %d", code)
+	}
+
+	rec := get(admin, routes.AdminExport, "/admin/export?table=categories")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("Expected a CSV, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil || len(records) != 2 || records[1][0] != "Games" || records[1][1] != "1" {
+		t.Errorf("Unexpected CSV %v (%v)", records, err)
+	}
+
+	rec = get(admin, routes.AdminExport, "/admin/export?table=daily")
+	if records, _ := csv.NewReader(rec.Body).ReadAll(); len(records) != 31 {
+		t.Errorf("Expected a header and 30 days, got %d rows", len(records))
+	}
+	rec = get(admin, routes.AdminExport, "/admin/export?table=threads")
+	if records, _ := csv.NewReader(rec.Body).ReadAll(); len(records) != 2 || !strings.HasPrefix(records[1][1], "'=") {
+		t.Errorf("Expected the formula in the topic to be escaped, got %v", records)
+	}
+	if code := get(admin, routes.AdminExport, "/admin/export?table=passwords").Code; code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown table to be refused, got %d", code)
+	}
+}
@@ -0,0 +1,212 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"forum"
+	"forum/internal"
+	"forum/internal/data"
+	"forum/models"
+	"forum/routes"
+	"forum/utils"
+)
+
+// useBackupDir points the snapshots at a directory of the test
+func useBackupDir(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "backups")
+	internal.SetBackupDir(dir)
+	t.Cleanup(func() { internal.SetBackupDir("pkg/backups") })
+	return dir
+}
+
+func TestBackupSnapshots(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+	dir := useBackupDir(t)
+	mustCreateUser(t, service, "alice")
+	ctx := context.Background()
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	backup, err := service.CreateBackup(ctx, start)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if backup.Name != "forum-20261001-120000.000.db" || backup.Size == 0 || !backup.CreatedAt.Equal(start) {
+		t.Errorf("Unexpected snapshot %+v", backup)
+	}
+	if err := internal.VerifyBackup(filepath.Join(dir, backup.Name)); err != nil {
+		t.Errorf("Expected the snapshot to pass the check: %v", err)
+	}
+	snapshot, err := data.OpenSnapshot(filepath.Join(dir, backup.Name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var journal string
+	var users int
+	snapshot.GetDB().QueryRow("PRAGMA journal_mode").Scan(&journal)
+	snapshot.GetDB().QueryRow("SELECT COUNT(*) FROM users").Scan(&users)
+	snapshot.Close()
+	if journal != "delete" || users != 1 {
+		t.Errorf("Expected a self-contained copy with alice, got journal %q and %d users", journal, users)
+	}
+	if _, err := service.CreateBackup(ctx, start); !errors.Is(err, internal.ErrBackupExists) {
+		t.Errorf("Expected a second snapshot in the same millisecond to be refused, got %v", err)
+	}
+
+	job := internal.BackupJob{Service: service, Keep: 2}
+	for hour := 1; hour <= 3; hour++ {
+		if _, err := job.RunOnce(ctx, start.Add(time.Duration(hour)*time.Hour)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	backups, err := internal.ListBackups()
+	if err != nil || len(backups) != 2 || backups[0].Name != "forum-20261001-150000.000.db" || backups[1].Name != "forum-20261001-140000.000.db" {
+		t.Errorf("Expected the 2 newest snapshots, got %v (%v)", backups, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Expected no partial files left, got %d files", len(entries))
+	}
+
+	if _, err := internal.BackupPath(backups[0].Name); err != nil {
+		t.Errorf("Expected a listed snapshot to be found: %v", err)
+	}
+	for _, name := range []string{"../test.db", backup.Name, "forum-20261001-150000.000.db/../x", ""} {
+		if _, err := internal.BackupPath(name); err == nil {
+			t.Errorf("Expected %q not to be served", name)
+		}
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+	dir := useBackupDir(t)
+	mustCreateUser(t, service, "alice")
+	ctx := context.Background()
+
+	backup, err := service.CreateBackup(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mustCreateUser(t, service, "bob")
+
+	garbage := filepath.Join(dir, "forum-20200101-000000.000.db")
+	os.WriteFile(garbage, []byte("not a database, just some text that is long enough"), 0o600)
+	if _, err := service.RestoreBackup(ctx, garbage); err == nil || !strings.Contains(err.Error(), "nothing restored") {
+		t.Errorf("Expected a damaged snapshot to be refused, got %v", err)
+	}
+	other := filepath.Join(t.TempDir(), "other.db")
+	otherDB, _ := data.NewDatabaseManager(other)
+	otherDB.DoExec("CREATE TABLE notes (id integer)")
+	otherDB.Close()
+	if _, err := service.RestoreBackup(ctx, other); err == nil {
+		t.Error("Expected a database of another application to be refused")
+	}
+	if backups, _ := internal.ListBackups(); len(backups) != 2 {
+		t.Errorf("Expected refused restores not to save the database, got %d snapshots", len(backups))
+	}
+
+	saved, err := service.RestoreBackup(ctx, backup.Name)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := dm.GetUserByName("bob"); err == nil {
+		t.Error("Expected bob, who signed up after the snapshot, to be gone")
+	}
+	if _, err := dm.GetUserByName("alice"); err != nil {
+		t.Errorf("Expected alice to be restored: %v", err)
+	}
+	mustCreateUser(t, service, "carol") // the restored database takes writes
+
+	// the database saved before the restore undoes it
+	if _, err := service.RestoreBackup(ctx, filepath.Join(dir, saved.Name)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := dm.GetUserByName("bob"); err != nil {
+		t.Errorf("Expected bob to be back: %v", err)
+	}
+	if err := dm.IntegrityCheck(); err != nil {
+		t.Errorf("Expected an intact database: %v", err)
+	}
+
+	if _, err := runCLI(t, service, "backup", "restore", backup.Name); err == nil {
+		t.Error("Expected a restore without --yes to be refused")
+	}
+	out, err := runCLI(t, service, "backup", "verify", backup.Name)
+	if err != nil || !strings.HasSuffix(strings.TrimSpace(out), "ok") {
+		t.Errorf("Expected the snapshot to verify, got %q (%v)", out, err)
+	}
+	if _, err := runCLI(t, service, "backup", "verify", garbage); err == nil {
+		t.Error("Expected the damaged snapshot to fail verification")
+	}
+}
+
+func TestBackupRoutes(t *testing.T) {
+	dm := openTestDB(t)
+	useBackupDir(t)
+	if _, err := utils.LoadTemplates(forum.Templates(), false); err != nil {
+		t.Fatal(err)
+	}
+	s := internal.NewService(dm)
+	admin := mustCreateUser(t, s, "admin")
+	member := mustCreateUser(t, s, "member")
+	dm.SetUserRole(admin.Id, models.RoleAdmin)
+
+	serve := func(user models.User, handler http.HandlerFunc, method, target string) *httptest.ResponseRecorder {
+		session, _ := s.Sessions.CreateSession(&user)
+		chain := routes.Chain(routes.WithService(s), routes.WithAuthentication(), routes.RequireAuth(), routes.RequireAdmin())
+		request := httptest.NewRequest(method, target, nil)
+		request.AddCookie(&http.Cookie{Name: utils.SessionCookieName(), Value: session.CookieString})
+		recorder := httptest.NewRecorder()
+		chain(handler)(recorder, request)
+		return recorder
+	}
+
+	if code := serve(member, routes.AdminBackups, "POST", "/admin/backups").Code; code != http.StatusForbidden {
+		t.Errorf("Expected members to be refused, got %d", code)
+	}
+	if rec := serve(admin, routes.AdminBackups, "POST", "/admin/backups"); rec.Code != http.StatusFound {
+		t.Fatalf("Expected a redirect to the list, got %d", rec.Code)
+	}
+	backups, _ := internal.ListBackups()
+	if len(backups) != 1 {
+		t.Fatalf("Expected a snapshot, got %d", len(backups))
+	}
+	name := backups[0].Name
+	if rec := serve(admin, routes.AdminBackups, "GET", "/admin/backups"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), name) {
+		t.Errorf("Expected the snapshot to be listed, got %d", rec.Code)
+	}
+
+	rec := serve(admin, routes.AdminBackupDownload, "GET", "/admin/backups/download?name="+name)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/vnd.sqlite3" {
+		t.Fatalf("Expected the snapshot, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected the snapshot not to be cached, got %q", rec.Header().Get("Cache-Control"))
+	}
+	if header, _ := io.ReadAll(io.LimitReader(rec.Body, 15)); string(header) != "SQLite format 3" {
+		t.Errorf("Expected an SQLite file, got %q", header)
+	}
+	if code := serve(admin, routes.AdminBackupDownload, "GET", "/admin/backups/download?name=../test.db").Code; code != http.StatusNotFound {
+		t.Errorf("Expected a path outside the directory to be refused, got %d", code)
+	}
+
+	entries, _ := dm.GetAuditLog(10)
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+	}
+	if got := strings.Join(actions, ","); got != "download_backup,create_backup" {
+		t.Errorf("Expected the backup and the download to be audited, got %s", got)
+	}
+}
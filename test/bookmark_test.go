@@ -0,0 +1,88 @@
+package test
+
+import (
+	"testing"
+
+	"forum/internal"
+	"forum/models"
+)
+
+func TestBookmarksAndToken(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	owner := models.User{Name: "owner", Email: "owner@example.com", Password: "Pass123!"}
+	other := models.User{Name: "other", Email: "other@example.com", Password: "Pass123!"}
+	for _, user := range []*models.User{&owner, &other} {
+		if err := dm.CreateUser(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	threadID, err := dm.CreateThreadByUser("Useful topic", "Body", owner.Id, "Other", "")
+	if err != nil {
+		t.Fatalf("Failed to create thread: %v", err)
+	}
+	postID, err := service.CreatePost(int(threadID), "A great answer", other.Id)
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	if err := service.CreateCollection(owner.Id, "Answers"); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	if err := service.CreateCollection(owner.Id, "Answers"); err == nil {
+		t.Error("Expected duplicate collection name to be rejected")
+	}
+	collections, _ := service.Collections(owner.Id)
+	if len(collections) != 1 {
+		t.Fatalf("Expected 1 collection, got %d", len(collections))
+	}
+	collectionID := collections[0].Id
+
+	if err := service.SaveBookmark(owner.Id, int(threadID), 0, 0, ""); err != nil {
+		t.Fatalf("Failed to bookmark thread: %v", err)
+	}
+	if err := service.SaveBookmark(owner.Id, int(threadID), int(postID), collectionID, "keep this"); err != nil {
+		t.Fatalf("Failed to bookmark post: %v", err)
+	}
+	if err := service.SaveBookmark(other.Id, int(threadID), 0, collectionID, ""); err != internal.ErrUnknownCollection {
+		t.Errorf("Expected someone else's collection to be refused, got %v", err)
+	}
+
+	all := models.BookmarkFilter{CollectionId: -1}
+	cases := []struct {
+		name   string
+		filter models.BookmarkFilter
+		want   int
+	}{
+		{"all", all, 2},
+		{"posts", models.BookmarkFilter{CollectionId: -1, Kind: "post"}, 1},
+		{"unsorted", models.BookmarkFilter{CollectionId: 0}, 1},
+		{"collection", models.BookmarkFilter{CollectionId: collectionID}, 1},
+		{"note search", models.BookmarkFilter{CollectionId: -1, Query: "keep"}, 1},
+	}
+	for _, c := range cases {
+		bookmarks, err := service.UserBookmarks(owner.Id, c.filter)
+		if err != nil || len(bookmarks) != c.want {
+			t.Errorf("%s: expected %d bookmarks, got %d (%v)", c.name, c.want, len(bookmarks), err)
+		}
+	}
+	if bookmarks, _ := service.UserBookmarks(other.Id, all); len(bookmarks) != 0 {
+		t.Errorf("Expected bookmarks to be private, other user sees %d", len(bookmarks))
+	}
+
+	token, err := service.GenerateAPIToken(owner.Id)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	if user, err := service.UserByAPIToken(token); err != nil || user.Id != owner.Id {
+		t.Errorf("Expected token to resolve to owner, got %d (%v)", user.Id, err)
+	}
+	if _, err := service.GenerateAPIToken(owner.Id); err != nil {
+		t.Fatalf("Failed to replace token: %v", err)
+	}
+	if _, err := service.UserByAPIToken(token); err != internal.ErrInvalidAPIToken {
+		t.Errorf("Expected replaced token to stop working, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"forum"
+	"forum/cli"
+	"forum/internal"
+	"forum/models"
+	"forum/routes"
+	"forum/utils"
+)
+
+// runCLI runs a subcommand and returns what it printed
+func runCLI(t *testing.T, service *internal.Service, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := cli.Run(service, args, &out)
+	return out.String(), err
+}
+
+func TestCLIUsers(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	out, err := runCLI(t, service, "user", "create", "--name", "alice", "--email", "alice@example.com", "--role", "admin")
+	if err != nil || !strings.Contains(out, "created user alice") {
+		t.Fatalf("Expected alice to be created, got %q (%v)", out, err)
+	}
+	_, generated, _ := strings.Cut(out, "password: ")
+	generated = strings.TrimSpace(generated)
+	alice, err := dm.GetUserByName("alice")
+	if err != nil || alice.Role != models.RoleAdmin || !utils.CheckPassword(alice.Password, generated) {
+		t.Errorf("Expected an admin with the printed password, got %+v (%v)", alice, err)
+	}
+
+	if _, err := runCLI(t, service, "user", "create", "--name", "alice", "--email", "other@example.com", "--password", "Pass123!"); err == nil {
+		t.Error("Expected a taken name to be refused")
+	}
+	if _, err := runCLI(t, service, "user", "create", "--name", "bob", "--email", "bob@example.com", "--password", "weak"); !errors.Is(err, internal.ErrWeakPassword) {
+		t.Errorf("Expected a weak password to be refused, got %v", err)
+	}
+	if _, err := runCLI(t, service, "user", "create", "--name", "bob"); !errors.Is(err, cli.ErrUsage) {
+		t.Errorf("Expected a usage error without email, got %v", err)
+	}
+
+	if _, err := runCLI(t, service, "user", "set-role", "alice@example.com", "moderator"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if alice, _ = dm.GetUserByName("alice"); alice.Role != models.RoleModerator {
+		t.Errorf("Expected a moderator, got %s", alice.Role)
+	}
+	if _, err := runCLI(t, service, "user", "set-role", "alice", "owner"); !errors.Is(err, internal.ErrUnknownRole) {
+		t.Errorf("Expected an unknown role to be refused, got %v", err)
+	}
+	if _, err := runCLI(t, service, "user", "set-role", "nobody", "admin"); err == nil {
+		t.Error("Expected an unknown user to be reported")
+	}
+
+	dm.CreateSession(&alice)
+	if _, err := runCLI(t, service, "user", "reset-password", "--password", "NewPass1!", "alice"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	alice, _ = dm.GetUserByName("alice")
+	if !utils.CheckPassword(alice.Password, "NewPass1!") {
+		t.Error("Expected the new password")
+	}
+	if count, _ := dm.CountSessions(); count != 0 {
+		t.Errorf("Expected the reset to end the sessions, %d left", count)
+	}
+
+	dm.CreateSession(&alice)
+	if _, err := runCLI(t, service, "user", "ban", "alice"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if alice, _ = dm.GetUserByName("alice"); alice.Status != models.StatusBanned {
+		t.Errorf("Expected a banned user, got %s", alice.Status)
+	}
+	if count, _ := dm.CountSessions(); count != 0 {
+		t.Errorf("Expected the ban to end the sessions, %d left", count)
+	}
+	runCLI(t, service, "user", "ban", "--lift", "alice")
+	if alice, _ = dm.GetUserByName("alice"); alice.Status != models.StatusActive {
+		t.Errorf("Expected the ban to be lifted, got %s", alice.Status)
+	}
+
+	if _, err := runCLI(t, service, "user", "delete", "alice"); err == nil {
+		t.Error("Expected a deletion without --yes to be refused")
+	}
+	if _, err := runCLI(t, service, "user", "delete", "--yes", "alice"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := dm.GetUserByName("alice"); err == nil {
+		t.Error("Expected alice to be deleted")
+	}
+}
+
+func TestCLIMaintenance(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	alice := models.User{Name: "alice", Email: "alice@example.com", Password: "Pass123!"}
+	bob := models.User{Name: "bob", Email: "bob@example.com", Password: "Pass123!"}
+	for _, user := range []*models.User{&alice, &bob} {
+		if err := dm.CreateUser(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	service.BanUser(bob.Id)
+	threadID, _ := dm.CreateThreadByUser("Topic", "Body", alice.Id, "Games", "")
+	dm.CreatePostByUser("Reply", alice.Id, int(threadID))
+	dm.CreateSession(&alice)
+	old, _ := dm.CreateSession(&bob)
+	dm.GetDB().Exec("UPDATE sessions SET created_at = ? WHERE uuid = ?", time.Now().Add(-48*time.Hour), old.Uuid)
+
+	// bob's session has expired and is not counted
+	out, err := runCLI(t, service, "stats")
+	if err != nil || !strings.Contains(out, "users     2 (1 active, 1 banned)") || !strings.Contains(out, "threads   1") ||
+		!strings.Contains(out, "replies   1") || !strings.Contains(out, "sessions  1") {
+		t.Errorf("Unexpected stats %q (%v)", out, err)
+	}
+
+	if out, err := runCLI(t, service, "session", "purge"); err != nil || !strings.Contains(out, "removed 1 sessions") {
+		t.Errorf("Expected the expired session to be removed, got %q (%v)", out, err)
+	}
+	if out, err := runCLI(t, service, "session", "purge", "--all"); err != nil || !strings.Contains(out, "removed 1 sessions") {
+		t.Errorf("Expected the last session to be removed, got %q (%v)", out, err)
+	}
+
+	out, err = runCLI(t, service, "db", "check")
+	if err != nil || strings.Count(out, " ok") != 4 {
+		t.Errorf("Expected every check to pass, got %q (%v)", out, err)
+	}
+	dm.GetDB().Exec("INSERT INTO posts(uuid, body, user_id, thread_id, created_at) VALUES('orphan', 'x', 99, ?, ?)", threadID, time.Now())
+	if out, err := runCLI(t, service, "db", "check"); err == nil || !strings.Contains(out, "references  FAIL") || !strings.Contains(out, "1 rows of posts -> users") {
+		t.Errorf("Expected the orphaned reply to be reported, got %q", out)
+	}
+
+	if out, err := runCLI(t, service, "thread", "purge"); !errors.Is(err, cli.ErrUsage) || !strings.Contains(out, "unknown command") {
+		t.Errorf("Expected an unknown command to print the usage, got %q (%v)", out, err)
+	}
+}
+
+// TestCheckCommand needs no Service, main checks the subcommand before
+// opening the database
+func TestCheckCommand(t *testing.T) {
+	var out bytes.Buffer
+	if err := cli.Check([]string{"thread", "purge"}, &out); !errors.Is(err, cli.ErrUsage) || !strings.Contains(out.String(), "unknown command") {
+		t.Errorf("Expected an unknown command to print the usage, got %q (%v)", out.String(), err)
+	}
+	out.Reset()
+	if err := cli.Check([]string{"user", "ban", "--lift", "alice"}, &out); err != nil || out.Len() != 0 {
+		t.Errorf("Expected user ban to be accepted quietly, got %q (%v)", out.String(), err)
+	}
+}
+
+func TestBannedUserSignIn(t *testing.T) {
+	dm := openTestDB(t)
+	if _, err := utils.LoadTemplates(forum.Templates(), false); err != nil {
+		t.Fatal(err)
+	}
+	s := internal.NewService(dm)
+
+	alice := models.User{Name: "alice", Email: "alice@example.com", Password: "Pass123!"}
+	if err := dm.CreateUser(&alice); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	session, _ := s.Sessions.CreateSession(&alice)
+	dm.SetUserStatus(alice.Id, models.StatusBanned)
+
+	var signedIn *models.User
+	chain := routes.Chain(routes.WithService(s), routes.WithAuthentication())
+	request := httptest.NewRequest("GET", "/", nil)
+	request.AddCookie(&http.Cookie{Name: utils.SessionCookieName(), Value: session.CookieString})
+	chain(func(w http.ResponseWriter, r *http.Request) { signedIn = routes.GetCurrentUser(r) })(httptest.NewRecorder(), request)
+	if signedIn != nil {
+		t.Error("Expected the session of a banned user to be ignored")
+	}
+
+	form := url.Values{"email": {"alice@example.com"}, "password": {"Pass123!"}}
+	request = httptest.NewRequest("POST", "/authenticate", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	routes.Chain(routes.WithService(s))(routes.Authenticate)(recorder, request)
+	if len(recorder.Result().Cookies()) != 0 || !strings.Contains(recorder.Body.String(), "banned") {
+		t.Errorf("Expected a banned user to be refused, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,139 @@
+package test
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"forum/config"
+	"forum/routes"
+	"forum/utils"
+)
+
+func loadConfig(args []string, env map[string]string) (config.Configuration, error) {
+	fs := flag.NewFlagSet("forum", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return config.Load(fs, args, func(key string) string { return env[key] })
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestConfigLayers checks that the file overrides the defaults,
+// the environment the file and the flags the environment
+func TestConfigLayers(t *testing.T) {
+	file := writeConfigFile(t, `{"ReadTimeout": 20, "WriteTimeout": 30, "BaseURL": "https://forum.example", "Log": {"Level": "warn"}}`)
+	env := map[string]string{
+		"FORUM_CONFIG":            file,
+		"FORUM_WRITE_TIMEOUT":     "40",
+		"FORUM_LOG_FORMAT":        "json",
+		"FORUM_SESSION_HTTP_ONLY": "false",
+	}
+	c, err := loadConfig([]string{"--log-format", "text", "--session-lifetime=2", "--dev-templates"}, env)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if c.Address != config.Defaults().Address || c.Session.Cookie != "_cookie" {
+		t.Errorf("Expected defaults for unset values, got %q and %q", c.Address, c.Session.Cookie)
+	}
+	if c.ReadTimeout != 20 || c.BaseURL != "https://forum.example" || c.Log.Level != "warn" {
+		t.Errorf("Expected the file values, got %d, %q and %q", c.ReadTimeout, c.BaseURL, c.Log.Level)
+	}
+	if c.WriteTimeout != 40 || c.Session.HttpOnly {
+		t.Errorf("Expected the environment over the file, got %d and %v", c.WriteTimeout, c.Session.HttpOnly)
+	}
+	if c.Log.Format != "text" || c.Session.Lifetime != 2 || !c.DevTemplates {
+		t.Errorf("Expected the flags over the environment, got %q, %d and %v", c.Log.Format, c.Session.Lifetime, c.DevTemplates)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	_, err := loadConfig([]string{"--config", writeConfigFile(t, "{}"), "--address", "nowhere", "--log-level", "loud",
+		"--session-same-site", "none", "--limits-max-body-bytes", "0"}, nil)
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{"Address", "Log.Level", "Session.SameSite", "Limits.MaxBodyBytes"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected an error about %s, got %v", want, err)
+		}
+	}
+
+	if _, err := loadConfig(nil, map[string]string{"FORUM_READ_TIMEOUT": "soon"}); err == nil || !strings.Contains(err.Error(), "FORUM_READ_TIMEOUT") {
+		t.Errorf("Expected an error naming the variable, got %v", err)
+	}
+	if _, err := loadConfig([]string{"--no-such-flag"}, nil); err == nil {
+		t.Error("Expected an unknown flag to fail")
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	if _, err := loadConfig([]string{"--config", filepath.Join(t.TempDir(), "missing.json")}, nil); err == nil {
+		t.Error("Expected a missing --config file to fail")
+	}
+	if _, err := loadConfig([]string{"--config", writeConfigFile(t, `{"Adress": "0.0.0.0:80"}`)}, nil); err == nil {
+		t.Error("Expected an unknown key to fail")
+	}
+
+	c, err := loadConfig([]string{"--config", writeConfigFile(t, `{"Database": "postgres://forum:hunter2@db/forum", "Metrics": {"Token": "abc"}}`)}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	printed := c.Redacted()
+	if strings.Contains(printed.Database, "hunter2") || printed.Metrics.Token != "REDACTED" || printed.SecretKey != "" {
+		t.Errorf("Expected secrets hidden, got %q, %q and %q", printed.Database, printed.Metrics.Token, printed.SecretKey)
+	}
+	if c.Metrics.Token != "abc" {
+		t.Error("Expected Redacted to leave the configuration alone")
+	}
+}
+
+// TestSessionLifetime checks that a session older than the configured
+// lifetime no longer signs the user in
+func TestSessionLifetime(t *testing.T) {
+	s := newMemoryService(t)
+	alice := mustCreateUser(t, s, "alice")
+	session, _ := s.Sessions.CreateSession(&alice)
+
+	signedIn := func() bool {
+		var user bool
+		handler := routes.Chain(routes.WithService(s), routes.WithAuthentication())(func(w http.ResponseWriter, r *http.Request) {
+			user = routes.GetCurrentUser(r) != nil
+		})
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.AddCookie(&http.Cookie{Name: utils.SessionCookieName(), Value: session.CookieString})
+		handler(httptest.NewRecorder(), request)
+		return user
+	}
+
+	if !signedIn() {
+		t.Fatal("Expected a fresh session to sign in")
+	}
+	utils.SetCookieSettings(utils.CookieSettings{Lifetime: time.Nanosecond, HttpOnly: true})
+	defer utils.SetCookieSettings(utils.CookieSettings{Lifetime: 24 * time.Hour, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+	time.Sleep(time.Millisecond)
+	if signedIn() {
+		t.Error("Expected an expired session to be refused")
+	}
+	if n, _ := s.Sessions.CountSessions(); n != 0 {
+		t.Errorf("Expected the expired session removed, %d left", n)
+	}
+
+	cookie := utils.SessionCookie("value")
+	if !cookie.HttpOnly || cookie.Name != "_cookie" {
+		t.Errorf("Unexpected session cookie %+v", cookie)
+	}
+}
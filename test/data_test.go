@@ -91,7 +91,7 @@ func TestUpdateUser(t *testing.T) {
 		t.Fatalf("Failed to open DB: %v", err)
 	}
 	defer dm.Close()
-	internal.InitUserDM(dm)
+	service := internal.NewService(dm)
 
 	// Create a user to update
 	user := models.User{
@@ -116,7 +116,7 @@ func TestUpdateUser(t *testing.T) {
 	newName := "ok"
 	userTo, err := dm.GetUserByEmail("updateuser2@example.com")
 
-	err = internal.TryUpdate(newName, userTo.Id)
+	err = service.TryUpdate(newName, userTo.Id)
 	if err != nil {
 		log.SetPrefix("[WARN] ")
 		Warn("Failed to update user:", err)
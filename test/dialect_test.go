@@ -0,0 +1,57 @@
+package test
+
+import (
+	"testing"
+
+	"forum/internal/data"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{"SELECT id FROM users WHERE name=? AND id != ?", "SELECT id FROM users WHERE name=$1 AND id != $2"},
+		{"SELECT 1 WHERE ?2 = '' OR t.topic LIKE '%' || ?2 || '%'", "SELECT 1 WHERE $2 = '' OR t.topic ILIKE '%' || $2 || '%'"},
+		{"UPDATE t SET note='who?' WHERE id=?", "UPDATE t SET note='who?' WHERE id=$1"},
+		{"INSERT OR IGNORE INTO tags(name) VALUES(?)", "INSERT INTO tags(name) VALUES($1) ON CONFLICT DO NOTHING"},
+		{"SELECT group_concat(u.name, ', ') FROM users u", "SELECT string_agg(u.name, ', ') FROM users u"},
+	}
+	for _, tt := range tests {
+		if got := data.Postgres.Rebind(tt.query); got != tt.want {
+			t.Errorf("Rebind(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+		if got := data.SQLite.Rebind(tt.query); got != tt.query {
+			t.Errorf("SQLite should keep %q, got %q", tt.query, got)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	stmt := `CREATE TABLE posts (
+	  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	  thread_id  integer references threads(id),
+	  created_at timestamp not null
+	);`
+	want := `CREATE TABLE posts (
+	  id         SERIAL PRIMARY KEY,
+	  thread_id  integer,
+	  created_at timestamptz not null
+	);`
+	if got := data.Postgres.Schema(stmt); got != want {
+		t.Errorf("Unexpected schema:\n%s", got)
+	}
+	// the references are still checked on Postgres, from the SQLite definition
+	refs := data.References(stmt, "DROP TABLE IF EXISTS posts;")
+	if len(refs) != 1 || refs[0] != (data.Reference{Table: "posts", Column: "thread_id", Parent: "threads", ParentColumn: "id"}) {
+		t.Errorf("Unexpected references %+v", refs)
+	}
+	if got := data.Postgres.Schema("boolean not null default 0"); got != "boolean not null default false" {
+		t.Errorf("Unexpected column definition %q", got)
+	}
+	if got := data.Postgres.Schema("DROP TABLE IF EXISTS users;"); got != "DROP TABLE IF EXISTS users CASCADE;" {
+		t.Errorf("Unexpected drop %q", got)
+	}
+	if data.DialectOf("postgres://forum@localhost/forum") != data.Postgres || data.DialectOf("pkg/mydb.db") != data.SQLite {
+		t.Error("Unexpected dialect for DSN")
+	}
+}
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"forum/internal"
+	"forum/models"
+)
+
+func TestDrafts(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	user := models.User{Name: "writer", Email: "writer@example.com", Password: "Pass123!"}
+	if err := dm.CreateUser(&user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	threadID, err := dm.CreateThreadByUser("Topic", "Body", user.Id, "Other", "")
+	if err != nil {
+		t.Fatalf("Failed to create thread: %v", err)
+	}
+
+	if _, err := service.Draft(user.Id, 0); !errors.Is(err, internal.ErrNoDraft) {
+		t.Fatalf("Expected no draft, got %v", err)
+	}
+
+	_, err = service.SaveDraft(user.Id, models.Draft{Topic: "Idea", Body: "first", Category1: "Games"})
+	if err != nil {
+		t.Fatalf("Failed to save draft: %v", err)
+	}
+	// saving again replaces the draft
+	if _, err := service.SaveDraft(user.Id, models.Draft{Topic: "Idea", Body: "second"}); err != nil {
+		t.Fatalf("Failed to update draft: %v", err)
+	}
+	draft, err := service.Draft(user.Id, 0)
+	if err != nil || draft.Body != "second" {
+		t.Errorf("Expected updated draft, got %+v (%v)", draft, err)
+	}
+
+	reply, err := service.SaveDraft(user.Id, models.Draft{ThreadId: int(threadID), Topic: "ignored", Body: "reply"})
+	if err != nil {
+		t.Fatalf("Failed to save reply draft: %v", err)
+	}
+	if reply.Topic != "" {
+		t.Errorf("Expected reply draft without topic, got %q", reply.Topic)
+	}
+	if _, err := service.SaveDraft(user.Id, models.Draft{ThreadId: 9999, Body: "x"}); err == nil {
+		t.Error("Expected draft for a missing thread to be refused")
+	}
+
+	drafts, _ := service.UserDrafts(user.Id)
+	if len(drafts) != 2 {
+		t.Fatalf("Expected 2 drafts, got %d", len(drafts))
+	}
+	if drafts[0].Target != "Topic" {
+		t.Errorf("Expected most recent draft to be the reply to %q, got %q", "Topic", drafts[0].Target)
+	}
+
+	// an emptied composer removes the draft
+	if _, err := service.SaveDraft(user.Id, models.Draft{Body: "  "}); err != nil {
+		t.Fatalf("Failed to clear draft: %v", err)
+	}
+	if _, err := service.Draft(user.Id, 0); !errors.Is(err, internal.ErrNoDraft) {
+		t.Errorf("Expected cleared draft, got %v", err)
+	}
+
+	service.DiscardDraft(user.Id, int(threadID))
+	if drafts, _ := service.UserDrafts(user.Id); len(drafts) != 0 {
+		t.Errorf("Expected no drafts after posting, got %d", len(drafts))
+	}
+}
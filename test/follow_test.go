@@ -0,0 +1,79 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"forum/internal"
+	"forum/models"
+)
+
+func TestFeed(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	var users []models.User
+	for _, name := range []string{"reader", "followed", "stranger"} {
+		user := models.User{Name: name, Email: name + "@example.com", Password: "Pass123!"}
+		if err := dm.CreateUser(&user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		users = append(users, user)
+	}
+	reader, followed, stranger := users[0], users[1], users[2]
+
+	for i := 0; i < internal.FeedPageSize; i++ {
+		if _, err := dm.CreateThreadByUser(fmt.Sprintf("Followed %d", i), "Body", followed.Id, "Other", ""); err != nil {
+			t.Fatalf("Failed to create thread: %v", err)
+		}
+	}
+	if _, err := dm.CreateThreadByUser("Stranger games", "Body", stranger.Id, "Games", ""); err != nil {
+		t.Fatalf("Failed to create thread: %v", err)
+	}
+	if _, err := dm.CreateThreadByUser("Stranger sports", "Body", stranger.Id, "Sports", ""); err != nil {
+		t.Fatalf("Failed to create thread: %v", err)
+	}
+	if _, err := dm.CreateThreadByUser("Own games", "Body", reader.Id, "Games", ""); err != nil {
+		t.Fatalf("Failed to create thread: %v", err)
+	}
+
+	threads, _, err := service.Feed(reader.Id, 1)
+	if err != nil || len(threads) != 0 {
+		t.Fatalf("Expected an empty feed, got %d threads (%v)", len(threads), err)
+	}
+
+	if err := service.FollowUser(reader.Id, followed.Id); err != nil {
+		t.Fatalf("Failed to follow user: %v", err)
+	}
+	if err := service.FollowCategory(reader.Id, "Games"); err != nil {
+		t.Fatalf("Failed to follow category: %v", err)
+	}
+	if err := service.FollowCategory(reader.Id, "Nope"); err == nil {
+		t.Error("Expected unknown category to be rejected")
+	}
+
+	first, hasNext, err := service.Feed(reader.Id, 1)
+	if err != nil || len(first) != internal.FeedPageSize || !hasNext {
+		t.Fatalf("Expected a full first page with a next page, got %d threads, next=%v (%v)", len(first), hasNext, err)
+	}
+	if first[0].Topic != "Stranger games" {
+		t.Errorf("Expected newest thread first, got %q", first[0].Topic)
+	}
+	second, hasNext, err := service.Feed(reader.Id, 2)
+	if err != nil || len(second) != 1 || hasNext {
+		t.Fatalf("Expected 1 thread on the last page, got %d, next=%v (%v)", len(second), hasNext, err)
+	}
+	for _, thread := range append(first, second...) {
+		if thread.Topic == "Stranger sports" || thread.Topic == "Own games" {
+			t.Errorf("Unexpected thread in feed: %q", thread.Topic)
+		}
+	}
+
+	if err := service.BlockUser(reader.Id, stranger.Id); err != nil {
+		t.Fatalf("Failed to block: %v", err)
+	}
+	first, _, _ = service.Feed(reader.Id, 1)
+	if first[0].Topic == "Stranger games" {
+		t.Error("Expected threads of blocked users to be left out of the feed")
+	}
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"forum"
+	"forum/internal"
+	"forum/routes"
+	"forum/utils"
+)
+
+func readyz(t *testing.T, health *internal.Health) (int, map[string]string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	routes.Readyz(health)(rec, httptest.NewRequest("GET", "/readyz", nil))
+	var body struct {
+		Status string
+		Checks map[string]string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected JSON, got %q", rec.Body.String())
+	}
+	return rec.Code, body.Checks
+}
+
+func TestReadiness(t *testing.T) {
+	dm := openTestDB(t)
+	if _, err := utils.LoadTemplates(forum.Templates(), false); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	health := internal.NewHealth(dm, dir)
+	if code, checks := readyz(t, health); code != http.StatusOK || checks["migrations"] != "ok" || checks["writable:"+dir] != "ok" {
+		t.Errorf("Expected ready, got %d %v", code, checks)
+	}
+
+	missing := filepath.Join(dir, "missing")
+	if code, checks := readyz(t, internal.NewHealth(dm, missing)); code != http.StatusServiceUnavailable || checks["writable:"+missing] == "ok" {
+		t.Errorf("Expected a missing directory to fail, got %d %v", code, checks)
+	}
+
+	if _, err := dm.DoExec("DROP TABLE tags"); err != nil {
+		t.Fatal(err)
+	}
+	if code, checks := readyz(t, health); code != http.StatusServiceUnavailable || checks["migrations"] != "table tags is missing" {
+		t.Errorf("Expected missing migrations to fail, got %d %v", code, checks)
+	}
+
+	health = internal.NewHealth(dm)
+	health.Drain()
+	if code, checks := readyz(t, health); code != http.StatusServiceUnavailable || checks["shutdown"] == "" {
+		t.Errorf("Expected readiness to fail while draining, got %d %v", code, checks)
+	}
+
+	rec := httptest.NewRecorder()
+	routes.Healthz(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected /healthz to be ok, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,97 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"forum/routes"
+	"forum/utils"
+)
+
+func TestLoggerRedactsAndAddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := utils.NewLogger(&buf, "json", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := utils.WithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "login", "user_id", 7, "password", "hunter2", "session_cookie", "abc")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q", buf.String())
+	}
+	if record["request_id"] != "req-1" {
+		t.Errorf("Expected the request id from the context, got %v", record["request_id"])
+	}
+	if record["password"] != "[REDACTED]" || record["session_cookie"] != "[REDACTED]" {
+		t.Errorf("Expected secrets to be redacted, got %v", record)
+	}
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Error("Secret leaked into the log")
+	}
+
+	if _, err := utils.NewLogger(&buf, "xml", ""); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
+
+func TestWithLoggingLogsStatus(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := utils.NewLogger(&buf, "json", "info")
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(previous)
+
+	var seenID string
+	handler := routes.WithLogging()(func(w http.ResponseWriter, r *http.Request) {
+		seenID = utils.RequestID(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/brew", nil))
+
+	if seenID == "" || rec.Header().Get("X-Request-ID") != seenID {
+		t.Errorf("Expected the request id in context and header, got %q and %q", seenID, rec.Header().Get("X-Request-ID"))
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q", buf.String())
+	}
+	if record["status"] != float64(http.StatusTeapot) || record["path"] != "/brew" || record["request_id"] != seenID {
+		t.Errorf("Unexpected request record %v", record)
+	}
+	if _, ok := record["duration"]; !ok {
+		t.Error("Expected the latency to be logged")
+	}
+}
+
+func TestWithLoggingHidesFeedToken(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := utils.NewLogger(&buf, "json", "info")
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(previous)
+
+	handler := routes.WithLogging()(func(w http.ResponseWriter, r *http.Request) {})
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/feeds/notifications/s3cret.atom", nil))
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q", buf.String())
+	}
+	if record["path"] != "/feeds/notifications/{token}.atom" {
+		t.Errorf("Expected the token to be replaced, got %v", record["path"])
+	}
+	if strings.Contains(buf.String(), "s3cret") {
+		t.Error("Feed token leaked into the log")
+	}
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"forum/internal"
+	"forum/models"
+)
+
+func TestMentionedNames(t *testing.T) {
+	cases := []struct {
+		body string
+		want []string
+	}{
+		{"hey @alice", []string{"alice"}},
+		{"@alice and @bob, then @alice again", []string{"alice", "bob"}},
+		{"mail me at someone@example.com", nil},
+		{"too short @ab", nil},
+		{"(@carol)", []string{"carol"}},
+	}
+	for _, c := range cases {
+		got := internal.MentionedNames(c.body)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("MentionedNames(%q) = %v, want %v", c.body, got, c.want)
+		}
+	}
+}
+
+func TestRecordMentions(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+	author := mustCreateUser(t, service, "author")
+	alice := mustCreateUser(t, service, "alice")
+	blocker := mustCreateUser(t, service, "blocker")
+	banned := mustCreateUser(t, service, "banned")
+	threadID := mustCreateThread(t, service, author.Id, "Topic", "Games")
+
+	service.BlockUser(blocker.Id, author.Id)
+	dm.SetUserStatus(banned.Id, models.StatusBanned)
+	service.RecordMentions(author.Id, threadID, 0, "@author @alice @nobody @blocker @banned")
+
+	if mentions, _ := service.UserMentions(alice.Id); len(mentions) != 1 || mentions[0].Author != "author" || mentions[0].ThreadId != threadID {
+		t.Errorf("Expected alice to be mentioned once, got %+v", mentions)
+	}
+	for _, user := range []models.User{author, blocker, banned} {
+		if mentions, _ := service.UserMentions(user.Id); len(mentions) != 0 {
+			t.Errorf("Expected no mention of %s, got %+v", user.Name, mentions)
+		}
+	}
+}
+
+func TestLookupUsers(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+	viewer := mustCreateUser(t, service, "user00")
+	for i := 1; i <= 12; i++ {
+		mustCreateUser(t, service, fmt.Sprintf("user%02d", i))
+	}
+	banned := mustCreateUser(t, service, "userbanned")
+	dm.SetUserStatus(banned.Id, models.StatusBanned)
+
+	found, err := service.LookupUsers("user", viewer.Id)
+	if err != nil || len(found) != 10 {
+		t.Fatalf("Expected the lookup to stop at 10 users, got %+v (%v)", found, err)
+	}
+	for _, user := range found {
+		if user.Id == viewer.Id || user.Id == banned.Id {
+			t.Errorf("Expected the viewer and inactive accounts to be left out, got %+v", user)
+		}
+	}
+	if found, _ := service.LookupUsers("userb", viewer.Id); len(found) != 0 {
+		t.Errorf("Expected the banned user not to be found, got %+v", found)
+	}
+	if found, _ := service.LookupUsers("us%", viewer.Id); len(found) != 0 {
+		t.Errorf("Expected an invalid prefix to find nobody, got %+v", found)
+	}
+}
+
+func TestRenderMentions(t *testing.T) {
+	service := internal.NewService(openTestDB(t))
+	mustCreateUser(t, service, "alice")
+
+	got := service.RenderMentions("hi @alice and @nobody")
+	want := `hi <a class="mention" href="/u/alice">@alice</a> and @nobody`
+	if got != want {
+		t.Errorf("RenderMentions = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"forum/internal"
+	"forum/models"
+	"forum/routes"
+	"forum/utils"
+)
+
+func TestConversationMembership(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	var users []models.User
+	for _, name := range []string{"sender", "receiver", "outsider"} {
+		user := models.User{Name: name, Email: name + "@example.com", Password: "Pass123!"}
+		if err := dm.CreateUser(&user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		users = append(users, user)
+	}
+	sender, receiver, outsider := users[0], users[1], users[2]
+
+	if _, err := service.StartConversation(sender, "sender", "Hi", "Hello"); err == nil {
+		t.Error("Expected an error for a conversation with only yourself")
+	}
+
+	id, err := service.StartConversation(sender, "@receiver, receiver", "Hi", "Hello")
+	if err != nil {
+		t.Fatalf("Failed to start conversation: %v", err)
+	}
+	conversationID := int(id)
+
+	if got := service.UnreadMessagesCount(receiver.Id); got != 1 {
+		t.Errorf("Expected 1 unread message, got %d", got)
+	}
+	if got := service.UnreadMessagesCount(sender.Id); got != 0 {
+		t.Errorf("Expected the sender to have no unread messages, got %d", got)
+	}
+
+	if _, _, err := service.OpenConversation(conversationID, outsider.Id); !errors.Is(err, internal.ErrNotConversationMember) {
+		t.Errorf("Expected outsider to be refused, got %v", err)
+	}
+	if err := service.ReplyToConversation(conversationID, outsider.Id, "let me in"); !errors.Is(err, internal.ErrNotConversationMember) {
+		t.Errorf("Expected outsider reply to be refused, got %v", err)
+	}
+
+	_, messages, err := service.OpenConversation(conversationID, receiver.Id)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d (%v)", len(messages), err)
+	}
+	if got := service.UnreadMessagesCount(receiver.Id); got != 0 {
+		t.Errorf("Expected messages to be read after opening, got %d", got)
+	}
+
+	if _, err := service.ReportMessage(messages[0].Id, outsider.Id, "spam"); err == nil {
+		t.Error("Expected outsider report to be refused")
+	}
+	if _, err := service.ReportMessage(messages[0].Id, receiver.Id, "spam"); err != nil {
+		t.Fatalf("Failed to report message: %v", err)
+	}
+
+	reports, err := service.OpenMessageReports()
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("Expected 1 open report, got %d (%v)", len(reports), err)
+	}
+	if _, err := service.ReviewMessageReport(reports[0].Id, receiver); err == nil {
+		t.Error("Expected members to be refused report review")
+	}
+	moderator := outsider
+	moderator.Role = models.RoleModerator
+	report, err := service.ReviewMessageReport(reports[0].Id, moderator)
+	if err != nil || report.Message.Body != "Hello" {
+		t.Fatalf("Expected reported message body, got %q (%v)", report.Message.Body, err)
+	}
+	audit, err := service.AuditLog(10)
+	if err != nil || len(audit) != 1 || audit[0].ActorId != moderator.Id {
+		t.Errorf("Expected one audit entry by the moderator, got %+v (%v)", audit, err)
+	}
+
+	if err := service.ResolveMessageReport(reports[0].Id+100, moderator); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected an unknown report to be not found, got %v", err)
+	}
+	if audit, _ := service.AuditLog(10); len(audit) != 1 {
+		t.Errorf("Expected no audit entry for an unknown report, got %d entries", len(audit))
+	}
+	if err := service.ResolveMessageReport(reports[0].Id, moderator); err != nil {
+		t.Fatalf("Failed to resolve report: %v", err)
+	}
+	if audit, _ := service.AuditLog(10); len(audit) != 2 || audit[0].Action != "resolve_message_report" {
+		t.Errorf("Expected the resolution to be audited, got %+v", audit)
+	}
+	if reports, _ := service.OpenMessageReports(); len(reports) != 0 {
+		t.Errorf("Expected no open report left, got %d", len(reports))
+	}
+
+	if err := service.LeaveConversation(conversationID, receiver.Id); err != nil {
+		t.Fatalf("Failed to leave: %v", err)
+	}
+	if _, _, err := service.OpenConversation(conversationID, receiver.Id); err == nil {
+		t.Error("Expected conversation to be closed after leaving")
+	}
+}
+
+func TestResolveReportRoute(t *testing.T) {
+	dm := openTestDB(t)
+	s := internal.NewService(dm)
+	moderator := mustCreateUser(t, s, "moderator")
+	dm.SetUserRole(moderator.Id, models.RoleModerator)
+	session, _ := s.Sessions.CreateSession(&moderator)
+
+	resolve := func(id string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("POST", "/moderation/resolve", strings.NewReader(url.Values{"id": {id}}.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.AddCookie(&http.Cookie{Name: utils.SessionCookieName(), Value: session.CookieString})
+		recorder := httptest.NewRecorder()
+		routes.Chain(routes.WithService(s), routes.WithAuthentication())(routes.ResolveReport)(recorder, request)
+		return recorder
+	}
+	if code := resolve("42").Code; code != http.StatusNotFound {
+		t.Errorf("Expected an unknown report to be not found, got %d", code)
+	}
+	if audit, _ := s.AuditLog(10); len(audit) != 0 {
+		t.Errorf("Expected nothing audited, got %+v", audit)
+	}
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"forum/internal/metrics"
+	"forum/routes"
+)
+
+func TestMetricsFormat(t *testing.T) {
+	counter := metrics.NewCounter("test_events_total", "Events.", "kind")
+	counter.Inc("a")
+	counter.Add(2, `quote"d`)
+	histogram := metrics.NewHistogram("test_wait_seconds", "Waits.", []float64{0.1, 1})
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(3)
+
+	var buf bytes.Buffer
+	metrics.Default.Write(&buf)
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE test_events_total counter\n",
+		`test_events_total{kind="a"} 1` + "\n",
+		`test_events_total{kind="quote\"d"} 2` + "\n",
+		"# TYPE test_wait_seconds histogram\n",
+		`test_wait_seconds_bucket{le="0.1"} 1` + "\n",
+		`test_wait_seconds_bucket{le="1"} 2` + "\n",
+		`test_wait_seconds_bucket{le="+Inf"} 3` + "\n",
+		"test_wait_seconds_sum 3.55\n",
+		"test_wait_seconds_count 3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestMetricsInstrumentation(t *testing.T) {
+	service := newSQLiteService(t)
+	queries, execs := metrics.DBQueries.Value("query"), metrics.DBQueries.Value("exec")
+	threads := metrics.ThreadsCreated.Value()
+
+	alice := mustCreateUser(t, service, "alice")
+	service.Sessions.CreateSession(&alice)
+	service.Users.GetUserByEmailDetailed("alice@example.com")
+	if metrics.DBQueries.Value("query") <= queries || metrics.DBQueries.Value("exec") <= execs {
+		t.Error("Expected database statements to be counted")
+	}
+
+	before := metrics.VotesCast.Value("thread", "like")
+	threadID := mustCreateThread(t, service, alice.Id, "Counted", "General")
+	if err := service.ToggleThreadVote(alice.Id, threadID, true); err != nil {
+		t.Fatal(err)
+	}
+	if metrics.VotesCast.Value("thread", "like") != before+1 {
+		t.Error("Expected the vote to be counted")
+	}
+	if metrics.ThreadsCreated.Value() != threads {
+		t.Error("Expected only threads created through the forum logic to be counted")
+	}
+
+	var buf bytes.Buffer
+	metrics.Default.Write(&buf)
+	if !strings.Contains(buf.String(), "forum_sessions_active 1\n") || !strings.Contains(buf.String(), "forum_users_online 1\n") {
+		t.Errorf("Expected session gauges in\n%s", buf.String())
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	handler := routes.Chain(routes.WithMetrics())(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/things/{id}", handler)
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/things/42", nil))
+	if metrics.HTTPRequests.Value("/things/{id}", "GET", "404") != 1 {
+		t.Error("Expected the request counted under its route pattern")
+	}
+
+	endpoint := routes.Metrics("s3cret")
+	rec := httptest.NewRecorder()
+	endpoint(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the token, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	endpoint(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "forum_http_requests_total") {
+		t.Errorf("Expected the metrics with the token, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"testing"
+
+	"forum/internal"
+	"forum/models"
+)
+
+func TestProfileEmailVisibility(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	owner := models.User{Name: "owner", Email: "owner@example.com", Password: "Pass123!"}
+	other := models.User{Name: "other", Email: "other@example.com", Password: "Pass123!"}
+	for _, user := range []*models.User{&owner, &other} {
+		if err := dm.CreateUser(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	if _, err := dm.CreateThreadByUser("Topic", "Body", owner.Id, "Games", ""); err != nil {
+		t.Fatalf("Failed to create thread: %v", err)
+	}
+
+	admin := other
+	admin.Role = models.RoleAdmin
+	cases := []struct {
+		name   string
+		viewer *models.User
+		email  string
+	}{
+		{"guest", nil, ""},
+		{"member", &other, ""},
+		{"owner", &owner, owner.Email},
+		{"admin", &admin, owner.Email},
+	}
+	for _, c := range cases {
+		profile, err := service.UserProfile("owner", c.viewer)
+		if err != nil {
+			t.Fatalf("%s: failed to load profile: %v", c.name, err)
+		}
+		if profile.Email != c.email {
+			t.Errorf("%s: expected email %q, got %q", c.name, c.email, profile.Email)
+		}
+		if profile.ThreadCount != 1 || len(profile.Recent) != 1 {
+			t.Errorf("%s: expected 1 thread in counts and activity, got %d and %d", c.name, profile.ThreadCount, len(profile.Recent))
+		}
+	}
+
+	if _, err := service.UserProfile("missing", nil); err != internal.ErrProfileNotFound {
+		t.Errorf("Expected ErrProfileNotFound, got %v", err)
+	}
+	if err := service.UpdateProfile(owner.Id, "bio", "javascript:alert(1)"); err == nil {
+		t.Error("Expected non-http avatar to be rejected")
+	}
+}
@@ -0,0 +1,195 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"forum"
+	"forum/internal"
+	"forum/models"
+	"forum/routes"
+	"forum/utils"
+)
+
+func TestThreadViews(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	alice := models.User{Name: "alice", Email: "alice@example.com", Password: "Pass123!"}
+	if err := dm.CreateUser(&alice); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	id, _ := dm.CreateThreadByUser("Topic", "Body", alice.Id, "Games", "")
+	threadID := int(id)
+
+	now := time.Now()
+	service.RecordThreadView(threadID, "user:1", now)
+	service.RecordThreadView(threadID, "user:1", now.Add(time.Minute))
+	service.RecordThreadView(threadID, "192.0.2.1", now)
+	if got := service.ThreadViews(threadID); got != 2 {
+		t.Errorf("Expected 2 pending views, got %d", got)
+	}
+	if views, _ := dm.GetThreadViews(threadID); views != 0 {
+		t.Errorf("Expected views to wait for the flush, got %d written", views)
+	}
+
+	if err := service.FlushThreadViews(now.Add(time.Minute)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if views, _ := dm.GetThreadViews(threadID); views != 2 {
+		t.Errorf("Expected 2 written views, got %d", views)
+	}
+	if got := service.ThreadViews(threadID); got != 2 {
+		t.Errorf("Expected no pending views after the flush, got %d in total", got)
+	}
+
+	// a viewer counts again once the window has passed
+	service.RecordThreadView(threadID, "user:1", now.Add(10*time.Minute))
+	service.RecordThreadView(threadID, "user:1", now.Add(internal.ViewWindow+11*time.Minute))
+	service.FlushThreadViews(now.Add(internal.ViewWindow + 11*time.Minute))
+	if views, _ := dm.GetThreadViews(threadID); views != 3 {
+		t.Errorf("Expected 3 written views, got %d", views)
+	}
+}
+
+func TestReadMarkers(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	alice := models.User{Name: "alice", Email: "alice@example.com", Password: "Pass123!"}
+	bob := models.User{Name: "bob", Email: "bob@example.com", Password: "Pass123!"}
+	for _, user := range []*models.User{&alice, &bob} {
+		if err := dm.CreateUser(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	id, _ := dm.CreateThreadByUser("Topic", "Body", alice.Id, "Games", "")
+	threadID := int(id)
+	other, _ := dm.CreateThreadByUser("Other", "Body", alice.Id, "Games", "")
+	first, _ := dm.CreatePostByUser("First", bob.Id, threadID)
+
+	if err := service.MarkThreadRead(alice.Id, threadID, int(first)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, _ := dm.CreatePostByUser("Second", bob.Id, threadID)
+	dm.CreatePostByUser("Third", bob.Id, threadID)
+	dm.CreatePostByUser("Own reply", alice.Id, threadID)
+
+	threads := []models.Thread{{Id: threadID}, {Id: int(other)}}
+	service.AttachReadState(threads, alice.Id)
+	if threads[0].Unread != 2 || threads[0].FirstUnread != int(second) {
+		t.Errorf("Expected 2 unread replies from %d, got %d from %d", second, threads[0].Unread, threads[0].FirstUnread)
+	}
+	if threads[1].Unread != 0 {
+		t.Errorf("Expected a thread never opened to have no badge, got %d", threads[1].Unread)
+	}
+
+	// markers never move back
+	service.MarkThreadRead(alice.Id, threadID, 0)
+	if last, _ := dm.GetLastReadPost(alice.Id, threadID); last != int(first) {
+		t.Errorf("Expected the marker to stay at %d, got %d", first, last)
+	}
+
+	if err := service.MarkAllThreadsRead(alice.Id); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	service.AttachReadState(threads, alice.Id)
+	if threads[0].Unread != 0 || threads[1].Unread != 0 {
+		t.Errorf("Expected everything read, got %d and %d", threads[0].Unread, threads[1].Unread)
+	}
+	if _, err := dm.GetLastReadPost(alice.Id, int(other)); err != nil {
+		t.Errorf("Expected a marker on the thread without replies: %v", err)
+	}
+	if _, err := dm.GetLastReadPost(bob.Id, threadID); err == nil {
+		t.Error("Expected marking all as read to leave other users alone")
+	}
+}
+
+func TestReadRoutes(t *testing.T) {
+	dm := openTestDB(t)
+	if _, err := utils.LoadTemplates(forum.Templates(), false); err != nil {
+		t.Fatal(err)
+	}
+	s := internal.NewService(dm)
+
+	alice := models.User{Name: "alice", Email: "alice@example.com", Password: "Pass123!"}
+	bob := models.User{Name: "bob", Email: "bob@example.com", Password: "Pass123!"}
+	for _, user := range []*models.User{&alice, &bob} {
+		if err := dm.CreateUser(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	id, _ := dm.CreateThreadByUser("Topic", "Body", alice.Id, "Games", "")
+	threadID := int(id)
+	dm.CreatePostByUser("Reply", bob.Id, threadID)
+	session, _ := s.Sessions.CreateSession(&alice)
+
+	serve := func(handler http.HandlerFunc, method, target string) *httptest.ResponseRecorder {
+		chain := routes.Chain(routes.WithService(s), routes.WithAuthentication())
+		request := httptest.NewRequest(method, target, nil)
+		request.AddCookie(&http.Cookie{Name: utils.SessionCookieName(), Value: session.CookieString})
+		recorder := httptest.NewRecorder()
+		chain(handler)(recorder, request)
+		return recorder
+	}
+
+	rec := serve(routes.ShowThread, "GET", utils.ThreadPath(threadID, "Topic"))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "1 views") {
+		t.Errorf("Expected the thread with its view, got %d", rec.Code)
+	}
+	reply, _ := dm.CreatePostByUser("Unread", bob.Id, threadID)
+
+	rec = serve(routes.Index, "GET", "/")
+	want := utils.PostPath(threadID, "Topic", int(reply))
+	if !strings.Contains(rec.Body.String(), "1 new") || !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("Expected an unread badge linking %s", want)
+	}
+
+	rec = serve(routes.MarkAllRead, "POST", "/threads/read-all")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/" {
+		t.Errorf("Expected a redirect to the index, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec = serve(routes.Index, "GET", "/"); strings.Contains(rec.Body.String(), "1 new") {
+		t.Error("Expected no unread badge after marking all as read")
+	}
+	if code := serve(routes.MarkAllRead, "GET", "/threads/read-all").Code; code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected GET to be refused, got %d", code)
+	}
+}
+
+// TestGuestViewsBehindOneAddress counts guests sharing an address apart,
+// each by its visitor cookie
+func TestGuestViewsBehindOneAddress(t *testing.T) {
+	dm := openTestDB(t)
+	if _, err := utils.LoadTemplates(forum.Templates(), false); err != nil {
+		t.Fatal(err)
+	}
+	s := internal.NewService(dm)
+	alice := mustCreateUser(t, s, "alice")
+	threadID := mustCreateThread(t, s, alice.Id, "Topic", "Games")
+
+	visit := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", utils.ThreadPath(threadID, "Topic"), nil)
+		request.RemoteAddr = "192.0.2.1:1234"
+		if cookie != nil {
+			request.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		routes.Chain(routes.WithService(s), routes.WithAuthentication())(routes.ShowThread)(recorder, request)
+		return recorder
+	}
+
+	first := visit(nil)
+	cookies := first.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != utils.VisitorCookieName || cookies[0].Value == "" {
+		t.Fatalf("Expected a visitor cookie, got %v", cookies)
+	}
+	visit(cookies[0])
+	visit(nil)
+	if views := s.PendingThreadViews(threadID); views != 2 {
+		t.Errorf("Expected two guests and a revisit to count 2 views, got %d", views)
+	}
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"forum/internal"
+	"forum/models"
+	"forum/routes"
+	"forum/utils"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Hello World":               "hello-world",
+		"  Go  &amp; SQLite!! ":     "go-sqlite",
+		"Tom &amp; &lt;Jerry&gt;":   "tom-jerry",
+		"???":                       "thread",
+		"":                          "thread",
+		"version 1.24 -- released":  "version-1-24-released",
+		strings.Repeat("word ", 40): strings.TrimSuffix(strings.Repeat("word-", 12), "-"),
+	}
+	for topic, want := range cases {
+		if got := utils.Slugify(topic); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", topic, got, want)
+		}
+	}
+
+	if got := utils.ThreadPath(7, "Hello World"); got != "/t/7-hello-world" {
+		t.Errorf("Unexpected thread path %q", got)
+	}
+	if got := utils.PostPath(7, "Hello World", 3); got != "/t/7-hello-world#post-3" {
+		t.Errorf("Unexpected post path %q", got)
+	}
+}
+
+func TestSitemap(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	author := models.User{Name: "mapper", Email: "mapper@example.com", Password: "Pass123!"}
+	if err := dm.CreateUser(&author); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	threadID, _ := dm.CreateThreadByUser("Sitemap Topic", "body", author.Id, "Movies", "")
+	service.SetThreadTags(int(threadID), []string{"maps"})
+
+	body, err := service.Sitemap()
+	if err != nil {
+		t.Fatalf("Failed to build sitemap: %v", err)
+	}
+	for _, loc := range []string{
+		utils.BaseURL() + utils.ThreadPath(int(threadID), "Sitemap Topic"),
+		utils.BaseURL() + "/tag/maps",
+		utils.BaseURL() + "/u/mapper",
+	} {
+		if !strings.Contains(string(body), "<loc>"+loc+"</loc>") {
+			t.Errorf("Expected %s in sitemap", loc)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	routes.Robots(recorder, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+	robots := recorder.Body.String()
+	if !strings.Contains(robots, "Disallow: /messages") || !strings.Contains(robots, "Sitemap: "+utils.BaseURL()+"/sitemap.xml") {
+		t.Errorf("Unexpected robots.txt:\n%s", robots)
+	}
+}
@@ -0,0 +1,545 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"forum"
+	"forum/internal"
+	"forum/internal/data"
+	"forum/internal/data/memory"
+	"forum/models"
+	"forum/routes"
+	"forum/utils"
+)
+
+// newMemoryService returns a Service keeping the forum content in memory.
+// Feeds, Stats, Accounts, Maintenance and Backups work on the database
+// itself and stay unset.
+func newMemoryService(t *testing.T) *internal.Service {
+	store := memory.New()
+	return &internal.Service{
+		Threads: store, Posts: store, Users: store, Sessions: store, Votes: store, Audit: store, Reads: store,
+
+		Watches: store, Blocks: store, Mentions: store, Messages: store, Profiles: store,
+		Follows: store, Bookmarks: store, Drafts: store, Tags: store,
+	}
+}
+
+// newSQLiteService also registers the gauges of its Service for /metrics
+func newSQLiteService(t *testing.T) *internal.Service {
+	return internal.InitAllDatabaseManagers(openTestDB(t))
+}
+
+// newPostgresService migrates the database at FORUM_TEST_POSTGRES_DSN from
+// scratch, the tables are dropped and created again for every subtest
+func newPostgresService(t *testing.T) *internal.Service {
+	t.Helper()
+	dm, err := data.Open(os.Getenv("FORUM_TEST_POSTGRES_DSN"))
+	if err != nil {
+		t.Fatalf("Failed to open Postgres: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+	if err := internal.RunMigrations(dm); err != nil {
+		t.Fatalf("Failed to migrate Postgres: %v", err)
+	}
+	return internal.InitAllDatabaseManagers(dm)
+}
+
+func TestMemoryStoreConformance(t *testing.T) {
+	runStoreConformance(t, newMemoryService)
+}
+
+func TestSQLiteStoreConformance(t *testing.T) {
+	runStoreConformance(t, newSQLiteService)
+}
+
+// TestPostgresStoreConformance runs against a local Postgres,
+// start one with ./testpostgres.sh
+func TestPostgresStoreConformance(t *testing.T) {
+	if os.Getenv("FORUM_TEST_POSTGRES_DSN") == "" {
+		t.Skip("FORUM_TEST_POSTGRES_DSN is not set")
+	}
+	runStoreConformance(t, newPostgresService)
+}
+
+// runStoreConformance checks the behaviour every store implementation must share
+func runStoreConformance(t *testing.T, newService func(t *testing.T) *internal.Service) {
+	t.Run("Users", func(t *testing.T) { testUserStore(t, newService(t)) })
+	t.Run("Sessions", func(t *testing.T) { testSessionStore(t, newService(t)) })
+	t.Run("Threads", func(t *testing.T) { testThreadStore(t, newService(t)) })
+	t.Run("Posts", func(t *testing.T) { testPostStore(t, newService(t)) })
+	t.Run("Votes", func(t *testing.T) { testVoteStore(t, newService(t)) })
+	t.Run("Audit", func(t *testing.T) { testAuditStore(t, newService(t)) })
+	t.Run("Watches", func(t *testing.T) { testWatchStore(t, newService(t)) })
+	t.Run("Drafts", func(t *testing.T) { testDraftStore(t, newService(t)) })
+	t.Run("Tags", func(t *testing.T) { testTagStore(t, newService(t)) })
+}
+
+func mustCreateUser(t *testing.T, s *internal.Service, name string) models.User {
+	t.Helper()
+	user := models.User{Name: name, Email: name + "@example.com", Password: "Pass123!"}
+	if err := s.Users.CreateUser(&user); err != nil {
+		t.Fatalf("Failed to create user %s: %v", name, err)
+	}
+	return user
+}
+
+func mustCreateThread(t *testing.T, s *internal.Service, userID int, topic, category string) int {
+	t.Helper()
+	id, err := s.Threads.CreateThreadByUser(topic, "body of "+topic, userID, category, "")
+	if err != nil {
+		t.Fatalf("Failed to create thread: %v", err)
+	}
+	return int(id)
+}
+
+func testUserStore(t *testing.T, s *internal.Service) {
+	alice := mustCreateUser(t, s, "alice")
+	if alice.Id == 0 || alice.Uuid == "" {
+		t.Fatalf("Expected id and uuid to be assigned, got %+v", alice)
+	}
+	if err := s.Users.CreateUser(&models.User{Name: "other", Email: "alice@example.com", Password: "x"}); err == nil {
+		t.Error("Expected a duplicate email to be rejected")
+	}
+
+	byEmail, err := s.Users.GetUserByEmailDetailed("alice@example.com")
+	if err != nil || byEmail.Id != alice.Id || !utils.CheckPassword(byEmail.Password, "Pass123!") {
+		t.Errorf("Unexpected user by email %+v (%v)", byEmail, err)
+	}
+	if u, err := s.Users.GetUserByUUID(alice.Uuid); err != nil || u.Id != alice.Id {
+		t.Errorf("Unexpected user by uuid %+v (%v)", u, err)
+	}
+	if u, err := s.Users.GetUserByName("alice"); err != nil || u.Id != alice.Id {
+		t.Errorf("Unexpected user by name %+v (%v)", u, err)
+	}
+	if _, err := s.Users.GetUserByID(alice.Id + 1000); err == nil {
+		t.Error("Expected an unknown id to fail")
+	}
+	if exists, _ := s.Users.CheckUserExists("nobody@example.com", "alice"); !exists {
+		t.Error("Expected alice to exist by name")
+	}
+	if exists, _ := s.Users.CheckUserExists("nobody@example.com", "nobody"); exists {
+		t.Error("Expected nobody not to exist")
+	}
+
+	if err := s.Users.Update("alicia", alice.Id); err != nil {
+		t.Fatalf("Failed to rename: %v", err)
+	}
+	if err := s.Users.UpdateUserPreferences(alice.Id, "Movies", "Music"); err != nil {
+		t.Fatalf("Failed to update preferences: %v", err)
+	}
+	u, _ := s.Users.GetUserByID(alice.Id)
+	if u.Name != "alicia" || u.PreferedCategory1 != "Movies" || u.PreferedCategory2 != "Music" {
+		t.Errorf("Expected the changes to be stored, got %+v", u)
+	}
+	if u.Status != "active" || u.Role != models.RoleMember {
+		t.Errorf("Expected a new user to be an active member, got %q/%q", u.Status, u.Role)
+	}
+
+	bob := mustCreateUser(t, s, "bob")
+	mustCreateThread(t, s, bob.Id, "Bob's thread", "Movies")
+	if active, _ := s.Users.GetMostActiveUsers(1); len(active) != 1 || active[0].Id != bob.Id {
+		t.Errorf("Expected bob to be the most active user, got %+v", active)
+	}
+	if count, _ := s.Users.GetUserCount(); count != 2 {
+		t.Errorf("Expected 2 users, got %d", count)
+	}
+	if all, _ := s.Users.GetAllUsers(); len(all) != 2 {
+		t.Errorf("Expected 2 users listed, got %d", len(all))
+	}
+
+	if err := s.Users.DeleteUserByID(alice.Id); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	if err := s.Users.DeleteUserByID(alice.Id); err == nil {
+		t.Error("Expected deleting a missing user to fail")
+	}
+	if err := s.Users.DeleteAllUsers(); err != nil {
+		t.Fatalf("Failed to delete users: %v", err)
+	}
+	if count, _ := s.Users.GetUserCount(); count != 0 {
+		t.Errorf("Expected no users left, got %d", count)
+	}
+}
+
+func testSessionStore(t *testing.T, s *internal.Service) {
+	alice := mustCreateUser(t, s, "alice")
+
+	first, err := s.Sessions.CreateSession(&alice)
+	if err != nil || first.UserId != alice.Id || first.CookieString == "" {
+		t.Fatalf("Unexpected session %+v (%v)", first, err)
+	}
+	second, _ := s.Sessions.CreateSession(&alice)
+	if _, err := s.Sessions.GetSessionByCookie(first.CookieString); err == nil {
+		t.Error("Expected a new login to replace the previous session")
+	}
+	sess, err := s.Sessions.GetSessionByCookie(second.CookieString)
+	if err != nil || sess.Uuid != second.Uuid {
+		t.Fatalf("Unexpected session by cookie %+v (%v)", sess, err)
+	}
+
+	if _, valid, _ := s.Sessions.ValidateSession(second.Uuid); !valid {
+		t.Error("Expected the session to be valid")
+	}
+	if _, valid, _ := s.Sessions.ValidateSession("unknown"); valid {
+		t.Error("Expected an unknown session to be invalid")
+	}
+	if online, _ := s.Sessions.CheckOnlineUsers(5); len(online) != 1 || online[0].Id != alice.Id {
+		t.Errorf("Expected alice to be online, got %+v", online)
+	}
+	if count, err := s.Sessions.CountSessions(); err != nil || count != 1 {
+		t.Errorf("Expected one session, got %d (%v)", count, err)
+	}
+
+	if err := s.Sessions.UpdateSessionCookieString(second.Uuid, "new-cookie"); err != nil {
+		t.Fatalf("Failed to update cookie: %v", err)
+	}
+	if sess, err := s.Sessions.GetSessionByCookie("new-cookie"); err != nil || sess.Uuid != second.Uuid {
+		t.Errorf("Expected the session under its new cookie, got %+v (%v)", sess, err)
+	}
+
+	if err := s.Sessions.DeleteSessionByUUID(second.Uuid); err != nil {
+		t.Fatalf("Failed to delete session: %v", err)
+	}
+	if _, err := s.Sessions.GetSessionByCookie("new-cookie"); err == nil {
+		t.Error("Expected the session to be gone")
+	}
+
+	s.Sessions.CreateSession(&alice)
+	if err := s.Sessions.DeleteAllSessions(); err != nil {
+		t.Fatalf("Failed to delete sessions: %v", err)
+	}
+	if online, _ := s.Sessions.CheckOnlineUsers(5); len(online) != 0 {
+		t.Errorf("Expected nobody online, got %+v", online)
+	}
+}
+
+func testThreadStore(t *testing.T, s *internal.Service) {
+	alice := mustCreateUser(t, s, "alice")
+	older := mustCreateThread(t, s, alice.Id, "Older", "Movies")
+	time.Sleep(5 * time.Millisecond)
+	newer := mustCreateThread(t, s, alice.Id, "Newer", "Music")
+
+	thread, err := s.Threads.GetThreadByID(older)
+	if err != nil || thread.Topic != "Older" || thread.Body != "body of Older" || thread.UserId != alice.Id || thread.Category1 != "Movies" {
+		t.Fatalf("Unexpected thread %+v (%v)", thread, err)
+	}
+	if _, err := s.Threads.GetThreadByID(newer + 1000); err == nil {
+		t.Error("Expected an unknown thread to fail")
+	}
+
+	all, _ := s.Threads.GetAllThreads()
+	if len(all) != 2 || all[0].Id != newer || all[0].User != "alice" {
+		t.Fatalf("Expected the newest thread first, got %+v", all)
+	}
+	s.Threads.SetThreadPinned(older, true)
+	if all, _ := s.Threads.GetAllThreads(); all[0].Id != older || !all[0].Pinned {
+		t.Errorf("Expected the pinned thread first, got %+v", all)
+	}
+	if movies, _ := s.Threads.GetThreadsByCategories("Movies", ""); len(movies) != 1 || movies[0].Id != older {
+		t.Errorf("Expected only the Movies thread, got %+v", movies)
+	}
+	if both, _ := s.Threads.GetThreadsByCategories("Movies", "Music"); len(both) != 1 {
+		t.Errorf("Expected category2 to match the second category only, got %+v", both)
+	}
+
+	s.Posts.CreatePostByUser("first", alice.Id, newer)
+	s.Posts.CreatePostByUser("second", alice.Id, newer)
+	withPosts, err := s.Threads.GetThreadWithPosts(newer)
+	if err != nil || len(withPosts.Cards) != 2 || withPosts.Cards[0].Body != "first" || withPosts.Cards[1].User != "alice" || withPosts.User != "alice" {
+		t.Errorf("Unexpected thread with posts %+v (%v)", withPosts, err)
+	}
+	if all, _ := s.Threads.GetAllThreads(); all[1].NumReplies != 2 {
+		t.Errorf("Expected 2 replies counted, got %d", all[1].NumReplies)
+	}
+	created, _ := s.Threads.GetUserCreatedThreads(alice.Id)
+	if len(created) != 2 || created[0].Id != newer || created[0].LengthOfPosts != 2 {
+		t.Errorf("Unexpected threads of alice %+v", created)
+	}
+
+	s.Threads.SetThreadLocked(newer, true)
+	if thread, _ := s.Threads.GetThreadByID(newer); !thread.Locked {
+		t.Error("Expected the thread to be locked")
+	}
+	archived, err := s.Threads.ArchiveInactiveThreads(time.Now().Add(time.Hour))
+	if err != nil || archived != 1 {
+		t.Errorf("Expected only the unpinned thread to be archived, got %d (%v)", archived, err)
+	}
+	if thread, _ := s.Threads.GetThreadByID(newer); !thread.Archived {
+		t.Error("Expected the thread to be archived")
+	}
+	s.Threads.SetThreadArchived(newer, false)
+	if thread, _ := s.Threads.GetThreadByID(newer); thread.Archived {
+		t.Error("Expected the thread to be unarchived")
+	}
+	if count, _ := s.Threads.GetTotalThreadsCount(); count != 2 {
+		t.Errorf("Expected 2 threads, got %d", count)
+	}
+}
+
+func testPostStore(t *testing.T, s *internal.Service) {
+	alice := mustCreateUser(t, s, "alice")
+	threadID := mustCreateThread(t, s, alice.Id, "Topic", "Movies")
+
+	firstID, err := s.Posts.CreatePostByUser("first", alice.Id, threadID)
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	secondID, _ := s.Posts.CreatePostByUser("second", alice.Id, threadID)
+
+	post, err := s.Posts.GetPostByID(int(firstID))
+	if err != nil || post.Body != "first" || post.ThreadId != threadID || post.UserId != alice.Id {
+		t.Errorf("Unexpected post %+v (%v)", post, err)
+	}
+	if _, err := s.Posts.GetPostByID(int(secondID) + 1000); err == nil {
+		t.Error("Expected an unknown post to fail")
+	}
+	if posts, _ := s.Posts.GetThreadPosts(threadID); len(posts) != 2 || posts[0].Id != int(firstID) {
+		t.Errorf("Expected posts in the order they were written, got %+v", posts)
+	}
+	if posts, _ := s.Posts.GetUserCreatedPosts(alice.Id); len(posts) != 2 || posts[0].Id != int(secondID) || posts[0].User != "alice" {
+		t.Errorf("Expected the newest post first, got %+v", posts)
+	}
+	if count, _ := s.Posts.GetTotalPostsCount(); count != 2 {
+		t.Errorf("Expected 2 posts, got %d", count)
+	}
+}
+
+func testVoteStore(t *testing.T, s *internal.Service) {
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	threadID := mustCreateThread(t, s, alice.Id, "Topic", "Movies")
+	postID64, _ := s.Posts.CreatePostByUser("reply", alice.Id, threadID)
+	postID := int(postID64)
+
+	s.ToggleThreadVote(alice.Id, threadID, true)
+	s.ToggleThreadVote(bob.Id, threadID, true)
+	if status := s.ThreadVotes(alice.Id, threadID); status.Likes != 2 || !status.UserLiked || status.UserDisliked {
+		t.Errorf("Unexpected thread votes %+v", status)
+	}
+	s.ToggleThreadVote(bob.Id, threadID, false)
+	if status := s.ThreadVotes(bob.Id, threadID); status.Likes != 1 || status.Dislikes != 1 || status.UserLiked || !status.UserDisliked {
+		t.Errorf("Expected bob's like to become a dislike, got %+v", status)
+	}
+	s.ToggleThreadVote(bob.Id, threadID, false)
+	if status := s.ThreadVotes(bob.Id, threadID); status.Dislikes != 0 || status.UserDisliked {
+		t.Errorf("Expected a second dislike to take the vote back, got %+v", status)
+	}
+	if status := s.ThreadVotes(0, threadID); status.Likes != 1 || status.UserLiked {
+		t.Errorf("Expected guests to see counts only, got %+v", status)
+	}
+	if likes, _ := s.Votes.GetUserLikedThreads(alice.Id); len(likes) != 1 || likes[0].ThreadId != threadID {
+		t.Errorf("Unexpected liked threads %+v", likes)
+	}
+
+	s.TogglePostVote(bob.Id, postID, false)
+	if status := s.PostVotes(bob.Id, postID); status.Dislikes != 1 || !status.UserDisliked {
+		t.Errorf("Unexpected post votes %+v", status)
+	}
+	if dislikes, _ := s.Votes.GetUserDislikedPosts(bob.Id); len(dislikes) != 1 || dislikes[0].PostId != postID {
+		t.Errorf("Unexpected disliked posts %+v", dislikes)
+	}
+	s.TogglePostVote(bob.Id, postID, true)
+	if status := s.PostVotes(bob.Id, postID); status.Likes != 1 || status.Dislikes != 0 || !status.UserLiked {
+		t.Errorf("Expected bob's dislike to become a like, got %+v", status)
+	}
+	if likes, _ := s.Votes.GetUserLikedPosts(bob.Id); len(likes) != 1 || likes[0].PostId != postID {
+		t.Errorf("Unexpected liked posts %+v", likes)
+	}
+}
+
+func testAuditStore(t *testing.T, s *internal.Service) {
+	moderator := mustCreateUser(t, s, "moderator")
+	moderator.Role = models.RoleModerator
+	threadID := mustCreateThread(t, s, moderator.Id, "Rules", "Games")
+
+	if err := s.ModerateThread(moderator, threadID, "pin"); err != nil {
+		t.Fatalf("Failed to pin thread: %v", err)
+	}
+	if err := s.Audit.WriteAudit(0, "restore_backup", "from the command line"); err != nil {
+		t.Fatalf("Failed to write audit entry: %v", err)
+	}
+
+	entries, err := s.AuditLog(10)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %+v (%v)", entries, err)
+	}
+	if entries[0].Action != "restore_backup" || entries[0].Actor != "" {
+		t.Errorf("Expected the newest entry first without an actor, got %+v", entries[0])
+	}
+	if entries[1].Action != "pin_thread" || entries[1].Actor != "moderator" || entries[1].Detail != "thread "+strconv.Itoa(threadID) {
+		t.Errorf("Unexpected moderation entry %+v", entries[1])
+	}
+	if entries, _ := s.AuditLog(1); len(entries) != 1 {
+		t.Errorf("Expected the limit to apply, got %d entries", len(entries))
+	}
+}
+
+func testWatchStore(t *testing.T, s *internal.Service) {
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	threadID := mustCreateThread(t, s, alice.Id, "Topic", "Movies")
+
+	s.WatchThread(alice.Id, threadID)
+	if err := s.WatchThread(alice.Id, threadID); err != nil {
+		t.Fatalf("Expected watching twice to be accepted: %v", err)
+	}
+	if watches, _ := s.WatchedThreads(alice.Id); len(watches) != 1 || watches[0].Topic != "Topic" {
+		t.Errorf("Expected one watched thread, got %+v", watches)
+	}
+	if s.DigestFrequency(alice.Id) != "daily" {
+		t.Errorf("Expected daily digests by default, got %q", s.DigestFrequency(alice.Id))
+	}
+
+	since := time.Now().Add(-time.Minute)
+	time.Sleep(5 * time.Millisecond)
+	s.Posts.CreatePostByUser("alice's own reply", alice.Id, threadID)
+	s.Posts.CreatePostByUser("bob's reply", bob.Id, threadID)
+	entries, err := s.Watches.GetDigestEntries(alice.Id, since)
+	if err != nil || len(entries) != 1 || entries[0].Author != "bob" || entries[0].Topic != "Topic" {
+		t.Errorf("Expected bob's reply only, got %+v (%v)", entries, err)
+	}
+	if candidates, _ := s.Watches.GetDigestCandidates(); len(candidates) != 1 || candidates[0].UserId != alice.Id {
+		t.Errorf("Expected alice to be the only digest candidate, got %+v", candidates)
+	}
+
+	s.UnwatchThread(alice.Id, threadID)
+	if s.IsWatchingThread(alice.Id, threadID) {
+		t.Error("Expected the watch to be removed")
+	}
+}
+
+func testDraftStore(t *testing.T, s *internal.Service) {
+	alice := mustCreateUser(t, s, "alice")
+	threadID := mustCreateThread(t, s, alice.Id, "Topic", "Movies")
+
+	s.SaveDraft(alice.Id, models.Draft{ThreadId: threadID, Body: "first"})
+	s.SaveDraft(alice.Id, models.Draft{ThreadId: threadID, Body: "second"})
+	if draft, err := s.Draft(alice.Id, threadID); err != nil || draft.Body != "second" {
+		t.Errorf("Expected the draft to be replaced, got %+v (%v)", draft, err)
+	}
+	if drafts, _ := s.UserDrafts(alice.Id); len(drafts) != 1 || drafts[0].Target != "Topic" {
+		t.Errorf("Expected one draft replying to Topic, got %+v", drafts)
+	}
+	s.DiscardDraft(alice.Id, threadID)
+	if _, err := s.Draft(alice.Id, threadID); err == nil {
+		t.Error("Expected the draft to be discarded")
+	}
+}
+
+func testTagStore(t *testing.T, s *internal.Service) {
+	alice := mustCreateUser(t, s, "alice")
+	alice.Role = models.RoleModerator
+	first := mustCreateThread(t, s, alice.Id, "First", "Movies")
+	second := mustCreateThread(t, s, alice.Id, "Second", "Movies")
+
+	s.SetThreadTags(first, []string{"golang", "web"})
+	s.SetThreadTags(second, []string{"golang", "go"})
+	if tags, _ := s.Tags.GetThreadTags(first); len(tags) != 2 || tags[0] != "golang" || tags[1] != "web" {
+		t.Errorf("Expected the tags sorted by name, got %v", tags)
+	}
+	if tags, _ := s.SuggestTags("g"); len(tags) != 2 || tags[0].Name != "golang" || tags[0].UsageCount != 2 {
+		t.Errorf("Expected the most used tag first, got %+v", tags)
+	}
+
+	if err := s.AddTagSynonym(alice, "go", "golang"); err != nil {
+		t.Fatalf("Failed to add synonym: %v", err)
+	}
+	if _, err := s.TagInfo("go"); err == nil {
+		t.Error("Expected the alias tag to be merged away")
+	}
+	if tag, _ := s.TagInfo("golang"); tag.UsageCount != 2 {
+		t.Errorf("Expected golang to keep its 2 threads, got %+v", tag)
+	}
+	if synonyms, _ := s.TagSynonyms(); len(synonyms) != 1 || synonyms[0].Tag != "golang" || synonyms[0].CreatedBy != "alice" {
+		t.Errorf("Unexpected synonyms %+v", synonyms)
+	}
+	if s.CanonicalTag("go") != "golang" {
+		t.Errorf("Expected go to stand for golang, got %q", s.CanonicalTag("go"))
+	}
+}
+
+// TestVoteHandlerInMemory runs a handler against the memory store, no database involved
+func TestVoteHandlerInMemory(t *testing.T) {
+	s := newMemoryService(t)
+	alice := mustCreateUser(t, s, "alice")
+	threadID := mustCreateThread(t, s, alice.Id, "Topic", "Movies")
+	session, _ := s.Sessions.CreateSession(&alice)
+
+	handler := routes.Chain(routes.WithService(s), routes.WithAuthentication())(routes.LikeThread)
+	request := httptest.NewRequest(http.MethodPost, "/api/thread/"+strconv.Itoa(threadID)+"/like", nil)
+	request.AddCookie(&http.Cookie{Name: "_cookie", Value: session.CookieString})
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+
+	var status models.ThreadVoteStatus
+	if err := json.NewDecoder(recorder.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode response (%d): %v", recorder.Code, err)
+	}
+	if status.Likes != 1 || !status.UserLiked {
+		t.Errorf("Expected the like to be stored, got %+v", status)
+	}
+}
+
+// TestThreadHandlersInMemory replies to and shows a thread on the memory
+// stores, tags, mentions, watches, drafts and views included, no database
+// involved
+func TestThreadHandlersInMemory(t *testing.T) {
+	if _, err := utils.LoadTemplates(forum.Templates(), false); err != nil {
+		t.Fatal(err)
+	}
+	s := newMemoryService(t)
+	alice := mustCreateUser(t, s, "alice")
+	bob := mustCreateUser(t, s, "bob")
+	threadID := mustCreateThread(t, s, alice.Id, "Topic", "Movies")
+	s.SetThreadTags(threadID, []string{"golang"})
+	s.SaveDraft(bob.Id, models.Draft{ThreadId: threadID, Body: "half written"})
+
+	serve := func(handler http.HandlerFunc, user models.User, method, target string, form url.Values) *httptest.ResponseRecorder {
+		session, _ := s.Sessions.CreateSession(&user)
+		request := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.AddCookie(&http.Cookie{Name: utils.SessionCookieName(), Value: session.CookieString})
+		recorder := httptest.NewRecorder()
+		routes.Chain(routes.WithService(s), routes.WithAuthentication())(handler)(recorder, request)
+		return recorder
+	}
+
+	form := url.Values{"id": {strconv.Itoa(threadID)}, "body": {"Thanks @alice"}}
+	if rec := serve(routes.PostThread, bob, "POST", "/thread/post", form); rec.Code != http.StatusFound {
+		t.Fatalf("Expected the reply to redirect, got %d: %s", rec.Code, rec.Body)
+	}
+	if mentions, _ := s.UserMentions(alice.Id); len(mentions) != 1 || mentions[0].Author != "bob" {
+		t.Errorf("Expected alice to be mentioned by bob, got %+v", mentions)
+	}
+	if !s.IsWatchingThread(bob.Id, threadID) {
+		t.Error("Expected replying to watch the thread")
+	}
+	if _, err := s.Draft(bob.Id, threadID); err == nil {
+		t.Error("Expected replying to discard the draft")
+	}
+
+	rec := serve(routes.ShowThread, alice, "GET", utils.ThreadPath(threadID, "Topic"), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the thread, got %d: %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "golang") || !strings.Contains(body, `href="/u/alice"`) {
+		t.Errorf("Expected the tag and the mention link on the page")
+	}
+	if s.PendingThreadViews(threadID) != 1 {
+		t.Errorf("Expected the view to be counted, got %d", s.PendingThreadViews(threadID))
+	}
+	if lastRead, err := s.Reads.GetLastReadPost(alice.Id, threadID); err != nil || lastRead == 0 {
+		t.Errorf("Expected alice's read marker to move, got %d (%v)", lastRead, err)
+	}
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"forum/internal"
+	"forum/models"
+	"forum/routes"
+)
+
+func TestFeeds(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	author := models.User{Name: "author", Email: "author@example.com", Password: "Pass123!"}
+	reader := models.User{Name: "reader", Email: "reader@example.com", Password: "Pass123!"}
+	for _, user := range []*models.User{&author, &reader} {
+		if err := dm.CreateUser(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	threadID, _ := dm.CreateThreadByUser("Tom & <Jerry>", "Hi @reader", author.Id, "Movies", "")
+	service.RecordMentions(author.Id, int(threadID), 0, "Hi @reader")
+	service.WatchThread(reader.Id, int(threadID))
+	service.CreatePost(int(threadID), "First reply", author.Id)
+
+	feed, err := service.ThreadRepliesFeed(int(threadID))
+	if err != nil || len(feed.Entries) != 1 {
+		t.Fatalf("Expected 1 reply in the thread feed, got %+v (%v)", feed.Entries, err)
+	}
+	if _, err := service.LatestThreadsFeed("Unknown"); err != internal.ErrFeedNotFound {
+		t.Errorf("Expected unknown category to have no feed, got %v", err)
+	}
+
+	body, err := internal.RenderFeed(feed, "atom")
+	if err != nil {
+		t.Fatalf("Failed to render feed: %v", err)
+	}
+	var atom struct {
+		Title   string `xml:"title"`
+		Updated string `xml:"updated"`
+		Entries []struct {
+			Title string `xml:"title"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(body, &atom); err != nil {
+		t.Fatalf("Rendered feed is not valid XML: %v", err)
+	}
+	if atom.Title != "Forum Talk: Tom & <Jerry>" || len(atom.Entries) != 1 || atom.Entries[0].Title != "Re: Tom & <Jerry>" {
+		t.Errorf("Unexpected feed content: %+v", atom)
+	}
+
+	// the notifications feed needs the secret token
+	if _, err := service.NotificationsFeed("guess"); err != internal.ErrInvalidFeedToken {
+		t.Errorf("Expected unknown token to be refused, got %v", err)
+	}
+	token, err := service.GenerateFeedToken(reader.Id)
+	if err != nil {
+		t.Fatalf("Failed to generate feed token: %v", err)
+	}
+	notifications, err := service.NotificationsFeed(token)
+	if err != nil || len(notifications.Entries) != 2 {
+		t.Errorf("Expected a mention and a reply, got %+v (%v)", notifications.Entries, err)
+	}
+	service.RevokeFeedToken(reader.Id)
+	if _, err := service.NotificationsFeed(token); err != internal.ErrInvalidFeedToken {
+		t.Errorf("Expected revoked token to be refused, got %v", err)
+	}
+}
+
+func TestFeedConditionalGet(t *testing.T) {
+	dm := openTestDB(t)
+	feeds := routes.Chain(routes.WithService(internal.NewService(dm)))(routes.Feeds)
+
+	author := models.User{Name: "author", Email: "author@example.com", Password: "Pass123!"}
+	if err := dm.CreateUser(&author); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	dm.CreateThreadByUser("Topic", "Body", author.Id, "Games", "")
+
+	request := httptest.NewRequest("GET", "/feeds/latest.rss", nil)
+	recorder := httptest.NewRecorder()
+	feeds(recorder, request)
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "application/rss+xml") {
+		t.Fatalf("Expected RSS feed, got %d %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	etag := recorder.Header().Get("ETag")
+	lastModified := recorder.Header().Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("Expected ETag and Last-Modified, got %q and %q", etag, lastModified)
+	}
+
+	for header, value := range map[string]string{"If-None-Match": etag, "If-Modified-Since": lastModified} {
+		request = httptest.NewRequest("GET", "/feeds/latest.rss", nil)
+		request.Header.Set(header, value)
+		recorder = httptest.NewRecorder()
+		feeds(recorder, request)
+		if recorder.Code != http.StatusNotModified {
+			t.Errorf("Expected 304 with %s, got %d", header, recorder.Code)
+		}
+	}
+}
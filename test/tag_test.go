@@ -0,0 +1,90 @@
+package test
+
+import (
+	"testing"
+
+	"forum/internal"
+	"forum/models"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	cases := map[string]string{
+		"Go":          "go",
+		" #golang ":   "golang",
+		"Web  Dev":    "web-dev",
+		"snake_case":  "snake-case",
+		"C++":         "c++",
+		"c#":          "c#",
+		"--x--":       "x",
+		"<script>":    "script",
+		"éte":         "te",
+		"!!!":         "",
+		"node.js 2.0": "node.js-2.0",
+	}
+	for raw, want := range cases {
+		if got := internal.NormalizeTag(raw); got != want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestTagsAndSynonyms(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	author := models.User{Name: "author", Email: "author@example.com", Password: "Pass123!"}
+	if err := dm.CreateUser(&author); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	moderator := author
+	moderator.Role = models.RoleModerator
+
+	if _, err := service.ParseTags("a, b, c, d, e, f"); err == nil {
+		t.Error("Expected more than 5 tags to be refused")
+	}
+	tags, err := service.ParseTags("Go, #go, golang,, Web Dev")
+	if err != nil || len(tags) != 3 {
+		t.Fatalf("Expected 3 distinct tags, got %v (%v)", tags, err)
+	}
+
+	games, _ := dm.CreateThreadByUser("Go games", "Body", author.Id, "Games", "")
+	sports, _ := dm.CreateThreadByUser("Go sports", "Body", author.Id, "Sports", "")
+	service.SetThreadTags(int(games), tags)
+	service.SetThreadTags(int(sports), []string{"golang"})
+
+	// tag filters narrow the category filter down
+	threads, _ := service.FilterThreadsByCategories("Games", "")
+	threads, err = service.FilterThreadsByTag(threads, "golang")
+	if err != nil || len(threads) != 1 || threads[0].Id != int(games) {
+		t.Errorf("Expected only the games thread, got %+v (%v)", threads, err)
+	}
+
+	if err := service.AddTagSynonym(author, "golang", "go"); err == nil {
+		t.Error("Expected members to be refused")
+	}
+	if err := service.AddTagSynonym(moderator, "Golang", "go"); err != nil {
+		t.Fatalf("Failed to add synonym: %v", err)
+	}
+
+	// the alias tag is merged into its tag
+	if _, err := service.TagInfo("golang"); err != internal.ErrTagNotFound {
+		t.Errorf("Expected alias tag to be removed, got %v", err)
+	}
+	tag, err := service.TagInfo("go")
+	if err != nil || tag.UsageCount != 2 {
+		t.Errorf("Expected go used by 2 threads, got %+v (%v)", tag, err)
+	}
+	if got := service.CanonicalTag("#GoLang"); got != "go" {
+		t.Errorf("Expected synonym to resolve to go, got %q", got)
+	}
+	suggestions, _ := service.SuggestTags("gol")
+	if len(suggestions) != 1 || suggestions[0].Name != "go" {
+		t.Errorf("Expected synonym prefix to suggest go, got %+v", suggestions)
+	}
+
+	// retagging updates the counts
+	service.SetThreadTags(int(sports), nil)
+	if tag, _ := service.TagInfo("go"); tag.UsageCount != 1 {
+		t.Errorf("Expected go used by 1 thread, got %d", tag.UsageCount)
+	}
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"forum"
+	"forum/utils"
+)
+
+func TestTemplatesRender(t *testing.T) {
+	if _, err := utils.LoadTemplates(forum.Templates(), false); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err := utils.Render(&buf, utils.PublicPage("error"), map[string]interface{}{"Title": "Oops", "Message": "broken", "Code": 500})
+	if err != nil {
+		t.Fatalf("Failed to render error page: %v", err)
+	}
+	if !strings.Contains(buf.String(), "broken") {
+		t.Errorf("Expected the message in the page, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := utils.Render(&buf, utils.PublicPage("missing"), nil); err == nil {
+		t.Error("Expected an unknown page to be an error")
+	}
+	if buf.Len() != 0 {
+		t.Error("Expected nothing written for a failed render")
+	}
+}
+
+func TestTemplatesInvalid(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "layout.html", `{{ define "layout" }}{{ template "navbar" . }}{{ template "content" . }}{{ end }}`)
+	writeTemplate(t, dir, "public.navbar.html", `{{ define "navbar" }}nav{{ end }}`)
+	writeTemplate(t, dir, "home.html", `{{ define "content" }}{{ .Missing }{{ end }}`)
+
+	if _, err := utils.LoadTemplates(os.DirFS(dir), false); err == nil || !strings.Contains(err.Error(), "home.html") {
+		t.Errorf("Expected a parse error naming home.html, got %v", err)
+	}
+}
+
+func TestTemplatesDevReload(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "layout.html", `{{ define "layout" }}{{ template "navbar" . }}|{{ template "content" . }}{{ end }}`)
+	writeTemplate(t, dir, "public.navbar.html", `{{ define "navbar" }}nav{{ end }}`)
+	writeTemplate(t, dir, "home.html", `{{ define "content" }}first{{ end }}`)
+
+	registry, err := utils.LoadTemplates(os.DirFS(dir), true)
+	if err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+	page := utils.PublicPage("home")
+
+	var buf bytes.Buffer
+	if err := registry.Render(&buf, page, nil); err != nil || buf.String() != "nav|first" {
+		t.Fatalf("Unexpected render %q (%v)", buf.String(), err)
+	}
+
+	writeTemplate(t, dir, "home.html", `{{ define "content" }}second{{ end }}`)
+	later := time.Now().Add(time.Second)
+	os.Chtimes(filepath.Join(dir, "home.html"), later, later)
+
+	buf.Reset()
+	if err := registry.Render(&buf, page, nil); err != nil || buf.String() != "nav|second" {
+		t.Errorf("Expected the changed template in dev mode, got %q (%v)", buf.String(), err)
+	}
+}
+
+func TestTemplatesOverride(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "error.html", `{{ define "content" }}themed {{ .Message }}{{ end }}`)
+
+	registry, err := utils.LoadTemplates(utils.Overlay(dir, forum.Templates()), false)
+	if err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := registry.Render(&buf, utils.PublicPage("error"), map[string]string{"Message": "oops"}); err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if !strings.Contains(buf.String(), "themed oops") {
+		t.Error("Expected the override to replace the embedded error page")
+	}
+	buf.Reset()
+	if err := registry.Render(&buf, utils.PublicPage("index"), nil); err != nil && strings.Contains(err.Error(), "unknown page") {
+		t.Errorf("Expected embedded pages next to the override: %v", err)
+	}
+	if f, err := forum.Static().Open("css/layout.css"); err != nil {
+		t.Errorf("Expected embedded static files: %v", err)
+	} else {
+		f.Close()
+	}
+}
+
+func writeTemplate(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"forum/internal"
+	"forum/models"
+)
+
+func TestThreadStates(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	member := models.User{Name: "member", Email: "member@example.com", Password: "Pass123!"}
+	moderator := models.User{Name: "moderator", Email: "moderator@example.com", Password: "Pass123!"}
+	for _, user := range []*models.User{&member, &moderator} {
+		if err := dm.CreateUser(user); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	moderator.Role = models.RoleModerator
+
+	rules, _ := dm.CreateThreadByUser("Rules", "Be nice", moderator.Id, "Other", "")
+	old, _ := dm.CreateThreadByUser("Old question", "Body", member.Id, "Other", "")
+	recent, _ := dm.CreateThreadByUser("Recent question", "Body", member.Id, "Other", "")
+
+	if err := service.ModerateThread(member, int(rules), "pin"); err == nil {
+		t.Error("Expected members to be refused")
+	}
+	if err := service.ModerateThread(moderator, int(rules), "explode"); err != internal.ErrUnknownThreadAction {
+		t.Errorf("Expected unknown action error, got %v", err)
+	}
+	if err := service.ModerateThread(moderator, int(rules), "pin"); err != nil {
+		t.Fatalf("Failed to pin thread: %v", err)
+	}
+
+	threads, _ := service.GetAllThreads()
+	if len(threads) != 3 || threads[0].Id != int(rules) {
+		t.Fatalf("Expected pinned thread first, got %+v", threads)
+	}
+	// pinned threads stay first whatever the sort
+	threads[0].LikesCount, threads[1].LikesCount = 0, 5
+	sorted, _ := internal.SortThreadsByLikesDesc(threads)
+	if sorted[0].Id != int(rules) {
+		t.Errorf("Expected pinned thread first when sorting by likes, got %d", sorted[0].Id)
+	}
+
+	if err := service.ModerateThread(moderator, int(old), "lock"); err != nil {
+		t.Fatalf("Failed to lock thread: %v", err)
+	}
+	thread, _ := service.ThreadById(int(old))
+	if err := internal.CanReply(thread); err != internal.ErrThreadLocked {
+		t.Errorf("Expected locked thread to refuse replies, got %v", err)
+	}
+	service.ModerateThread(moderator, int(old), "unlock")
+
+	// only the thread without recent activity is archived, never the pinned one
+	weekAgo := time.Now().Add(-7 * 24 * time.Hour)
+	if _, err := dm.DoExec("UPDATE threads SET created_at=? WHERE id IN (?, ?, ?)", weekAgo, rules, old, recent); err != nil {
+		t.Fatalf("Failed to age threads: %v", err)
+	}
+	if _, err := service.CreatePost(int(recent), "Still alive", member.Id); err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	job := internal.ArchiveJob{Service: service, After: 24 * time.Hour}
+	archived, err := job.RunOnce(time.Now())
+	if err != nil {
+		t.Fatalf("Auto-archive failed: %v", err)
+	}
+	if archived != 1 {
+		t.Errorf("Expected 1 archived thread, got %d", archived)
+	}
+	thread, _ = service.ThreadById(int(rules))
+	if thread.Archived {
+		t.Error("Expected pinned thread not to be archived")
+	}
+	thread, _ = service.ThreadById(int(old))
+	if err := internal.CanReply(thread); err != internal.ErrThreadArchived {
+		t.Errorf("Expected archived thread to refuse replies, got %v", err)
+	}
+
+	audit, _ := service.AuditLog(10)
+	if len(audit) != 3 {
+		t.Errorf("Expected 3 audited actions, got %d", len(audit))
+	}
+}
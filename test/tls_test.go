@@ -0,0 +1,78 @@
+package test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"forum/routes"
+	"forum/utils"
+)
+
+func TestGenerateCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "certs", "cert.pem"), filepath.Join(dir, "certs", "key.pem")
+	if err := utils.GenerateCertificate(certFile, keyFile, []string{"localhost", "127.0.0.1", "forum.test"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Expected a usable key pair: %v", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"localhost", "forum.test", "127.0.0.1"} {
+		if err := cert.VerifyHostname(host); err != nil {
+			t.Errorf("Expected the certificate to cover %s: %v", host, err)
+		}
+	}
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	cases := []struct {
+		address, host, path, want string
+	}{
+		{"0.0.0.0:443", "forum.test", "/thread?id=3", "https://forum.test/thread?id=3"},
+		{"0.0.0.0:443", "forum.test:80", "/", "https://forum.test/"},
+		{":8443", "localhost:8080", "/login/", "https://localhost:8443/login/"},
+		{":443", "[::1]:8080", "/", "https://[::1]/"},
+	}
+	for _, c := range cases {
+		request := httptest.NewRequest("GET", c.path, nil)
+		request.Host = c.host
+		recorder := httptest.NewRecorder()
+		routes.RedirectHTTPS(c.address)(recorder, request)
+		if recorder.Code != 301 || recorder.Header().Get("Location") != c.want {
+			t.Errorf("%s%s: expected 301 to %s, got %d to %s", c.host, c.path, c.want, recorder.Code, recorder.Header().Get("Location"))
+		}
+	}
+}
+
+func TestConfigTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := utils.GenerateCertificate(certFile, keyFile, []string{"localhost"}); err != nil {
+		t.Fatal(err)
+	}
+	file := writeConfigFile(t, "{}")
+
+	c, err := loadConfig([]string{"--config", file, "--tls-cert", certFile, "--tls-key", keyFile, "--session-same-site", "none"}, nil)
+	if err != nil || !c.TLS.Enabled() {
+		t.Errorf("Expected TLS enabled, got %v", err)
+	}
+
+	_, err = loadConfig([]string{"--config", file, "--tls-cert", certFile, "--tls-redirect", ":80"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "Cert and Key") || !strings.Contains(err.Error(), "TLS.Redirect") {
+		t.Errorf("Expected errors about the missing key and the redirect, got %v", err)
+	}
+	_, err = loadConfig([]string{"--config", file, "--tls-cert", certFile, "--tls-key", filepath.Join(dir, "missing.pem")}, nil)
+	if err == nil || !strings.Contains(err.Error(), "missing.pem") {
+		t.Errorf("Expected an error about the missing key file, got %v", err)
+	}
+}
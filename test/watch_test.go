@@ -0,0 +1,121 @@
+package test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"forum/internal"
+	"forum/internal/data"
+	"forum/models"
+)
+
+// openTestDB creates a fresh migrated database in a temp dir
+func openTestDB(t *testing.T) *data.DatabaseManager {
+	t.Helper()
+	dm, err := data.NewDatabaseManager(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open DB: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+	if err := internal.RunMigrations(dm); err != nil {
+		t.Fatalf("Failed to migrate DB: %v", err)
+	}
+	return dm
+}
+
+type capturedMail struct {
+	to, subject, body string
+}
+
+type captureSender struct {
+	sent []capturedMail
+}
+
+func (c *captureSender) Send(to, subject, body string) error {
+	c.sent = append(c.sent, capturedMail{to, subject, body})
+	return nil
+}
+
+func TestDigestAndUnsubscribe(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+
+	author := models.User{Name: "author", Email: "author@example.com", Password: "Pass123!"}
+	reader := models.User{Name: "reader", Email: "reader@example.com", Password: "Pass123!"}
+	if err := dm.CreateUser(&author); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := dm.CreateUser(&reader); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	threadID, err := dm.CreateThreadByUser("Watched", "Body", author.Id, "Games", "")
+	if err != nil {
+		t.Fatalf("Failed to create thread: %v", err)
+	}
+	if err := service.WatchThread(author.Id, int(threadID)); err != nil {
+		t.Fatalf("Failed to watch thread: %v", err)
+	}
+	if _, err := dm.CreatePostByUser("a reply", reader.Id, int(threadID)); err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+
+	sender := &captureSender{}
+	job := internal.DigestJob{Service: service, Sender: sender, BaseURL: "http://forum.test", Interval: time.Hour}
+	if err := job.RunOnce(time.Now()); err != nil {
+		t.Fatalf("Digest run failed: %v", err)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].to != author.Email {
+		t.Fatalf("Expected one digest to %s, got %+v", author.Email, sender.sent)
+	}
+	if !strings.Contains(sender.sent[0].body, "a reply") {
+		t.Errorf("Digest does not contain the new post:\n%s", sender.sent[0].body)
+	}
+
+	// A second run on the same day must not resend
+	if err := job.RunOnce(time.Now()); err != nil {
+		t.Fatalf("Digest run failed: %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Errorf("Digest was sent twice in one day")
+	}
+
+	if err := service.Unsubscribe(author.Id, int(threadID), "deadbeef"); err == nil {
+		t.Error("Unsubscribe accepted a forged signature")
+	}
+	link := internal.UnsubscribeURL("", author.Id, int(threadID))
+	signature := link[strings.LastIndex(link, "sig=")+len("sig="):]
+	if err := service.Unsubscribe(author.Id, int(threadID), signature); err != nil {
+		t.Fatalf("Unsubscribe rejected a valid link: %v", err)
+	}
+	if service.IsWatchingThread(author.Id, int(threadID)) {
+		t.Error("Thread is still watched after unsubscribe")
+	}
+}
+
+func TestDigestSkipsInactiveWatchers(t *testing.T) {
+	dm := openTestDB(t)
+	service := internal.NewService(dm)
+	author := mustCreateUser(t, service, "author")
+	banned := mustCreateUser(t, service, "banned")
+	threadID := mustCreateThread(t, service, author.Id, "Watched", "Games")
+	service.WatchThread(banned.Id, threadID)
+	dm.CreatePostByUser("a reply", author.Id, threadID)
+	if err := dm.SetUserStatus(banned.Id, models.StatusBanned); err != nil {
+		t.Fatalf("Failed to ban user: %v", err)
+	}
+
+	if candidates, _ := dm.GetDigestCandidates(); len(candidates) != 0 {
+		t.Errorf("Expected no digest candidates, got %+v", candidates)
+	}
+	sender := &captureSender{}
+	job := internal.DigestJob{Service: service, Sender: sender, BaseURL: "http://forum.test", Interval: time.Hour}
+	if err := job.RunOnce(time.Now()); err != nil {
+		t.Fatalf("Digest run failed: %v", err)
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("Expected no digest for a banned watcher, got %+v", sender.sent)
+	}
+}
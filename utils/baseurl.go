@@ -0,0 +1,18 @@
+package utils
+
+import "strings"
+
+// baseURL is the public address of the forum, used for absolute links
+// in emails and feeds
+var baseURL = "http://localhost:8080"
+
+// SetBaseURL replaces the default public address, an empty url keeps it
+func SetBaseURL(url string) {
+	if url != "" {
+		baseURL = strings.TrimRight(url, "/")
+	}
+}
+
+func BaseURL() string {
+	return baseURL
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CookieSettings shape the session cookie set at login
+type CookieSettings struct {
+	Name     string
+	Lifetime time.Duration
+	HttpOnly bool
+	Secure   bool
+	SameSite http.SameSite
+}
+
+var cookieSettings = CookieSettings{
+	Name:     "_cookie",
+	Lifetime: 24 * time.Hour,
+	HttpOnly: true,
+	SameSite: http.SameSiteLaxMode,
+}
+
+// SetCookieSettings replaces the default session cookie settings,
+// an empty Name or zero Lifetime keeps the default
+func SetCookieSettings(s CookieSettings) {
+	if s.Name == "" {
+		s.Name = cookieSettings.Name
+	}
+	if s.Lifetime <= 0 {
+		s.Lifetime = cookieSettings.Lifetime
+	}
+	cookieSettings = s
+}
+
+// ParseSameSite maps lax, strict and none to the cookie attribute
+func ParseSameSite(s string) http.SameSite {
+	switch strings.ToLower(s) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// SessionCookieName is the name of the cookie holding the session
+func SessionCookieName() string {
+	return cookieSettings.Name
+}
+
+// SessionCookie is the cookie carrying a new session's value
+func SessionCookie(value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     cookieSettings.Name,
+		Value:    value,
+		Path:     "/",
+		Expires:  time.Now().Add(cookieSettings.Lifetime),
+		HttpOnly: cookieSettings.HttpOnly,
+		Secure:   cookieSettings.Secure,
+		SameSite: cookieSettings.SameSite,
+	}
+}
+
+// VisitorCookieName is the cookie telling guests apart when counting views
+const VisitorCookieName = "_visitor"
+
+// VisitorCookie keeps the random id of a guest for lifetime
+func VisitorCookie(value string, lifetime time.Duration) *http.Cookie {
+	return &http.Cookie{
+		Name:     VisitorCookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(lifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   cookieSettings.Secure,
+		SameSite: cookieSettings.SameSite,
+	}
+}
+
+// SessionLifetime is how long a session stays signed in
+func SessionLifetime() time.Duration {
+	return cookieSettings.Lifetime
+}
+
+// SessionExpired reports whether a session created at created outlived the lifetime
+func SessionExpired(created time.Time) bool {
+	return time.Since(created) > cookieSettings.Lifetime
+}
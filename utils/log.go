@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces the value of any attribute whose key names a secret
+const redacted = "[REDACTED]"
+
+var secretKeys = []string{"password", "secret", "token", "cookie", "authorization"}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactSecrets(groups []string, a slog.Attr) slog.Attr {
+	if isSecretKey(a.Key) && a.Value.Kind() != slog.KindGroup {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request id,
+// every record logged with that context includes it
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request id of ctx, empty outside a request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// NewRequestID keeps a well formed id from a proxy, otherwise makes one
+func NewRequestID(incoming string) string {
+	if validRequestID.MatchString(incoming) {
+		return incoming
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// contextHandler adds the request id of the context to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// NewLogger builds a logger writing text or json records to w,
+// secrets are redacted and request ids added from the context
+func NewLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("log level %q: %w", level, err)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redactSecrets}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, opts)
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("log format %q: want text or json", format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// SetupLogging makes the default logger write to file (stdout when empty)
+// in the given format and level
+func SetupLogging(format, level, file string) error {
+	var w io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			return err
+		}
+		w = f
+	}
+	logger, err := NewLogger(w, format, level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// logMessage joins the arguments of Info, Warn and Danger
+func logMessage(args []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+func Info(args ...interface{}) {
+	slog.Info(logMessage(args))
+}
+
+func Danger(args ...interface{}) {
+	slog.Error(logMessage(args))
+}
+
+func Warn(args ...interface{}) {
+	slog.Warn(logMessage(args))
+}
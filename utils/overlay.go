@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// overlayFS serves files from a directory on disk first and falls back to
+// the embedded files, so a theme only has to contain the files it changes
+type overlayFS struct {
+	dir  fs.FS
+	base fs.FS
+}
+
+// Overlay puts the directory dir over base, an empty or missing dir is base
+func Overlay(dir string, base fs.FS) fs.FS {
+	if dir == "" {
+		return base
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		Warn("Override directory not found, using embedded files:", dir)
+		return base
+	}
+	return overlayFS{dir: os.DirFS(dir), base: base}
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.dir.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return o.base.Open(name)
+}
+
+// ReadDir merges both listings, the directory wins for names in both
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	top, topErr := fs.ReadDir(o.dir, name)
+	bottom, bottomErr := fs.ReadDir(o.base, name)
+	if topErr != nil && bottomErr != nil {
+		return nil, bottomErr
+	}
+
+	seen := map[string]bool{}
+	var entries []fs.DirEntry
+	for _, e := range top {
+		seen[e.Name()] = true
+		entries = append(entries, e)
+	}
+	for _, e := range bottom {
+		if !seen[e.Name()] {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
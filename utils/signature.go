@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// signingKey is used to sign links that must work without a session
+// (e.g. unsubscribe links in digest emails)
+var signingKey []byte
+
+func init() {
+	signingKey = make([]byte, 32)
+	rand.Read(signingKey) // never fails since go 1.24
+}
+
+// SetSigningKey replaces the random startup key with a configured secret,
+// so signed links stay valid across restarts
+func SetSigningKey(key string) {
+	if key == "" {
+		Warn("No SecretKey configured, signed links will expire on restart")
+		return
+	}
+	signingKey = []byte(key)
+}
+
+// Sign returns a hex encoded HMAC-SHA256 of the value
+func Sign(value string) string {
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a signature created by Sign in constant time
+func VerifySignature(value, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write([]byte(value))
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+const maxSlugLength = 60
+
+// Slugify turns a thread topic into the readable part of its URL:
+// lowercase ASCII letters and digits separated by single dashes
+func Slugify(topic string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(html.UnescapeString(topic)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		if i := strings.LastIndexByte(slug, '-'); i > maxSlugLength/2 {
+			slug = slug[:i]
+		}
+		slug = strings.TrimRight(slug, "-")
+	}
+	if slug == "" {
+		return "thread"
+	}
+	return slug
+}
+
+// ThreadPath is the canonical path of a thread, /t/{id}-{slug}
+func ThreadPath(threadID int, topic string) string {
+	return fmt.Sprintf("/t/%d-%s", threadID, Slugify(topic))
+}
+
+// PostPath is the permalink of a post inside its thread page
+func PostPath(threadID int, topic string, postID int) string {
+	if postID == 0 {
+		return ThreadPath(threadID, topic)
+	}
+	return fmt.Sprintf("%s#post-%d", ThreadPath(threadID, topic), postID)
+}
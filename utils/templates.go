@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"forum/internal/metrics"
+)
+
+// Page names the three templates a page is rendered from: a file defining
+// "layout", one defining "navbar" and one defining "content"
+type Page struct {
+	Layout  string
+	Navbar  string
+	Content string
+}
+
+// PublicPage is a page for guests
+func PublicPage(content string) Page {
+	return Page{Layout: "layout", Navbar: "public.navbar", Content: content}
+}
+
+// PrivatePage is a page for signed in users
+func PrivatePage(content string) Page {
+	return Page{Layout: "layout", Navbar: "private.navbar", Content: content}
+}
+
+// LoginPage is a page on the login layout
+func LoginPage(content string) Page {
+	return Page{Layout: "login.layout", Navbar: "public.navbar", Content: content}
+}
+
+func (p Page) String() string {
+	return p.Layout + "+" + p.Navbar + "+" + p.Content
+}
+
+var errTemplatesNotLoaded = errors.New("templates are not loaded")
+
+// Templates holds every page parsed once at startup. In dev mode the
+// files are checked on each render and parsed again when one changed.
+type Templates struct {
+	files fs.FS
+	dev   bool
+
+	mu       sync.RWMutex
+	pages    map[Page]*template.Template
+	loadedAt time.Time
+}
+
+var templates *Templates
+
+// LoadTemplates parses every .html template in files and makes it the registry
+// used by Render, an invalid template is an error so the server does not start
+func LoadTemplates(files fs.FS, dev bool) (*Templates, error) {
+	t := &Templates{files: files, dev: dev}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	templates = t
+	return t, nil
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"safeHTML": func(s string) template.HTML {
+			return template.HTML(s) // Marks the string as safe HTML (no escaping)
+		},
+		"threadURL": ThreadPath,
+		"percent": func(part, whole int) int {
+			if whole <= 0 {
+				return 0
+			}
+			return part * 100 / whole
+		},
+		"postURL": PostPath,
+	}
+}
+
+// load parses the templates: files defining "layout", "navbar" or "content"
+// are combined into pages, any other file is a partial shared by all pages
+func (t *Templates) load() error {
+	files, err := fs.Glob(t.files, "*.html")
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("no templates found")
+	}
+
+	var layouts, navbars, contents, partials []string
+	for _, file := range files {
+		parsed, err := template.New("").Funcs(templateFuncs()).ParseFS(t.files, file)
+		if err != nil {
+			return fmt.Errorf("template %s: %w", file, err)
+		}
+		switch {
+		case parsed.Lookup("layout") != nil:
+			layouts = append(layouts, file)
+		case parsed.Lookup("navbar") != nil:
+			navbars = append(navbars, file)
+		case parsed.Lookup("content") != nil:
+			contents = append(contents, file)
+		default:
+			partials = append(partials, file)
+		}
+	}
+
+	pages := map[Page]*template.Template{}
+	for _, layout := range layouts {
+		for _, navbar := range navbars {
+			base, err := template.New("").Funcs(templateFuncs()).ParseFS(t.files, append([]string{layout, navbar}, partials...)...)
+			if err != nil {
+				return fmt.Errorf("template %s: %w", layout, err)
+			}
+			for _, content := range contents {
+				clone, err := base.Clone()
+				if err != nil {
+					return err
+				}
+				page, err := clone.ParseFS(t.files, content)
+				if err != nil {
+					return fmt.Errorf("template %s: %w", content, err)
+				}
+				pages[Page{Layout: templateName(layout), Navbar: templateName(navbar), Content: templateName(content)}] = page
+			}
+		}
+	}
+
+	t.mu.Lock()
+	t.pages = pages
+	t.loadedAt = time.Now()
+	t.mu.Unlock()
+	return nil
+}
+
+func templateName(file string) string {
+	return strings.TrimSuffix(path.Base(file), ".html")
+}
+
+// changed reports whether a template file was modified after the last load,
+// embedded files have no modification time and never change
+func (t *Templates) changed() bool {
+	t.mu.RLock()
+	loadedAt := t.loadedAt
+	t.mu.RUnlock()
+
+	files, _ := fs.Glob(t.files, "*.html")
+	for _, file := range files {
+		if info, err := fs.Stat(t.files, file); err == nil && info.ModTime().After(loadedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// Render executes page with data into w. The page is rendered to a buffer
+// first, so nothing is written when it fails.
+func (t *Templates) Render(w io.Writer, page Page, data interface{}) error {
+	if t.dev && t.changed() {
+		if err := t.load(); err != nil {
+			return err
+		}
+	}
+
+	t.mu.RLock()
+	tmpl, ok := t.pages[page]
+	t.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown page %s", page)
+	}
+
+	start := time.Now()
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return fmt.Errorf("render %s: %w", page, err)
+	}
+	metrics.TemplateRender.Observe(time.Since(start).Seconds(), page.Content)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// Render executes page with the templates loaded by LoadTemplates
+func Render(w io.Writer, page Page, data interface{}) error {
+	if templates == nil {
+		return errTemplatesNotLoaded
+	}
+	return templates.Render(w, page, data)
+}
+
+// TemplatesLoaded reports an error until LoadTemplates succeeded
+func TemplatesLoaded() error {
+	if templates == nil {
+		return errTemplatesNotLoaded
+	}
+	return nil
+}
+
+// ServePage renders page as the response, a failed render answers 500
+func ServePage(writer http.ResponseWriter, request *http.Request, page Page, data interface{}) {
+	if err := Render(writer, page, data); err != nil {
+		InternalServerError(writer, request, err)
+	}
+}
+
+// renderError writes the error page after the status is set,
+// falling back to plain text when the page itself cannot be rendered
+func renderError(writer http.ResponseWriter, title, message string, code int) {
+	err := Render(writer, PublicPage("error"), map[string]interface{}{
+		"Title":   title,
+		"Message": message,
+		"Code":    code,
+	})
+	if err != nil {
+		Danger("Failed to render error page:", err)
+		fmt.Fprintf(writer, "%d %s: %s\n", code, title, message)
+	}
+}
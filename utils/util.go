@@ -4,8 +4,8 @@ import (
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
-	"html/template"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"regexp"
@@ -14,14 +14,10 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-var logger *log.Logger
-
 func init() {
-	file, err := os.OpenFile("casual-talk.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		log.Fatalln("Failed to open log file", err)
-	}
-	logger = log.New(file, "[INFO] ", log.Ldate|log.Ltime|log.Lshortfile)
+	// text on stdout until SetupLogging applies the configuration
+	logger, _ := NewLogger(os.Stdout, "text", "info")
+	slog.SetDefault(logger)
 }
 
 // create a random UUID with from RFC 4122
@@ -75,43 +71,6 @@ func PasswordMeetsCriteria(writer http.ResponseWriter, request *http.Request, pa
 	return true
 }
 
-// parse HTML templates
-// pass in a list of file names, and get a template
-func ParseTemplateFiles(filenames ...string) (t *template.Template) {
-	var files []string
-	t = template.New("layout.html")
-	for _, file := range filenames {
-		files = append(files, fmt.Sprintf("templates/%s.html", file))
-	}
-	t = template.Must(t.ParseFiles(files...))
-	return
-}
-
-func GenerateHTML(writer http.ResponseWriter, data interface{}, fn ...string) {
-
-	funcMap := template.FuncMap{
-		"safeHTML": func(s string) template.HTML {
-			return template.HTML(s) // Marks the string as safe HTML (no escaping)
-		},
-		// Add more functions here if needed, e.g., "upper": strings.ToUpper
-	}
-	// Create a new template with functions
-	tmpl := template.New("").Funcs(funcMap)
-
-	var files []string
-	for _, file := range fn {
-		files = append(files, fmt.Sprintf("templates/%s.html", file))
-	}
-	// Always include cookie-consent and lidi templates
-	files = append(files, "templates/cookie-consent.html")
-	// files = append(files, "templates/lidi.html")
-	template := template.Must(tmpl.ParseFiles(files...))
-	err := template.ExecuteTemplate(writer, "layout", data)
-	if err != nil {
-		Danger("Failed to execute template:", err)
-	}
-}
-
 // convenience function to redirect to the error message page
 func ErrorMessage(writer http.ResponseWriter, request *http.Request, msg string) {
 	url := []string{"/err?msg=", msg}
@@ -125,11 +84,7 @@ func BadRequest(writer http.ResponseWriter, request *http.Request, message strin
 		writeJSONError(writer, http.StatusBadRequest, message)
 		return
 	}
-	GenerateHTML(writer, map[string]interface{}{
-		"Title":   "Bad Request",
-		"Message": message,
-		"Code":    400,
-	}, "layout", "public.navbar", "error")
+	renderError(writer, "Bad Request", message, 400)
 }
 
 // Handle 404 Not Found errors
@@ -139,26 +94,18 @@ func NotFound(writer http.ResponseWriter, request *http.Request) {
 		writeJSONError(writer, http.StatusNotFound, "Resource not found")
 		return
 	}
-	GenerateHTML(writer, map[string]interface{}{
-		"Title":   "Page Not Found",
-		"Message": "The page you're looking for doesn't exist.",
-		"Code":    404,
-	}, "layout", "public.navbar", "error")
+	renderError(writer, "Page Not Found", "The page you're looking for doesn't exist.", 404)
 }
 
 // Handle 500 Internal Server Error
 func InternalServerError(writer http.ResponseWriter, request *http.Request, err error) {
-	Danger("Internal server error:", err)
+	slog.ErrorContext(request.Context(), "internal server error", "path", request.URL.Path, "error", err)
 	writer.WriteHeader(http.StatusInternalServerError)
 	if isAPIRequest(request) {
 		writeJSONError(writer, http.StatusInternalServerError, "Internal server error")
 		return
 	}
-	GenerateHTML(writer, map[string]interface{}{
-		"Title":   "Server Error",
-		"Message": "Something went wrong on our end. Please try again later.",
-		"Code":    500,
-	}, "layout", "public.navbar", "error")
+	renderError(writer, "Server Error", "Something went wrong on our end. Please try again later.", 500)
 }
 
 // Handle 405 Method Not Allowed errors
@@ -168,11 +115,7 @@ func MethodNotAllowed(writer http.ResponseWriter, request *http.Request, message
 		writeJSONError(writer, http.StatusMethodNotAllowed, message)
 		return
 	}
-	GenerateHTML(writer, map[string]interface{}{
-		"Title":   "Method Not Allowed",
-		"Message": message,
-		"Code":    405,
-	}, "layout", "public.navbar", "error")
+	renderError(writer, "Method Not Allowed", message, 405)
 }
 
 // Handle 401 Unauthorized errors
@@ -182,11 +125,7 @@ func Unauthorized(writer http.ResponseWriter, request *http.Request, message str
 		writeJSONError(writer, http.StatusUnauthorized, "Authentication required")
 		return
 	}
-	GenerateHTML(writer, map[string]interface{}{
-		"Title":   "Unauthorized",
-		"Message": message,
-		"Code":    401,
-	}, "layout", "public.navbar", "error")
+	renderError(writer, "Unauthorized", message, 401)
 	http.Redirect(writer, request, "/login", http.StatusSeeOther)
 }
 
@@ -197,11 +136,7 @@ func Forbidden(writer http.ResponseWriter, request *http.Request, message string
 		writeJSONError(writer, http.StatusForbidden, message)
 		return
 	}
-	GenerateHTML(writer, map[string]interface{}{
-		"Title":   "Access Forbidden",
-		"Message": message,
-		"Code":    403,
-	}, "layout", "public.navbar", "error")
+	renderError(writer, "Access Forbidden", message, 403)
 }
 
 // Check if the request is an API request
@@ -227,20 +162,3 @@ func FileExists(fileName string) bool {
 	}
 	return true
 }
-
-// log
-func Info(args ...interface{}) {
-	logger.SetPrefix("[INFO] ")
-	logger.Println(args...)
-}
-
-func Danger(args ...interface{}) {
-	fmt.Println("[ERROR]", fmt.Sprintf("%v", args...))
-	logger.SetPrefix("[ERROR] ")
-	logger.Println(args...)
-}
-
-func Warn(args ...interface{}) {
-	logger.SetPrefix("[WARN] ")
-	logger.Println(args...)
-}